@@ -8,6 +8,7 @@ import (
 
 	"github.com/renproject/id"
 	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
 	"github.com/renproject/multichain/api/contract"
 	"github.com/renproject/pack"
 )
@@ -79,8 +80,39 @@ type Client interface {
 	// transaction is invalid, then an error should be returned.
 	Tx(context.Context, pack.Bytes) (Tx, pack.U64, error)
 
+	// TxReceipt returns the outcome of the transaction uniquely identified by
+	// the given transaction hash. The status of the receipt distinguishes
+	// pending, confirmed, and failed transactions. Transactions that cannot be
+	// found must not return an error, and must use TxStatusUnknown instead.
+	TxReceipt(context.Context, pack.Bytes) (confirmation.TxReceipt, error)
+
 	// SubmitTx to the underlying chain. If the transaction cannot be found
 	// before the context is done, or the transaction is invalid, then an error
 	// should be returned.
 	SubmitTx(context.Context, Tx) error
 }
+
+// The ClientAt interface defines the functionality required to read the state
+// of accounts at a specific block. This is needed to reconcile balances at a
+// finalized height, instead of at the latest block.
+type ClientAt interface {
+	// AccountBalanceAt returns the balance of the given account at the given
+	// block.
+	AccountBalanceAt(context.Context, address.Address, contract.BlockRef) (pack.U256, error)
+
+	// AccountNonceAt returns the nonce of the given account at the given
+	// block.
+	AccountNonceAt(context.Context, address.Address, contract.BlockRef) (pack.U256, error)
+}
+
+// The Simulator interface defines the functionality required to simulate a
+// transaction before it is submitted. This allows transactions that would fail
+// to be abandoned before collecting signatures.
+type Simulator interface {
+	// Simulate the execution of the transaction, without submitting it. The
+	// transaction does not need to be signed, unless required by the chain. A
+	// transaction that would fail must not return an error, and must return an
+	// unsuccessful simulation instead. An error should only be returned if the
+	// simulation could not be done.
+	Simulate(context.Context, Tx) (confirmation.Simulation, error)
+}
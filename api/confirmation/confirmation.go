@@ -0,0 +1,349 @@
+// Package confirmation defines a reorg-aware confirmation tracker that can be
+// used with all chains. Chains report the block in which a transaction was
+// included, and the tracker re-checks that block against the longest
+// blockchain on every poll, so that transactions in orphaned blocks are
+// reported as reorged instead of silently keeping their confirmations. The
+// number of confirmations is the same for all chains: one when the
+// transaction is in the latest block, and one more for every block after it.
+package confirmation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultTrackerPollInterval used by the Tracker.
+	DefaultTrackerPollInterval = 15 * time.Second
+	// DefaultTrackerMaxConfirmations used by the Tracker.
+	DefaultTrackerMaxConfirmations = 6
+	// DefaultTrackerDropTimeout used by the Tracker.
+	DefaultTrackerDropTimeout = 30 * time.Minute
+)
+
+// TxStatus is the status of a transaction, and is the same for all chains.
+type TxStatus uint8
+
+const (
+	// TxStatusUnknown is used for transactions that are not known to the
+	// chain (they cannot be found).
+	TxStatusUnknown = TxStatus(iota)
+	// TxStatusPending is used for transactions that are known to the chain,
+	// but that have not been included in a block.
+	TxStatusPending
+	// TxStatusConfirmed is used for transactions that have been included in a
+	// block in the longest blockchain.
+	TxStatusConfirmed
+	// TxStatusReorged is used for transactions that were included in a block
+	// that is no longer in the longest blockchain, and that have not been
+	// included in another block.
+	TxStatusReorged
+	// TxStatusDropped is used for transactions that have not been known to
+	// the chain for longer than the drop timeout. Dropped transactions are no
+	// longer tracked.
+	TxStatusDropped
+	// TxStatusFailed is used for transactions that have been included in a
+	// block in the longest blockchain, but that failed to execute (for
+	// example, because they were reverted). Failed transactions can still
+	// consume fees.
+	TxStatusFailed
+)
+
+// String implements the Stringer interface.
+func (status TxStatus) String() string {
+	switch status {
+	case TxStatusUnknown:
+		return "unknown"
+	case TxStatusPending:
+		return "pending"
+	case TxStatusConfirmed:
+		return "confirmed"
+	case TxStatusReorged:
+		return "reorged"
+	case TxStatusDropped:
+		return "dropped"
+	case TxStatusFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("TxStatus(%d)", uint8(status))
+	}
+}
+
+// An Inclusion is the block in which a transaction was included, as reported
+// by the chain. The status is one of TxStatusUnknown, TxStatusPending, or
+// TxStatusConfirmed. The block hash and height are only set for confirmed
+// transactions.
+type Inclusion struct {
+	Status    TxStatus
+	BlockHash pack.Bytes
+	Height    pack.U64
+}
+
+// The Reader interface defines the functionality required to track the
+// confirmations of transactions on a chain.
+type Reader interface {
+	// LatestBlock returns the height of the longest blockchain.
+	LatestBlock(context.Context) (pack.U64, error)
+
+	// BlockHash returns the hash of the block at the given height in the
+	// longest blockchain.
+	BlockHash(context.Context, pack.U64) (pack.Bytes, error)
+
+	// TxInclusion returns the block in which the transaction with the given
+	// hash was included. Transactions that are not known to the chain must
+	// not return an error, and must use TxStatusUnknown instead.
+	TxInclusion(context.Context, pack.Bytes) (Inclusion, error)
+}
+
+// A TxUpdate is emitted by the Tracker whenever the status of a transaction,
+// the block in which it was included, or its number of confirmations
+// changes. Updates for reorged transactions include the block from which the
+// transaction was removed.
+type TxUpdate struct {
+	TxHash        pack.Bytes
+	Status        TxStatus
+	BlockHash     pack.Bytes
+	Height        pack.U64
+	Confirmations pack.U64
+}
+
+// TrackerOptions are used to parameterise the behaviour of the Tracker.
+type TrackerOptions struct {
+	// PollInterval is the time between polls, when the tracker is running.
+	PollInterval time.Duration
+	// MaxConfirmations is the number of confirmations after which a
+	// transaction is considered final. Final transactions are no longer
+	// tracked.
+	MaxConfirmations pack.U64
+	// DropTimeout is the time for which a transaction can be unknown to the
+	// chain before it is dropped.
+	DropTimeout time.Duration
+}
+
+// DefaultTrackerOptions returns TrackerOptions with the default settings.
+func DefaultTrackerOptions() TrackerOptions {
+	return TrackerOptions{
+		PollInterval:     DefaultTrackerPollInterval,
+		MaxConfirmations: pack.NewU64(DefaultTrackerMaxConfirmations),
+		DropTimeout:      DefaultTrackerDropTimeout,
+	}
+}
+
+// WithPollInterval sets the time between polls.
+func (opts TrackerOptions) WithPollInterval(pollInterval time.Duration) TrackerOptions {
+	opts.PollInterval = pollInterval
+	return opts
+}
+
+// WithMaxConfirmations sets the number of confirmations after which a
+// transaction is considered final.
+func (opts TrackerOptions) WithMaxConfirmations(maxConfirmations pack.U64) TrackerOptions {
+	opts.MaxConfirmations = maxConfirmations
+	return opts
+}
+
+// WithDropTimeout sets the time for which a transaction can be unknown to the
+// chain before it is dropped.
+func (opts TrackerOptions) WithDropTimeout(dropTimeout time.Duration) TrackerOptions {
+	opts.DropTimeout = dropTimeout
+	return opts
+}
+
+// trackedTx is a transaction that is tracked by the Tracker.
+type trackedTx struct {
+	update   TxUpdate
+	lastSeen time.Time
+}
+
+// The Tracker polls a chain for the status of transactions, and emits updates
+// whenever they change. It is safe for concurrent use.
+type Tracker struct {
+	opts   TrackerOptions
+	reader Reader
+
+	mu  *sync.Mutex
+	txs map[string]*trackedTx
+}
+
+// NewTracker returns a Tracker that reads transactions using the given
+// reader. No transactions are tracked until they are added.
+func NewTracker(reader Reader, opts TrackerOptions) *Tracker {
+	return &Tracker{
+		opts:   opts,
+		reader: reader,
+
+		mu:  new(sync.Mutex),
+		txs: map[string]*trackedTx{},
+	}
+}
+
+// Track the transaction with the given hash. Tracking a transaction that is
+// already tracked does nothing.
+func (tracker *Tracker) Track(txHash pack.Bytes) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if _, ok := tracker.txs[string(txHash)]; ok {
+		return
+	}
+	tracker.txs[string(txHash)] = &trackedTx{
+		update:   TxUpdate{TxHash: txHash, Status: TxStatusUnknown},
+		lastSeen: time.Now(),
+	}
+}
+
+// Untrack the transaction with the given hash.
+func (tracker *Tracker) Untrack(txHash pack.Bytes) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	delete(tracker.txs, string(txHash))
+}
+
+// Status returns the latest update for the transaction with the given hash.
+// It returns false if the transaction is not tracked.
+func (tracker *Tracker) Status(txHash pack.Bytes) (TxUpdate, bool) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tx, ok := tracker.txs[string(txHash)]
+	if !ok {
+		return TxUpdate{}, false
+	}
+	return tx.update, true
+}
+
+// Run the Tracker, polling at the poll interval and sending updates to the
+// channel, until the context is done. Errors from polling are ignored,
+// because transactions that could not be checked are checked again by the
+// next poll. Use Poll directly to handle errors.
+func (tracker *Tracker) Run(ctx context.Context, updates chan<- TxUpdate) error {
+	ticker := time.NewTicker(tracker.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		newUpdates, _ := tracker.Poll(ctx)
+		for _, update := range newUpdates {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case updates <- update:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks all tracked transactions, and returns the updates that have
+// happened since the last poll. Transactions that are final, or that have
+// been dropped, are no longer tracked. If an error is returned, the updates
+// that happened before the error are still returned.
+func (tracker *Tracker) Poll(ctx context.Context) ([]TxUpdate, error) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	updates := []TxUpdate{}
+	tip, err := tracker.reader.LatestBlock(ctx)
+	if err != nil {
+		return updates, fmt.Errorf("latest block: %v", err)
+	}
+
+	keys := make([]string, 0, len(tracker.txs))
+	for key := range tracker.txs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		newUpdates, err := tracker.check(ctx, tracker.txs[key], tip)
+		updates = append(updates, newUpdates...)
+		if err != nil {
+			return updates, err
+		}
+		switch update := tracker.txs[key].update; update.Status {
+		case TxStatusConfirmed:
+			if update.Confirmations >= tracker.opts.MaxConfirmations {
+				delete(tracker.txs, key)
+			}
+		case TxStatusDropped:
+			delete(tracker.txs, key)
+		}
+	}
+	return updates, nil
+}
+
+// check the transaction against the longest blockchain, and return the
+// updates that have happened since it was last checked.
+func (tracker *Tracker) check(ctx context.Context, tx *trackedTx, tip pack.U64) ([]TxUpdate, error) {
+	updates := []TxUpdate{}
+	txHash := tx.update.TxHash
+	inclusion, err := tracker.reader.TxInclusion(ctx, txHash)
+	if err != nil {
+		return updates, fmt.Errorf("tx inclusion %x: %v", []byte(txHash), err)
+	}
+	if inclusion.Status == TxStatusConfirmed {
+		// Indices used to look up transactions are not always updated at the
+		// same time as the longest blockchain, so the block is checked too.
+		hash, err := tracker.reader.BlockHash(ctx, inclusion.Height)
+		if err != nil {
+			return updates, fmt.Errorf("block hash %v: %v", inclusion.Height, err)
+		}
+		if !bytes.Equal(hash, inclusion.BlockHash) {
+			inclusion = Inclusion{Status: TxStatusPending}
+		}
+	}
+
+	// Transactions that are no longer in the block in which they were
+	// included have been reorged.
+	if tx.update.Status == TxStatusConfirmed && (inclusion.Status != TxStatusConfirmed || !bytes.Equal(inclusion.BlockHash, tx.update.BlockHash)) {
+		tx.update.Status = TxStatusReorged
+		tx.update.Confirmations = pack.NewU64(0)
+		updates = append(updates, tx.update)
+	}
+
+	update := tx.update
+	switch inclusion.Status {
+	case TxStatusConfirmed:
+		tx.lastSeen = time.Now()
+		update = TxUpdate{
+			TxHash:        txHash,
+			Status:        TxStatusConfirmed,
+			BlockHash:     inclusion.BlockHash,
+			Height:        inclusion.Height,
+			Confirmations: Confirmations(inclusion.Height, tip),
+		}
+	case TxStatusPending:
+		tx.lastSeen = time.Now()
+		if update.Status != TxStatusReorged {
+			update = TxUpdate{TxHash: txHash, Status: TxStatusPending}
+		}
+	default:
+		if time.Since(tx.lastSeen) >= tracker.opts.DropTimeout {
+			update = TxUpdate{TxHash: txHash, Status: TxStatusDropped}
+		}
+	}
+	if update.Status != tx.update.Status || update.Confirmations != tx.update.Confirmations || !bytes.Equal(update.BlockHash, tx.update.BlockHash) {
+		tx.update = update
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
+
+// Confirmations returns the number of confirmations of a block at the given
+// height, when the longest blockchain is at the given tip.
+func Confirmations(height, tip pack.U64) pack.U64 {
+	if height > tip {
+		return pack.NewU64(0)
+	}
+	return tip - height + 1
+}
@@ -0,0 +1,13 @@
+package confirmation_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestConfirmation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Confirmation Suite")
+}
@@ -0,0 +1,193 @@
+package confirmation_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// chain is an in-memory Reader. Blocks are identified by their fork and
+// height, so that blocks on different forks have different hashes.
+type chain struct {
+	blocks  []pack.Bytes
+	pending map[string]bool
+	txs     map[string]int
+}
+
+func newChain() *chain {
+	return &chain{
+		blocks:  []pack.Bytes{pack.Bytes("genesis")},
+		pending: map[string]bool{},
+		txs:     map[string]int{},
+	}
+}
+
+func (chain *chain) mine(fork string, txs ...string) {
+	for _, tx := range txs {
+		delete(chain.pending, tx)
+		chain.txs[tx] = len(chain.blocks)
+	}
+	chain.blocks = append(chain.blocks, pack.Bytes(fmt.Sprintf("%v/%v", fork, len(chain.blocks))))
+}
+
+func (chain *chain) reorg(height int) {
+	for tx, txHeight := range chain.txs {
+		if txHeight >= height {
+			delete(chain.txs, tx)
+			chain.pending[tx] = true
+		}
+	}
+	chain.blocks = chain.blocks[:height]
+}
+
+func (chain *chain) LatestBlock(ctx context.Context) (pack.U64, error) {
+	return pack.NewU64(uint64(len(chain.blocks) - 1)), nil
+}
+
+func (chain *chain) BlockHash(ctx context.Context, height pack.U64) (pack.Bytes, error) {
+	if int(height) >= len(chain.blocks) {
+		return nil, fmt.Errorf("block %v: not found", height)
+	}
+	return chain.blocks[height], nil
+}
+
+func (chain *chain) TxInclusion(ctx context.Context, txHash pack.Bytes) (confirmation.Inclusion, error) {
+	if height, ok := chain.txs[string(txHash)]; ok {
+		return confirmation.Inclusion{
+			Status:    confirmation.TxStatusConfirmed,
+			BlockHash: chain.blocks[height],
+			Height:    pack.NewU64(uint64(height)),
+		}, nil
+	}
+	if chain.pending[string(txHash)] {
+		return confirmation.Inclusion{Status: confirmation.TxStatusPending}, nil
+	}
+	return confirmation.Inclusion{Status: confirmation.TxStatusUnknown}, nil
+}
+
+var _ = Describe("Tracker", func() {
+	ctx := context.Background()
+
+	Context("when a transaction is included in a block", func() {
+		It("should update its confirmations until it is final", func() {
+			chain := newChain()
+			chain.pending["tx"] = true
+
+			tracker := confirmation.NewTracker(chain, confirmation.DefaultTrackerOptions().WithMaxConfirmations(pack.NewU64(3)))
+			tracker.Track(pack.Bytes("tx"))
+			updates, err := tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(HaveLen(1))
+			Expect(updates[0].Status).To(Equal(confirmation.TxStatusPending))
+
+			chain.mine("a", "tx")
+			updates, err = tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(HaveLen(1))
+			Expect(updates[0].Status).To(Equal(confirmation.TxStatusConfirmed))
+			Expect(updates[0].BlockHash).To(Equal(pack.Bytes("a/1")))
+			Expect(updates[0].Confirmations).To(Equal(pack.NewU64(1)))
+
+			// Nothing has changed, so there are no updates.
+			updates, err = tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(BeEmpty())
+
+			chain.mine("a")
+			chain.mine("a")
+			updates, err = tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(HaveLen(1))
+			Expect(updates[0].Confirmations).To(Equal(pack.NewU64(3)))
+
+			// The transaction is final, so it is no longer tracked.
+			_, ok := tracker.Status(pack.Bytes("tx"))
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("when the block that included a transaction is orphaned", func() {
+		It("should report the transaction as reorged until it is included again", func() {
+			chain := newChain()
+			chain.mine("a", "tx")
+			chain.mine("a")
+
+			tracker := confirmation.NewTracker(chain, confirmation.DefaultTrackerOptions())
+			tracker.Track(pack.Bytes("tx"))
+			updates, err := tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(HaveLen(1))
+			Expect(updates[0].Confirmations).To(Equal(pack.NewU64(2)))
+
+			chain.reorg(1)
+			chain.mine("b")
+			chain.mine("b")
+			updates, err = tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(HaveLen(1))
+			Expect(updates[0].Status).To(Equal(confirmation.TxStatusReorged))
+			Expect(updates[0].BlockHash).To(Equal(pack.Bytes("a/1")))
+			Expect(updates[0].Confirmations).To(Equal(pack.NewU64(0)))
+
+			// The transaction is pending again, but it is still reported as
+			// reorged.
+			updates, err = tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(BeEmpty())
+
+			chain.mine("b", "tx")
+			updates, err = tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(HaveLen(1))
+			Expect(updates[0].Status).To(Equal(confirmation.TxStatusConfirmed))
+			Expect(updates[0].BlockHash).To(Equal(pack.Bytes("b/3")))
+			Expect(updates[0].Confirmations).To(Equal(pack.NewU64(1)))
+		})
+
+		It("should report the reorg and the new block, if it is included in another block", func() {
+			chain := newChain()
+			chain.mine("a", "tx")
+
+			tracker := confirmation.NewTracker(chain, confirmation.DefaultTrackerOptions())
+			tracker.Track(pack.Bytes("tx"))
+			_, err := tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			chain.reorg(1)
+			chain.mine("b", "tx")
+			updates, err := tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(HaveLen(2))
+			Expect(updates[0].Status).To(Equal(confirmation.TxStatusReorged))
+			Expect(updates[0].BlockHash).To(Equal(pack.Bytes("a/1")))
+			Expect(updates[1].Status).To(Equal(confirmation.TxStatusConfirmed))
+			Expect(updates[1].BlockHash).To(Equal(pack.Bytes("b/1")))
+		})
+	})
+
+	Context("when a transaction is unknown for longer than the drop timeout", func() {
+		It("should report the transaction as dropped", func() {
+			chain := newChain()
+
+			tracker := confirmation.NewTracker(chain, confirmation.DefaultTrackerOptions().WithDropTimeout(100*time.Millisecond))
+			tracker.Track(pack.Bytes("tx"))
+			updates, err := tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(BeEmpty())
+
+			time.Sleep(100 * time.Millisecond)
+			updates, err = tracker.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updates).To(HaveLen(1))
+			Expect(updates[0].Status).To(Equal(confirmation.TxStatusDropped))
+			_, ok := tracker.Status(pack.Bytes("tx"))
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
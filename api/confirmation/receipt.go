@@ -0,0 +1,80 @@
+package confirmation
+
+import (
+	"errors"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/pack"
+)
+
+var (
+	// ErrTxNotFound is returned when a transaction is not known to the chain.
+	ErrTxNotFound = errors.New("tx not found")
+	// ErrTxPending is returned when a transaction is known to the chain, but
+	// has not been included in a block.
+	ErrTxPending = errors.New("tx is pending")
+	// ErrTxFailed is returned when a transaction has been included in a
+	// block, but failed to execute.
+	ErrTxFailed = errors.New("tx failed")
+)
+
+// A TxReceipt is the outcome of a transaction, and is the same for all chains.
+// The status is one of TxStatusUnknown (the transaction cannot be found),
+// TxStatusPending, TxStatusConfirmed, or TxStatusFailed. The block hash,
+// height, and number of confirmations are only set for confirmed and failed
+// transactions. The gas used and the revert reason are only set by chains
+// that support them. The contract address is only set for transactions that
+// deployed a contract.
+type TxReceipt struct {
+	TxHash          pack.Bytes
+	Status          TxStatus
+	BlockHash       pack.Bytes
+	Height          pack.U64
+	Confirmations   pack.U64
+	GasUsed         pack.U256
+	RevertReason    pack.String
+	ContractAddress address.Address
+}
+
+// NewTxReceipt returns the receipt for a transaction that was included in the
+// given block (or not), when the longest blockchain is at the given tip. It
+// is used by chains for which transactions cannot fail once they have been
+// included in a block.
+func NewTxReceipt(txHash pack.Bytes, inclusion Inclusion, tip pack.U64) TxReceipt {
+	if inclusion.Status != TxStatusConfirmed {
+		return TxReceipt{TxHash: txHash, Status: inclusion.Status}
+	}
+	return TxReceipt{
+		TxHash:        txHash,
+		Status:        TxStatusConfirmed,
+		BlockHash:     inclusion.BlockHash,
+		Height:        inclusion.Height,
+		Confirmations: Confirmations(inclusion.Height, tip),
+	}
+}
+
+// Err returns the error that corresponds to the status of the receipt, so
+// that it can be checked using errors.Is. Confirmed transactions do not have
+// an error.
+func (receipt TxReceipt) Err() error {
+	switch receipt.Status {
+	case TxStatusConfirmed:
+		return nil
+	case TxStatusPending:
+		return ErrTxPending
+	case TxStatusFailed:
+		return ErrTxFailed
+	default:
+		return ErrTxNotFound
+	}
+}
+
+// A Simulation is the outcome of executing a transaction without submitting
+// it, and is the same for all chains. If the transaction would fail, success
+// is false and the revert reason explains why. The gas used is only set by
+// chains that support it.
+type Simulation struct {
+	Success      bool
+	GasUsed      pack.U256
+	RevertReason pack.String
+}
@@ -5,6 +5,7 @@ package contract
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/renproject/multichain/api/address"
 	"github.com/renproject/pack"
@@ -44,3 +45,81 @@ type Caller interface {
 	// error should be returned.
 	CallContract(context.Context, address.Address, CallData) (pack.Bytes, error)
 }
+
+// BlockTag identifies a block by its position relative to the head of the
+// chain, instead of by its height.
+type BlockTag uint8
+
+const (
+	// BlockTagLatest is the latest block.
+	BlockTagLatest = BlockTag(iota)
+	// BlockTagSafe is the latest block that is unlikely to be reorged. Chains
+	// that do not distinguish safe blocks use their finalized block.
+	BlockTagSafe
+	// BlockTagFinalized is the latest block that cannot be reorged. Chains
+	// with instant finality use their latest block.
+	BlockTagFinalized
+	// BlockTagHeight is the block at a specific height.
+	BlockTagHeight
+)
+
+// String implements the Stringer interface.
+func (tag BlockTag) String() string {
+	switch tag {
+	case BlockTagLatest:
+		return "latest"
+	case BlockTagSafe:
+		return "safe"
+	case BlockTagFinalized:
+		return "finalized"
+	case BlockTagHeight:
+		return "height"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(tag))
+	}
+}
+
+// A BlockRef identifies the block at which state is read. The height is only
+// used when the tag is BlockTagHeight. The zero value is the latest block.
+type BlockRef struct {
+	Tag    BlockTag
+	Height pack.U64
+}
+
+// LatestBlock returns a reference to the latest block.
+func LatestBlock() BlockRef {
+	return BlockRef{Tag: BlockTagLatest}
+}
+
+// SafeBlock returns a reference to the latest safe block.
+func SafeBlock() BlockRef {
+	return BlockRef{Tag: BlockTagSafe}
+}
+
+// FinalizedBlock returns a reference to the latest finalized block.
+func FinalizedBlock() BlockRef {
+	return BlockRef{Tag: BlockTagFinalized}
+}
+
+// BlockAtHeight returns a reference to the block at the height.
+func BlockAtHeight(height pack.U64) BlockRef {
+	return BlockRef{Tag: BlockTagHeight, Height: height}
+}
+
+// String implements the Stringer interface.
+func (ref BlockRef) String() string {
+	if ref.Tag == BlockTagHeight {
+		return fmt.Sprintf("%d", ref.Height.Uint64())
+	}
+	return ref.Tag.String()
+}
+
+// The CallerAt interface defines the functionality required to call readonly
+// functions on a contract, using the state of the chain at a specific block.
+type CallerAt interface {
+	// CallContractAt is the same as CallContract, but reads the state of the
+	// chain at the given block. If the chain cannot read the state at the
+	// block (for example, because the node has pruned it), then an error
+	// should be returned.
+	CallContractAt(context.Context, address.Address, CallData, BlockRef) (pack.Bytes, error)
+}
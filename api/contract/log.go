@@ -0,0 +1,247 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultLogFiltererMaxBlockRange is the largest number of blocks filtered
+	// by a single request by default. Most providers reject requests for larger
+	// ranges.
+	DefaultLogFiltererMaxBlockRange = 2000
+	// DefaultLogFiltererConfirmations is the number of confirmations that a
+	// block must have by default before its logs are returned.
+	DefaultLogFiltererConfirmations = 1
+)
+
+// ErrLogRangeTooLarge is returned by a LogReader when the block range of a
+// filter is too large, or when the filter matches too many logs, to be
+// returned by a single request. Readers must wrap it in their errors, so that
+// the LogFilterer returned by NewLogFilterer knows to split the range.
+var ErrLogRangeTooLarge = errors.New("log range too large")
+
+// A Log is an event that is emitted by a contract when a transaction is
+// executed. The meaning of the topics and data is defined by the chain: EVM
+// chains use the topics and data of the log; Cosmos chains use the event type
+// as the only topic, and the JSON-encoded attributes as the data; Solana uses
+// the kind of message as the only topic, and the message as the data.
+type Log struct {
+	Address     address.Address
+	Topics      []pack.Bytes
+	Data        pack.Bytes
+	BlockNumber pack.U64
+	BlockHash   pack.Bytes
+	TxHash      pack.Bytes
+	Index       pack.U32
+}
+
+// A LogFilter defines the logs that are returned by a LogFilterer. Logs are
+// returned if they are in a block between the from block and the to block
+// (inclusive), were emitted by one of the addresses, and match the topics. An
+// empty list of addresses matches all addresses. The topics are matched by
+// position, and a log matches a position if its topic is any of the topics at
+// that position. An empty list of topics at a position matches any topic.
+type LogFilter struct {
+	FromBlock pack.U64
+	ToBlock   pack.U64
+	Addresses []address.Address
+	Topics    [][]pack.Bytes
+}
+
+// Match returns true if the log matches the addresses and topics of the
+// filter. The block range is not checked.
+func (filter LogFilter) Match(log Log) bool {
+	if len(filter.Addresses) > 0 {
+		found := false
+		for _, addr := range filter.Addresses {
+			if addr == log.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for i, topics := range filter.Topics {
+		if len(topics) == 0 {
+			continue
+		}
+		if i >= len(log.Topics) {
+			return false
+		}
+		found := false
+		for _, topic := range topics {
+			if bytes.Equal(topic, log.Topics[i]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// The LogFilterer interface defines the functionality required to read the
+// logs emitted by contracts.
+type LogFilterer interface {
+	// FilterLogs returns the logs that match the filter, ordered by block and
+	// then by index.
+	FilterLogs(context.Context, LogFilter) ([]Log, error)
+}
+
+// A LogResult is the result of filtering logs in confirmed blocks. Filters
+// are only applied up to the last confirmed block, so blocks from the next
+// block onwards have not been filtered yet, and must be filtered again later
+// (even if they are before the to block of the filter).
+type LogResult struct {
+	Logs      []Log
+	NextBlock pack.U64
+}
+
+// The ConfirmedLogFilterer interface defines the functionality required to
+// read the logs emitted by contracts in blocks that have enough
+// confirmations.
+type ConfirmedLogFilterer interface {
+	// FilterLogs returns the logs that match the filter in confirmed blocks,
+	// ordered by block and then by index, and the next block that has not
+	// been filtered.
+	FilterLogs(context.Context, LogFilter) (LogResult, error)
+}
+
+// The LogReader interface defines the functionality required by the
+// ConfirmedLogFilterer returned by NewLogFilterer.
+type LogReader interface {
+	LogFilterer
+
+	// LatestBlock returns the height of the longest blockchain.
+	LatestBlock(context.Context) (pack.U64, error)
+}
+
+// LogFiltererOptions are used to parameterise the behaviour of the
+// ConfirmedLogFilterer returned by NewLogFilterer.
+type LogFiltererOptions struct {
+	// MaxBlockRange is the largest number of blocks filtered by a single
+	// request.
+	MaxBlockRange pack.U64
+	// Confirmations is the number of confirmations that a block must have
+	// before its logs are returned. Zero returns logs in all blocks.
+	Confirmations pack.U64
+}
+
+// DefaultLogFiltererOptions returns LogFiltererOptions with the default
+// settings.
+func DefaultLogFiltererOptions() LogFiltererOptions {
+	return LogFiltererOptions{
+		MaxBlockRange: pack.NewU64(DefaultLogFiltererMaxBlockRange),
+		Confirmations: pack.NewU64(DefaultLogFiltererConfirmations),
+	}
+}
+
+// WithMaxBlockRange sets the largest number of blocks filtered by a single
+// request.
+func (opts LogFiltererOptions) WithMaxBlockRange(maxBlockRange pack.U64) LogFiltererOptions {
+	opts.MaxBlockRange = maxBlockRange
+	return opts
+}
+
+// WithConfirmations sets the number of confirmations that a block must have
+// before its logs are returned.
+func (opts LogFiltererOptions) WithConfirmations(confirmations pack.U64) LogFiltererOptions {
+	opts.Confirmations = confirmations
+	return opts
+}
+
+type logFilterer struct {
+	opts   LogFiltererOptions
+	reader LogReader
+}
+
+// NewLogFilterer returns a ConfirmedLogFilterer that splits the block range of
+// filters into chunks of at most the max block range, and only filters blocks
+// that have enough confirmations. A to block of zero is the latest block. If a
+// chunk cannot be filtered because its range is too large, or it has too many
+// logs (see ErrLogRangeTooLarge), it is split in half and filtered again,
+// until it has a single block. Other errors are returned immediately.
+func NewLogFilterer(reader LogReader, opts LogFiltererOptions) ConfirmedLogFilterer {
+	return &logFilterer{
+		opts:   opts,
+		reader: reader,
+	}
+}
+
+// FilterLogs implements the ConfirmedLogFilterer interface.
+func (filterer *logFilterer) FilterLogs(ctx context.Context, filter LogFilter) (LogResult, error) {
+	tip, err := filterer.reader.LatestBlock(ctx)
+	if err != nil {
+		return LogResult{}, fmt.Errorf("latest block: %v", err)
+	}
+	toBlock := filter.ToBlock
+	if toBlock == 0 || toBlock > tip {
+		toBlock = tip
+	}
+	if filterer.opts.Confirmations > 0 {
+		if tip+1 < filterer.opts.Confirmations {
+			return LogResult{Logs: []Log{}, NextBlock: filter.FromBlock}, nil
+		}
+		if confirmed := tip + 1 - filterer.opts.Confirmations; toBlock > confirmed {
+			toBlock = confirmed
+		}
+	}
+	if filter.FromBlock > toBlock {
+		return LogResult{Logs: []Log{}, NextBlock: filter.FromBlock}, nil
+	}
+
+	maxBlockRange := filterer.opts.MaxBlockRange
+	if maxBlockRange == 0 {
+		maxBlockRange = pack.NewU64(DefaultLogFiltererMaxBlockRange)
+	}
+	logs := []Log{}
+	for from := filter.FromBlock; from <= toBlock; {
+		to := toBlock
+		if toBlock-from >= maxBlockRange {
+			to = from + maxBlockRange - 1
+		}
+		chunk, err := filterer.filterChunk(ctx, filter, from, to)
+		if err != nil {
+			return LogResult{}, err
+		}
+		logs = append(logs, chunk...)
+		if to == toBlock {
+			break
+		}
+		from = to + 1
+	}
+	return LogResult{Logs: logs, NextBlock: toBlock + 1}, nil
+}
+
+// filterChunk returns the logs between the blocks (inclusive), and splits the
+// blocks in half if their range is too large.
+func (filterer *logFilterer) filterChunk(ctx context.Context, filter LogFilter, from, to pack.U64) ([]Log, error) {
+	filter.FromBlock, filter.ToBlock = from, to
+	logs, err := filterer.reader.FilterLogs(ctx, filter)
+	if err == nil {
+		return logs, nil
+	}
+	if from == to || ctx.Err() != nil || !errors.Is(err, ErrLogRangeTooLarge) {
+		return nil, fmt.Errorf("filtering logs in blocks %v to %v: %v", from, to, err)
+	}
+	mid := from + (to-from)/2
+	lower, err := filterer.filterChunk(ctx, filter, from, mid)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := filterer.filterChunk(ctx, filter, mid+1, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(lower, upper...), nil
+}
@@ -0,0 +1,148 @@
+package contract_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// logReader is an in-memory LogReader with one log per block. It records the
+// block ranges that are filtered, rejects ranges that are larger than its
+// limit, and fails with its error if it is not nil.
+type logReader struct {
+	tip    pack.U64
+	limit  pack.U64
+	err    error
+	ranges [][2]pack.U64
+}
+
+func (reader *logReader) LatestBlock(ctx context.Context) (pack.U64, error) {
+	return reader.tip, nil
+}
+
+func (reader *logReader) FilterLogs(ctx context.Context, filter contract.LogFilter) ([]contract.Log, error) {
+	reader.ranges = append(reader.ranges, [2]pack.U64{filter.FromBlock, filter.ToBlock})
+	if reader.limit > 0 && filter.ToBlock-filter.FromBlock+1 > reader.limit {
+		return nil, fmt.Errorf("query returned more than %v results: %w", reader.limit, contract.ErrLogRangeTooLarge)
+	}
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	logs := []contract.Log{}
+	for block := filter.FromBlock; block <= filter.ToBlock; block++ {
+		logs = append(logs, contract.Log{BlockNumber: block})
+	}
+	return logs, nil
+}
+
+func blockNumbers(logs []contract.Log) []pack.U64 {
+	blocks := make([]pack.U64, len(logs))
+	for i, log := range logs {
+		blocks[i] = log.BlockNumber
+	}
+	return blocks
+}
+
+var _ = Describe("Logs", func() {
+	ctx := context.Background()
+
+	Context("when filtering logs over a large range", func() {
+		It("should split the range into chunks", func() {
+			reader := &logReader{tip: 100}
+			filterer := contract.NewLogFilterer(reader, contract.DefaultLogFiltererOptions().WithMaxBlockRange(4).WithConfirmations(0))
+
+			result, err := filterer.FilterLogs(ctx, contract.LogFilter{FromBlock: 1, ToBlock: 10})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumbers(result.Logs)).To(Equal([]pack.U64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}))
+			Expect(result.NextBlock).To(Equal(pack.U64(11)))
+			Expect(reader.ranges).To(Equal([][2]pack.U64{{1, 4}, {5, 8}, {9, 10}}))
+		})
+
+		It("should split chunks that cannot be filtered", func() {
+			reader := &logReader{tip: 100, limit: 2}
+			filterer := contract.NewLogFilterer(reader, contract.DefaultLogFiltererOptions().WithMaxBlockRange(8).WithConfirmations(0))
+
+			result, err := filterer.FilterLogs(ctx, contract.LogFilter{FromBlock: 1, ToBlock: 8})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumbers(result.Logs)).To(Equal([]pack.U64{1, 2, 3, 4, 5, 6, 7, 8}))
+		})
+
+		It("should not split chunks that fail for other reasons", func() {
+			reader := &logReader{tip: 100, err: fmt.Errorf("connection refused")}
+			filterer := contract.NewLogFilterer(reader, contract.DefaultLogFiltererOptions().WithMaxBlockRange(8).WithConfirmations(0))
+
+			_, err := filterer.FilterLogs(ctx, contract.LogFilter{FromBlock: 1, ToBlock: 8})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("connection refused"))
+			Expect(reader.ranges).To(Equal([][2]pack.U64{{1, 8}}))
+		})
+	})
+
+	Context("when filtering logs near the tip", func() {
+		It("should only return logs in confirmed blocks", func() {
+			reader := &logReader{tip: 10}
+			filterer := contract.NewLogFilterer(reader, contract.DefaultLogFiltererOptions().WithConfirmations(3))
+
+			result, err := filterer.FilterLogs(ctx, contract.LogFilter{FromBlock: 5})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumbers(result.Logs)).To(Equal([]pack.U64{5, 6, 7, 8}))
+			Expect(result.NextBlock).To(Equal(pack.U64(9)))
+
+			result, err = filterer.FilterLogs(ctx, contract.LogFilter{FromBlock: 9, ToBlock: 10})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Logs).To(BeEmpty())
+			Expect(result.NextBlock).To(Equal(pack.U64(9)))
+		})
+
+		It("should return the next block that has not been filtered", func() {
+			reader := &logReader{tip: 10}
+			filterer := contract.NewLogFilterer(reader, contract.DefaultLogFiltererOptions().WithConfirmations(3))
+
+			// The to block is not confirmed, so the filter stops early.
+			result, err := filterer.FilterLogs(ctx, contract.LogFilter{FromBlock: 5, ToBlock: 20})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumbers(result.Logs)).To(Equal([]pack.U64{5, 6, 7, 8}))
+			Expect(result.NextBlock).To(Equal(pack.U64(9)))
+
+			// Filtering again from the next block returns the logs that were
+			// skipped, once their blocks are confirmed.
+			reader.tip = 15
+			result, err = filterer.FilterLogs(ctx, contract.LogFilter{FromBlock: result.NextBlock, ToBlock: 20})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockNumbers(result.Logs)).To(Equal([]pack.U64{9, 10, 11, 12, 13}))
+			Expect(result.NextBlock).To(Equal(pack.U64(14)))
+		})
+
+		It("should not filter any blocks before the first block is confirmed", func() {
+			reader := &logReader{tip: 1}
+			filterer := contract.NewLogFilterer(reader, contract.DefaultLogFiltererOptions().WithConfirmations(3))
+
+			result, err := filterer.FilterLogs(ctx, contract.LogFilter{FromBlock: 0})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Logs).To(BeEmpty())
+			Expect(result.NextBlock).To(Equal(pack.U64(0)))
+			Expect(reader.ranges).To(BeEmpty())
+		})
+	})
+
+	Context("when matching logs", func() {
+		It("should match addresses and topics by position", func() {
+			log := contract.Log{
+				Address: address.Address("a"),
+				Topics:  []pack.Bytes{pack.Bytes("t0"), pack.Bytes("t1")},
+			}
+			Expect(contract.LogFilter{}.Match(log)).To(BeTrue())
+			Expect(contract.LogFilter{Addresses: []address.Address{"b", "a"}}.Match(log)).To(BeTrue())
+			Expect(contract.LogFilter{Addresses: []address.Address{"b"}}.Match(log)).To(BeFalse())
+			Expect(contract.LogFilter{Topics: [][]pack.Bytes{nil, {pack.Bytes("x"), pack.Bytes("t1")}}}.Match(log)).To(BeTrue())
+			Expect(contract.LogFilter{Topics: [][]pack.Bytes{{pack.Bytes("t1")}}}.Match(log)).To(BeFalse())
+			Expect(contract.LogFilter{Topics: [][]pack.Bytes{nil, nil, {pack.Bytes("t2")}}}.Match(log)).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,400 @@
+package utxo
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/pack"
+)
+
+// A CoinSelectionStrategy decides which outputs are spent by a transaction.
+type CoinSelectionStrategy uint8
+
+const (
+	// BranchAndBound searches for a set of outputs that exactly covers the
+	// recipients and the fee (within the cost of creating, and eventually
+	// spending, a change output), so that no change output is needed. If no
+	// such set exists, it falls back to Knapsack.
+	BranchAndBound CoinSelectionStrategy = iota
+	// LargestFirst spends the largest outputs first, until the recipients and
+	// the fee are covered. It minimises the number of inputs.
+	LargestFirst
+	// Knapsack randomly searches for the set of outputs that covers the
+	// recipients and the fee with the least excess. The search is seeded, so
+	// that all nodes in a distributed network select the same outputs.
+	Knapsack
+)
+
+// String implements the fmt.Stringer interface.
+func (strategy CoinSelectionStrategy) String() string {
+	switch strategy {
+	case BranchAndBound:
+		return "BranchAndBound"
+	case LargestFirst:
+		return "LargestFirst"
+	case Knapsack:
+		return "Knapsack"
+	default:
+		return fmt.Sprintf("CoinSelectionStrategy(%d)", uint8(strategy))
+	}
+}
+
+// SizeParams describe the (virtual) size of transactions on a chain, in bytes.
+// They are used to compute the fee that must be paid by a transaction when
+// selecting coins. Sizes should be upper bounds, so that transactions are
+// never under-paid.
+type SizeParams struct {
+	// BaseSize of a transaction with no inputs and no outputs.
+	BaseSize uint64
+	// InputSize of a signed input.
+	InputSize uint64
+	// OutputSize of an output.
+	OutputSize uint64
+}
+
+const (
+	// DefaultCoinSelectionStrategy used by the CoinSelector.
+	DefaultCoinSelectionStrategy = BranchAndBound
+	// DefaultDustThreshold below which change outputs are not created (the
+	// value is paid as a fee instead).
+	DefaultDustThreshold = 546
+	// DefaultMaxTries is the maximum number of branches that are explored by
+	// BranchAndBound, and the number of iterations used by Knapsack.
+	DefaultMaxTries = 100000
+	// DefaultSeed used by Knapsack.
+	DefaultSeed = 0
+)
+
+var (
+	// DefaultSizeParams are the sizes of P2PKH transactions on Bitcoin-like
+	// chains. Chains should provide their own SizeParams.
+	DefaultSizeParams = SizeParams{BaseSize: 10, InputSize: 148, OutputSize: 34}
+)
+
+// CoinSelectorOptions are used to parameterise the behaviour of the
+// CoinSelector.
+type CoinSelectorOptions struct {
+	Strategy      CoinSelectionStrategy
+	SizeParams    SizeParams
+	DustThreshold pack.U256
+	MaxTries      int
+	Seed          int64
+}
+
+// DefaultCoinSelectorOptions returns CoinSelectorOptions with the default
+// settings.
+func DefaultCoinSelectorOptions() CoinSelectorOptions {
+	return CoinSelectorOptions{
+		Strategy:      DefaultCoinSelectionStrategy,
+		SizeParams:    DefaultSizeParams,
+		DustThreshold: pack.NewU256FromUint64(DefaultDustThreshold),
+		MaxTries:      DefaultMaxTries,
+		Seed:          DefaultSeed,
+	}
+}
+
+// WithStrategy sets the strategy used to select coins.
+func (opts CoinSelectorOptions) WithStrategy(strategy CoinSelectionStrategy) CoinSelectorOptions {
+	opts.Strategy = strategy
+	return opts
+}
+
+// WithSizeParams sets the transaction sizes used to compute fees.
+func (opts CoinSelectorOptions) WithSizeParams(sizeParams SizeParams) CoinSelectorOptions {
+	opts.SizeParams = sizeParams
+	return opts
+}
+
+// WithDustThreshold sets the value below which change outputs are not
+// created.
+func (opts CoinSelectorOptions) WithDustThreshold(dustThreshold pack.U256) CoinSelectorOptions {
+	opts.DustThreshold = dustThreshold
+	return opts
+}
+
+// WithMaxTries sets the maximum amount of work done by the BranchAndBound and
+// Knapsack strategies.
+func (opts CoinSelectorOptions) WithMaxTries(maxTries int) CoinSelectorOptions {
+	opts.MaxTries = maxTries
+	return opts
+}
+
+// WithSeed sets the seed used by the Knapsack strategy.
+func (opts CoinSelectorOptions) WithSeed(seed int64) CoinSelectorOptions {
+	opts.Seed = seed
+	return opts
+}
+
+// A CoinSelector selects the outputs that should be spent in order to pay a
+// set of recipients, and computes the change that should be returned to the
+// sender. It is chain-agnostic: the size of transactions on a specific chain
+// is described by its SizeParams.
+type CoinSelector struct {
+	opts CoinSelectorOptions
+}
+
+// NewCoinSelector returns a CoinSelector that uses the given options.
+func NewCoinSelector(opts CoinSelectorOptions) CoinSelector {
+	return CoinSelector{opts: opts}
+}
+
+// SelectCoins selects outputs from the given outputs (usually the unspent
+// outputs of the sender) that are sufficient to pay the recipients, and the
+// fee for the resulting transaction at the given fee rate (in the smallest
+// unit of the chain per byte; for example, SATs-per-byte as returned by a
+// gas.Estimator). If the selected outputs exceed what is needed, a change
+// recipient that pays the excess to the change address is appended to the
+// recipients. The returned inputs and recipients can be passed directly to
+// TxBuilder.BuildTx. Inputs are returned without a sig script, so it must be
+// filled in for outputs that need one (for example, P2SH outputs).
+func (selector CoinSelector) SelectCoins(outputs []Output, recipients []Recipient, feeRate pack.U256, changeAddr address.Address) ([]Input, []Recipient, error) {
+	if !feeRate.Int().IsUint64() {
+		return nil, nil, fmt.Errorf("bad fee rate: %v", feeRate)
+	}
+	if !selector.opts.DustThreshold.Int().IsUint64() {
+		return nil, nil, fmt.Errorf("bad dust threshold: %v", selector.opts.DustThreshold)
+	}
+	rate := feeRate.Int().Uint64()
+	sizeParams := selector.opts.SizeParams
+
+	target := uint64(0)
+	for i, recipient := range recipients {
+		if !recipient.Value.Int().IsUint64() {
+			return nil, nil, fmt.Errorf("bad recipient %v: value %v", i, recipient.Value)
+		}
+		target += recipient.Value.Int().Uint64()
+	}
+	// The amount that must be covered by the effective value of the inputs.
+	need := target + rate*(sizeParams.BaseSize+sizeParams.OutputSize*uint64(len(recipients)))
+	// The cost of creating a change output now, and spending it later.
+	costOfChange := rate * (sizeParams.OutputSize + sizeParams.InputSize)
+
+	// The effective value of an output is its value, minus the fee needed to
+	// spend it. Outputs that cost more to spend than they are worth are
+	// ignored.
+	candidates := make([]candidate, 0, len(outputs))
+	for _, output := range outputs {
+		if !output.Value.Int().IsUint64() {
+			continue
+		}
+		value := output.Value.Int().Uint64()
+		if value <= rate*sizeParams.InputSize {
+			continue
+		}
+		candidates = append(candidates, candidate{output: output, value: value, effectiveValue: value - rate*sizeParams.InputSize})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].effectiveValue > candidates[j].effectiveValue
+	})
+
+	available := uint64(0)
+	for _, c := range candidates {
+		available += c.effectiveValue
+	}
+	if available < need {
+		return nil, nil, fmt.Errorf("insufficient funds: expected >= %v, got %v", need, available)
+	}
+
+	var selected []candidate
+	switch selector.opts.Strategy {
+	case BranchAndBound:
+		selected = selector.selectBranchAndBound(candidates, need, costOfChange)
+		if selected == nil {
+			selected = selector.selectKnapsack(candidates, need)
+		}
+	case LargestFirst:
+		selected = selectLargestFirst(candidates, need)
+	case Knapsack:
+		selected = selector.selectKnapsack(candidates, need)
+	default:
+		return nil, nil, fmt.Errorf("unsupported coin selection strategy: %v", selector.opts.Strategy)
+	}
+	if selected == nil {
+		return nil, nil, fmt.Errorf("insufficient funds: no selection covers %v", need)
+	}
+
+	inputs := make([]Input, len(selected))
+	selectedValue := uint64(0)
+	for i, c := range selected {
+		inputs[i] = Input{Output: c.output}
+		selectedValue += c.effectiveValue
+	}
+
+	// Only create a change output when it is worth more than it costs, and is
+	// not dust. Otherwise, the excess is paid as a fee.
+	recipientsWithChange := append([]Recipient{}, recipients...)
+	excess := selectedValue - need
+	if excess > costOfChange {
+		change := excess - rate*sizeParams.OutputSize
+		if change >= selector.opts.DustThreshold.Int().Uint64() {
+			recipientsWithChange = append(recipientsWithChange, Recipient{
+				To:    changeAddr,
+				Value: pack.NewU256FromUint64(change),
+			})
+		}
+	}
+	return inputs, recipientsWithChange, nil
+}
+
+type candidate struct {
+	output         Output
+	value          uint64
+	effectiveValue uint64
+}
+
+// selectBranchAndBound does a depth-first search for a selection with an
+// effective value in the range [need, need+costOfChange]. Candidates must be
+// sorted by descending effective value. It returns nil if no selection is
+// found within the maximum number of tries.
+func (selector CoinSelector) selectBranchAndBound(candidates []candidate, need, costOfChange uint64) []candidate {
+	// remaining[i] is the sum of the effective values of candidates[i:].
+	remaining := make([]uint64, len(candidates)+1)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + candidates[i].effectiveValue
+	}
+
+	tries := 0
+	var best []int
+	bestExcess := uint64(0)
+	included := make([]int, 0, len(candidates))
+
+	var search func(depth int, value uint64)
+	search = func(depth int, value uint64) {
+		if tries >= selector.opts.MaxTries {
+			return
+		}
+		tries++
+		if value > need+costOfChange {
+			return
+		}
+		if value >= need {
+			if best == nil || value-need < bestExcess {
+				best = append([]int{}, included...)
+				bestExcess = value - need
+			}
+			return
+		}
+		if depth >= len(candidates) || value+remaining[depth] < need {
+			return
+		}
+		included = append(included, depth)
+		search(depth+1, value+candidates[depth].effectiveValue)
+		included = included[:len(included)-1]
+		if best != nil && bestExcess == 0 {
+			return
+		}
+		search(depth+1, value)
+	}
+	search(0, 0)
+
+	if best == nil {
+		return nil
+	}
+	selected := make([]candidate, len(best))
+	for i, index := range best {
+		selected[i] = candidates[index]
+	}
+	return selected
+}
+
+// selectLargestFirst selects candidates, in order, until the need is covered.
+// Candidates must be sorted by descending effective value.
+func selectLargestFirst(candidates []candidate, need uint64) []candidate {
+	value := uint64(0)
+	for i, c := range candidates {
+		value += c.effectiveValue
+		if value >= need {
+			return candidates[:i+1]
+		}
+	}
+	return nil
+}
+
+// selectKnapsack uses the stochastic approximation used by Bitcoin Core to
+// find the selection with the least excess. Candidates must be sorted by
+// descending effective value.
+func (selector CoinSelector) selectKnapsack(candidates []candidate, need uint64) []candidate {
+	// If a single candidate exactly covers the need, use it.
+	for _, c := range candidates {
+		if c.effectiveValue == need {
+			return []candidate{c}
+		}
+	}
+
+	// Separate the candidates into those that are larger than the need (of
+	// which only the smallest is interesting), and those that are smaller.
+	var smallestLarger *candidate
+	smaller := make([]candidate, 0, len(candidates))
+	smallerValue := uint64(0)
+	for i := range candidates {
+		if candidates[i].effectiveValue > need {
+			smallestLarger = &candidates[i]
+			continue
+		}
+		smaller = append(smaller, candidates[i])
+		smallerValue += candidates[i].effectiveValue
+	}
+	if smallerValue == need {
+		return smaller
+	}
+	if smallerValue < need {
+		if smallestLarger == nil {
+			return nil
+		}
+		return []candidate{*smallestLarger}
+	}
+
+	// Randomly search for the subset of the smaller candidates with the least
+	// excess.
+	r := rand.New(rand.NewSource(selector.opts.Seed))
+	best := make([]bool, len(smaller))
+	for i := range best {
+		best[i] = true
+	}
+	bestValue := smallerValue
+	included := make([]bool, len(smaller))
+	iterations := selector.opts.MaxTries / 100
+	if iterations < 1 {
+		iterations = 1
+	}
+	for iteration := 0; iteration < iterations && bestValue != need; iteration++ {
+		for i := range included {
+			included[i] = false
+		}
+		value := uint64(0)
+		reachedNeed := false
+		for pass := 0; pass < 2 && !reachedNeed; pass++ {
+			for i := range smaller {
+				// On the first pass, randomly include candidates. On the
+				// second pass, include all candidates that were not included.
+				if (pass == 0 && r.Intn(2) == 0) || (pass == 1 && !included[i]) {
+					value += smaller[i].effectiveValue
+					included[i] = true
+					if value >= need {
+						reachedNeed = true
+						if value < bestValue {
+							bestValue = value
+							copy(best, included)
+						}
+						value -= smaller[i].effectiveValue
+						included[i] = false
+					}
+				}
+			}
+		}
+	}
+
+	// Prefer the smallest larger candidate if it has less excess.
+	if smallestLarger != nil && smallestLarger.effectiveValue <= bestValue {
+		return []candidate{*smallestLarger}
+	}
+	selected := make([]candidate, 0, len(smaller))
+	for i, ok := range best {
+		if ok {
+			selected = append(selected, smaller[i])
+		}
+	}
+	return selected
+}
@@ -0,0 +1,117 @@
+package utxo_test
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Coin selection", func() {
+	sizeParams := utxo.SizeParams{BaseSize: 10, InputSize: 100, OutputSize: 30}
+	feeRate := pack.NewU256FromUint64(1)
+	changeAddr := address.Address("change")
+	recipients := []utxo.Recipient{{To: address.Address("recipient"), Value: pack.NewU256FromUint64(10000)}}
+
+	outputsWithValues := func(values ...uint64) []utxo.Output {
+		outputs := make([]utxo.Output, len(values))
+		for i, value := range values {
+			outputs[i] = utxo.Output{
+				Outpoint: utxo.Outpoint{Hash: pack.NewBytes([]byte{byte(i)}), Index: pack.NewU32(0)},
+				Value:    pack.NewU256FromUint64(value),
+			}
+		}
+		return outputs
+	}
+
+	// fee returns the fee implied by the inputs and recipients.
+	fee := func(inputs []utxo.Input, recipients []utxo.Recipient) uint64 {
+		fee := new(big.Int)
+		for _, input := range inputs {
+			fee.Add(fee, input.Value.Int())
+		}
+		for _, recipient := range recipients {
+			fee.Sub(fee, recipient.Value.Int())
+		}
+		return fee.Uint64()
+	}
+
+	// expectedFee returns the fee required by a transaction with the given
+	// number of inputs and outputs.
+	expectedFee := func(numInputs, numOutputs uint64) uint64 {
+		return sizeParams.BaseSize + sizeParams.InputSize*numInputs + sizeParams.OutputSize*numOutputs
+	}
+
+	strategies := []utxo.CoinSelectionStrategy{utxo.BranchAndBound, utxo.LargestFirst, utxo.Knapsack}
+	for _, strategy := range strategies {
+		strategy := strategy
+		selector := utxo.NewCoinSelector(utxo.DefaultCoinSelectorOptions().WithStrategy(strategy).WithSizeParams(sizeParams))
+
+		Context(fmt.Sprintf("when using %v", strategy), func() {
+			It("should pay the recipients, the fee, and the change", func() {
+				inputs, recipientsWithChange, err := selector.SelectCoins(outputsWithValues(3000, 50000, 7000, 2000), recipients, feeRate, changeAddr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recipientsWithChange[0]).To(Equal(recipients[0]))
+				if len(recipientsWithChange) == 2 {
+					Expect(recipientsWithChange[1].To).To(Equal(changeAddr))
+					Expect(fee(inputs, recipientsWithChange)).To(Equal(expectedFee(uint64(len(inputs)), 2)))
+				} else {
+					Expect(fee(inputs, recipientsWithChange)).To(BeNumerically(">=", expectedFee(uint64(len(inputs)), 1)))
+				}
+			})
+
+			It("should return an error when funds are insufficient", func() {
+				_, _, err := selector.SelectCoins(outputsWithValues(3000, 4000, 2000), recipients, feeRate, changeAddr)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("should ignore outputs that cost more to spend than they are worth", func() {
+				inputs, _, err := selector.SelectCoins(outputsWithValues(100, 20000), recipients, feeRate, changeAddr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(inputs).To(HaveLen(1))
+				Expect(inputs[0].Value).To(Equal(pack.NewU256FromUint64(20000)))
+			})
+		})
+	}
+
+	Context("when an exact match exists", func() {
+		It("should not create change when using branch and bound", func() {
+			selector := utxo.NewCoinSelector(utxo.DefaultCoinSelectorOptions().WithStrategy(utxo.BranchAndBound).WithSizeParams(sizeParams))
+			// Two inputs that exactly cover the recipient and the fee.
+			exact := 10000 + expectedFee(2, 1)
+			inputs, recipientsWithChange, err := selector.SelectCoins(outputsWithValues(50000, exact-6000, 6000, 9000), recipients, feeRate, changeAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inputs).To(HaveLen(2))
+			Expect(recipientsWithChange).To(HaveLen(1))
+			Expect(fee(inputs, recipientsWithChange)).To(Equal(expectedFee(2, 1)))
+		})
+	})
+
+	Context("when using largest first", func() {
+		It("should spend the largest output", func() {
+			selector := utxo.NewCoinSelector(utxo.DefaultCoinSelectorOptions().WithStrategy(utxo.LargestFirst).WithSizeParams(sizeParams))
+			inputs, recipientsWithChange, err := selector.SelectCoins(outputsWithValues(12000, 50000, 11000), recipients, feeRate, changeAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inputs).To(HaveLen(1))
+			Expect(inputs[0].Value).To(Equal(pack.NewU256FromUint64(50000)))
+			Expect(recipientsWithChange).To(HaveLen(2))
+			Expect(recipientsWithChange[1].Value).To(Equal(pack.NewU256FromUint64(50000 - 10000 - expectedFee(1, 2))))
+		})
+	})
+
+	Context("when the change would be dust", func() {
+		It("should pay the change as a fee", func() {
+			selector := utxo.NewCoinSelector(utxo.DefaultCoinSelectorOptions().WithStrategy(utxo.LargestFirst).WithSizeParams(sizeParams))
+			value := 10000 + expectedFee(1, 2) + 500
+			inputs, recipientsWithChange, err := selector.SelectCoins(outputsWithValues(value), recipients, feeRate, changeAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recipientsWithChange).To(HaveLen(1))
+			Expect(fee(inputs, recipientsWithChange)).To(Equal(expectedFee(1, 2) + 500))
+		})
+	})
+})
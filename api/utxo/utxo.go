@@ -7,6 +7,7 @@ import (
 	"context"
 
 	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
 	"github.com/renproject/pack"
 )
 
@@ -77,6 +78,25 @@ type TxBuilder interface {
 	BuildTx([]Input, []Recipient) (Tx, error)
 }
 
+// The SizeEstimator interface defines the functionality required to compute
+// the size of transactions, so that the fee paid by a transaction can be
+// computed by multiplying its size by the fee rate returned by a
+// gas.Estimator. For chains that support segregated witnesses, sizes are
+// virtual sizes.
+type SizeEstimator interface {
+	// EstimateSize returns an upper bound on the size of the signed
+	// transaction that would be built by consuming the given inputs and
+	// sending to the given recipients. The pubkey script (and, where needed,
+	// the sig script) of every input is used to determine how it will be
+	// signed.
+	EstimateSize([]Input, []Recipient) (pack.U64, error)
+
+	// TxSize returns the size of the given transaction. If the transaction is
+	// signed, the size is exact. Otherwise, it is an upper bound on the size
+	// of the transaction once it has been signed.
+	TxSize(Tx) (pack.U64, error)
+}
+
 // The Client interface defines the functionality required to interact with a
 // chain over RPC.
 type Client interface {
@@ -104,4 +124,21 @@ type Client interface {
 
 	// TxSenders returns the senders' addresses of the transaction.
 	TxSenders(context.Context, pack.Bytes) ([]pack.String, error)
+
+	// TxReceipt returns the outcome of the transaction uniquely identified by
+	// the given transaction hash. The status of the receipt distinguishes
+	// pending, confirmed, and failed transactions. Transactions that cannot be
+	// found must not return an error, and must use TxStatusUnknown instead.
+	TxReceipt(context.Context, pack.Bytes) (confirmation.TxReceipt, error)
+}
+
+// The Simulator interface defines the functionality required to check that a
+// transaction would be accepted, before it is submitted.
+type Simulator interface {
+	// Simulate the acceptance of the transaction into the mempool, without
+	// submitting it. Most chains require the transaction to be signed. A
+	// transaction that would be rejected must not return an error, and must
+	// return an unsuccessful simulation instead. An error should only be
+	// returned if the simulation could not be done.
+	Simulate(context.Context, Tx) (confirmation.Simulation, error)
 }
@@ -0,0 +1,380 @@
+package utxo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultWatcherPollInterval used by the Watcher.
+	DefaultWatcherPollInterval = 30 * time.Second
+	// DefaultWatcherMaxConfirmations used by the Watcher.
+	DefaultWatcherMaxConfirmations = 6
+	// DefaultWatcherMaxReorgDepth used by the Watcher.
+	DefaultWatcherMaxReorgDepth = 100
+)
+
+// A BlockOutput is an output produced by a transaction in a block, along with
+// the addresses that can spend it (as reported by the chain).
+type BlockOutput struct {
+	Output
+	Addresses []address.Address
+}
+
+// A Block in the longest blockchain, and the outputs produced by the
+// transactions in it.
+type Block struct {
+	Hash     pack.Bytes
+	PrevHash pack.Bytes
+	Height   pack.U64
+	Outputs  []BlockOutput
+}
+
+// The BlockReader interface defines the functionality required to walk the
+// blocks of a chain.
+type BlockReader interface {
+	// LatestBlock returns the height of the longest blockchain.
+	LatestBlock(context.Context) (pack.U64, error)
+
+	// BlockHash returns the hash of the block at the given height in the
+	// longest blockchain.
+	BlockHash(context.Context, pack.U64) (pack.Bytes, error)
+
+	// Block returns the block with the given hash.
+	Block(context.Context, pack.Bytes) (Block, error)
+}
+
+// A DepositEvent is emitted by the Watcher when an output is paid to a watched
+// script (or address), and whenever the number of confirmations for that
+// output changes. If the block that included the output is removed from the
+// longest blockchain by a reorg, the deposit is retracted. If the output is
+// then included in another block, a new deposit event is emitted for the new
+// block.
+type DepositEvent struct {
+	Output        Output
+	Addresses     []address.Address
+	BlockHash     pack.Bytes
+	Height        pack.U64
+	Confirmations pack.U64
+	Retracted     bool
+}
+
+// WatcherOptions are used to parameterise the behaviour of the Watcher.
+type WatcherOptions struct {
+	// Checkpoint is the height of the first block that is scanned.
+	Checkpoint pack.U64
+	// PollInterval is the time between polls, when the watcher is running.
+	PollInterval time.Duration
+	// MaxConfirmations is the number of confirmations after which a deposit
+	// is considered final. No more events are emitted for final deposits.
+	MaxConfirmations pack.U64
+	// MaxReorgDepth is the number of blocks that are remembered, so that
+	// reorgs can be detected. Reorgs that are deeper than this are not
+	// detected.
+	MaxReorgDepth int
+}
+
+// DefaultWatcherOptions returns WatcherOptions with the default settings.
+func DefaultWatcherOptions() WatcherOptions {
+	return WatcherOptions{
+		Checkpoint:       pack.NewU64(0),
+		PollInterval:     DefaultWatcherPollInterval,
+		MaxConfirmations: pack.NewU64(DefaultWatcherMaxConfirmations),
+		MaxReorgDepth:    DefaultWatcherMaxReorgDepth,
+	}
+}
+
+// WithCheckpoint sets the height of the first block that is scanned.
+func (opts WatcherOptions) WithCheckpoint(checkpoint pack.U64) WatcherOptions {
+	opts.Checkpoint = checkpoint
+	return opts
+}
+
+// WithPollInterval sets the time between polls.
+func (opts WatcherOptions) WithPollInterval(pollInterval time.Duration) WatcherOptions {
+	opts.PollInterval = pollInterval
+	return opts
+}
+
+// WithMaxConfirmations sets the number of confirmations after which a deposit
+// is considered final.
+func (opts WatcherOptions) WithMaxConfirmations(maxConfirmations pack.U64) WatcherOptions {
+	opts.MaxConfirmations = maxConfirmations
+	return opts
+}
+
+// WithMaxReorgDepth sets the number of blocks that are remembered, so that
+// reorgs can be detected.
+func (opts WatcherOptions) WithMaxReorgDepth(maxReorgDepth int) WatcherOptions {
+	opts.MaxReorgDepth = maxReorgDepth
+	return opts
+}
+
+// blockRef identifies a block that has been scanned by the Watcher.
+type blockRef struct {
+	hash   pack.Bytes
+	height pack.U64
+}
+
+// The Watcher walks the blocks of a chain, starting from a checkpoint, and
+// emits deposit events for outputs that pay to watched scripts or addresses.
+// It is safe for concurrent use.
+type Watcher struct {
+	opts   WatcherOptions
+	reader BlockReader
+
+	mu        *sync.Mutex
+	scripts   map[string]bool
+	addresses map[address.Address]bool
+	blocks    []blockRef
+	next      pack.U64
+	deposits  map[string]*DepositEvent
+}
+
+// NewWatcher returns a Watcher that reads blocks using the given reader. No
+// scripts or addresses are watched until they are added.
+func NewWatcher(reader BlockReader, opts WatcherOptions) *Watcher {
+	return &Watcher{
+		opts:   opts,
+		reader: reader,
+
+		mu:        new(sync.Mutex),
+		scripts:   map[string]bool{},
+		addresses: map[address.Address]bool{},
+		blocks:    []blockRef{},
+		next:      opts.Checkpoint,
+		deposits:  map[string]*DepositEvent{},
+	}
+}
+
+// WatchScript adds a pubkey script to the set of watched scripts. Outputs
+// that were scanned before the script was added are not emitted.
+func (watcher *Watcher) WatchScript(script pack.Bytes) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+
+	watcher.scripts[string(script)] = true
+}
+
+// UnwatchScript removes a pubkey script from the set of watched scripts.
+func (watcher *Watcher) UnwatchScript(script pack.Bytes) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+
+	delete(watcher.scripts, string(script))
+}
+
+// WatchAddress adds an address to the set of watched addresses. Addresses
+// must be in the format that is reported by the chain. Outputs that were
+// scanned before the address was added are not emitted.
+func (watcher *Watcher) WatchAddress(addr address.Address) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+
+	watcher.addresses[addr] = true
+}
+
+// UnwatchAddress removes an address from the set of watched addresses.
+func (watcher *Watcher) UnwatchAddress(addr address.Address) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+
+	delete(watcher.addresses, addr)
+}
+
+// Checkpoint returns the height from which a new Watcher should start
+// scanning, so that it emits all deposits that are not yet final. This should
+// be persisted, so that deposits are not missed when restarting.
+func (watcher *Watcher) Checkpoint() pack.U64 {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+
+	checkpoint := watcher.next
+	for _, deposit := range watcher.deposits {
+		if deposit.Height < checkpoint {
+			checkpoint = deposit.Height
+		}
+	}
+	return checkpoint
+}
+
+// Run the Watcher, polling at the poll interval and sending deposit events to
+// the channel, until the context is done. Errors from polling are ignored,
+// because polling is resumed from the last block that was successfully
+// scanned. Use Poll directly to handle errors.
+func (watcher *Watcher) Run(ctx context.Context, events chan<- DepositEvent) error {
+	ticker := time.NewTicker(watcher.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		newEvents, _ := watcher.Poll(ctx)
+		for _, event := range newEvents {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case events <- event:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll scans all blocks since the last poll, and returns the deposit events
+// that have happened since the last poll. Deposits in blocks that have been
+// removed from the longest blockchain are retracted, and the confirmations of
+// all deposits that are not yet final are updated. If an error is returned,
+// the events that happened before the error are still returned, and the next
+// poll resumes from where this poll stopped.
+func (watcher *Watcher) Poll(ctx context.Context) ([]DepositEvent, error) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+
+	events := []DepositEvent{}
+	tip, err := watcher.reader.LatestBlock(ctx)
+	if err != nil {
+		return events, fmt.Errorf("latest block: %v", err)
+	}
+
+	// Remove blocks that are no longer in the longest blockchain, and retract
+	// the deposits in them.
+	for len(watcher.blocks) > 0 {
+		last := watcher.blocks[len(watcher.blocks)-1]
+		if last.height <= tip {
+			hash, err := watcher.reader.BlockHash(ctx, last.height)
+			if err != nil {
+				return events, fmt.Errorf("block hash %v: %v", last.height, err)
+			}
+			if bytes.Equal(hash, last.hash) {
+				break
+			}
+		}
+		events = append(events, watcher.retract(last)...)
+		watcher.blocks = watcher.blocks[:len(watcher.blocks)-1]
+		watcher.next = last.height
+	}
+
+	// Scan new blocks.
+	for height := watcher.next; height <= tip; height++ {
+		hash, err := watcher.reader.BlockHash(ctx, height)
+		if err != nil {
+			return events, fmt.Errorf("block hash %v: %v", height, err)
+		}
+		block, err := watcher.reader.Block(ctx, hash)
+		if err != nil {
+			return events, fmt.Errorf("block %v: %v", hash, err)
+		}
+		if len(watcher.blocks) > 0 && !bytes.Equal(block.PrevHash, watcher.blocks[len(watcher.blocks)-1].hash) {
+			// The longest blockchain changed while scanning, so the reorg
+			// will be handled by the next poll.
+			break
+		}
+		events = append(events, watcher.scan(block, height, tip)...)
+		watcher.blocks = append(watcher.blocks, blockRef{hash: block.Hash, height: height})
+		watcher.next = height + 1
+		if watcher.opts.MaxReorgDepth > 0 && len(watcher.blocks) > watcher.opts.MaxReorgDepth {
+			watcher.blocks = watcher.blocks[len(watcher.blocks)-watcher.opts.MaxReorgDepth:]
+		}
+	}
+
+	// Update the confirmations of existing deposits, in the order that they
+	// were included.
+	keys := make([]string, 0, len(watcher.deposits))
+	for key := range watcher.deposits {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if watcher.deposits[keys[i]].Height != watcher.deposits[keys[j]].Height {
+			return watcher.deposits[keys[i]].Height < watcher.deposits[keys[j]].Height
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		deposit := watcher.deposits[key]
+		confs := confirmations(deposit.Height, tip)
+		if confs != deposit.Confirmations {
+			deposit.Confirmations = confs
+			events = append(events, *deposit)
+		}
+		if confs >= watcher.opts.MaxConfirmations {
+			delete(watcher.deposits, key)
+		}
+	}
+	return events, nil
+}
+
+// scan the block for outputs that pay to watched scripts or addresses, and
+// return the new deposit events.
+func (watcher *Watcher) scan(block Block, height, tip pack.U64) []DepositEvent {
+	events := []DepositEvent{}
+	for _, output := range block.Outputs {
+		if !watcher.isWatched(output) {
+			continue
+		}
+		event := DepositEvent{
+			Output:        output.Output,
+			Addresses:     output.Addresses,
+			BlockHash:     block.Hash,
+			Height:        height,
+			Confirmations: confirmations(height, tip),
+		}
+		events = append(events, event)
+		if event.Confirmations < watcher.opts.MaxConfirmations {
+			watcher.deposits[depositKey(output.Outpoint)] = &event
+		}
+	}
+	return events
+}
+
+// retract the deposits in the block, and return the retraction events. Final
+// deposits are not retracted.
+func (watcher *Watcher) retract(block blockRef) []DepositEvent {
+	events := []DepositEvent{}
+	for key, deposit := range watcher.deposits {
+		if !bytes.Equal(deposit.BlockHash, block.hash) {
+			continue
+		}
+		event := *deposit
+		event.Confirmations = pack.NewU64(0)
+		event.Retracted = true
+		events = append(events, event)
+		delete(watcher.deposits, key)
+	}
+	return events
+}
+
+func (watcher *Watcher) isWatched(output BlockOutput) bool {
+	if watcher.scripts[string(output.PubKeyScript)] {
+		return true
+	}
+	for _, addr := range output.Addresses {
+		if watcher.addresses[addr] {
+			return true
+		}
+	}
+	return false
+}
+
+func depositKey(outpoint Outpoint) string {
+	return fmt.Sprintf("%x:%v", []byte(outpoint.Hash), outpoint.Index)
+}
+
+// confirmations returns the number of confirmations of a block at the given
+// height, when the longest blockchain is at the given tip.
+func confirmations(height, tip pack.U64) pack.U64 {
+	if height > tip {
+		return pack.NewU64(0)
+	}
+	return tip - height + 1
+}
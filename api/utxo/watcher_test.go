@@ -0,0 +1,141 @@
+package utxo_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// chain is an in-memory BlockReader. Blocks are identified by their fork and
+// height, so that blocks on different forks have different hashes.
+type chain struct {
+	blocks []utxo.Block
+}
+
+func (chain *chain) mine(fork string, outputs ...utxo.BlockOutput) {
+	height := pack.NewU64(uint64(len(chain.blocks)))
+	block := utxo.Block{
+		Hash:    pack.Bytes(fmt.Sprintf("%v/%v", fork, len(chain.blocks))),
+		Height:  height,
+		Outputs: outputs,
+	}
+	if len(chain.blocks) > 0 {
+		block.PrevHash = chain.blocks[len(chain.blocks)-1].Hash
+	}
+	chain.blocks = append(chain.blocks, block)
+}
+
+func (chain *chain) reorg(height int) {
+	chain.blocks = chain.blocks[:height]
+}
+
+func (chain *chain) LatestBlock(ctx context.Context) (pack.U64, error) {
+	return pack.NewU64(uint64(len(chain.blocks) - 1)), nil
+}
+
+func (chain *chain) BlockHash(ctx context.Context, height pack.U64) (pack.Bytes, error) {
+	if int(height) >= len(chain.blocks) {
+		return nil, fmt.Errorf("block %v: not found", height)
+	}
+	return chain.blocks[height].Hash, nil
+}
+
+func (chain *chain) Block(ctx context.Context, hash pack.Bytes) (utxo.Block, error) {
+	for _, block := range chain.blocks {
+		if string(block.Hash) == string(hash) {
+			return block, nil
+		}
+	}
+	return utxo.Block{}, fmt.Errorf("block %v: not found", hash)
+}
+
+var _ = Describe("Watcher", func() {
+	ctx := context.Background()
+
+	script := pack.Bytes("watched script")
+	output := func(i uint32, script pack.Bytes, addrs ...address.Address) utxo.BlockOutput {
+		return utxo.BlockOutput{
+			Output: utxo.Output{
+				Outpoint:     utxo.Outpoint{Hash: pack.Bytes("tx"), Index: pack.NewU32(i)},
+				Value:        pack.NewU256FromUint64(1000),
+				PubKeyScript: script,
+			},
+			Addresses: addrs,
+		}
+	}
+
+	Context("when outputs pay to watched scripts and addresses", func() {
+		It("should emit deposits, and update their confirmations until they are final", func() {
+			chain := &chain{}
+			chain.mine("a")
+			chain.mine("a", output(0, script), output(1, pack.Bytes("other script")), output(2, pack.Bytes("script"), "watched address"))
+
+			watcher := utxo.NewWatcher(chain, utxo.DefaultWatcherOptions().WithMaxConfirmations(pack.NewU64(3)))
+			watcher.WatchScript(script)
+			watcher.WatchAddress("watched address")
+
+			events, err := watcher.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Output.Index).To(Equal(pack.NewU32(0)))
+			Expect(events[1].Output.Index).To(Equal(pack.NewU32(2)))
+			Expect(events[0].Confirmations).To(Equal(pack.NewU64(1)))
+
+			chain.mine("a")
+			events, err = watcher.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Confirmations).To(Equal(pack.NewU64(2)))
+
+			chain.mine("a")
+			chain.mine("a")
+			events, err = watcher.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Confirmations).To(Equal(pack.NewU64(4)))
+
+			// The deposits are final, so no more events are emitted.
+			chain.mine("a")
+			events, err = watcher.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(BeEmpty())
+			Expect(watcher.Checkpoint()).To(Equal(pack.NewU64(6)))
+		})
+	})
+
+	Context("when a reorg removes a deposit", func() {
+		It("should retract the deposit, and re-emit it if it is included again", func() {
+			chain := &chain{}
+			chain.mine("a")
+			chain.mine("a", output(0, script))
+			chain.mine("a")
+
+			watcher := utxo.NewWatcher(chain, utxo.DefaultWatcherOptions())
+			watcher.WatchScript(script)
+			events, err := watcher.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].BlockHash).To(Equal(pack.Bytes("a/1")))
+			Expect(watcher.Checkpoint()).To(Equal(pack.NewU64(1)))
+
+			chain.reorg(1)
+			chain.mine("b")
+			chain.mine("b", output(0, script))
+			chain.mine("b")
+			events, err = watcher.Poll(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Retracted).To(BeTrue())
+			Expect(events[0].BlockHash).To(Equal(pack.Bytes("a/1")))
+			Expect(events[1].Retracted).To(BeFalse())
+			Expect(events[1].BlockHash).To(Equal(pack.Bytes("b/2")))
+			Expect(events[1].Confirmations).To(Equal(pack.NewU64(2)))
+		})
+	})
+})
@@ -0,0 +1,19 @@
+package arbitrum
+
+import (
+	"github.com/renproject/multichain/chain/evm"
+)
+
+var (
+	// NewRecipient re-exports evm.NewRecipient.
+	NewRecipient = evm.NewRecipient
+
+	// DeployCode re-exports evm.DeployCode.
+	DeployCode = evm.DeployCode
+
+	// CreateAddress re-exports evm.CreateAddress.
+	CreateAddress = evm.CreateAddress
+
+	// Create2Address re-exports evm.Create2Address.
+	Create2Address = evm.Create2Address
+)
@@ -174,7 +174,7 @@ var _ = Describe("Encoding", func() {
 				resString := hex.EncodeToString(resBytes)
 
 				expectedBytes := make([]byte, 32)
-				copy(expectedBytes, x[:])
+				copy(expectedBytes[12:], x[:])
 				expectedString := hex.EncodeToString(expectedBytes)
 
 				Expect(resString).To(Equal(expectedString))
@@ -188,9 +188,8 @@ var _ = Describe("Encoding", func() {
 
 	Context("when encoding an unsupported type", func() {
 		It("should panic", func() {
-			f := func(x bool) bool {
-				arg := pack.NewBool(x)
-				Expect(func() { arbitrum.Encode(arg) }).To(Panic())
+			f := func(x float64) bool {
+				Expect(func() { arbitrum.Encode(x) }).To(Panic())
 				return true
 			}
 
@@ -211,13 +210,13 @@ var _ = Describe("Encoding", func() {
 			addr:   "797522Fb74d42bB9fbF6b76dEa24D01A538d5D66",
 			amount: 10000,
 			hash:   "702826c3977ee72158db2ce1fb758075ee2799db65fb27b5d0952f860a8084ed",
-			result: "797522fb74d42bb9fbf6b76dea24d01a538d5d660000000000000000000000000000000000000000000000000000000000000000000000000000000000002710702826c3977ee72158db2ce1fb758075ee2799db65fb27b5d0952f860a8084ed",
+			result: "000000000000000000000000797522fb74d42bb9fbf6b76dea24d01a538d5d660000000000000000000000000000000000000000000000000000000000002710702826c3977ee72158db2ce1fb758075ee2799db65fb27b5d0952f860a8084ed",
 		},
 		{
 			addr:   "58afb504ef2444a267b8c7ce57279417f1377ceb",
 			amount: 50000000000000000,
 			hash:   "dabff9ceb1b3dabb696d143326fdb98a8c7deb260e65d08a294b16659d573f93",
-			result: "58afb504ef2444a267b8c7ce57279417f1377ceb00000000000000000000000000000000000000000000000000000000000000000000000000b1a2bc2ec50000dabff9ceb1b3dabb696d143326fdb98a8c7deb260e65d08a294b16659d573f93",
+			result: "00000000000000000000000058afb504ef2444a267b8c7ce57279417f1377ceb00000000000000000000000000000000000000000000000000b1a2bc2ec50000dabff9ceb1b3dabb696d143326fdb98a8c7deb260e65d08a294b16659d573f93",
 		},
 		{
 			addr:   "0000000000000000000000000000000000000000",
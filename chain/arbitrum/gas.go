@@ -1,11 +1,170 @@
 package arbitrum
 
 import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/address"
 	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/pack"
+)
+
+const (
+	// NodeInterfaceAddress is the address of the NodeInterface virtual
+	// contract, which estimates the components of the gas used by a
+	// transaction. It can only be called using eth_call.
+	NodeInterfaceAddress = "0x00000000000000000000000000000000000000C8"
+
+	// nodeInterfaceABI is the part of the NodeInterface ABI that is used by
+	// the GasEstimator.
+	nodeInterfaceABI = `[{"inputs":[{"internalType":"address","name":"to","type":"address"},{"internalType":"bool","name":"contractCreation","type":"bool"},{"internalType":"bytes","name":"data","type":"bytes"}],"name":"gasEstimateComponents","outputs":[{"internalType":"uint64","name":"gasEstimate","type":"uint64"},{"internalType":"uint64","name":"gasEstimateForL1","type":"uint64"},{"internalType":"uint256","name":"baseFee","type":"uint256"},{"internalType":"uint256","name":"l1BaseFeeEstimate","type":"uint256"}],"stateMutability":"payable","type":"function"}]`
+)
+
+// GasComponents are the components of the gas used by a transaction on
+// arbitrum. The gas used to post the transaction to layer 1 is paid for using
+// gas on arbitrum, so it is included in the gas estimate.
+type GasComponents struct {
+	GasEstimate       pack.U64
+	GasEstimateForL1  pack.U64
+	BaseFee           pack.U256
+	L1BaseFeeEstimate pack.U256
+}
+
+// A GasEstimator estimates fees on arbitrum. Gas prices are estimated in the
+// same way as evm.GasEstimator, but the fee paid by a transaction is estimated
+// using the NodeInterface, so that it includes the gas used to post the
+// transaction to layer 1.
+type GasEstimator struct {
+	*evm.GasEstimator
+	client *Client
+}
+
+// NewGasEstimator returns a gas estimator that estimates fees using the
+// NodeInterface.
+func NewGasEstimator(client *Client) *GasEstimator {
+	return &GasEstimator{
+		GasEstimator: evm.NewGasEstimator(client),
+		client:       client,
+	}
+}
+
+// WithFeeOptions returns a copy of the gas estimator that estimates fees for
+// the type of transaction defined by the fee options.
+func (gasEstimator *GasEstimator) WithFeeOptions(feeOptions FeeOptions) *GasEstimator {
+	return &GasEstimator{
+		GasEstimator: gasEstimator.GasEstimator.WithFeeOptions(feeOptions),
+		client:       gasEstimator.client,
+	}
+}
+
+// GasEstimateComponents returns the components of the gas used by a
+// transaction from the sender to the recipient, with the given value and
+// payload, as reported by the NodeInterface. An empty recipient is used for
+// contract creation.
+func (gasEstimator *GasEstimator) GasEstimateComponents(ctx context.Context, from, to address.Address, value pack.U256, payload pack.Bytes) (GasComponents, error) {
+	fromAddr, err := NewAddressFromHex(string(pack.String(from)))
+	if err != nil {
+		return GasComponents{}, fmt.Errorf("bad from address '%v': %v", from, err)
+	}
+	contractCreation := to == ""
+	toAddr := evm.Address{}
+	if !contractCreation {
+		toAddr, err = NewAddressFromHex(string(pack.String(to)))
+		if err != nil {
+			return GasComponents{}, fmt.Errorf("bad to address '%v': %v", to, err)
+		}
+	}
+
+	nodeABI, err := abi.JSON(strings.NewReader(nodeInterfaceABI))
+	if err != nil {
+		return GasComponents{}, fmt.Errorf("parsing node interface abi: %v", err)
+	}
+	calldata, err := nodeABI.Pack("gasEstimateComponents", common.Address(toAddr), contractCreation, []byte(payload))
+	if err != nil {
+		return GasComponents{}, fmt.Errorf("packing gasEstimateComponents: %v", err)
+	}
+	nodeInterface := common.HexToAddress(NodeInterfaceAddress)
+	callMsg := ethereum.CallMsg{
+		From:  common.Address(fromAddr),
+		To:    &nodeInterface,
+		Value: value.Int(),
+		Data:  calldata,
+	}
+	result, err := gasEstimator.client.EthClient.CallContract(ctx, callMsg, nil)
+	if err != nil {
+		return GasComponents{}, fmt.Errorf("calling gasEstimateComponents: %v", err)
+	}
+	outputs, err := nodeABI.Unpack("gasEstimateComponents", result)
+	if err != nil {
+		return GasComponents{}, fmt.Errorf("unpacking gasEstimateComponents: %v", err)
+	}
+	gasEstimate, ok1 := outputs[0].(uint64)
+	gasEstimateForL1, ok2 := outputs[1].(uint64)
+	baseFee, ok3 := outputs[2].(*big.Int)
+	l1BaseFeeEstimate, ok4 := outputs[3].(*big.Int)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return GasComponents{}, fmt.Errorf("unexpected gasEstimateComponents outputs %v", outputs)
+	}
+	return GasComponents{
+		GasEstimate:       pack.NewU64(gasEstimate),
+		GasEstimateForL1:  pack.NewU64(gasEstimateForL1),
+		BaseFee:           pack.NewU256FromInt(baseFee),
+		L1BaseFeeEstimate: pack.NewU256FromInt(l1BaseFeeEstimate),
+	}, nil
+}
+
+// EstimateTxFee returns the fee that will be paid by the transaction,
+// including the gas used to post the transaction to layer 1. Priority fees
+// are not paid on arbitrum, so the fee is the estimated gas multiplied by the
+// base fee.
+func (gasEstimator *GasEstimator) EstimateTxFee(ctx context.Context, tx account.Tx) (pack.U256, error) {
+	ethTx, ok := tx.(*evm.Tx)
+	if !ok {
+		return pack.U256{}, fmt.Errorf("expected type %T, got type %T", new(evm.Tx), tx)
+	}
+	to := address.Address("")
+	if ethTx.EthTx.To() != nil {
+		to = tx.To()
+	}
+	components, err := gasEstimator.GasEstimateComponents(ctx, tx.From(), to, tx.Value(), pack.Bytes(tx.Payload()))
+	if err != nil {
+		return pack.U256{}, err
+	}
+	fee := new(big.Int).Mul(components.BaseFee.Int(), new(big.Int).SetUint64(components.GasEstimate.Uint64()))
+	return pack.NewU256FromInt(fee), nil
+}
+
+const (
+	// FeeMarketLegacy re-exports evm.FeeMarketLegacy.
+	FeeMarketLegacy = evm.FeeMarketLegacy
+	// FeeMarketDynamic re-exports evm.FeeMarketDynamic.
+	FeeMarketDynamic = evm.FeeMarketDynamic
+	// FeeMarketAuto re-exports evm.FeeMarketAuto.
+	FeeMarketAuto = evm.FeeMarketAuto
 )
 
-// GasEstimator re-exports evm.GasEstimator.
-type GasEstimator = evm.GasEstimator
+type (
+	// FeeMarket re-exports evm.FeeMarket.
+	FeeMarket = evm.FeeMarket
+
+	// FeeOptions re-exports evm.FeeOptions.
+	FeeOptions = evm.FeeOptions
+)
+
+// DefaultFeeOptions re-exports evm.DefaultFeeOptions.
+var DefaultFeeOptions = evm.DefaultFeeOptions
+
+// DefaultGasLimitMultiplier re-exports evm.DefaultGasLimitMultiplier.
+const DefaultGasLimitMultiplier = evm.DefaultGasLimitMultiplier
+
+// GasLimitOptions re-exports evm.GasLimitOptions.
+type GasLimitOptions = evm.GasLimitOptions
 
-// NewGasEstimator re-exports evm.NewGasEstimator.
-var NewGasEstimator = evm.NewGasEstimator
+// DefaultGasLimitOptions re-exports evm.DefaultGasLimitOptions.
+var DefaultGasLimitOptions = evm.DefaultGasLimitOptions
@@ -0,0 +1,169 @@
+package arbitrum_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/arbitrum"
+	"github.com/renproject/multichain/chain/evm/evmtest"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// gasEstimateComponentsCall is a call to gasEstimateComponents that was
+// received by the fake node.
+type gasEstimateComponentsCall struct {
+	From             common.Address
+	Value            *big.Int
+	To               common.Address
+	ContractCreation bool
+	Data             []byte
+}
+
+// gasEstimateComponentsNode returns a fake node with a NodeInterface that
+// reports the gas components, and a function that returns the calls that
+// were received by the NodeInterface.
+func gasEstimateComponentsNode(components arbitrum.GasComponents) (*evmtest.Node, func() []gasEstimateComponentsCall) {
+	newType := func(t string) abi.Type {
+		ty, err := abi.NewType(t, "", nil)
+		Expect(err).ToNot(HaveOccurred())
+		return ty
+	}
+	inputs := abi.Arguments{{Type: newType("address")}, {Type: newType("bool")}, {Type: newType("bytes")}}
+	outputs := abi.Arguments{{Type: newType("uint64")}, {Type: newType("uint64")}, {Type: newType("uint256")}, {Type: newType("uint256")}}
+	selector := crypto.Keccak256([]byte("gasEstimateComponents(address,bool,bytes)"))[:4]
+
+	node := evmtest.NewNode().Handle("eth_call", func(params []json.RawMessage) (interface{}, error) {
+		msg, err := evmtest.DecodeCallMsg(params)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(msg.To, arbitrum.NodeInterfaceAddress) {
+			return nil, fmt.Errorf("unexpected call to %v", msg.To)
+		}
+		if !bytes.Equal(msg.CallData()[:4], selector) {
+			return nil, fmt.Errorf("unexpected selector %x", msg.CallData()[:4])
+		}
+		if _, err := inputs.Unpack(msg.CallData()[4:]); err != nil {
+			return nil, err
+		}
+		result, err := outputs.Pack(components.GasEstimate.Uint64(), components.GasEstimateForL1.Uint64(), components.BaseFee.Int(), components.L1BaseFeeEstimate.Int())
+		if err != nil {
+			return nil, err
+		}
+		return hexutil.Encode(result), nil
+	})
+	calls := func() []gasEstimateComponentsCall {
+		calls := []gasEstimateComponentsCall{}
+		for _, req := range node.Requests("eth_call") {
+			msg, err := evmtest.DecodeCallMsg(req.Params)
+			Expect(err).ToNot(HaveOccurred())
+			args, err := inputs.Unpack(msg.CallData()[4:])
+			Expect(err).ToNot(HaveOccurred())
+			value := big.NewInt(0)
+			if msg.Value != nil {
+				value = msg.Value.ToInt()
+			}
+			calls = append(calls, gasEstimateComponentsCall{
+				From:             common.HexToAddress(msg.From),
+				Value:            value,
+				To:               args[0].(common.Address),
+				ContractCreation: args[1].(bool),
+				Data:             args[2].([]byte),
+			})
+		}
+		return calls
+	}
+	return node, calls
+}
+
+var _ = Describe("Gas", func() {
+	ctx := context.Background()
+	chainID := big.NewInt(42161)
+	to := address.Address("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+	components := arbitrum.GasComponents{
+		GasEstimate:       pack.NewU64(600000),
+		GasEstimateForL1:  pack.NewU64(500000),
+		BaseFee:           pack.NewU256FromUint64(100000000),
+		L1BaseFeeEstimate: pack.NewU256FromUint64(30000000000),
+	}
+
+	dial := func(node *evmtest.Node) (*arbitrum.Client, func()) {
+		client, closeNode, err := node.Dial(chainID)
+		Expect(err).ToNot(HaveOccurred())
+		return client, closeNode
+	}
+
+	pubKey := id.NewPrivKey().PubKey()
+	from := address.Address(crypto.PubkeyToAddress(ecdsa.PublicKey(*pubKey)).Hex())
+
+	Context("when getting the gas components", func() {
+		It("should return the components reported by the node interface", func() {
+			node, calls := gasEstimateComponentsNode(components)
+			client, done := dial(node)
+			defer done()
+
+			result, err := arbitrum.NewGasEstimator(client).GasEstimateComponents(ctx, from, to, pack.NewU256FromUint64(7), pack.Bytes{1, 2, 3})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(components))
+			Expect(calls()).To(Equal([]gasEstimateComponentsCall{{
+				From:             common.HexToAddress(string(from)),
+				Value:            big.NewInt(7),
+				To:               common.HexToAddress(string(to)),
+				ContractCreation: false,
+				Data:             []byte{1, 2, 3},
+			}}))
+		})
+
+		It("should estimate contract creation when there is no recipient", func() {
+			node, calls := gasEstimateComponentsNode(components)
+			client, done := dial(node)
+			defer done()
+
+			_, err := arbitrum.NewGasEstimator(client).GasEstimateComponents(ctx, from, "", pack.NewU256FromUint64(0), pack.Bytes{1, 2, 3})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calls()).To(HaveLen(1))
+			Expect(calls()[0].ContractCreation).To(BeTrue())
+			Expect(calls()[0].To).To(Equal(common.Address{}))
+		})
+
+		It("should return an error if the call fails", func() {
+			client, done := dial(evmtest.NewNode())
+			defer done()
+
+			_, err := arbitrum.NewGasEstimator(client).GasEstimateComponents(ctx, from, to, pack.NewU256FromUint64(0), nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when estimating the fee of a transaction", func() {
+		It("should multiply the gas estimate by the base fee", func() {
+			node, calls := gasEstimateComponentsNode(components)
+			client, done := dial(node)
+			defer done()
+
+			tx, err := arbitrum.NewTxBuilder(chainID).BuildTx(ctx, pubKey, to, pack.NewU256FromUint64(7), pack.NewU256FromUint64(0), pack.NewU256FromUint64(21000), pack.NewU256FromUint64(2), pack.NewU256FromUint64(2), pack.Bytes{1, 2, 3})
+			Expect(err).ToNot(HaveOccurred())
+			fee, err := arbitrum.NewGasEstimator(client).EstimateTxFee(ctx, tx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(Equal(pack.NewU256FromUint64(600000 * 100000000)))
+			Expect(calls()).To(HaveLen(1))
+			Expect(calls()[0].From).To(Equal(common.HexToAddress(string(from))))
+			Expect(calls()[0].Value).To(Equal(big.NewInt(7)))
+			Expect(calls()[0].Data).To(Equal([]byte{1, 2, 3}))
+		})
+	})
+})
@@ -0,0 +1,21 @@
+package arbitrum
+
+import (
+	"github.com/renproject/multichain/chain/evm"
+)
+
+type (
+	// DecodedLog re-exports evm.DecodedLog.
+	DecodedLog = evm.DecodedLog
+
+	// LogDecoder re-exports evm.LogDecoder.
+	LogDecoder = evm.LogDecoder
+)
+
+var (
+	// NewLog re-exports evm.NewLog.
+	NewLog = evm.NewLog
+
+	// NewLogDecoder re-exports evm.NewLogDecoder.
+	NewLogDecoder = evm.NewLogDecoder
+)
@@ -0,0 +1,38 @@
+package arbitrum
+
+import (
+	"github.com/renproject/multichain/chain/evm"
+)
+
+const (
+	// DefaultMulticallAddress re-exports evm.DefaultMulticallAddress.
+	DefaultMulticallAddress = evm.DefaultMulticallAddress
+	// DefaultMulticallBatchSize re-exports evm.DefaultMulticallBatchSize.
+	DefaultMulticallBatchSize = evm.DefaultMulticallBatchSize
+	// DefaultMulticallBatchInterval re-exports evm.DefaultMulticallBatchInterval.
+	DefaultMulticallBatchInterval = evm.DefaultMulticallBatchInterval
+	// DefaultMulticallTimeout re-exports evm.DefaultMulticallTimeout.
+	DefaultMulticallTimeout = evm.DefaultMulticallTimeout
+)
+
+type (
+	// Call re-exports evm.Call.
+	Call = evm.Call
+
+	// CallResult re-exports evm.CallResult.
+	CallResult = evm.CallResult
+
+	// Multicaller re-exports evm.Multicaller.
+	Multicaller = evm.Multicaller
+
+	// MulticallerOptions re-exports evm.MulticallerOptions.
+	MulticallerOptions = evm.MulticallerOptions
+)
+
+var (
+	// NewMulticaller re-exports evm.NewMulticaller.
+	NewMulticaller = evm.NewMulticaller
+
+	// DefaultMulticallerOptions re-exports evm.DefaultMulticallerOptions.
+	DefaultMulticallerOptions = evm.DefaultMulticallerOptions
+)
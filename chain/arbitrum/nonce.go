@@ -0,0 +1,39 @@
+package arbitrum
+
+import (
+	"github.com/renproject/multichain/chain/evm"
+)
+
+const (
+	// DefaultNonceManagerReservationTimeout re-exports
+	// evm.DefaultNonceManagerReservationTimeout.
+	DefaultNonceManagerReservationTimeout = evm.DefaultNonceManagerReservationTimeout
+)
+
+type (
+	// NonceManager re-exports evm.NonceManager.
+	NonceManager = evm.NonceManager
+
+	// NonceManagerOptions re-exports evm.NonceManagerOptions.
+	NonceManagerOptions = evm.NonceManagerOptions
+
+	// NonceState re-exports evm.NonceState.
+	NonceState = evm.NonceState
+
+	// NonceStore re-exports evm.NonceStore.
+	NonceStore = evm.NonceStore
+
+	// CancelTxBuilder re-exports evm.CancelTxBuilder.
+	CancelTxBuilder = evm.CancelTxBuilder
+)
+
+var (
+	// NewNonceManager re-exports evm.NewNonceManager.
+	NewNonceManager = evm.NewNonceManager
+
+	// DefaultNonceManagerOptions re-exports evm.DefaultNonceManagerOptions.
+	DefaultNonceManagerOptions = evm.DefaultNonceManagerOptions
+
+	// NewMemoryNonceStore re-exports evm.NewMemoryNonceStore.
+	NewMemoryNonceStore = evm.NewMemoryNonceStore
+)
@@ -67,6 +67,13 @@ func (encoder AddressEncoder) encodeBech32(rawAddr address.RawAddress) (address.
 		}
 		return address.Address(addr.EncodeAddress()), nil
 	case 33:
+		if rawAddr[0] == TaprootWitnessVersion {
+			addr, err := NewAddressTaproot(rawAddr[1:], encoder.params)
+			if err != nil {
+				return address.Address(""), fmt.Errorf("new address taproot: %v", err)
+			}
+			return address.Address(addr.EncodeAddress()), nil
+		}
 		addr, err := btcutil.NewAddressWitnessScriptHash(rawAddr[1:], encoder.params)
 		if err != nil {
 			return address.Address(""), fmt.Errorf("new address witness script hash: %v", err)
@@ -98,7 +105,7 @@ func (decoder AddressDecoder) DecodeAddress(addr address.Address) (address.RawAd
 			return nil, fmt.Errorf("invalid address: bad character %v", c)
 		}
 	}
-	decodedAddr, err := btcutil.DecodeAddress(string(addr), decoder.params)
+	decodedAddr, err := decodeAddress(string(addr), decoder.params)
 	if err != nil {
 		return nil, fmt.Errorf("decode address: %v", err)
 	}
@@ -115,6 +122,9 @@ func (decoder AddressDecoder) DecodeAddress(addr address.Address) (address.RawAd
 	case *btcutil.AddressWitnessScriptHash:
 		rawAddr := append([]byte{a.WitnessVersion()}, a.WitnessProgram()...)
 		return address.RawAddress(rawAddr), nil
+	case *AddressTaproot:
+		rawAddr := append([]byte{a.WitnessVersion()}, a.WitnessProgram()...)
+		return address.RawAddress(rawAddr), nil
 	default:
 		return nil, fmt.Errorf("non-exhaustive pattern: address %T", a)
 	}
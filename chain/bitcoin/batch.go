@@ -0,0 +1,262 @@
+package bitcoin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// Outputs associated with the outpoints, and their number of confirmations.
+// Each transaction that produced one of the outputs is fetched once, and all
+// transactions are fetched in batches. If any output cannot be found, then an
+// error is returned.
+func (client *client) Outputs(ctx context.Context, outpoints []utxo.Outpoint) ([]utxo.Output, []pack.U64, error) {
+	hashes := make([]string, len(outpoints))
+	for i, outpoint := range outpoints {
+		hash := chainhash.Hash{}
+		copy(hash[:], outpoint.Hash)
+		hashes[i] = hash.String()
+	}
+	txs, err := client.getRawTransactions(ctx, hashes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outputs := make([]utxo.Output, len(outpoints))
+	confs := make([]pack.U64, len(outpoints))
+	for i, outpoint := range outpoints {
+		outputs[i], confs[i], err = outputFromRawTx(outpoint, txs[hashes[i]])
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad output %v:%v: %v", hashes[i], outpoint.Index, err)
+		}
+	}
+	return outputs, confs, nil
+}
+
+// UnspentOutputsBatch returns the unspent outputs identified by the outpoints,
+// and their number of confirmations. The outputs are fetched in batches. If
+// any output cannot be found, or has been spent, then an error is returned.
+func (client *client) UnspentOutputsBatch(ctx context.Context, outpoints []utxo.Outpoint) ([]utxo.Output, []pack.U64, error) {
+	resps := make([]btcjson.GetTxOutResult, len(outpoints))
+	reqs := make([]batchRequest, len(outpoints))
+	for i, outpoint := range outpoints {
+		hash := chainhash.Hash{}
+		copy(hash[:], outpoint.Hash)
+		reqs[i] = batchRequest{method: "gettxout", params: []interface{}{hash.String(), outpoint.Index.Uint32()}, resp: &resps[i]}
+	}
+	errs, err := client.sendBatch(ctx, reqs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad \"gettxout\": %v", err)
+	}
+
+	outputs := make([]utxo.Output, len(outpoints))
+	confs := make([]pack.U64, len(outpoints))
+	for i, outpoint := range outpoints {
+		if errs[i] != nil {
+			return nil, nil, fmt.Errorf("bad \"gettxout\" for %v:%v: %v", reqs[i].params[0], outpoint.Index, errs[i])
+		}
+		outputs[i], confs[i], err = outputFromTxOut(outpoint, resps[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad output %v:%v: %v", reqs[i].params[0], outpoint.Index, err)
+		}
+	}
+	return outputs, confs, nil
+}
+
+// TxSendersBatch returns the senders of each transaction. The transactions are
+// fetched in batches, and then the transactions that produced their inputs are
+// fetched in batches. This means that the number of round trips does not grow
+// with the number of inputs.
+func (client *client) TxSendersBatch(ctx context.Context, txHashes []pack.Bytes) ([][]pack.String, error) {
+	hashes := make([]string, len(txHashes))
+	for i, txHash := range txHashes {
+		hash := chainhash.Hash{}
+		copy(hash[:], txHash)
+		hashes[i] = hash.String()
+	}
+	txs, err := client.getRawTransactions(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	prevHashes := []string{}
+	for _, hash := range hashes {
+		for _, vin := range txs[hash].Vin {
+			if !vin.IsCoinBase() {
+				prevHashes = append(prevHashes, vin.Txid)
+			}
+		}
+	}
+	prevTxs, err := client.getRawTransactions(ctx, prevHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	senders := make([][]pack.String, len(hashes))
+	for i, hash := range hashes {
+		senders[i] = make([]pack.String, 0)
+		for _, vin := range txs[hash].Vin {
+			if vin.IsCoinBase() {
+				continue
+			}
+			prevTx := prevTxs[vin.Txid]
+			if int(vin.Vout) >= len(prevTx.Vout) {
+				return nil, fmt.Errorf("bad input %v:%v: index out of range", vin.Txid, vin.Vout)
+			}
+			for _, addr := range prevTx.Vout[vin.Vout].ScriptPubKey.Addresses {
+				senders[i] = append(senders[i], pack.String(addr))
+			}
+		}
+	}
+	return senders, nil
+}
+
+// getRawTransactions returns the verbose "getrawtransaction" responses for the
+// transactions with the given (hex encoded, byte reversed) hashes. Duplicate
+// hashes are only fetched once.
+func (client *client) getRawTransactions(ctx context.Context, hashes []string) (map[string]btcjson.TxRawResult, error) {
+	reqs := make([]batchRequest, 0, len(hashes))
+	resps := make([]btcjson.TxRawResult, len(hashes))
+	seen := map[string]bool{}
+	for _, hash := range hashes {
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		reqs = append(reqs, batchRequest{method: "getrawtransaction", params: []interface{}{hash, 1}, resp: &resps[len(reqs)]})
+	}
+	errs, err := client.sendBatch(ctx, reqs)
+	if err != nil {
+		return nil, fmt.Errorf("bad \"getrawtransaction\": %v", err)
+	}
+
+	txs := make(map[string]btcjson.TxRawResult, len(reqs))
+	for i, req := range reqs {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("bad \"getrawtransaction\" for %v: %v", req.params[0], errs[i])
+		}
+		txs[req.params[0].(string)] = resps[i]
+	}
+	return txs, nil
+}
+
+// outputsOneByOne calls the function for each outpoint, for clients that do
+// not support batching.
+func outputsOneByOne(ctx context.Context, outpoints []utxo.Outpoint, f func(context.Context, utxo.Outpoint) (utxo.Output, pack.U64, error)) ([]utxo.Output, []pack.U64, error) {
+	outputs := make([]utxo.Output, len(outpoints))
+	confs := make([]pack.U64, len(outpoints))
+	for i, outpoint := range outpoints {
+		var err error
+		if outputs[i], confs[i], err = f(ctx, outpoint); err != nil {
+			return nil, nil, err
+		}
+	}
+	return outputs, confs, nil
+}
+
+// txSendersOneByOne calls the function for each transaction hash, for clients
+// that do not support batching.
+func txSendersOneByOne(ctx context.Context, txHashes []pack.Bytes, f func(context.Context, pack.Bytes) ([]pack.String, error)) ([][]pack.String, error) {
+	senders := make([][]pack.String, len(txHashes))
+	for i, txHash := range txHashes {
+		var err error
+		if senders[i], err = f(ctx, txHash); err != nil {
+			return nil, err
+		}
+	}
+	return senders, nil
+}
+
+// A batchRequest is one of the requests in a JSON-RPC batch. The result of the
+// request is decoded into the response.
+type batchRequest struct {
+	method string
+	params []interface{}
+	resp   interface{}
+}
+
+// sendBatch sends the requests as JSON-RPC batches of (at most) the maximum
+// batch size. An error is returned for each request that fails. If a batch
+// cannot be sent, then an error is returned for the whole batch instead.
+func (client *client) sendBatch(ctx context.Context, reqs []batchRequest) ([]error, error) {
+	maxBatchSize := client.opts.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = len(reqs)
+	}
+	errs := make([]error, len(reqs))
+	for begin := 0; begin < len(reqs); begin += maxBatchSize {
+		end := begin + maxBatchSize
+		if end > len(reqs) {
+			end = len(reqs)
+		}
+		if err := client.sendBatchChunk(ctx, reqs[begin:end], errs[begin:end]); err != nil {
+			return nil, err
+		}
+	}
+	return errs, nil
+}
+
+func (client *client) sendBatchChunk(ctx context.Context, reqs []batchRequest, errs []error) error {
+	// Encode the requests. The ID of each request is its index in the batch,
+	// because responses are not guaranteed to be returned in order.
+	type request struct {
+		Version string        `json:"jsonrpc"`
+		ID      int           `json:"id"`
+		Method  string        `json:"method"`
+		Params  []interface{} `json:"params"`
+	}
+	batch := make([]request, len(reqs))
+	for i, req := range reqs {
+		params := req.params
+		if params == nil {
+			params = []interface{}{}
+		}
+		batch[i] = request{Version: "2.0", ID: i, Method: req.method, Params: params}
+	}
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("encoding batch request: %v", err)
+	}
+
+	return retry(ctx, client.opts, "batch", func() error {
+		body, err := client.transport.Send(ctx, data)
+		if err != nil {
+			return err
+		}
+		resps := []rawResponse{}
+		if err := json.Unmarshal(body, &resps); err != nil {
+			// Nodes respond with a single error, instead of a batch, when the
+			// batch itself is invalid.
+			res := rawResponse{}
+			if json.Unmarshal(body, &res) == nil && res.Error != nil {
+				if err := decodeResult(nil, res); err != nil {
+					return err
+				}
+			}
+			return &decodeError{err: fmt.Errorf("decoding batch response: %v", err)}
+		}
+
+		for i := range errs {
+			errs[i] = &decodeError{err: fmt.Errorf("missing response")}
+		}
+		for _, res := range resps {
+			if res.ID < 0 || res.ID >= len(reqs) {
+				return &decodeError{err: fmt.Errorf("decoding batch response: unexpected id %v", res.ID)}
+			}
+			errs[res.ID] = decodeResult(reqs[res.ID].resp, res)
+		}
+		// If any request failed with a transient error (for example, because
+		// the node is still starting), the whole batch is retried.
+		for _, err := range errs {
+			if IsTransientError(err) {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,139 @@
+package bitcoin_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// batchTransport responds to batches of "getrawtransaction" requests using
+// the given transactions.
+type batchTransport struct {
+	txs      map[string]btcjson.TxRawResult
+	batches  int
+	requests int
+}
+
+func (transport *batchTransport) Send(ctx context.Context, req []byte) ([]byte, error) {
+	reqs := []struct {
+		ID     int           `json:"id"`
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+	}{}
+	if err := json.Unmarshal(req, &reqs); err != nil {
+		return nil, err
+	}
+	transport.batches++
+	transport.requests += len(reqs)
+
+	resps := make([]map[string]interface{}, len(reqs))
+	for i, req := range reqs {
+		// Respond in reverse order, to make sure that responses are matched
+		// to requests by their ID.
+		j := len(reqs) - 1 - i
+		tx, ok := transport.txs[req.Params[0].(string)]
+		if !ok {
+			resps[j] = map[string]interface{}{"id": req.ID, "result": nil, "error": map[string]interface{}{"code": -5, "message": "No such mempool or blockchain transaction"}}
+			continue
+		}
+		resps[j] = map[string]interface{}{"id": req.ID, "result": tx, "error": nil}
+	}
+	return json.Marshal(resps)
+}
+
+var _ = Describe("Batch", func() {
+	hash := func(i byte) chainhash.Hash {
+		return chainhash.Hash{i}
+	}
+	prevTx := func(i byte, addrs ...string) btcjson.TxRawResult {
+		tx := btcjson.TxRawResult{Txid: hash(i).String(), Confirmations: uint64(i)}
+		for j, addr := range addrs {
+			tx.Vout = append(tx.Vout, btcjson.Vout{
+				Value: 0.001,
+				N:     uint32(j),
+				ScriptPubKey: btcjson.ScriptPubKeyResult{
+					Hex:       "00",
+					Addresses: []string{addr},
+				},
+			})
+		}
+		return tx
+	}
+
+	txs := map[string]btcjson.TxRawResult{
+		hash(1).String(): prevTx(1, "addr1", "addr2"),
+		hash(2).String(): prevTx(2, "addr3"),
+		hash(3).String(): {
+			Txid: hash(3).String(),
+			Vin: []btcjson.Vin{
+				{Txid: hash(1).String(), Vout: 0},
+				{Txid: hash(2).String(), Vout: 0},
+				{Txid: hash(1).String(), Vout: 1},
+			},
+		},
+	}
+
+	Context("when fetching the senders of a transaction", func() {
+		It("should fetch the inputs in one batch", func() {
+			transport := &batchTransport{txs: txs}
+			client := bitcoin.NewClient(bitcoin.DefaultClientOptions().WithTransport(transport).WithLogger(nil))
+
+			txHash := hash(3)
+			senders, err := client.TxSenders(context.Background(), pack.NewBytes(txHash[:]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(senders).To(Equal([]pack.String{"addr1", "addr3", "addr2"}))
+			Expect(transport.batches).To(Equal(2))
+			Expect(transport.requests).To(Equal(3))
+		})
+
+		It("should split batches that are too large", func() {
+			transport := &batchTransport{txs: txs}
+			client := bitcoin.NewClient(bitcoin.DefaultClientOptions().WithTransport(transport).WithLogger(nil).WithMaxBatchSize(1))
+
+			txHash := hash(3)
+			_, err := client.TxSenders(context.Background(), pack.NewBytes(txHash[:]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transport.batches).To(Equal(3))
+		})
+	})
+
+	Context("when fetching outputs", func() {
+		It("should return the outputs and confirmations", func() {
+			transport := &batchTransport{txs: txs}
+			client := bitcoin.NewClient(bitcoin.DefaultClientOptions().WithTransport(transport).WithLogger(nil))
+
+			hash1, hash2 := hash(1), hash(2)
+			outpoints := []utxo.Outpoint{
+				{Hash: pack.NewBytes(hash1[:]), Index: pack.NewU32(1)},
+				{Hash: pack.NewBytes(hash2[:]), Index: pack.NewU32(0)},
+			}
+			outputs, confs, err := client.Outputs(context.Background(), outpoints)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transport.batches).To(Equal(1))
+			for i := range outpoints {
+				Expect(outputs[i].Outpoint).To(Equal(outpoints[i]))
+				Expect(outputs[i].Value).To(Equal(pack.NewU256FromUint64(100000)))
+			}
+			Expect(confs).To(Equal([]pack.U64{1, 2}))
+		})
+
+		It("should return an error if an output cannot be found", func() {
+			transport := &batchTransport{txs: txs}
+			client := bitcoin.NewClient(bitcoin.DefaultClientOptions().WithTransport(transport).WithLogger(nil))
+
+			missing := hash(4)
+			_, _, err := client.Outputs(context.Background(), []utxo.Outpoint{{Hash: pack.NewBytes(missing[:])}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(fmt.Sprintf("%v", missing)))
+		})
+	})
+})
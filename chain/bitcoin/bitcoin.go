@@ -5,19 +5,20 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"math/rand"
-	"net/http"
 	"time"
 
 	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcutil"
 	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
 	"github.com/renproject/multichain/api/utxo"
 	"github.com/renproject/pack"
+	"go.uber.org/zap"
 )
 
 const (
@@ -25,6 +26,17 @@ const (
 	DefaultClientTimeout = time.Minute
 	// DefaultClientTimeoutRetry used by the Client.
 	DefaultClientTimeoutRetry = time.Second
+	// DefaultClientMaxTimeoutRetry used by the Client.
+	DefaultClientMaxTimeoutRetry = 30 * time.Second
+	// MinClientTimeoutRetry is the smallest delay between attempts. Smaller
+	// values of TimeoutRetry (including zero) are increased to this value, so
+	// that failing requests are never retried in a tight loop.
+	MinClientTimeoutRetry = 10 * time.Millisecond
+	// DefaultClientMaxBatchSize used by the Client.
+	DefaultClientMaxBatchSize = 100
+	// DefaultClientMaxAttempts used by the Client. Requests that fail with
+	// transient errors are retried until the context is done.
+	DefaultClientMaxAttempts = 0
 	// DefaultClientHost used by the Client. This should only be used for local
 	// deployments of the multichain.
 	DefaultClientHost = "http://0.0.0.0:18443"
@@ -39,24 +51,44 @@ const (
 )
 
 // ClientOptions are used to parameterise the behaviour of the Client.
+//
+// Requests that fail with a transient error (see IsTransientError) are
+// retried. The delay between attempts starts at TimeoutRetry (but never below
+// MinClientTimeoutRetry), and doubles after every attempt up to
+// MaxTimeoutRetry. If MaxAttempts is zero, requests
+// are retried until the context is done. If Transport is nil, requests are
+// sent over HTTP to the Host.
 type ClientOptions struct {
-	Timeout      time.Duration
-	TimeoutRetry time.Duration
-	Host         string
-	User         string
-	Password     string
+	Timeout         time.Duration
+	TimeoutRetry    time.Duration
+	MaxTimeoutRetry time.Duration
+	MaxAttempts     int
+	MaxBatchSize    int
+	Host            string
+	User            string
+	Password        string
+	Logger          *zap.Logger
+	Transport       Transport
 }
 
 // DefaultClientOptions returns ClientOptions with the default settings. These
 // settings are valid for use with the default local deployment of the
 // multichain. In production, the host, user, and password should be changed.
 func DefaultClientOptions() ClientOptions {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		panic(err)
+	}
 	return ClientOptions{
-		Timeout:      DefaultClientTimeout,
-		TimeoutRetry: DefaultClientTimeoutRetry,
-		Host:         DefaultClientHost,
-		User:         DefaultClientUser,
-		Password:     DefaultClientPassword,
+		Timeout:         DefaultClientTimeout,
+		TimeoutRetry:    DefaultClientTimeoutRetry,
+		MaxTimeoutRetry: DefaultClientMaxTimeoutRetry,
+		MaxAttempts:     DefaultClientMaxAttempts,
+		MaxBatchSize:    DefaultClientMaxBatchSize,
+		Host:            DefaultClientHost,
+		User:            DefaultClientUser,
+		Password:        DefaultClientPassword,
+		Logger:          logger,
 	}
 }
 
@@ -80,14 +112,69 @@ func (opts ClientOptions) WithPassword(password string) ClientOptions {
 	return opts
 }
 
+// WithTimeoutRetry sets the initial delay between attempts, when retrying
+// requests.
+func (opts ClientOptions) WithTimeoutRetry(timeoutRetry time.Duration) ClientOptions {
+	opts.TimeoutRetry = timeoutRetry
+	return opts
+}
+
+// WithMaxTimeoutRetry sets the maximum delay between attempts, when retrying
+// requests.
+func (opts ClientOptions) WithMaxTimeoutRetry(maxTimeoutRetry time.Duration) ClientOptions {
+	opts.MaxTimeoutRetry = maxTimeoutRetry
+	return opts
+}
+
+// WithMaxAttempts sets the maximum number of attempts made for each request.
+// Zero means that requests are retried until the context is done.
+func (opts ClientOptions) WithMaxAttempts(maxAttempts int) ClientOptions {
+	opts.MaxAttempts = maxAttempts
+	return opts
+}
+
+// WithMaxBatchSize sets the maximum number of requests that are sent in one
+// JSON-RPC batch. Larger batches are split.
+func (opts ClientOptions) WithMaxBatchSize(maxBatchSize int) ClientOptions {
+	opts.MaxBatchSize = maxBatchSize
+	return opts
+}
+
+// WithLogger sets the logger used to report retries.
+func (opts ClientOptions) WithLogger(logger *zap.Logger) ClientOptions {
+	opts.Logger = logger
+	return opts
+}
+
+// WithTransport sets the transport used to send requests. This overrides the
+// host, user, password, and timeout.
+func (opts ClientOptions) WithTransport(transport Transport) ClientOptions {
+	opts.Transport = transport
+	return opts
+}
+
 // A Client interacts with an instance of the Bitcoin network using the RPC
 // interface exposed by a Bitcoin node.
 type Client interface {
 	utxo.Client
+	utxo.BlockReader
+	utxo.Simulator
 	// UnspentOutputs spendable by the given address.
 	UnspentOutputs(ctx context.Context, minConf, maxConf int64, address address.Address) ([]utxo.Output, error)
+	// Outputs associated with the outpoints, and their number of
+	// confirmations. The outputs are fetched in batches.
+	Outputs(ctx context.Context, outpoints []utxo.Outpoint) ([]utxo.Output, []pack.U64, error)
+	// UnspentOutputsBatch returns the unspent outputs identified by the
+	// outpoints, and their number of confirmations. The outputs are fetched in
+	// batches.
+	UnspentOutputsBatch(ctx context.Context, outpoints []utxo.Outpoint) ([]utxo.Output, []pack.U64, error)
+	// TxSendersBatch returns the senders of each transaction. The transactions
+	// are fetched in batches.
+	TxSendersBatch(ctx context.Context, txHashes []pack.Bytes) ([][]pack.String, error)
 	// Confirmations of a transaction in the Bitcoin network.
 	Confirmations(ctx context.Context, txHash pack.Bytes) (int64, error)
+	// TxInclusion returns the block in which a transaction was included.
+	TxInclusion(ctx context.Context, txHash pack.Bytes) (confirmation.Inclusion, error)
 	// EstimateSmartFee
 	EstimateSmartFee(ctx context.Context, numBlocks int64) (float64, error)
 	// EstimateFeeLegacy
@@ -95,17 +182,19 @@ type Client interface {
 }
 
 type client struct {
-	opts       ClientOptions
-	httpClient http.Client
+	opts      ClientOptions
+	transport Transport
 }
 
 // NewClient returns a new Client.
 func NewClient(opts ClientOptions) Client {
-	httpClient := http.Client{}
-	httpClient.Timeout = opts.Timeout
+	transport := opts.Transport
+	if transport == nil {
+		transport = NewHTTPTransport(opts.Host, opts.User, opts.Password, opts.Timeout)
+	}
 	return &client{
-		opts:       opts,
-		httpClient: httpClient,
+		opts:      opts,
+		transport: transport,
 	}
 }
 
@@ -130,27 +219,7 @@ func (client *client) Output(ctx context.Context, outpoint utxo.Outpoint) (utxo.
 	if err := client.send(ctx, &resp, "getrawtransaction", hash.String(), 1); err != nil {
 		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad \"getrawtransaction\": %v", err)
 	}
-	if outpoint.Index.Uint32() >= uint32(len(resp.Vout)) {
-		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad index: %v is out of range", outpoint.Index)
-	}
-	vout := resp.Vout[outpoint.Index.Uint32()]
-	amount, err := btcutil.NewAmount(vout.Value)
-	if err != nil {
-		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad amount: %v", err)
-	}
-	if amount < 0 {
-		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad amount: %v", amount)
-	}
-	pubKeyScript, err := hex.DecodeString(vout.ScriptPubKey.Hex)
-	if err != nil {
-		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad pubkey script: %v", err)
-	}
-	output := utxo.Output{
-		Outpoint:     outpoint,
-		Value:        pack.NewU256FromU64(pack.NewU64(uint64(amount))),
-		PubKeyScript: pack.NewBytes(pubKeyScript),
-	}
-	return output, pack.NewU64(resp.Confirmations), nil
+	return outputFromRawTx(outpoint, resp)
 }
 
 // UnspentOutput returns the unspent transaction output identified by the
@@ -165,26 +234,7 @@ func (client *client) UnspentOutput(ctx context.Context, outpoint utxo.Outpoint)
 	if err := client.send(ctx, &resp, "gettxout", hash.String(), outpoint.Index.Uint32()); err != nil {
 		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad \"gettxout\": %v", err)
 	}
-	amount, err := btcutil.NewAmount(resp.Value)
-	if err != nil {
-		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad amount: %v", err)
-	}
-	if amount < 0 {
-		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad amount: %v", amount)
-	}
-	if resp.Confirmations < 0 {
-		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad confirmations: %v", resp.Confirmations)
-	}
-	pubKeyScript, err := hex.DecodeString(resp.ScriptPubKey.Hex)
-	if err != nil {
-		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad pubkey script: %v", err)
-	}
-	output := utxo.Output{
-		Outpoint:     outpoint,
-		Value:        pack.NewU256FromU64(pack.NewU64(uint64(amount))),
-		PubKeyScript: pack.NewBytes(pubKeyScript),
-	}
-	return output, pack.NewU64(uint64(resp.Confirmations)), nil
+	return outputFromTxOut(outpoint, resp)
 }
 
 // SubmitTx to the Bitcoin network.
@@ -200,27 +250,39 @@ func (client *client) SubmitTx(ctx context.Context, tx utxo.Tx) error {
 	return nil
 }
 
-// TxSenders returns the senders of the transaction.
-func (client *client) TxSenders(ctx context.Context, id pack.Bytes) ([]pack.String, error) {
-	resp, err := client.getRawTransaction(ctx, id)
+// Simulate the acceptance of the transaction into the mempool using
+// testmempoolaccept. Nodes that do not support testmempoolaccept (for example,
+// Dogecoin nodes) return an error.
+func (client *client) Simulate(ctx context.Context, tx utxo.Tx) (confirmation.Simulation, error) {
+	serial, err := tx.Serialize()
 	if err != nil {
-		return nil, fmt.Errorf("bad \"getrawtransaction\": %v", err)
+		return confirmation.Simulation{}, fmt.Errorf("bad tx: %v", err)
 	}
-	addrs := make([]pack.String, 0)
-	for _, vin := range resp.Vin {
-		txHash, err := chainhash.NewHashFromStr(vin.Txid)
-		if err != nil {
-			return nil, err
-		}
-		rawTx, err := client.getRawTransaction(ctx, txHash.CloneBytes())
-		if err != nil {
-			return nil, err
-		}
-		for _, addr := range rawTx.Vout[int(vin.Vout)].ScriptPubKey.Addresses {
-			addrs = append(addrs, pack.String(addr))
-		}
+	resp := []struct {
+		TxID         string `json:"txid"`
+		Allowed      bool   `json:"allowed"`
+		RejectReason string `json:"reject-reason"`
+	}{}
+	if err := client.send(ctx, &resp, "testmempoolaccept", []string{hex.EncodeToString(serial)}); err != nil {
+		return confirmation.Simulation{}, fmt.Errorf("bad \"testmempoolaccept\": %v", err)
+	}
+	if len(resp) != 1 {
+		return confirmation.Simulation{}, fmt.Errorf("bad \"testmempoolaccept\": expected 1 result, got %v", len(resp))
+	}
+	return confirmation.Simulation{
+		Success:      resp[0].Allowed,
+		RevertReason: pack.String(resp[0].RejectReason),
+	}, nil
+}
+
+// TxSenders returns the senders of the transaction. The transactions that
+// produced the inputs of the transaction are fetched in one batch.
+func (client *client) TxSenders(ctx context.Context, id pack.Bytes) ([]pack.String, error) {
+	senders, err := client.TxSendersBatch(ctx, []pack.Bytes{id})
+	if err != nil {
+		return nil, err
 	}
-	return addrs, nil
+	return senders[0], nil
 }
 
 // UnspentOutputs spendable by the given address.
@@ -279,6 +341,47 @@ func (client *client) Confirmations(ctx context.Context, txHash pack.Bytes) (int
 	return confirmations, nil
 }
 
+// TxInclusion returns the block in which a transaction was included.
+// Transactions that are not known to the node (which must have a transaction
+// index) are reported as unknown.
+func (client *client) TxInclusion(ctx context.Context, txHash pack.Bytes) (confirmation.Inclusion, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], txHash)
+	resp := btcjson.TxRawResult{}
+	if err := client.send(ctx, &resp, "getrawtransaction", hash.String(), 1); err != nil {
+		rpcErr := new(RPCError)
+		if errors.As(err, &rpcErr) && rpcErr.Code == RPCErrorInvalidAddressOrKey {
+			return confirmation.Inclusion{Status: confirmation.TxStatusUnknown}, nil
+		}
+		return confirmation.Inclusion{}, fmt.Errorf("bad \"getrawtransaction\": %v", err)
+	}
+	if resp.BlockHash == "" {
+		return confirmation.Inclusion{Status: confirmation.TxStatusPending}, nil
+	}
+	blockHash, err := chainhash.NewHashFromStr(resp.BlockHash)
+	if err != nil {
+		return confirmation.Inclusion{}, fmt.Errorf("bad block hash: %v", err)
+	}
+	header := btcjson.GetBlockHeaderVerboseResult{}
+	if err := client.send(ctx, &header, "getblockheader", resp.BlockHash, true); err != nil {
+		return confirmation.Inclusion{}, fmt.Errorf("bad \"getblockheader\": %v", err)
+	}
+	if header.Height < 0 {
+		return confirmation.Inclusion{}, fmt.Errorf("bad height: %v", header.Height)
+	}
+	return confirmation.Inclusion{
+		Status:    confirmation.TxStatusConfirmed,
+		BlockHash: pack.NewBytes(blockHash[:]),
+		Height:    pack.NewU64(uint64(header.Height)),
+	}, nil
+}
+
+// TxReceipt returns the outcome of a transaction. Transactions cannot fail
+// once they have been included in a block.
+func (client *client) TxReceipt(ctx context.Context, txHash pack.Bytes) (confirmation.TxReceipt, error) {
+	return txReceipt(ctx, client, txHash)
+}
+
 // EstimateSmartFee fetches the estimated bitcoin network fees to be paid (in
 // BTC per kilobyte) needed for a transaction to be confirmed within `numBlocks`
 // blocks. An error will be returned if the bitcoin node hasn't observed enough
@@ -314,14 +417,21 @@ func (client *client) EstimateFeeLegacy(ctx context.Context, numBlocks int64) (f
 	return resp, nil
 }
 
-func (client *client) getRawTransaction(ctx context.Context, txhash pack.Bytes) (btcjson.TxRawResult, error) {
-	resp := btcjson.TxRawResult{}
-	hash := chainhash.Hash{}
-	copy(hash[:], txhash)
-	if err := client.send(ctx, &resp, "getrawtransaction", hash.String(), 1); err != nil {
-		return resp, fmt.Errorf("bad \"getrawtransaction\": %v", err)
+// txReceipt returns the receipt for a transaction, using the block in which it
+// was included.
+func txReceipt(ctx context.Context, client Client, txHash pack.Bytes) (confirmation.TxReceipt, error) {
+	inclusion, err := client.TxInclusion(ctx, txHash)
+	if err != nil {
+		return confirmation.TxReceipt{}, err
 	}
-	return resp, nil
+	if inclusion.Status != confirmation.TxStatusConfirmed {
+		return confirmation.NewTxReceipt(txHash, inclusion, pack.NewU64(0)), nil
+	}
+	tip, err := client.LatestBlock(ctx)
+	if err != nil {
+		return confirmation.TxReceipt{}, err
+	}
+	return confirmation.NewTxReceipt(txHash, inclusion, tip), nil
 }
 
 func (client *client) send(ctx context.Context, resp interface{}, method string, params ...interface{}) error {
@@ -331,27 +441,23 @@ func (client *client) send(ctx context.Context, resp interface{}, method string,
 		return err
 	}
 
-	return retry(ctx, client.opts.TimeoutRetry, func() error {
-		// Create request and add basic authentication headers. The context is
-		// not attached to the request, and instead we all each attempt to run
-		// for the timeout duration, and we keep attempting until success, or
-		// the context is done.
-		req, err := http.NewRequest("POST", client.opts.Host, bytes.NewBuffer(data))
-		if err != nil {
-			return fmt.Errorf("building http request: %v", err)
-		}
-		req.SetBasicAuth(client.opts.User, client.opts.Password)
-
-		// Send the request and decode the response.
-		res, err := client.httpClient.Do(req)
-		if err != nil {
-			return fmt.Errorf("sending http request: %v", err)
+	return retry(ctx, client.opts, method, func() error {
+		// Send the request and decode the response. Bitcoin nodes respond with
+		// an HTTP error when a JSON-RPC error is returned, so the JSON-RPC error
+		// is preferred (it is more specific).
+		body, sendErr := client.transport.Send(ctx, data)
+		if sendErr != nil && body == nil {
+			return sendErr
 		}
-		defer res.Body.Close()
-		if err := decodeResponse(resp, res.Body); err != nil {
-			return fmt.Errorf("decoding http response: %v", err)
+		if err := decodeResponse(resp, bytes.NewReader(body)); err != nil {
+			if sendErr != nil {
+				if _, ok := err.(*RPCError); !ok {
+					return sendErr
+				}
+			}
+			return err
 		}
-		return nil
+		return sendErr
 	})
 }
 
@@ -378,39 +484,86 @@ func encodeRequest(method string, params []interface{}) ([]byte, error) {
 	return rawReq, nil
 }
 
+// rawResponse is a JSON-RPC response with a result that has not been decoded.
+type rawResponse struct {
+	Version string           `json:"version"`
+	ID      int              `json:"id"`
+	Result  *json.RawMessage `json:"result"`
+	Error   *json.RawMessage `json:"error"`
+}
+
 func decodeResponse(resp interface{}, r io.Reader) error {
-	res := struct {
-		Version string           `json:"version"`
-		ID      int              `json:"id"`
-		Result  *json.RawMessage `json:"result"`
-		Error   *json.RawMessage `json:"error"`
-	}{}
+	res := rawResponse{}
 	if err := json.NewDecoder(r).Decode(&res); err != nil {
 		return fmt.Errorf("decoding response: %v", err)
 	}
-	if res.Error != nil {
-		return fmt.Errorf("decoding response: %v", string(*res.Error))
+	return decodeResult(resp, res)
+}
+
+func decodeResult(resp interface{}, res rawResponse) error {
+	if res.Error != nil && string(*res.Error) != "null" {
+		rpcErr := new(RPCError)
+		if err := json.Unmarshal(*res.Error, rpcErr); err != nil {
+			return &decodeError{err: fmt.Errorf("decoding response: %v", string(*res.Error))}
+		}
+		return rpcErr
 	}
 	if res.Result == nil {
-		return fmt.Errorf("decoding result: result is nil")
+		return &decodeError{err: fmt.Errorf("decoding result: result is nil")}
 	}
 	if err := json.Unmarshal(*res.Result, resp); err != nil {
-		return fmt.Errorf("decoding result: %v", err)
+		return &decodeError{err: fmt.Errorf("decoding result: %v", err)}
 	}
 	return nil
 }
 
-func retry(ctx context.Context, dur time.Duration, f func() error) error {
-	ticker := time.NewTicker(dur)
-	err := f()
-	for err != nil {
-		log.Printf("retrying: %v", err)
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("%v: %v", ctx.Err(), err)
-		case <-ticker.C:
-			err = f()
-		}
+// outputFromRawTx returns the output at the outpoint, from the verbose
+// "getrawtransaction" response for the transaction that produced the output.
+func outputFromRawTx(outpoint utxo.Outpoint, resp btcjson.TxRawResult) (utxo.Output, pack.U64, error) {
+	if outpoint.Index.Uint32() >= uint32(len(resp.Vout)) {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad index: %v is out of range", outpoint.Index)
 	}
-	return nil
+	vout := resp.Vout[outpoint.Index.Uint32()]
+	amount, err := btcutil.NewAmount(vout.Value)
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad amount: %v", err)
+	}
+	if amount < 0 {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad amount: %v", amount)
+	}
+	pubKeyScript, err := hex.DecodeString(vout.ScriptPubKey.Hex)
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad pubkey script: %v", err)
+	}
+	output := utxo.Output{
+		Outpoint:     outpoint,
+		Value:        pack.NewU256FromU64(pack.NewU64(uint64(amount))),
+		PubKeyScript: pack.NewBytes(pubKeyScript),
+	}
+	return output, pack.NewU64(resp.Confirmations), nil
+}
+
+// outputFromTxOut returns the output at the outpoint, from the "gettxout"
+// response for the outpoint.
+func outputFromTxOut(outpoint utxo.Outpoint, resp btcjson.GetTxOutResult) (utxo.Output, pack.U64, error) {
+	amount, err := btcutil.NewAmount(resp.Value)
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad amount: %v", err)
+	}
+	if amount < 0 {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad amount: %v", amount)
+	}
+	if resp.Confirmations < 0 {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad confirmations: %v", resp.Confirmations)
+	}
+	pubKeyScript, err := hex.DecodeString(resp.ScriptPubKey.Hex)
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad pubkey script: %v", err)
+	}
+	output := utxo.Output{
+		Outpoint:     outpoint,
+		Value:        pack.NewU256FromU64(pack.NewU64(uint64(amount))),
+		PubKeyScript: pack.NewBytes(pubKeyScript),
+	}
+	return output, pack.NewU64(uint64(resp.Confirmations)), nil
 }
@@ -0,0 +1,148 @@
+package bitcoin
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// blockTx is a transaction in a verbose "getblock" (or "getrawtransaction")
+// response. Newer nodes report a single address for each output, and older
+// nodes (and forks of Bitcoin) report a list of addresses.
+type blockTx struct {
+	Txid string `json:"txid"`
+	Vout []struct {
+		Value        float64 `json:"value"`
+		N            uint32  `json:"n"`
+		ScriptPubKey struct {
+			Hex       string   `json:"hex"`
+			Address   string   `json:"address"`
+			Addresses []string `json:"addresses"`
+		} `json:"scriptPubKey"`
+	} `json:"vout"`
+}
+
+// BlockHash returns the hash of the block at the given height in the longest
+// blockchain.
+func (client *client) BlockHash(ctx context.Context, height pack.U64) (pack.Bytes, error) {
+	resp := ""
+	if err := client.send(ctx, &resp, "getblockhash", height.Uint64()); err != nil {
+		return nil, fmt.Errorf("bad \"getblockhash\": %v", err)
+	}
+	hash, err := chainhash.NewHashFromStr(resp)
+	if err != nil {
+		return nil, fmt.Errorf("bad block hash: %v", err)
+	}
+	return pack.NewBytes(hash[:]), nil
+}
+
+// Block returns the block with the given hash, and all of the outputs produced
+// by the transactions in it. Nodes that do not support "getblock" with
+// verbosity 2 (such as Dogecoin nodes) are supported by fetching the
+// transactions in the block in batches.
+func (client *client) Block(ctx context.Context, blockHash pack.Bytes) (utxo.Block, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], blockHash)
+	resp := struct {
+		Hash              string            `json:"hash"`
+		PreviousBlockHash string            `json:"previousblockhash"`
+		Height            int64             `json:"height"`
+		Tx                []json.RawMessage `json:"tx"`
+	}{}
+	if err := client.send(ctx, &resp, "getblock", hash.String(), 2); err != nil {
+		rpcErr := new(RPCError)
+		if !errors.As(err, &rpcErr) {
+			return utxo.Block{}, fmt.Errorf("bad \"getblock\": %v", err)
+		}
+		if err := client.send(ctx, &resp, "getblock", hash.String(), true); err != nil {
+			return utxo.Block{}, fmt.Errorf("bad \"getblock\": %v", err)
+		}
+	}
+	if resp.Height < 0 {
+		return utxo.Block{}, fmt.Errorf("bad height: %v", resp.Height)
+	}
+
+	// Transactions are either objects (verbosity 2), or transaction hashes.
+	txs := make([]blockTx, len(resp.Tx))
+	reqs := []batchRequest{}
+	for i, rawTx := range resp.Tx {
+		txid := ""
+		if err := json.Unmarshal(rawTx, &txid); err == nil {
+			reqs = append(reqs, batchRequest{method: "getrawtransaction", params: []interface{}{txid, 1}, resp: &txs[i]})
+			continue
+		}
+		if err := json.Unmarshal(rawTx, &txs[i]); err != nil {
+			return utxo.Block{}, fmt.Errorf("bad tx %v: %v", i, err)
+		}
+	}
+	if len(reqs) > 0 {
+		errs, err := client.sendBatch(ctx, reqs)
+		if err != nil {
+			return utxo.Block{}, fmt.Errorf("bad \"getrawtransaction\": %v", err)
+		}
+		for i, err := range errs {
+			if err != nil {
+				return utxo.Block{}, fmt.Errorf("bad \"getrawtransaction\" for %v: %v", reqs[i].params[0], err)
+			}
+		}
+	}
+
+	block := utxo.Block{
+		Hash:    pack.NewBytes(hash[:]),
+		Height:  pack.NewU64(uint64(resp.Height)),
+		Outputs: []utxo.BlockOutput{},
+	}
+	if resp.PreviousBlockHash != "" {
+		prevHash, err := chainhash.NewHashFromStr(resp.PreviousBlockHash)
+		if err != nil {
+			return utxo.Block{}, fmt.Errorf("bad previous block hash: %v", err)
+		}
+		block.PrevHash = pack.NewBytes(prevHash[:])
+	}
+	for _, tx := range txs {
+		txid, err := chainhash.NewHashFromStr(tx.Txid)
+		if err != nil {
+			return utxo.Block{}, fmt.Errorf("bad txid: %v", err)
+		}
+		for _, vout := range tx.Vout {
+			amount, err := btcutil.NewAmount(vout.Value)
+			if err != nil {
+				return utxo.Block{}, fmt.Errorf("bad amount: %v", err)
+			}
+			if amount < 0 {
+				return utxo.Block{}, fmt.Errorf("bad amount: %v", amount)
+			}
+			pubKeyScript, err := hex.DecodeString(vout.ScriptPubKey.Hex)
+			if err != nil {
+				return utxo.Block{}, fmt.Errorf("bad pubkey script: %v", err)
+			}
+			addrs := make([]address.Address, 0, len(vout.ScriptPubKey.Addresses)+1)
+			if vout.ScriptPubKey.Address != "" {
+				addrs = append(addrs, address.Address(vout.ScriptPubKey.Address))
+			}
+			for _, addr := range vout.ScriptPubKey.Addresses {
+				addrs = append(addrs, address.Address(addr))
+			}
+			block.Outputs = append(block.Outputs, utxo.BlockOutput{
+				Output: utxo.Output{
+					Outpoint: utxo.Outpoint{
+						Hash:  pack.NewBytes(txid[:]),
+						Index: pack.NewU32(vout.N),
+					},
+					Value:        pack.NewU256FromU64(pack.NewU64(uint64(amount))),
+					PubKeyScript: pack.NewBytes(pubKeyScript),
+				},
+				Addresses: addrs,
+			})
+		}
+	}
+	return block, nil
+}
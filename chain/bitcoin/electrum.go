@@ -0,0 +1,566 @@
+package bitcoin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultElectrumHost used by the Electrum client. This is the default
+	// address of the Electrum server run by electrs in regtest mode, and should
+	// only be used for local deployments of the multichain.
+	DefaultElectrumHost = "tcp://0.0.0.0:60401"
+
+	// ElectrumProtocolVersion is the version of the Electrum protocol used by
+	// the Electrum client.
+	ElectrumProtocolVersion = "1.4"
+)
+
+type electrumClient struct {
+	opts   ClientOptions
+	params *chaincfg.Params
+
+	mu     *sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int
+}
+
+// NewElectrumClient returns a Client that is backed by the Electrum server at
+// the host. The host must be of the form tcp://host:port, or ssl://host:port
+// for servers that require TLS. Unlike the Client returned by NewClient, it
+// does not need a node with a wallet, so outputs can be queried for any
+// address without importing it. The timeout, retry, and logger options are
+// used in the same way as by NewClient. The user, password, and transport
+// options are ignored. The chain configuration is used to decode addresses.
+//
+// One connection is kept open to the server, and it is re-opened whenever a
+// request fails. Batch methods send one request per transaction.
+func NewElectrumClient(opts ClientOptions, params *chaincfg.Params) Client {
+	return &electrumClient{
+		opts:   opts,
+		params: params,
+
+		mu:     new(sync.Mutex),
+		nextID: 1,
+	}
+}
+
+// electrumUTXO is an unspent output returned by the Electrum server.
+type electrumUTXO struct {
+	TxHash string `json:"tx_hash"`
+	TxPos  uint32 `json:"tx_pos"`
+	Height int64  `json:"height"`
+	Value  int64  `json:"value"`
+}
+
+// LatestBlock returns the height of the longest blockchain.
+func (client *electrumClient) LatestBlock(ctx context.Context) (pack.U64, error) {
+	resp := struct {
+		Height int64 `json:"height"`
+	}{}
+	if err := client.call(ctx, &resp, "blockchain.headers.subscribe"); err != nil {
+		return pack.NewU64(0), fmt.Errorf("bad \"blockchain.headers.subscribe\": %v", err)
+	}
+	if resp.Height < 0 {
+		return pack.NewU64(0), fmt.Errorf("unexpected block count, expected > 0, got: %v", resp.Height)
+	}
+	return pack.NewU64(uint64(resp.Height)), nil
+}
+
+// Output associated with an outpoint, and its number of confirmations.
+func (client *electrumClient) Output(ctx context.Context, outpoint utxo.Outpoint) (utxo.Output, pack.U64, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], outpoint.Hash)
+	tx, err := client.tx(ctx, hash.String())
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), err
+	}
+	if outpoint.Index.Uint32() >= uint32(len(tx.TxOut)) {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad index: %v is out of range", outpoint.Index)
+	}
+	txOut := tx.TxOut[outpoint.Index.Uint32()]
+	if txOut.Value < 0 {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad amount: %v", txOut.Value)
+	}
+	confs, err := client.confirmations(ctx, hash.String(), txOut.PkScript)
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), err
+	}
+	output := utxo.Output{
+		Outpoint:     outpoint,
+		Value:        pack.NewU256FromU64(pack.NewU64(uint64(txOut.Value))),
+		PubKeyScript: pack.NewBytes(txOut.PkScript),
+	}
+	return output, confs, nil
+}
+
+// UnspentOutput returns the unspent transaction output identified by the
+// given outpoint. It also returns the number of confirmations for the
+// output. If the output cannot be found before the context is done, the
+// output is invalid, or the output has been spent, then an error should be
+// returned.
+func (client *electrumClient) UnspentOutput(ctx context.Context, outpoint utxo.Outpoint) (utxo.Output, pack.U64, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], outpoint.Hash)
+	tx, err := client.tx(ctx, hash.String())
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), err
+	}
+	if outpoint.Index.Uint32() >= uint32(len(tx.TxOut)) {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad index: %v is out of range", outpoint.Index)
+	}
+	pubKeyScript := tx.TxOut[outpoint.Index.Uint32()].PkScript
+	utxos, err := client.listUnspent(ctx, pubKeyScript)
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), err
+	}
+	tip, err := client.LatestBlock(ctx)
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), err
+	}
+	for _, u := range utxos {
+		if u.TxHash != hash.String() || u.TxPos != outpoint.Index.Uint32() {
+			continue
+		}
+		if u.Value < 0 {
+			return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad amount: %v", u.Value)
+		}
+		output := utxo.Output{
+			Outpoint:     outpoint,
+			Value:        pack.NewU256FromU64(pack.NewU64(uint64(u.Value))),
+			PubKeyScript: pack.NewBytes(pubKeyScript),
+		}
+		return output, electrumConfirmations(u.Height, tip), nil
+	}
+	return utxo.Output{}, pack.NewU64(0), fmt.Errorf("output %v:%v: spent or not found", hash, outpoint.Index)
+}
+
+// SubmitTx to the Bitcoin network.
+func (client *electrumClient) SubmitTx(ctx context.Context, tx utxo.Tx) error {
+	serial, err := tx.Serialize()
+	if err != nil {
+		return fmt.Errorf("bad tx: %v", err)
+	}
+	resp := ""
+	if err := client.call(ctx, &resp, "blockchain.transaction.broadcast", hex.EncodeToString(serial)); err != nil {
+		return fmt.Errorf("bad \"blockchain.transaction.broadcast\": %v", err)
+	}
+	return nil
+}
+
+// Simulate is not supported by Electrum servers, and always returns an error.
+func (client *electrumClient) Simulate(ctx context.Context, tx utxo.Tx) (confirmation.Simulation, error) {
+	return confirmation.Simulation{}, fmt.Errorf("simulating tx: not supported by electrum")
+}
+
+// TxSenders returns the senders of the transaction.
+func (client *electrumClient) TxSenders(ctx context.Context, id pack.Bytes) ([]pack.String, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], id)
+	tx, err := client.tx(ctx, hash.String())
+	if err != nil {
+		return nil, err
+	}
+	prevTxs := map[chainhash.Hash]*wire.MsgTx{}
+	addrs := make([]pack.String, 0)
+	for _, txIn := range tx.TxIn {
+		prevOut := txIn.PreviousOutPoint
+		if prevOut.Index == wire.MaxPrevOutIndex && prevOut.Hash == (chainhash.Hash{}) {
+			// Coinbase inputs have no senders.
+			continue
+		}
+		prevTx, ok := prevTxs[prevOut.Hash]
+		if !ok {
+			if prevTx, err = client.tx(ctx, prevOut.Hash.String()); err != nil {
+				return nil, err
+			}
+			prevTxs[prevOut.Hash] = prevTx
+		}
+		if prevOut.Index >= uint32(len(prevTx.TxOut)) {
+			return nil, fmt.Errorf("bad input %v: index out of range", prevOut)
+		}
+		_, prevAddrs, _, err := txscript.ExtractPkScriptAddrs(prevTx.TxOut[prevOut.Index].PkScript, client.params)
+		if err != nil {
+			return nil, fmt.Errorf("bad input %v: %v", prevOut, err)
+		}
+		for _, addr := range prevAddrs {
+			addrs = append(addrs, pack.String(addr.EncodeAddress()))
+		}
+	}
+	return addrs, nil
+}
+
+// UnspentOutputs spendable by the given address, with at least the minimum
+// number of confirmations, and at most the maximum number of confirmations.
+func (client *electrumClient) UnspentOutputs(ctx context.Context, minConf, maxConf int64, addr address.Address) ([]utxo.Output, error) {
+	decodedAddr, err := decodeAddress(string(addr), client.params)
+	if err != nil {
+		return nil, fmt.Errorf("bad address %v: %v", addr, err)
+	}
+	pubKeyScript, err := payToAddrScript(decodedAddr)
+	if err != nil {
+		return nil, fmt.Errorf("bad address %v: %v", addr, err)
+	}
+	utxos, err := client.listUnspent(ctx, pubKeyScript)
+	if err != nil {
+		return nil, err
+	}
+	tip, err := client.LatestBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]utxo.Output, 0, len(utxos))
+	for _, u := range utxos {
+		confs := int64(electrumConfirmations(u.Height, tip))
+		if confs < minConf || confs > maxConf {
+			continue
+		}
+		txid, err := chainhash.NewHashFromStr(u.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf("bad txid: %v", err)
+		}
+		if u.Value < 0 {
+			return nil, fmt.Errorf("bad amount: %v", u.Value)
+		}
+		outputs = append(outputs, utxo.Output{
+			Outpoint: utxo.Outpoint{
+				Hash:  pack.NewBytes(txid[:]),
+				Index: pack.NewU32(u.TxPos),
+			},
+			Value:        pack.NewU256FromU64(pack.NewU64(uint64(u.Value))),
+			PubKeyScript: pack.NewBytes(pubKeyScript),
+		})
+	}
+	return outputs, nil
+}
+
+// Confirmations of a transaction in the Bitcoin network.
+func (client *electrumClient) Confirmations(ctx context.Context, txHash pack.Bytes) (int64, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], txHash)
+	tx, err := client.tx(ctx, hash.String())
+	if err != nil {
+		return 0, err
+	}
+	if len(tx.TxOut) == 0 {
+		return 0, fmt.Errorf("bad tx %v: no outputs", hash)
+	}
+	// Electrum servers index transactions by the scripts that they touch, so
+	// the history of any output script includes the transaction.
+	confs, err := client.confirmations(ctx, hash.String(), tx.TxOut[0].PkScript)
+	if err != nil {
+		return 0, err
+	}
+	return int64(confs), nil
+}
+
+// TxInclusion returns the block in which a transaction was included. Electrum
+// servers only report the height of the block, so the hash of the block at
+// that height in the longest blockchain is used.
+func (client *electrumClient) TxInclusion(ctx context.Context, txHash pack.Bytes) (confirmation.Inclusion, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], txHash)
+	tx, err := client.tx(ctx, hash.String())
+	if err != nil {
+		if isElectrumTxNotFound(err) {
+			return confirmation.Inclusion{Status: confirmation.TxStatusUnknown}, nil
+		}
+		return confirmation.Inclusion{}, err
+	}
+	if len(tx.TxOut) == 0 {
+		return confirmation.Inclusion{}, fmt.Errorf("bad tx %v: no outputs", hash)
+	}
+	history := []struct {
+		TxHash string `json:"tx_hash"`
+		Height int64  `json:"height"`
+	}{}
+	if err := client.call(ctx, &history, "blockchain.scripthash.get_history", electrumScriptHash(tx.TxOut[0].PkScript)); err != nil {
+		return confirmation.Inclusion{}, fmt.Errorf("bad \"blockchain.scripthash.get_history\": %v", err)
+	}
+	for _, entry := range history {
+		if entry.TxHash != hash.String() || entry.Height <= 0 {
+			continue
+		}
+		blockHash, err := client.BlockHash(ctx, pack.NewU64(uint64(entry.Height)))
+		if err != nil {
+			return confirmation.Inclusion{}, err
+		}
+		return confirmation.Inclusion{
+			Status:    confirmation.TxStatusConfirmed,
+			BlockHash: blockHash,
+			Height:    pack.NewU64(uint64(entry.Height)),
+		}, nil
+	}
+	return confirmation.Inclusion{Status: confirmation.TxStatusPending}, nil
+}
+
+// TxReceipt returns the outcome of a transaction. Transactions cannot fail
+// once they have been included in a block.
+func (client *electrumClient) TxReceipt(ctx context.Context, txHash pack.Bytes) (confirmation.TxReceipt, error) {
+	return txReceipt(ctx, client, txHash)
+}
+
+// EstimateSmartFee fetches the estimated bitcoin network fees to be paid (in
+// BTC per kilobyte) needed for a transaction to be confirmed within `numBlocks`
+// blocks. An error will be returned if the server cannot make an estimate for
+// the provided target `numBlocks`.
+func (client *electrumClient) EstimateSmartFee(ctx context.Context, numBlocks int64) (float64, error) {
+	var resp float64
+	if err := client.call(ctx, &resp, "blockchain.estimatefee", numBlocks); err != nil {
+		return 0.0, fmt.Errorf("estimating smart fee: %v", err)
+	}
+	if resp < 0 {
+		return 0.0, fmt.Errorf("estimating smart fee: no estimate for %v blocks", numBlocks)
+	}
+	return resp, nil
+}
+
+// EstimateFeeLegacy is the same as EstimateSmartFee, because Electrum does not
+// distinguish between the two. If the number of blocks is zero, the estimate
+// for the next block is returned.
+func (client *electrumClient) EstimateFeeLegacy(ctx context.Context, numBlocks int64) (float64, error) {
+	if numBlocks < 1 {
+		numBlocks = 1
+	}
+	return client.EstimateSmartFee(ctx, numBlocks)
+}
+
+// Outputs associated with the outpoints, and their number of confirmations.
+func (client *electrumClient) Outputs(ctx context.Context, outpoints []utxo.Outpoint) ([]utxo.Output, []pack.U64, error) {
+	return outputsOneByOne(ctx, outpoints, client.Output)
+}
+
+// UnspentOutputsBatch returns the unspent outputs identified by the outpoints,
+// and their number of confirmations.
+func (client *electrumClient) UnspentOutputsBatch(ctx context.Context, outpoints []utxo.Outpoint) ([]utxo.Output, []pack.U64, error) {
+	return outputsOneByOne(ctx, outpoints, client.UnspentOutput)
+}
+
+// TxSendersBatch returns the senders of each transaction.
+func (client *electrumClient) TxSendersBatch(ctx context.Context, txHashes []pack.Bytes) ([][]pack.String, error) {
+	return txSendersOneByOne(ctx, txHashes, client.TxSenders)
+}
+
+// BlockHash returns the hash of the block at the given height in the longest
+// blockchain.
+func (client *electrumClient) BlockHash(ctx context.Context, height pack.U64) (pack.Bytes, error) {
+	resp := ""
+	if err := client.call(ctx, &resp, "blockchain.block.header", height.Uint64()); err != nil {
+		return nil, fmt.Errorf("bad \"blockchain.block.header\": %v", err)
+	}
+	header, err := hex.DecodeString(resp)
+	if err != nil {
+		return nil, fmt.Errorf("bad block header: %v", err)
+	}
+	hash := chainhash.DoubleHashH(header)
+	return pack.NewBytes(hash[:]), nil
+}
+
+// Block is not supported, because Electrum servers do not serve the
+// transactions in a block.
+func (client *electrumClient) Block(ctx context.Context, blockHash pack.Bytes) (utxo.Block, error) {
+	return utxo.Block{}, fmt.Errorf("block: not supported by electrum servers")
+}
+
+// tx returns the transaction with the given (hex encoded, byte reversed)
+// hash.
+func (client *electrumClient) tx(ctx context.Context, hash string) (*wire.MsgTx, error) {
+	resp := ""
+	if err := client.call(ctx, &resp, "blockchain.transaction.get", hash); err != nil {
+		return nil, fmt.Errorf("bad \"blockchain.transaction.get\": %w", err)
+	}
+	serial, err := hex.DecodeString(resp)
+	if err != nil {
+		return nil, fmt.Errorf("bad tx %v: %v", hash, err)
+	}
+	tx := new(wire.MsgTx)
+	if err := tx.Deserialize(bytes.NewReader(serial)); err != nil {
+		return nil, fmt.Errorf("bad tx %v: %v", hash, err)
+	}
+	return tx, nil
+}
+
+// isElectrumTxNotFound returns true if the error is returned by Electrum
+// servers for transactions that they do not know about. Electrs reports a
+// missing transaction, and ElectrumX forwards the error of its node.
+func isElectrumTxNotFound(err error) bool {
+	rpcErr := new(RPCError)
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return strings.Contains(rpcErr.Message, "missing transaction") ||
+		strings.Contains(rpcErr.Message, "No such mempool or blockchain transaction")
+}
+
+// confirmations returns the number of confirmations of the transaction with
+// the given hash, using the history of one of the scripts that it touches.
+func (client *electrumClient) confirmations(ctx context.Context, hash string, pubKeyScript []byte) (pack.U64, error) {
+	history := []struct {
+		TxHash string `json:"tx_hash"`
+		Height int64  `json:"height"`
+	}{}
+	if err := client.call(ctx, &history, "blockchain.scripthash.get_history", electrumScriptHash(pubKeyScript)); err != nil {
+		return pack.NewU64(0), fmt.Errorf("bad \"blockchain.scripthash.get_history\": %v", err)
+	}
+	tip, err := client.LatestBlock(ctx)
+	if err != nil {
+		return pack.NewU64(0), err
+	}
+	for _, entry := range history {
+		if entry.TxHash == hash {
+			return electrumConfirmations(entry.Height, tip), nil
+		}
+	}
+	return pack.NewU64(0), fmt.Errorf("tx %v: not found in history", hash)
+}
+
+func (client *electrumClient) listUnspent(ctx context.Context, pubKeyScript []byte) ([]electrumUTXO, error) {
+	utxos := []electrumUTXO{}
+	if err := client.call(ctx, &utxos, "blockchain.scripthash.listunspent", electrumScriptHash(pubKeyScript)); err != nil {
+		return nil, fmt.Errorf("bad \"blockchain.scripthash.listunspent\": %v", err)
+	}
+	return utxos, nil
+}
+
+// call the method on the Electrum server, and decode the result into resp.
+func (client *electrumClient) call(ctx context.Context, resp interface{}, method string, params ...interface{}) error {
+	return retry(ctx, client.opts, method, func() error {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+
+		if client.conn == nil {
+			if err := client.connect(ctx); err != nil {
+				return err
+			}
+		}
+		res, err := client.roundTrip(ctx, method, params)
+		if err != nil {
+			// The connection is in an unknown state, so it is closed and
+			// re-opened by the next request.
+			client.conn.Close()
+			client.conn = nil
+			return err
+		}
+		return decodeResult(resp, res)
+	})
+}
+
+// connect to the Electrum server, and negotiate the protocol version. This
+// must be called while holding the mutex.
+func (client *electrumClient) connect(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: client.opts.Timeout}
+	var conn net.Conn
+	var err error
+	switch {
+	case strings.HasPrefix(client.opts.Host, "tcp://"):
+		conn, err = dialer.DialContext(ctx, "tcp", strings.TrimPrefix(client.opts.Host, "tcp://"))
+	case strings.HasPrefix(client.opts.Host, "ssl://"):
+		conn, err = tls.DialWithDialer(dialer, "tcp", strings.TrimPrefix(client.opts.Host, "ssl://"), &tls.Config{})
+	default:
+		return fmt.Errorf("bad host %v: expected tcp:// or ssl://", client.opts.Host)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to %v: %v", client.opts.Host, err)
+	}
+	client.conn = conn
+	client.reader = bufio.NewReader(conn)
+
+	res, err := client.roundTrip(ctx, "server.version", []interface{}{"multichain", ElectrumProtocolVersion})
+	if err == nil {
+		err = decodeResult(new(json.RawMessage), res)
+	}
+	if err != nil {
+		client.conn.Close()
+		client.conn = nil
+		return fmt.Errorf("negotiating version: %v", err)
+	}
+	return nil
+}
+
+// roundTrip sends one request, and waits for its response. Notifications
+// (which are sent by the server after subscribing to new headers) are
+// skipped. This must be called while holding the mutex.
+func (client *electrumClient) roundTrip(ctx context.Context, method string, params []interface{}) (rawResponse, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	id := client.nextID
+	client.nextID++
+	req, err := json.Marshal(struct {
+		Version string        `json:"jsonrpc"`
+		ID      int           `json:"id"`
+		Method  string        `json:"method"`
+		Params  []interface{} `json:"params"`
+	}{"2.0", id, method, params})
+	if err != nil {
+		return rawResponse{}, &decodeError{err: fmt.Errorf("encoding request: %v", err)}
+	}
+
+	deadline := time.Now().Add(client.opts.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := client.conn.SetDeadline(deadline); err != nil {
+		return rawResponse{}, fmt.Errorf("setting deadline: %v", err)
+	}
+	if _, err := client.conn.Write(append(req, '\n')); err != nil {
+		return rawResponse{}, fmt.Errorf("writing request: %v", err)
+	}
+	for {
+		line, err := client.reader.ReadBytes('\n')
+		if err != nil {
+			return rawResponse{}, fmt.Errorf("reading response: %v", err)
+		}
+		res := struct {
+			rawResponse
+			Method string `json:"method"`
+		}{}
+		if err := json.Unmarshal(line, &res); err != nil {
+			return rawResponse{}, fmt.Errorf("decoding response: %v", err)
+		}
+		if res.Method != "" || res.ID != id {
+			continue
+		}
+		return res.rawResponse, nil
+	}
+}
+
+// electrumScriptHash returns the hash of the script, as used by the Electrum
+// protocol to identify addresses: the byte reversed SHA256 hash of the
+// script, encoded as hex.
+func electrumScriptHash(script []byte) string {
+	hash := sha256.Sum256(script)
+	for i := 0; i < len(hash)/2; i++ {
+		hash[i], hash[len(hash)-1-i] = hash[len(hash)-1-i], hash[i]
+	}
+	return hex.EncodeToString(hash[:])
+}
+
+// electrumConfirmations returns the number of confirmations of a transaction
+// at the given height. Electrum servers use heights of zero, or less, for
+// transactions in the mempool.
+func electrumConfirmations(height int64, tip pack.U64) pack.U64 {
+	return confirmationsAt(height > 0, uint64(height), tip.Uint64())
+}
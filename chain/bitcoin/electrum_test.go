@@ -0,0 +1,242 @@
+package bitcoin_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// serveElectrum serves newline delimited JSON-RPC requests on the listener,
+// using the handler to compute results. Results that are RPC errors are sent
+// as errors. A notification is sent before every response, to make sure that
+// clients skip notifications.
+func serveElectrum(listener net.Listener, handler func(method string, params []interface{}) interface{}) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				line, err := reader.ReadBytes('\n')
+				if err != nil {
+					return
+				}
+				req := struct {
+					ID     int           `json:"id"`
+					Method string        `json:"method"`
+					Params []interface{} `json:"params"`
+				}{}
+				if err := json.Unmarshal(line, &req); err != nil {
+					return
+				}
+				notification, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "method": "blockchain.headers.subscribe", "params": []interface{}{map[string]interface{}{"height": 1}}})
+				res := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+				result := handler(req.Method, req.Params)
+				if rpcErr, ok := result.(*bitcoin.RPCError); ok {
+					res["error"] = rpcErr
+				} else {
+					res["result"] = result
+				}
+				data, _ := json.Marshal(res)
+				conn.Write(append(append(notification, '\n'), append(data, '\n')...))
+			}
+		}()
+	}
+}
+
+var _ = Describe("Electrum", func() {
+	ctx := context.Background()
+
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160([]byte("pubkey")), &chaincfg.RegressionNetParams)
+	if err != nil {
+		panic(err)
+	}
+	pubKeyScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		panic(err)
+	}
+	scriptHash := sha256.Sum256(pubKeyScript)
+	for i := 0; i < len(scriptHash)/2; i++ {
+		scriptHash[i], scriptHash[len(scriptHash)-1-i] = scriptHash[len(scriptHash)-1-i], scriptHash[i]
+	}
+
+	// The previous transaction pays to the address, and the transaction spends
+	// it and pays back to the address.
+	prevTx := wire.NewMsgTx(wire.TxVersion)
+	prevTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), nil, nil))
+	prevTx.AddTxOut(wire.NewTxOut(200000, pubKeyScript))
+	prevTxHash := prevTx.TxHash()
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevTxHash, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(100000, pubKeyScript))
+	txHash := tx.TxHash()
+
+	serialize := func(msgTx *wire.MsgTx) string {
+		buf := new(bytes.Buffer)
+		if err := msgTx.Serialize(buf); err != nil {
+			panic(err)
+		}
+		return hex.EncodeToString(buf.Bytes())
+	}
+	txs := map[string]string{
+		prevTxHash.String(): serialize(prevTx),
+		txHash.String():     serialize(tx),
+	}
+
+	// The server fails to look up this transaction.
+	failingTxHash := chainhash.Hash{1}
+
+	// The chain is at height 110, the previous transaction was confirmed at
+	// height 100, and the transaction was confirmed at height 101.
+	handler := func(method string, params []interface{}) interface{} {
+		switch method {
+		case "server.version":
+			return []string{"stub", "1.4"}
+		case "blockchain.headers.subscribe":
+			return map[string]interface{}{"height": 110, "hex": ""}
+		case "blockchain.transaction.get":
+			if params[0] == failingTxHash.String() {
+				return &bitcoin.RPCError{Code: 2, Message: "daemon error: connection refused"}
+			}
+			serial, ok := txs[params[0].(string)]
+			if !ok {
+				return &bitcoin.RPCError{Code: 2, Message: "missing transaction"}
+			}
+			return serial
+		case "blockchain.block.header":
+			return hex.EncodeToString(make([]byte, 80))
+		case "blockchain.scripthash.get_history":
+			if params[0] != hex.EncodeToString(scriptHash[:]) {
+				return []interface{}{}
+			}
+			return []map[string]interface{}{
+				{"tx_hash": prevTxHash.String(), "height": 100},
+				{"tx_hash": txHash.String(), "height": 101},
+			}
+		case "blockchain.scripthash.listunspent":
+			if params[0] != hex.EncodeToString(scriptHash[:]) {
+				return []interface{}{}
+			}
+			return []map[string]interface{}{
+				{"tx_hash": txHash.String(), "tx_pos": 0, "height": 101, "value": 100000},
+			}
+		case "blockchain.estimatefee":
+			return 0.0002
+		}
+		return nil
+	}
+
+	newClient := func() (bitcoin.Client, func()) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		go serveElectrum(listener, handler)
+		opts := bitcoin.DefaultClientOptions().WithHost("tcp://" + listener.Addr().String()).WithLogger(nil).WithMaxAttempts(1)
+		return bitcoin.NewElectrumClient(opts, &chaincfg.RegressionNetParams), func() { listener.Close() }
+	}
+
+	Context("when querying an output", func() {
+		It("should return the output and its confirmations", func() {
+			client, closeServer := newClient()
+			defer closeServer()
+
+			outpoint := utxo.Outpoint{Hash: pack.NewBytes(txHash[:]), Index: pack.NewU32(0)}
+			output, confs, err := client.UnspentOutput(ctx, outpoint)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(output.Value).To(Equal(pack.NewU256FromUint64(100000)))
+			Expect(confs).To(Equal(pack.NewU64(10)))
+
+			prevOutpoint := utxo.Outpoint{Hash: pack.NewBytes(prevTxHash[:]), Index: pack.NewU32(0)}
+			_, confs, err = client.Output(ctx, prevOutpoint)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(pack.NewU64(11)))
+
+			// The previous output has been spent.
+			_, _, err = client.UnspentOutput(ctx, prevOutpoint)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return the senders", func() {
+			client, closeServer := newClient()
+			defer closeServer()
+
+			senders, err := client.TxSenders(ctx, pack.NewBytes(txHash[:]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(senders).To(Equal([]pack.String{pack.String(addr.EncodeAddress())}))
+		})
+	})
+
+	Context("when querying the inclusion of a transaction", func() {
+		It("should return the block of confirmed transactions", func() {
+			client, closeServer := newClient()
+			defer closeServer()
+
+			inclusion, err := client.TxInclusion(ctx, pack.NewBytes(txHash[:]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inclusion.Status).To(Equal(confirmation.TxStatusConfirmed))
+			Expect(inclusion.Height).To(Equal(pack.NewU64(101)))
+		})
+
+		It("should return an unknown status for missing transactions", func() {
+			client, closeServer := newClient()
+			defer closeServer()
+
+			missingTxHash := chainhash.Hash{2}
+			inclusion, err := client.TxInclusion(ctx, pack.NewBytes(missingTxHash[:]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inclusion.Status).To(Equal(confirmation.TxStatusUnknown))
+		})
+
+		It("should return other server errors", func() {
+			client, closeServer := newClient()
+			defer closeServer()
+
+			_, err := client.TxInclusion(ctx, pack.NewBytes(failingTxHash[:]))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when querying the unspent outputs of an address", func() {
+		It("should return the outputs", func() {
+			client, closeServer := newClient()
+			defer closeServer()
+
+			outputs, err := client.UnspentOutputs(ctx, 1, 999999, address.Address(addr.EncodeAddress()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputs).To(HaveLen(1))
+			Expect(outputs[0].Outpoint.Hash).To(Equal(pack.NewBytes(txHash[:])))
+			Expect(hex.EncodeToString(outputs[0].PubKeyScript)).To(Equal(hex.EncodeToString(pubKeyScript)))
+		})
+	})
+
+	Context("when estimating fees", func() {
+		It("should return the estimate", func() {
+			client, closeServer := newClient()
+			defer closeServer()
+
+			fee, err := client.EstimateSmartFee(ctx, 6)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(BeNumerically("~", 0.0002))
+		})
+	})
+})
@@ -0,0 +1,475 @@
+package bitcoin
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultEsploraHost used by the Esplora client. This is the default
+	// address of the Esplora REST API served by electrs in regtest mode, and
+	// should only be used for local deployments of the multichain.
+	DefaultEsploraHost = "http://0.0.0.0:3002"
+)
+
+type esploraClient struct {
+	opts       ClientOptions
+	params     *chaincfg.Params
+	httpClient *http.Client
+}
+
+// NewEsploraClient returns a Client that is backed by the Esplora REST API at
+// the host (for example, https://blockstream.info/api). Unlike the Client
+// returned by NewClient, it does not need a node with a wallet, so outputs can
+// be queried for any address without importing it. The timeout, retry, and
+// logger options are used in the same way as by NewClient. The user,
+// password, and transport options are ignored. The chain configuration is
+// used to decode addresses.
+//
+// Esplora does not support batching, so batch methods send one request per
+// transaction.
+func NewEsploraClient(opts ClientOptions, params *chaincfg.Params) Client {
+	return &esploraClient{
+		opts:       opts,
+		params:     params,
+		httpClient: &http.Client{Timeout: opts.Timeout},
+	}
+}
+
+// esploraTx is a transaction returned by the Esplora API.
+type esploraTx struct {
+	TxID string `json:"txid"`
+	Vin  []struct {
+		TxID       string         `json:"txid"`
+		Vout       uint32         `json:"vout"`
+		IsCoinbase bool           `json:"is_coinbase"`
+		Prevout    *esploraOutput `json:"prevout"`
+	} `json:"vin"`
+	Vout   []esploraOutput `json:"vout"`
+	Status esploraStatus   `json:"status"`
+}
+
+// esploraOutput is a transaction output returned by the Esplora API.
+type esploraOutput struct {
+	ScriptPubKey        string `json:"scriptpubkey"`
+	ScriptPubKeyAddress string `json:"scriptpubkey_address"`
+	Value               int64  `json:"value"`
+}
+
+// esploraStatus is the confirmation status of a transaction returned by the
+// Esplora API.
+type esploraStatus struct {
+	Confirmed   bool   `json:"confirmed"`
+	BlockHeight uint64 `json:"block_height"`
+	BlockHash   string `json:"block_hash"`
+}
+
+// esploraUTXO is an unspent output returned by the Esplora API.
+type esploraUTXO struct {
+	TxID   string        `json:"txid"`
+	Vout   uint32        `json:"vout"`
+	Value  int64         `json:"value"`
+	Status esploraStatus `json:"status"`
+}
+
+// LatestBlock returns the height of the longest blockchain.
+func (client *esploraClient) LatestBlock(ctx context.Context) (pack.U64, error) {
+	var resp string
+	if err := client.get(ctx, "/blocks/tip/height", &resp); err != nil {
+		return pack.NewU64(0), fmt.Errorf("get tip height: %v", err)
+	}
+	height, err := strconv.ParseUint(strings.TrimSpace(resp), 10, 64)
+	if err != nil {
+		return pack.NewU64(0), fmt.Errorf("bad tip height: %v", err)
+	}
+	return pack.NewU64(height), nil
+}
+
+// Output associated with an outpoint, and its number of confirmations.
+func (client *esploraClient) Output(ctx context.Context, outpoint utxo.Outpoint) (utxo.Output, pack.U64, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], outpoint.Hash)
+	tx := esploraTx{}
+	if err := client.get(ctx, fmt.Sprintf("/tx/%v", hash), &tx); err != nil {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("get tx %v: %v", hash, err)
+	}
+	if outpoint.Index.Uint32() >= uint32(len(tx.Vout)) {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("bad index: %v is out of range", outpoint.Index)
+	}
+	output, err := esploraOutputToOutput(outpoint, tx.Vout[outpoint.Index.Uint32()])
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), err
+	}
+	tip, err := client.LatestBlock(ctx)
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), err
+	}
+	return output, esploraConfirmations(tx.Status, tip), nil
+}
+
+// UnspentOutput returns the unspent transaction output identified by the
+// given outpoint. It also returns the number of confirmations for the
+// output. If the output cannot be found before the context is done, the
+// output is invalid, or the output has been spent, then an error should be
+// returned.
+func (client *esploraClient) UnspentOutput(ctx context.Context, outpoint utxo.Outpoint) (utxo.Output, pack.U64, error) {
+	output, confs, err := client.Output(ctx, outpoint)
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), err
+	}
+	hash := chainhash.Hash{}
+	copy(hash[:], outpoint.Hash)
+	outspend := struct {
+		Spent bool `json:"spent"`
+	}{}
+	if err := client.get(ctx, fmt.Sprintf("/tx/%v/outspend/%v", hash, outpoint.Index), &outspend); err != nil {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("get outspend %v:%v: %v", hash, outpoint.Index, err)
+	}
+	if outspend.Spent {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("output %v:%v: spent", hash, outpoint.Index)
+	}
+	return output, confs, nil
+}
+
+// SubmitTx to the Bitcoin network.
+func (client *esploraClient) SubmitTx(ctx context.Context, tx utxo.Tx) error {
+	serial, err := tx.Serialize()
+	if err != nil {
+		return fmt.Errorf("bad tx: %v", err)
+	}
+	return retry(ctx, client.opts, "/tx", func() error {
+		_, err := client.do(ctx, http.MethodPost, "/tx", []byte(hex.EncodeToString(serial)))
+		return err
+	})
+}
+
+// Simulate is not supported by Esplora servers, and always returns an error.
+func (client *esploraClient) Simulate(ctx context.Context, tx utxo.Tx) (confirmation.Simulation, error) {
+	return confirmation.Simulation{}, fmt.Errorf("simulating tx: not supported by esplora")
+}
+
+// TxSenders returns the senders of the transaction. Esplora includes the
+// outputs spent by a transaction in its inputs, so only the transaction
+// itself is fetched.
+func (client *esploraClient) TxSenders(ctx context.Context, id pack.Bytes) ([]pack.String, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], id)
+	tx := esploraTx{}
+	if err := client.get(ctx, fmt.Sprintf("/tx/%v", hash), &tx); err != nil {
+		return nil, fmt.Errorf("get tx %v: %v", hash, err)
+	}
+	addrs := make([]pack.String, 0)
+	for _, vin := range tx.Vin {
+		if vin.IsCoinbase || vin.Prevout == nil || vin.Prevout.ScriptPubKeyAddress == "" {
+			continue
+		}
+		addrs = append(addrs, pack.String(vin.Prevout.ScriptPubKeyAddress))
+	}
+	return addrs, nil
+}
+
+// UnspentOutputs spendable by the given address, with at least the minimum
+// number of confirmations, and at most the maximum number of confirmations.
+func (client *esploraClient) UnspentOutputs(ctx context.Context, minConf, maxConf int64, addr address.Address) ([]utxo.Output, error) {
+	decodedAddr, err := decodeAddress(string(addr), client.params)
+	if err != nil {
+		return nil, fmt.Errorf("bad address %v: %v", addr, err)
+	}
+	pubKeyScript, err := payToAddrScript(decodedAddr)
+	if err != nil {
+		return nil, fmt.Errorf("bad address %v: %v", addr, err)
+	}
+
+	utxos := []esploraUTXO{}
+	if err := client.get(ctx, fmt.Sprintf("/address/%v/utxo", decodedAddr.EncodeAddress()), &utxos); err != nil {
+		return nil, fmt.Errorf("get utxos: %v", err)
+	}
+	tip, err := client.LatestBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]utxo.Output, 0, len(utxos))
+	for _, u := range utxos {
+		confs := int64(esploraConfirmations(u.Status, tip))
+		if confs < minConf || confs > maxConf {
+			continue
+		}
+		txid, err := chainhash.NewHashFromStr(u.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("bad txid: %v", err)
+		}
+		if u.Value < 0 {
+			return nil, fmt.Errorf("bad amount: %v", u.Value)
+		}
+		outputs = append(outputs, utxo.Output{
+			Outpoint: utxo.Outpoint{
+				Hash:  pack.NewBytes(txid[:]),
+				Index: pack.NewU32(u.Vout),
+			},
+			Value:        pack.NewU256FromU64(pack.NewU64(uint64(u.Value))),
+			PubKeyScript: pack.NewBytes(pubKeyScript),
+		})
+	}
+	return outputs, nil
+}
+
+// Confirmations of a transaction in the Bitcoin network.
+func (client *esploraClient) Confirmations(ctx context.Context, txHash pack.Bytes) (int64, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], txHash)
+	status := esploraStatus{}
+	if err := client.get(ctx, fmt.Sprintf("/tx/%v/status", hash), &status); err != nil {
+		return 0, fmt.Errorf("get tx status %v: %v", hash, err)
+	}
+	tip, err := client.LatestBlock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int64(esploraConfirmations(status, tip)), nil
+}
+
+// TxInclusion returns the block in which a transaction was included.
+func (client *esploraClient) TxInclusion(ctx context.Context, txHash pack.Bytes) (confirmation.Inclusion, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], txHash)
+	status := esploraStatus{}
+	if err := client.get(ctx, fmt.Sprintf("/tx/%v/status", hash), &status); err != nil {
+		httpErr := new(HTTPError)
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			return confirmation.Inclusion{Status: confirmation.TxStatusUnknown}, nil
+		}
+		return confirmation.Inclusion{}, fmt.Errorf("get tx status %v: %v", hash, err)
+	}
+	if !status.Confirmed {
+		return confirmation.Inclusion{Status: confirmation.TxStatusPending}, nil
+	}
+	blockHash, err := chainhash.NewHashFromStr(status.BlockHash)
+	if err != nil {
+		return confirmation.Inclusion{}, fmt.Errorf("bad block hash: %v", err)
+	}
+	return confirmation.Inclusion{
+		Status:    confirmation.TxStatusConfirmed,
+		BlockHash: pack.NewBytes(blockHash[:]),
+		Height:    pack.NewU64(status.BlockHeight),
+	}, nil
+}
+
+// TxReceipt returns the outcome of a transaction. Transactions cannot fail
+// once they have been included in a block.
+func (client *esploraClient) TxReceipt(ctx context.Context, txHash pack.Bytes) (confirmation.TxReceipt, error) {
+	return txReceipt(ctx, client, txHash)
+}
+
+// EstimateSmartFee fetches the estimated bitcoin network fees to be paid (in
+// BTC per kilobyte) needed for a transaction to be confirmed within `numBlocks`
+// blocks. Esplora only returns estimates for some confirmation targets, so
+// the estimate for the largest target that is not greater than `numBlocks` is
+// used.
+func (client *esploraClient) EstimateSmartFee(ctx context.Context, numBlocks int64) (float64, error) {
+	// Estimates are returned in SATs-per-byte, keyed by the confirmation
+	// target.
+	resp := map[string]float64{}
+	if err := client.get(ctx, "/fee-estimates", &resp); err != nil {
+		return 0.0, fmt.Errorf("estimating smart fee: %v", err)
+	}
+	targets := make([]int64, 0, len(resp))
+	for key := range resp {
+		target, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return 0.0, fmt.Errorf("estimating smart fee: bad target %v", key)
+		}
+		if target <= numBlocks {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		return 0.0, fmt.Errorf("estimating smart fee: no estimate for %v blocks", numBlocks)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] > targets[j] })
+	satsPerByte := resp[strconv.FormatInt(targets[0], 10)]
+	return satsPerByte * 1000 / 1e8, nil
+}
+
+// EstimateFeeLegacy is the same as EstimateSmartFee, because Esplora does not
+// distinguish between the two. If the number of blocks is zero, the estimate
+// for the next block is returned.
+func (client *esploraClient) EstimateFeeLegacy(ctx context.Context, numBlocks int64) (float64, error) {
+	if numBlocks < 1 {
+		numBlocks = 1
+	}
+	return client.EstimateSmartFee(ctx, numBlocks)
+}
+
+// Outputs associated with the outpoints, and their number of confirmations.
+func (client *esploraClient) Outputs(ctx context.Context, outpoints []utxo.Outpoint) ([]utxo.Output, []pack.U64, error) {
+	return outputsOneByOne(ctx, outpoints, client.Output)
+}
+
+// UnspentOutputsBatch returns the unspent outputs identified by the outpoints,
+// and their number of confirmations.
+func (client *esploraClient) UnspentOutputsBatch(ctx context.Context, outpoints []utxo.Outpoint) ([]utxo.Output, []pack.U64, error) {
+	return outputsOneByOne(ctx, outpoints, client.UnspentOutput)
+}
+
+// TxSendersBatch returns the senders of each transaction.
+func (client *esploraClient) TxSendersBatch(ctx context.Context, txHashes []pack.Bytes) ([][]pack.String, error) {
+	return txSendersOneByOne(ctx, txHashes, client.TxSenders)
+}
+
+// BlockHash returns the hash of the block at the given height in the longest
+// blockchain.
+func (client *esploraClient) BlockHash(ctx context.Context, height pack.U64) (pack.Bytes, error) {
+	var resp string
+	if err := client.get(ctx, fmt.Sprintf("/block-height/%v", height.Uint64()), &resp); err != nil {
+		return nil, fmt.Errorf("get block hash %v: %v", height, err)
+	}
+	hash, err := chainhash.NewHashFromStr(strings.TrimSpace(resp))
+	if err != nil {
+		return nil, fmt.Errorf("bad block hash: %v", err)
+	}
+	return pack.NewBytes(hash[:]), nil
+}
+
+// Block returns the block with the given hash, and all of the outputs produced
+// by the transactions in it. Esplora returns the transactions in a block in
+// pages, so one request is sent per page.
+func (client *esploraClient) Block(ctx context.Context, blockHash pack.Bytes) (utxo.Block, error) {
+	hash := chainhash.Hash{}
+	copy(hash[:], blockHash)
+	resp := struct {
+		Height            uint64 `json:"height"`
+		PreviousBlockHash string `json:"previousblockhash"`
+		TxCount           int    `json:"tx_count"`
+	}{}
+	if err := client.get(ctx, fmt.Sprintf("/block/%v", hash), &resp); err != nil {
+		return utxo.Block{}, fmt.Errorf("get block %v: %v", hash, err)
+	}
+
+	block := utxo.Block{
+		Hash:    pack.NewBytes(hash[:]),
+		Height:  pack.NewU64(resp.Height),
+		Outputs: []utxo.BlockOutput{},
+	}
+	if resp.PreviousBlockHash != "" {
+		prevHash, err := chainhash.NewHashFromStr(resp.PreviousBlockHash)
+		if err != nil {
+			return utxo.Block{}, fmt.Errorf("bad previous block hash: %v", err)
+		}
+		block.PrevHash = pack.NewBytes(prevHash[:])
+	}
+	for start := 0; start < resp.TxCount; {
+		txs := []esploraTx{}
+		if err := client.get(ctx, fmt.Sprintf("/block/%v/txs/%v", hash, start), &txs); err != nil {
+			return utxo.Block{}, fmt.Errorf("get block %v txs: %v", hash, err)
+		}
+		if len(txs) == 0 {
+			return utxo.Block{}, fmt.Errorf("get block %v txs: expected %v txs, got %v txs", hash, resp.TxCount, start)
+		}
+		for _, tx := range txs {
+			txid, err := chainhash.NewHashFromStr(tx.TxID)
+			if err != nil {
+				return utxo.Block{}, fmt.Errorf("bad txid: %v", err)
+			}
+			for i, vout := range tx.Vout {
+				output, err := esploraOutputToOutput(utxo.Outpoint{Hash: pack.NewBytes(txid[:]), Index: pack.NewU32(uint32(i))}, vout)
+				if err != nil {
+					return utxo.Block{}, err
+				}
+				addrs := []address.Address{}
+				if vout.ScriptPubKeyAddress != "" {
+					addrs = append(addrs, address.Address(vout.ScriptPubKeyAddress))
+				}
+				block.Outputs = append(block.Outputs, utxo.BlockOutput{Output: output, Addresses: addrs})
+			}
+		}
+		start += len(txs)
+	}
+	return block, nil
+}
+
+// get the path, and decode the response into resp. If resp is a string
+// pointer, the response is not decoded.
+func (client *esploraClient) get(ctx context.Context, path string, resp interface{}) error {
+	return retry(ctx, client.opts, path, func() error {
+		body, err := client.do(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return err
+		}
+		if str, ok := resp.(*string); ok {
+			*str = string(body)
+			return nil
+		}
+		if err := json.Unmarshal(body, resp); err != nil {
+			return &decodeError{err: fmt.Errorf("decoding response: %v", err)}
+		}
+		return nil
+	})
+}
+
+func (client *esploraClient) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(client.opts.Host, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("building http request: %v", err)
+	}
+	res, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending http request: %v", err)
+	}
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading http response: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, &HTTPError{StatusCode: res.StatusCode, Body: string(resBody)}
+	}
+	return resBody, nil
+}
+
+// esploraOutputToOutput converts an output returned by the Esplora API.
+func esploraOutputToOutput(outpoint utxo.Outpoint, out esploraOutput) (utxo.Output, error) {
+	if out.Value < 0 {
+		return utxo.Output{}, fmt.Errorf("bad amount: %v", out.Value)
+	}
+	pubKeyScript, err := hex.DecodeString(out.ScriptPubKey)
+	if err != nil {
+		return utxo.Output{}, fmt.Errorf("bad pubkey script: %v", err)
+	}
+	return utxo.Output{
+		Outpoint:     outpoint,
+		Value:        pack.NewU256FromU64(pack.NewU64(uint64(out.Value))),
+		PubKeyScript: pack.NewBytes(pubKeyScript),
+	}, nil
+}
+
+// esploraConfirmations returns the number of confirmations of a transaction
+// with the given status, when the chain is at the given height.
+func esploraConfirmations(status esploraStatus, tip pack.U64) pack.U64 {
+	return confirmationsAt(status.Confirmed, status.BlockHeight, tip.Uint64())
+}
+
+// confirmationsAt returns the number of confirmations of something that was
+// included at the given height, when the chain is at the given tip.
+func confirmationsAt(confirmed bool, height, tip uint64) pack.U64 {
+	if !confirmed || height > tip {
+		return pack.NewU64(0)
+	}
+	return pack.NewU64(tip - height + 1)
+}
@@ -0,0 +1,160 @@
+package bitcoin_test
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Esplora", func() {
+	ctx := context.Background()
+
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160([]byte("pubkey")), &chaincfg.RegressionNetParams)
+	if err != nil {
+		panic(err)
+	}
+	pubKeyScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		panic(err)
+	}
+
+	txHash := chainhash.Hash{1}
+	prevTxHash := chainhash.Hash{2}
+	blockHash := chainhash.Hash{3}
+
+	// newServer returns a stub Esplora server. The chain is at height 110, the
+	// transaction was confirmed at height 101, and its first output is
+	// unspent.
+	newServer := func() *httptest.Server {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/blocks/tip/height", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "110")
+		})
+		mux.HandleFunc(fmt.Sprintf("/tx/%v", txHash), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"txid":"%v","vin":[{"txid":"%v","vout":0,"is_coinbase":false,"prevout":{"scriptpubkey":"%x","scriptpubkey_address":"%v","value":200000}}],"vout":[{"scriptpubkey":"%x","scriptpubkey_address":"%v","value":100000}],"status":{"confirmed":true,"block_height":101}}`, txHash, prevTxHash, pubKeyScript, addr.EncodeAddress(), pubKeyScript, addr.EncodeAddress())
+		})
+		mux.HandleFunc(fmt.Sprintf("/tx/%v/status", txHash), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"confirmed":true,"block_height":101,"block_hash":"%v"}`, blockHash)
+		})
+		mux.HandleFunc(fmt.Sprintf("/tx/%v/outspend/0", txHash), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"spent":false}`)
+		})
+		mux.HandleFunc(fmt.Sprintf("/address/%v/utxo", addr.EncodeAddress()), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `[{"txid":"%v","vout":0,"value":100000,"status":{"confirmed":true,"block_height":101}},{"txid":"%v","vout":1,"value":5000,"status":{"confirmed":false}}]`, txHash, prevTxHash)
+		})
+		mux.HandleFunc("/fee-estimates", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"1":20.0,"6":10.0,"144":1.0}`)
+		})
+		return httptest.NewServer(mux)
+	}
+
+	newClient := func(server *httptest.Server) bitcoin.Client {
+		opts := bitcoin.DefaultClientOptions().WithHost(server.URL).WithLogger(nil).WithMaxAttempts(1)
+		return bitcoin.NewEsploraClient(opts, &chaincfg.RegressionNetParams)
+	}
+
+	Context("when querying an output", func() {
+		It("should return the output and its confirmations", func() {
+			server := newServer()
+			defer server.Close()
+			client := newClient(server)
+
+			outpoint := utxo.Outpoint{Hash: pack.NewBytes(txHash[:]), Index: pack.NewU32(0)}
+			output, confs, err := client.UnspentOutput(ctx, outpoint)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(output.Value).To(Equal(pack.NewU256FromUint64(100000)))
+			Expect(hex.EncodeToString(output.PubKeyScript)).To(Equal(hex.EncodeToString(pubKeyScript)))
+			Expect(confs).To(Equal(pack.NewU64(10)))
+
+			txConfs, err := client.Confirmations(ctx, pack.NewBytes(txHash[:]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txConfs).To(Equal(int64(10)))
+
+			senders, err := client.TxSenders(ctx, pack.NewBytes(txHash[:]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(senders).To(Equal([]pack.String{pack.String(addr.EncodeAddress())}))
+		})
+
+		It("should return an error if the output cannot be found", func() {
+			server := newServer()
+			defer server.Close()
+			client := newClient(server)
+
+			outpoint := utxo.Outpoint{Hash: pack.NewBytes(prevTxHash[:]), Index: pack.NewU32(0)}
+			_, _, err := client.Output(ctx, outpoint)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when querying the unspent outputs of an address", func() {
+		It("should filter by confirmations", func() {
+			server := newServer()
+			defer server.Close()
+			client := newClient(server)
+
+			outputs, err := client.UnspentOutputs(ctx, 0, 999999, address.Address(addr.EncodeAddress()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputs).To(HaveLen(2))
+
+			outputs, err = client.UnspentOutputs(ctx, 1, 999999, address.Address(addr.EncodeAddress()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputs).To(HaveLen(1))
+			Expect(outputs[0].Outpoint.Hash).To(Equal(pack.NewBytes(txHash[:])))
+			Expect(hex.EncodeToString(outputs[0].PubKeyScript)).To(Equal(hex.EncodeToString(pubKeyScript)))
+		})
+	})
+
+	Context("when querying the inclusion of a transaction", func() {
+		It("should return the block in which it was included", func() {
+			server := newServer()
+			defer server.Close()
+			client := newClient(server)
+
+			inclusion, err := client.TxInclusion(ctx, pack.NewBytes(txHash[:]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inclusion.Status).To(Equal(confirmation.TxStatusConfirmed))
+			Expect(inclusion.BlockHash).To(Equal(pack.NewBytes(blockHash[:])))
+			Expect(inclusion.Height).To(Equal(pack.NewU64(101)))
+		})
+
+		It("should return an unknown status if the transaction cannot be found", func() {
+			server := newServer()
+			defer server.Close()
+			client := newClient(server)
+
+			inclusion, err := client.TxInclusion(ctx, pack.NewBytes(prevTxHash[:]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inclusion.Status).To(Equal(confirmation.TxStatusUnknown))
+		})
+	})
+
+	Context("when estimating fees", func() {
+		It("should use the largest target that is not greater than the number of blocks", func() {
+			server := newServer()
+			defer server.Close()
+			client := newClient(server)
+
+			fee, err := client.EstimateSmartFee(ctx, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(BeNumerically("~", 0.0001))
+
+			_, err = client.EstimateSmartFee(ctx, 0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
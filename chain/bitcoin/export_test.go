@@ -0,0 +1,4 @@
+package bitcoin
+
+// CalcTaprootSignatureHash exports calcTaprootSignatureHash for testing.
+var CalcTaprootSignatureHash = calcTaprootSignatureHash
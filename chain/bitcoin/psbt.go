@@ -0,0 +1,326 @@
+package bitcoin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// Bip32Derivation describes how the private key for a public key can be
+// derived from a master key. It is included in a PSBT so that external
+// signers (such as hardware wallets) can identify the inputs that they are
+// able to sign.
+type Bip32Derivation struct {
+	PubKey               pack.Bytes
+	MasterKeyFingerprint uint32
+	Path                 []uint32
+}
+
+// PSBTInput contains additional information about an input that is needed to
+// export it into a PSBT, but that is not part of the utxo.Input.
+type PSBTInput struct {
+	// PrevTx is the serialized transaction that produced the output being
+	// spent. It is required for non-segwit inputs, and ignored for segwit
+	// inputs (for which the value and pubkey script of the output are enough).
+	PrevTx pack.Bytes
+	// Bip32Derivations of the public keys that are able to sign the input.
+	Bip32Derivations []Bip32Derivation
+}
+
+// PSBT exports the unsigned transaction as a BIP-174 partially signed Bitcoin
+// transaction. The witness UTXO (or, for non-segwit inputs, the previous
+// transaction) and redeem/witness script of every input are included. The
+// PSBT inputs are optional, but when they are given there must be exactly one
+// for every input of the transaction.
+func (tx *Tx) PSBT(psbtInputs []PSBTInput) (*psbt.Packet, error) {
+	if tx.signed {
+		return nil, fmt.Errorf("already signed")
+	}
+	if psbtInputs != nil && len(psbtInputs) != len(tx.inputs) {
+		return nil, fmt.Errorf("expected %v psbt inputs, got %v psbt inputs", len(tx.inputs), len(psbtInputs))
+	}
+
+	packet, err := psbt.NewFromUnsignedTx(tx.msgTx.Copy())
+	if err != nil {
+		return nil, fmt.Errorf("creating psbt: %v", err)
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return nil, fmt.Errorf("creating psbt updater: %v", err)
+	}
+
+	for i, input := range tx.inputs {
+		psbtInput := PSBTInput{}
+		if psbtInputs != nil {
+			psbtInput = psbtInputs[i]
+		}
+
+		value := input.Value.Int().Int64()
+		if value < 0 {
+			return nil, fmt.Errorf("bad input %v: expected value >= 0, got value %v", i, value)
+		}
+		pubKeyScript := []byte(input.PubKeyScript)
+		sigScript := []byte(input.SigScript)
+
+		// The sig script of a utxo.Input is the script that must be revealed
+		// when spending it. This is the witness script for P2WSH outputs, and
+		// the redeem script for P2SH outputs.
+		isWitness := false
+		switch {
+		case txscript.IsPayToWitnessPubKeyHash(pubKeyScript), IsPayToTaproot(pubKeyScript):
+			isWitness = true
+		case txscript.IsPayToWitnessScriptHash(pubKeyScript):
+			isWitness = true
+			if sigScript != nil {
+				if err := updater.AddInWitnessScript(sigScript, i); err != nil {
+					return nil, fmt.Errorf("bad input %v: adding witness script: %v", i, err)
+				}
+			}
+		case txscript.IsPayToScriptHash(pubKeyScript):
+			isWitness = txscript.IsPayToWitnessPubKeyHash(sigScript) || txscript.IsPayToWitnessScriptHash(sigScript)
+			if sigScript != nil {
+				if err := updater.AddInRedeemScript(sigScript, i); err != nil {
+					return nil, fmt.Errorf("bad input %v: adding redeem script: %v", i, err)
+				}
+			}
+		}
+
+		if isWitness {
+			if err := updater.AddInWitnessUtxo(wire.NewTxOut(value, pubKeyScript), i); err != nil {
+				return nil, fmt.Errorf("bad input %v: adding witness utxo: %v", i, err)
+			}
+		} else {
+			if psbtInput.PrevTx == nil {
+				return nil, fmt.Errorf("bad input %v: previous tx is required for non-segwit inputs", i)
+			}
+			prevTx := new(wire.MsgTx)
+			if err := prevTx.Deserialize(bytes.NewReader(psbtInput.PrevTx)); err != nil {
+				return nil, fmt.Errorf("bad input %v: deserializing previous tx: %v", i, err)
+			}
+			if prevTxHash := prevTx.TxHash(); !bytes.Equal(prevTxHash[:], input.Hash) {
+				return nil, fmt.Errorf("bad input %v: expected previous tx %v, got %v", i, input.Hash, prevTxHash)
+			}
+			if err := updater.AddInNonWitnessUtxo(prevTx, i); err != nil {
+				return nil, fmt.Errorf("bad input %v: adding non-witness utxo: %v", i, err)
+			}
+		}
+
+		if err := updater.AddInSighashType(txscript.SigHashAll, i); err != nil {
+			return nil, fmt.Errorf("bad input %v: adding sighash type: %v", i, err)
+		}
+		for _, derivation := range psbtInput.Bip32Derivations {
+			if err := updater.AddInBip32Derivation(derivation.MasterKeyFingerprint, derivation.Path, derivation.PubKey, i); err != nil {
+				return nil, fmt.Errorf("bad input %v: adding bip32 derivation: %v", i, err)
+			}
+		}
+	}
+
+	return packet, nil
+}
+
+// EncodePSBT exports the unsigned transaction as a BIP-174 partially signed
+// Bitcoin transaction, and encodes it using base64 (the format accepted by
+// most wallets).
+func (tx *Tx) EncodePSBT(psbtInputs []PSBTInput) (string, error) {
+	packet, err := tx.PSBT(psbtInputs)
+	if err != nil {
+		return "", err
+	}
+	return EncodePSBT(packet)
+}
+
+// EncodePSBT encodes a PSBT using base64.
+func EncodePSBT(packet *psbt.Packet) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := packet.Serialize(buf); err != nil {
+		return "", fmt.Errorf("serializing psbt: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecodePSBT decodes a base64 encoded PSBT.
+func DecodePSBT(str string) (*psbt.Packet, error) {
+	packet, err := psbt.NewFromRawBytes(bytes.NewReader([]byte(str)), true)
+	if err != nil {
+		return nil, fmt.Errorf("deserializing psbt: %v", err)
+	}
+	return packet, nil
+}
+
+// SignPSBT adds a partial signature for one of the inputs of a PSBT. The
+// signature must be over the sighash returned by Tx.Sighashes for the same
+// input, and is expected to use the same r||s||v format as the signatures
+// consumed by Tx.Sign.
+func SignPSBT(packet *psbt.Packet, index int, rsv pack.Bytes65, pubKey pack.Bytes) error {
+	if index < 0 || index >= len(packet.Inputs) {
+		return fmt.Errorf("bad input %v: out of range", index)
+	}
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return fmt.Errorf("creating psbt updater: %v", err)
+	}
+	signature := btcec.Signature{
+		R: new(big.Int).SetBytes(rsv[:32]),
+		S: new(big.Int).SetBytes(rsv[32:64]),
+	}
+	pInput := packet.Inputs[index]
+	outcome, err := updater.Sign(index, append(signature.Serialize(), byte(txscript.SigHashAll)), pubKey, pInput.RedeemScript, pInput.WitnessScript)
+	if err != nil {
+		return fmt.Errorf("bad input %v: signing: %v", index, err)
+	}
+	if outcome != psbt.SignSuccesful {
+		return fmt.Errorf("bad input %v: signing: outcome %v", index, outcome)
+	}
+	return nil
+}
+
+// CombinePSBTs merges PSBTs that describe the same unsigned transaction (for
+// example, PSBTs that have been signed by different cosigners) into one PSBT.
+// This is the combiner role defined by BIP-174.
+func CombinePSBTs(packets ...*psbt.Packet) (*psbt.Packet, error) {
+	if len(packets) == 0 {
+		return nil, fmt.Errorf("expected at least 1 psbt, got 0 psbts")
+	}
+	expectedTxHash := packets[0].UnsignedTx.TxHash()
+
+	// Copy the first packet so that none of the given packets are modified.
+	encoded, err := EncodePSBT(packets[0])
+	if err != nil {
+		return nil, err
+	}
+	combined, err := DecodePSBT(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, packet := range packets[1:] {
+		if txHash := packet.UnsignedTx.TxHash(); txHash != expectedTxHash {
+			return nil, fmt.Errorf("cannot combine psbts: expected tx %v, got tx %v", expectedTxHash, txHash)
+		}
+		for i := range packet.Inputs {
+			combinePSBTInput(&combined.Inputs[i], &packet.Inputs[i])
+		}
+		for i := range packet.Outputs {
+			combinePSBTOutput(&combined.Outputs[i], &packet.Outputs[i])
+		}
+	}
+
+	if err := combined.SanityCheck(); err != nil {
+		return nil, fmt.Errorf("cannot combine psbts: %v", err)
+	}
+	return combined, nil
+}
+
+// FinalizePSBT finalizes all of the inputs of a fully signed PSBT, and
+// extracts the signed transaction. The returned transaction can be submitted
+// using a Client. Recipients are not known by the PSBT, so the returned
+// transaction only exposes its outputs.
+func FinalizePSBT(packet *psbt.Packet) (*Tx, error) {
+	if err := psbt.MaybeFinalizeAll(packet); err != nil {
+		return nil, fmt.Errorf("finalizing psbt: %v", err)
+	}
+	inputs := make([]utxo.Input, len(packet.Inputs))
+	for i, pInput := range packet.Inputs {
+		outpoint := packet.UnsignedTx.TxIn[i].PreviousOutPoint
+		var txOut *wire.TxOut
+		switch {
+		case pInput.WitnessUtxo != nil:
+			txOut = pInput.WitnessUtxo
+		case pInput.NonWitnessUtxo != nil && int(outpoint.Index) < len(pInput.NonWitnessUtxo.TxOut):
+			txOut = pInput.NonWitnessUtxo.TxOut[outpoint.Index]
+		default:
+			return nil, fmt.Errorf("bad input %v: missing utxo", i)
+		}
+		inputs[i] = utxo.Input{
+			Output: utxo.Output{
+				Outpoint: utxo.Outpoint{
+					Hash:  pack.NewBytes(outpoint.Hash[:]),
+					Index: pack.NewU32(outpoint.Index),
+				},
+				Value:        pack.NewU256FromU64(pack.NewU64(uint64(txOut.Value))),
+				PubKeyScript: pack.NewBytes(txOut.PkScript),
+			},
+		}
+		if pInput.WitnessScript != nil {
+			inputs[i].SigScript = pack.NewBytes(pInput.WitnessScript)
+		} else if pInput.RedeemScript != nil {
+			inputs[i].SigScript = pack.NewBytes(pInput.RedeemScript)
+		}
+	}
+	msgTx, err := psbt.Extract(packet)
+	if err != nil {
+		return nil, fmt.Errorf("extracting tx: %v", err)
+	}
+	return &Tx{inputs: inputs, msgTx: msgTx, signed: true}, nil
+}
+
+func combinePSBTInput(dst, src *psbt.PInput) {
+	if dst.NonWitnessUtxo == nil {
+		dst.NonWitnessUtxo = src.NonWitnessUtxo
+	}
+	if dst.WitnessUtxo == nil {
+		dst.WitnessUtxo = src.WitnessUtxo
+	}
+	if dst.SighashType == 0 {
+		dst.SighashType = src.SighashType
+	}
+	if dst.RedeemScript == nil {
+		dst.RedeemScript = src.RedeemScript
+	}
+	if dst.WitnessScript == nil {
+		dst.WitnessScript = src.WitnessScript
+	}
+	if dst.FinalScriptSig == nil {
+		dst.FinalScriptSig = src.FinalScriptSig
+	}
+	if dst.FinalScriptWitness == nil {
+		dst.FinalScriptWitness = src.FinalScriptWitness
+	}
+	for _, partialSig := range src.PartialSigs {
+		exists := false
+		for _, existing := range dst.PartialSigs {
+			if bytes.Equal(existing.PubKey, partialSig.PubKey) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			dst.PartialSigs = append(dst.PartialSigs, partialSig)
+		}
+	}
+	dst.Bip32Derivation = combineBip32Derivations(dst.Bip32Derivation, src.Bip32Derivation)
+}
+
+func combinePSBTOutput(dst, src *psbt.POutput) {
+	if dst.RedeemScript == nil {
+		dst.RedeemScript = src.RedeemScript
+	}
+	if dst.WitnessScript == nil {
+		dst.WitnessScript = src.WitnessScript
+	}
+	dst.Bip32Derivation = combineBip32Derivations(dst.Bip32Derivation, src.Bip32Derivation)
+}
+
+func combineBip32Derivations(dst, src []*psbt.Bip32Derivation) []*psbt.Bip32Derivation {
+	for _, derivation := range src {
+		exists := false
+		for _, existing := range dst {
+			if bytes.Equal(existing.PubKey, derivation.PubKey) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			dst = append(dst, derivation)
+		}
+	}
+	return dst
+}
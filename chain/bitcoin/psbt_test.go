@@ -0,0 +1,149 @@
+package bitcoin_test
+
+import (
+	"crypto/rand"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PSBT", func() {
+	buildTx := func(privKey *id.PrivKey) (*bitcoin.Tx, pack.Bytes) {
+		pubKey := (*btcec.PublicKey)(privKey.PubKey()).SerializeCompressed()
+		wpkhAddr, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey), &chaincfg.RegressionNetParams)
+		Expect(err).ToNot(HaveOccurred())
+		pubKeyScript, err := txscript.PayToAddrScript(wpkhAddr)
+		Expect(err).ToNot(HaveOccurred())
+
+		prevHash := [32]byte{}
+		_, err = rand.Read(prevHash[:])
+		Expect(err).ToNot(HaveOccurred())
+
+		tx, err := bitcoin.NewTxBuilder(&chaincfg.RegressionNetParams).BuildTx(
+			[]utxo.Input{{
+				Output: utxo.Output{
+					Outpoint: utxo.Outpoint{
+						Hash:  pack.NewBytes(prevHash[:]),
+						Index: pack.NewU32(0),
+					},
+					Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+					PubKeyScript: pack.NewBytes(pubKeyScript),
+				},
+			}},
+			[]utxo.Recipient{{
+				To:    address.Address(wpkhAddr.EncodeAddress()),
+				Value: pack.NewU256FromU64(pack.NewU64(90000)),
+			}},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		return tx.(*bitcoin.Tx), pack.NewBytes(pubKey)
+	}
+
+	sign := func(tx *bitcoin.Tx, privKey *id.PrivKey) []pack.Bytes65 {
+		sighashes, err := tx.Sighashes()
+		Expect(err).ToNot(HaveOccurred())
+		signatures := make([]pack.Bytes65, len(sighashes))
+		for i := range sighashes {
+			hash := id.Hash(sighashes[i])
+			signature, err := privKey.Sign(&hash)
+			Expect(err).ToNot(HaveOccurred())
+			signatures[i] = pack.NewBytes65(signature)
+		}
+		return signatures
+	}
+
+	Context("when exporting an unsigned transaction", func() {
+		It("should round-trip through base64", func() {
+			privKey := id.NewPrivKey()
+			tx, pubKey := buildTx(privKey)
+
+			encoded, err := tx.EncodePSBT([]bitcoin.PSBTInput{{
+				Bip32Derivations: []bitcoin.Bip32Derivation{{
+					PubKey:               pubKey,
+					MasterKeyFingerprint: 0xdeadbeef,
+					Path:                 []uint32{0x80000054, 0x80000000, 0x80000000, 0, 0},
+				}},
+			}})
+			Expect(err).ToNot(HaveOccurred())
+
+			packet, err := bitcoin.DecodePSBT(encoded)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packet.Inputs).To(HaveLen(1))
+			Expect(packet.Inputs[0].WitnessUtxo).ToNot(BeNil())
+			Expect(packet.Inputs[0].WitnessUtxo.Value).To(Equal(int64(100000)))
+			Expect(packet.Inputs[0].Bip32Derivation).To(HaveLen(1))
+			Expect(packet.Inputs[0].Bip32Derivation[0].MasterKeyFingerprint).To(Equal(uint32(0xdeadbeef)))
+
+			txHash, err := tx.Hash()
+			Expect(err).ToNot(HaveOccurred())
+			unsignedTxHash := packet.UnsignedTx.TxHash()
+			Expect(unsignedTxHash[:]).To(Equal([]byte(txHash)))
+		})
+
+		It("should require the previous transaction for non-segwit inputs", func() {
+			tx, err := bitcoin.NewTxBuilder(&chaincfg.RegressionNetParams).BuildTx(
+				[]utxo.Input{{
+					Output: utxo.Output{
+						Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32))},
+						Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+						PubKeyScript: pack.NewBytes([]byte{0x76, 0xa9, 0x14}),
+					},
+				}},
+				[]utxo.Recipient{},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = tx.(*bitcoin.Tx).PSBT(nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when combining and finalizing signed PSBTs", func() {
+		It("should extract the same transaction as signing directly", func() {
+			privKey := id.NewPrivKey()
+			tx, pubKey := buildTx(privKey)
+			signatures := sign(tx, privKey)
+
+			unsigned, err := tx.PSBT(nil)
+			Expect(err).ToNot(HaveOccurred())
+			signed, err := tx.PSBT(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bitcoin.SignPSBT(signed, 0, signatures[0], pubKey)).To(Succeed())
+
+			combined, err := bitcoin.CombinePSBTs(unsigned, signed)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(combined.Inputs[0].PartialSigs).To(HaveLen(1))
+			Expect(unsigned.Inputs[0].PartialSigs).To(HaveLen(0))
+
+			finalized, err := bitcoin.FinalizePSBT(combined)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(tx.Sign(signatures, pubKey)).To(Succeed())
+			expected, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			actual, err := finalized.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(expected))
+		})
+
+		It("should not combine PSBTs for different transactions", func() {
+			txA, _ := buildTx(id.NewPrivKey())
+			txB, _ := buildTx(id.NewPrivKey())
+			packetA, err := txA.PSBT(nil)
+			Expect(err).ToNot(HaveOccurred())
+			packetB, err := txB.PSBT(nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = bitcoin.CombinePSBTs(packetA, packetB)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,172 @@
+package bitcoin
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+const (
+	// SequenceRBF is the sequence number used by inputs of transactions that
+	// signal opt-in replace-by-fee. Any sequence number below
+	// wire.MaxTxInSequenceNum-1 signals replaceability, as defined by BIP-125.
+	SequenceRBF uint32 = wire.MaxTxInSequenceNum - 2
+
+	// DefaultIncrementalRelayFee is the minimum amount, in SATs-per-byte, by
+	// which the fee rate of a replacement transaction must exceed the fee rate
+	// of the transaction that it replaces. This is the default used by Bitcoin
+	// Core nodes.
+	DefaultIncrementalRelayFee = 1
+
+	// DefaultDustThreshold is the value below which outputs are considered to
+	// be dust, and will not be relayed by Bitcoin Core nodes.
+	DefaultDustThreshold = 546
+)
+
+// SignalsRBF returns true if the transaction signals opt-in replace-by-fee.
+func (tx *Tx) SignalsRBF() bool {
+	for _, txIn := range tx.msgTx.TxIn {
+		if txIn.Sequence < wire.MaxTxInSequenceNum-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Fee returns the fee paid by the transaction, which is the difference between
+// the sum value of its inputs and the sum value of its outputs.
+func (tx *Tx) Fee() (pack.U256, error) {
+	fee := new(big.Int)
+	for _, input := range tx.inputs {
+		fee.Add(fee, input.Value.Int())
+	}
+	for _, txOut := range tx.msgTx.TxOut {
+		fee.Sub(fee, big.NewInt(txOut.Value))
+	}
+	if fee.Sign() < 0 {
+		return pack.U256{}, fmt.Errorf("expected fee >= 0, got fee %v", fee)
+	}
+	return pack.NewU256FromInt(fee), nil
+}
+
+// BumpFee returns a replacement for the given transaction that pays the given
+// fee rate (in SATs-per-byte). The replacement spends the same inputs, and
+// pays the same recipients, except for the recipient at the change index
+// whose value is reduced to pay for the increased fee. The replacement always
+// signals opt-in replace-by-fee, so that it can be bumped again. The given
+// transaction must signal opt-in replace-by-fee, and the replacement must be
+// signed before it can be submitted.
+//
+// As required by BIP-125, the replacement must pay a higher fee rate, and a
+// higher absolute fee that also pays for its own relay at the incremental
+// relay fee.
+func (txBuilder TxBuilder) BumpFee(tx utxo.Tx, changeIndex int, feeRate pack.U256) (utxo.Tx, error) {
+	btcTx, ok := tx.(*Tx)
+	if !ok {
+		return nil, fmt.Errorf("expected type %T, got type %T", new(Tx), tx)
+	}
+	if !btcTx.SignalsRBF() {
+		return nil, fmt.Errorf("cannot bump fee: tx does not signal rbf")
+	}
+	if btcTx.recipients == nil || len(btcTx.recipients) != len(btcTx.msgTx.TxOut) {
+		return nil, fmt.Errorf("cannot bump fee: tx recipients are unknown")
+	}
+	if changeIndex < 0 || changeIndex >= len(btcTx.recipients) {
+		return nil, fmt.Errorf("cannot bump fee: bad change index %v", changeIndex)
+	}
+	if !feeRate.Int().IsUint64() {
+		return nil, fmt.Errorf("cannot bump fee: bad fee rate %v", feeRate)
+	}
+
+	estimator := NewSizeEstimator(txBuilder.params)
+	oldFee, err := btcTx.Fee()
+	if err != nil {
+		return nil, fmt.Errorf("cannot bump fee: %v", err)
+	}
+	oldSize, err := estimator.TxSize(btcTx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot bump fee: %v", err)
+	}
+	// Sizes are the same for the original and the replacement, because they
+	// have the same inputs and outputs (only values change).
+	size, err := estimator.EstimateSize(btcTx.inputs, btcTx.recipients)
+	if err != nil {
+		return nil, fmt.Errorf("cannot bump fee: %v", err)
+	}
+
+	newFee := new(big.Int).Mul(feeRate.Int(), new(big.Int).SetUint64(uint64(size)))
+	minFee := new(big.Int).Add(oldFee.Int(), new(big.Int).SetUint64(DefaultIncrementalRelayFee*uint64(size)))
+	if newFee.Cmp(minFee) < 0 {
+		return nil, fmt.Errorf("cannot bump fee: expected fee >= %v, got fee %v", minFee, newFee)
+	}
+	if new(big.Int).Mul(newFee, new(big.Int).SetUint64(uint64(oldSize))).Cmp(new(big.Int).Mul(oldFee.Int(), new(big.Int).SetUint64(uint64(size)))) <= 0 {
+		return nil, fmt.Errorf("cannot bump fee: fee rate %v does not exceed original fee rate", feeRate)
+	}
+
+	recipients := append([]utxo.Recipient{}, btcTx.recipients...)
+	change := new(big.Int).Sub(recipients[changeIndex].Value.Int(), new(big.Int).Sub(newFee, oldFee.Int()))
+	if change.Cmp(big.NewInt(DefaultDustThreshold)) < 0 {
+		return nil, fmt.Errorf("cannot bump fee: insufficient change, expected >= %v, got %v", DefaultDustThreshold, change)
+	}
+	recipients[changeIndex].Value = pack.NewU256FromInt(change)
+
+	return txBuilder.WithRBF().BuildTx(btcTx.inputs, recipients)
+}
+
+// BuildCPFPTx builds a child-pays-for-parent transaction that spends the output
+// at the given index of an unconfirmed parent transaction (usually the change
+// output), and sends it to the given address. The child pays a fee large
+// enough for the parent and the child, together, to pay the given fee rate (in
+// SATs-per-byte). If the output being spent is not a P2WPKH, P2PKH, or P2TR
+// output, the sig script must be filled in on the returned input before the
+// child is signed.
+func (txBuilder TxBuilder) BuildCPFPTx(parent utxo.Tx, outputIndex int, to address.Address, feeRate pack.U256) (utxo.Tx, error) {
+	parentTx, ok := parent.(*Tx)
+	if !ok {
+		return nil, fmt.Errorf("expected type %T, got type %T", new(Tx), parent)
+	}
+	outputs, err := parentTx.Outputs()
+	if err != nil {
+		return nil, fmt.Errorf("cannot build cpfp tx: %v", err)
+	}
+	if outputIndex < 0 || outputIndex >= len(outputs) {
+		return nil, fmt.Errorf("cannot build cpfp tx: bad output index %v", outputIndex)
+	}
+
+	estimator := NewSizeEstimator(txBuilder.params)
+	parentFee, err := parentTx.Fee()
+	if err != nil {
+		return nil, fmt.Errorf("cannot build cpfp tx: %v", err)
+	}
+	parentSize, err := estimator.TxSize(parentTx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build cpfp tx: %v", err)
+	}
+
+	inputs := []utxo.Input{{Output: outputs[outputIndex]}}
+	recipients := []utxo.Recipient{{To: to, Value: outputs[outputIndex].Value}}
+	childSize, err := estimator.EstimateSize(inputs, recipients)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build cpfp tx: %v", err)
+	}
+
+	// The child pays for the whole package, minus what the parent has already
+	// paid.
+	packageFee := new(big.Int).Mul(feeRate.Int(), new(big.Int).SetUint64(uint64(parentSize)+uint64(childSize)))
+	childFee := new(big.Int).Sub(packageFee, parentFee.Int())
+	if childFee.Cmp(new(big.Int).SetUint64(uint64(childSize))) < 0 {
+		// The child must always pay at least the minimum relay fee for itself.
+		childFee = new(big.Int).SetUint64(uint64(childSize))
+	}
+	value := new(big.Int).Sub(outputs[outputIndex].Value.Int(), childFee)
+	if value.Cmp(big.NewInt(DefaultDustThreshold)) < 0 {
+		return nil, fmt.Errorf("cannot build cpfp tx: insufficient value, expected >= %v, got %v", DefaultDustThreshold, value)
+	}
+	recipients[0].Value = pack.NewU256FromInt(value)
+
+	return txBuilder.BuildTx(inputs, recipients)
+}
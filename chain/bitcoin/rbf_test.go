@@ -0,0 +1,114 @@
+package bitcoin_test
+
+import (
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RBF", func() {
+	params := &chaincfg.RegressionNetParams
+
+	// build a transaction that spends a P2WPKH output worth 100000 SATs, and
+	// sends 50000 SATs to a recipient and 49000 SATs of change (a fee of 1000
+	// SATs for 141 virtual bytes).
+	build := func(txBuilder bitcoin.TxBuilder) (utxo.Tx, address.Address) {
+		pubKey := (*btcec.PublicKey)(id.NewPrivKey().PubKey()).SerializeCompressed()
+		wpkhAddr, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey), params)
+		Expect(err).ToNot(HaveOccurred())
+		script, err := txscript.PayToAddrScript(wpkhAddr)
+		Expect(err).ToNot(HaveOccurred())
+		addr := address.Address(wpkhAddr.EncodeAddress())
+
+		tx, err := txBuilder.BuildTx(
+			[]utxo.Input{{
+				Output: utxo.Output{
+					Outpoint:     utxo.Outpoint{Hash: pack.NewBytes(make([]byte, 32)), Index: pack.NewU32(0)},
+					Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+					PubKeyScript: pack.NewBytes(script),
+				},
+			}},
+			[]utxo.Recipient{
+				{To: addr, Value: pack.NewU256FromU64(pack.NewU64(50000))},
+				{To: addr, Value: pack.NewU256FromU64(pack.NewU64(49000))},
+			},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		return tx, addr
+	}
+
+	Context("when building transactions", func() {
+		It("should only signal rbf when enabled", func() {
+			tx, _ := build(bitcoin.NewTxBuilder(params))
+			Expect(tx.(*bitcoin.Tx).SignalsRBF()).To(BeFalse())
+			tx, _ = build(bitcoin.NewTxBuilder(params).WithRBF())
+			Expect(tx.(*bitcoin.Tx).SignalsRBF()).To(BeTrue())
+		})
+	})
+
+	Context("when bumping the fee", func() {
+		It("should reduce the change to pay the higher fee", func() {
+			txBuilder := bitcoin.NewTxBuilder(params).WithRBF()
+			tx, _ := build(txBuilder)
+
+			replacement, err := txBuilder.BumpFee(tx, 1, pack.NewU256FromU64(pack.NewU64(20)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(replacement.(*bitcoin.Tx).SignalsRBF()).To(BeTrue())
+			fee, err := replacement.(*bitcoin.Tx).Fee()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(Equal(pack.NewU256FromU64(pack.NewU64(20 * 141))))
+
+			outputs, err := replacement.Outputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputs[0].Value).To(Equal(pack.NewU256FromU64(pack.NewU64(50000))))
+			Expect(outputs[1].Value).To(Equal(pack.NewU256FromU64(pack.NewU64(49000 - (20*141 - 1000)))))
+		})
+
+		It("should reject fee rates that do not pay for relaying the replacement", func() {
+			txBuilder := bitcoin.NewTxBuilder(params).WithRBF()
+			tx, _ := build(txBuilder)
+			_, err := txBuilder.BumpFee(tx, 1, pack.NewU256FromU64(pack.NewU64(8)))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject transactions that do not signal rbf", func() {
+			txBuilder := bitcoin.NewTxBuilder(params)
+			tx, _ := build(txBuilder)
+			_, err := txBuilder.BumpFee(tx, 1, pack.NewU256FromU64(pack.NewU64(20)))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when building a cpfp transaction", func() {
+		It("should pay for the parent and the child", func() {
+			txBuilder := bitcoin.NewTxBuilder(params)
+			parent, addr := build(txBuilder)
+
+			child, err := txBuilder.BuildCPFPTx(parent, 1, addr, pack.NewU256FromU64(pack.NewU64(20)))
+			Expect(err).ToNot(HaveOccurred())
+
+			inputs, err := child.Inputs()
+			Expect(err).ToNot(HaveOccurred())
+			parentHash, err := parent.Hash()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inputs).To(HaveLen(1))
+			Expect(inputs[0].Hash).To(Equal(parentHash))
+			Expect(inputs[0].Index).To(Equal(pack.NewU32(1)))
+
+			// The parent is 141 virtual bytes, and the child is 110 virtual
+			// bytes. The parent has already paid 1000 SATs.
+			fee, err := child.(*bitcoin.Tx).Fee()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(Equal(pack.NewU256FromU64(pack.NewU64(20*(141+110) - 1000))))
+		})
+	})
+})
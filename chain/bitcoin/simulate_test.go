@@ -0,0 +1,53 @@
+package bitcoin_test
+
+import (
+	"context"
+
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// serializedTx is a transaction that only knows its serialization.
+type serializedTx struct {
+	utxo.Tx
+	serial pack.Bytes
+}
+
+func (tx serializedTx) Serialize() (pack.Bytes, error) {
+	return tx.serial, nil
+}
+
+var _ = Describe("Simulate", func() {
+	opts := bitcoin.DefaultClientOptions().WithLogger(nil)
+	tx := serializedTx{serial: pack.Bytes{1, 2, 3}}
+
+	Context("when the tx would be accepted", func() {
+		It("should return a successful simulation", func() {
+			transport := &mockTransport{
+				result: []map[string]interface{}{{"txid": "00", "allowed": true}},
+			}
+			client := bitcoin.NewClient(opts.WithTransport(transport))
+			sim, err := client.Simulate(context.Background(), tx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sim.Success).To(BeTrue())
+			Expect(sim.RevertReason).To(BeEmpty())
+		})
+	})
+
+	Context("when the tx would be rejected", func() {
+		It("should return the reject reason", func() {
+			transport := &mockTransport{
+				result: []map[string]interface{}{{"txid": "00", "allowed": false, "reject-reason": "missing-inputs"}},
+			}
+			client := bitcoin.NewClient(opts.WithTransport(transport))
+			sim, err := client.Simulate(context.Background(), tx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sim.Success).To(BeFalse())
+			Expect(sim.RevertReason).To(Equal(pack.String("missing-inputs")))
+		})
+	})
+})
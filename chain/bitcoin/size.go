@@ -0,0 +1,146 @@
+package bitcoin
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+const (
+	// MaxSignatureSize is the maximum size of a DER encoded low-S ECDSA
+	// signature, including the sighash type.
+	MaxSignatureSize = 72
+	// SchnorrSignatureSize is the size of a BIP-340 Schnorr signature that
+	// uses SIGHASH_DEFAULT.
+	SchnorrSignatureSize = 64
+	// CompressedPubKeySize is the size of a serialized compressed public key.
+	CompressedPubKeySize = 33
+
+	// witnessScaleFactor is the weight of a non-witness byte, as defined by
+	// BIP-141.
+	witnessScaleFactor = 4
+)
+
+// The SizeEstimator computes the virtual size of Bitcoin transactions. It
+// implements the utxo.SizeEstimator interface. Estimates assume that inputs
+// are signed in the same way as Tx.Sign, using a compressed public key.
+type SizeEstimator struct {
+	params *chaincfg.Params
+}
+
+// NewSizeEstimator returns a SizeEstimator for the given chain configuration.
+func NewSizeEstimator(params *chaincfg.Params) SizeEstimator {
+	return SizeEstimator{params: params}
+}
+
+// EstimateSize returns an upper bound on the virtual size of the signed
+// transaction that consumes the given inputs and sends to the given
+// recipients.
+func (estimator SizeEstimator) EstimateSize(inputs []utxo.Input, recipients []utxo.Recipient) (pack.U64, error) {
+	txOuts := make([]*wire.TxOut, len(recipients))
+	for i, recipient := range recipients {
+		addr, err := decodeAddress(string(recipient.To), estimator.params)
+		if err != nil {
+			return pack.U64(0), fmt.Errorf("bad recipient %v: %v", i, err)
+		}
+		script, err := payToAddrScript(addr)
+		if err != nil {
+			return pack.U64(0), fmt.Errorf("bad recipient %v: %v", i, err)
+		}
+		txOuts[i] = wire.NewTxOut(0, script)
+	}
+	return estimateVirtualSize(inputs, txOuts), nil
+}
+
+// TxSize returns the virtual size of the given transaction. If the transaction
+// has not been signed, an upper bound on its virtual size once it has been
+// signed is returned.
+func (estimator SizeEstimator) TxSize(tx utxo.Tx) (pack.U64, error) {
+	btcTx, ok := tx.(*Tx)
+	if !ok {
+		return pack.U64(0), fmt.Errorf("expected type %T, got type %T", new(Tx), tx)
+	}
+	if btcTx.signed {
+		weight := btcTx.msgTx.SerializeSizeStripped()*(witnessScaleFactor-1) + btcTx.msgTx.SerializeSize()
+		return pack.NewU64(uint64(virtualSize(weight))), nil
+	}
+	return estimateVirtualSize(btcTx.inputs, btcTx.msgTx.TxOut), nil
+}
+
+// estimateVirtualSize returns an upper bound on the virtual size of a signed
+// transaction with the given inputs and outputs.
+func estimateVirtualSize(inputs []utxo.Input, txOuts []*wire.TxOut) pack.U64 {
+	baseSize := 8 + wire.VarIntSerializeSize(uint64(len(inputs))) + wire.VarIntSerializeSize(uint64(len(txOuts)))
+	witnessSize := 0
+	hasWitness := false
+	for _, input := range inputs {
+		sigScriptItems, witnessItems := signedInputItems(input)
+		sigScriptSize := 0
+		for _, item := range sigScriptItems {
+			sigScriptSize += PushDataSize(item)
+		}
+		baseSize += 40 + wire.VarIntSerializeSize(uint64(sigScriptSize)) + sigScriptSize
+
+		witnessSize += wire.VarIntSerializeSize(uint64(len(witnessItems)))
+		for _, item := range witnessItems {
+			witnessSize += wire.VarIntSerializeSize(uint64(item)) + item
+		}
+		hasWitness = hasWitness || len(witnessItems) > 0
+	}
+	for _, txOut := range txOuts {
+		baseSize += txOut.SerializeSize()
+	}
+
+	weight := baseSize * witnessScaleFactor
+	if hasWitness {
+		// The segwit marker and flag, and the witnesses.
+		weight += 2 + witnessSize
+	}
+	return pack.NewU64(uint64(virtualSize(weight)))
+}
+
+// signedInputItems returns the sizes of the items that are pushed into the
+// sig script, and the sizes of the items in the witness, when the input is
+// signed by Tx.Sign.
+func signedInputItems(input utxo.Input) ([]int, []int) {
+	pubKeyScript := []byte(input.PubKeyScript)
+	sigScript := []byte(input.SigScript)
+
+	if IsPayToTaproot(pubKeyScript) {
+		return nil, []int{SchnorrSignatureSize}
+	}
+	if sigScript == nil {
+		if txscript.IsPayToWitnessPubKeyHash(pubKeyScript) || txscript.IsPayToWitnessScriptHash(pubKeyScript) {
+			return nil, []int{MaxSignatureSize, CompressedPubKeySize}
+		}
+		return []int{MaxSignatureSize, CompressedPubKeySize}, nil
+	}
+	if txscript.IsPayToWitnessPubKeyHash(sigScript) || txscript.IsPayToWitnessScriptHash(sigScript) {
+		return nil, []int{MaxSignatureSize, CompressedPubKeySize, len(sigScript)}
+	}
+	return []int{MaxSignatureSize, CompressedPubKeySize, len(sigScript)}, nil
+}
+
+// PushDataSize returns the size of a script that pushes data of the given
+// size onto the stack.
+func PushDataSize(size int) int {
+	switch {
+	case size < txscript.OP_PUSHDATA1:
+		return 1 + size
+	case size <= 0xff:
+		return 2 + size
+	case size <= 0xffff:
+		return 3 + size
+	default:
+		return 5 + size
+	}
+}
+
+// virtualSize returns the virtual size of a transaction with the given weight.
+func virtualSize(weight int) int {
+	return (weight + witnessScaleFactor - 1) / witnessScaleFactor
+}
@@ -0,0 +1,113 @@
+package bitcoin_test
+
+import (
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Size", func() {
+	params := &chaincfg.RegressionNetParams
+	estimator := bitcoin.NewSizeEstimator(params)
+
+	addrs := func(privKey *id.PrivKey) (btcutil.Address, btcutil.Address) {
+		pubKey := (*btcec.PublicKey)(privKey.PubKey()).SerializeCompressed()
+		pkhAddr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), params)
+		Expect(err).ToNot(HaveOccurred())
+		wpkhAddr, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey), params)
+		Expect(err).ToNot(HaveOccurred())
+		return pkhAddr, wpkhAddr
+	}
+
+	inputFor := func(addr btcutil.Address, index uint32) utxo.Input {
+		script, err := txscript.PayToAddrScript(addr)
+		Expect(err).ToNot(HaveOccurred())
+		return utxo.Input{
+			Output: utxo.Output{
+				Outpoint: utxo.Outpoint{
+					Hash:  pack.NewBytes(make([]byte, 32)),
+					Index: pack.NewU32(index),
+				},
+				Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+				PubKeyScript: pack.NewBytes(script),
+			},
+		}
+	}
+
+	signAndCompare := func(inputs []utxo.Input, recipients []utxo.Recipient, privKey *id.PrivKey) {
+		estimate, err := estimator.EstimateSize(inputs, recipients)
+		Expect(err).ToNot(HaveOccurred())
+
+		tx, err := bitcoin.NewTxBuilder(params).BuildTx(inputs, recipients)
+		Expect(err).ToNot(HaveOccurred())
+		unsignedSize, err := estimator.TxSize(tx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unsignedSize).To(Equal(estimate))
+
+		sighashes, err := tx.Sighashes()
+		Expect(err).ToNot(HaveOccurred())
+		signatures := make([]pack.Bytes65, len(sighashes))
+		for i := range sighashes {
+			hash := id.Hash(sighashes[i])
+			signature, err := privKey.Sign(&hash)
+			Expect(err).ToNot(HaveOccurred())
+			signatures[i] = pack.NewBytes65(signature)
+		}
+		pubKey := (*btcec.PublicKey)(privKey.PubKey()).SerializeCompressed()
+		Expect(tx.Sign(signatures, pack.NewBytes(pubKey))).To(Succeed())
+
+		signedSize, err := estimator.TxSize(tx)
+		Expect(err).ToNot(HaveOccurred())
+		// Signatures can be at most one byte shorter than the maximum.
+		Expect(signedSize).To(BeNumerically("<=", estimate))
+		Expect(signedSize).To(BeNumerically(">=", uint64(estimate)-uint64(len(inputs))))
+	}
+
+	Context("when spending P2PKH outputs", func() {
+		It("should return an upper bound on the size", func() {
+			privKey := id.NewPrivKey()
+			pkhAddr, wpkhAddr := addrs(privKey)
+			signAndCompare(
+				[]utxo.Input{inputFor(pkhAddr, 0), inputFor(pkhAddr, 1)},
+				[]utxo.Recipient{
+					{To: address.Address(pkhAddr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(1000))},
+					{To: address.Address(wpkhAddr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(1000))},
+				},
+				privKey,
+			)
+		})
+	})
+
+	Context("when spending P2WPKH outputs", func() {
+		It("should return an upper bound on the virtual size", func() {
+			privKey := id.NewPrivKey()
+			_, wpkhAddr := addrs(privKey)
+			signAndCompare(
+				[]utxo.Input{inputFor(wpkhAddr, 0), inputFor(wpkhAddr, 1), inputFor(wpkhAddr, 2)},
+				[]utxo.Recipient{
+					{To: address.Address(wpkhAddr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(1000))},
+				},
+				privKey,
+			)
+		})
+
+		It("should match the well-known size of a one input, one output transaction", func() {
+			_, wpkhAddr := addrs(id.NewPrivKey())
+			size, err := estimator.EstimateSize(
+				[]utxo.Input{inputFor(wpkhAddr, 0)},
+				[]utxo.Recipient{{To: address.Address(wpkhAddr.EncodeAddress()), Value: pack.NewU256FromU64(pack.NewU64(1000))}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(Equal(pack.NewU64(110)))
+		})
+	})
+})
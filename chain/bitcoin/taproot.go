@@ -0,0 +1,292 @@
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/renproject/multichain/api/utxo"
+)
+
+const (
+	// TaprootWitnessVersion is the witness version used by P2TR outputs.
+	TaprootWitnessVersion = 0x01
+
+	// bech32mConst is the constant used by BIP-350 bech32m checksums (instead
+	// of the constant 1 used by BIP-173 bech32 checksums).
+	bech32mConst = 0x2bc830a3
+
+	bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+// AddressTaproot represents a P2TR (pay-to-taproot) address, as defined by
+// BIP-341. It is encoded using bech32m, as defined by BIP-350.
+type AddressTaproot struct {
+	hrp            string
+	witnessProgram [32]byte
+}
+
+// NewAddressTaproot returns a P2TR address for the given 32 byte x-only output
+// key. See TaprootOutputKey for computing the output key of a key-path only
+// taproot output.
+func NewAddressTaproot(outputKey []byte, params *chaincfg.Params) (*AddressTaproot, error) {
+	if len(outputKey) != 32 {
+		return nil, fmt.Errorf("invalid output key length: expected 32, got %v", len(outputKey))
+	}
+	addr := &AddressTaproot{hrp: strings.ToLower(params.Bech32HRPSegwit)}
+	copy(addr.witnessProgram[:], outputKey)
+	return addr, nil
+}
+
+// DecodeAddressTaproot decodes a bech32m encoded P2TR address for the given
+// network.
+func DecodeAddressTaproot(addr string, params *chaincfg.Params) (*AddressTaproot, error) {
+	hrp, data, err := decodeBech32m(addr)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != strings.ToLower(params.Bech32HRPSegwit) {
+		return nil, fmt.Errorf("invalid hrp: expected %v, got %v", params.Bech32HRPSegwit, hrp)
+	}
+	if len(data) < 1 || data[0] != TaprootWitnessVersion {
+		return nil, fmt.Errorf("invalid witness version")
+	}
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("converting bits: %v", err)
+	}
+	return NewAddressTaproot(program, params)
+}
+
+// EncodeAddress returns the bech32m encoding of the address. This is part of
+// the btcutil.Address interface.
+func (addr *AddressTaproot) EncodeAddress() string {
+	data, err := bech32.ConvertBits(addr.witnessProgram[:], 8, 5, true)
+	if err != nil {
+		// Converting 32 bytes into 5 bit groups cannot fail.
+		panic(fmt.Errorf("converting bits: %v", err))
+	}
+	return encodeBech32m(addr.hrp, append([]byte{TaprootWitnessVersion}, data...))
+}
+
+// ScriptAddress returns the witness program of the address. This is part of
+// the btcutil.Address interface.
+func (addr *AddressTaproot) ScriptAddress() []byte {
+	return addr.witnessProgram[:]
+}
+
+// IsForNet returns whether or not the address is associated with the given
+// network. This is part of the btcutil.Address interface.
+func (addr *AddressTaproot) IsForNet(params *chaincfg.Params) bool {
+	return addr.hrp == strings.ToLower(params.Bech32HRPSegwit)
+}
+
+// String returns the bech32m encoding of the address. This is part of the
+// btcutil.Address interface.
+func (addr *AddressTaproot) String() string {
+	return addr.EncodeAddress()
+}
+
+// WitnessVersion returns the witness version of the address.
+func (addr *AddressTaproot) WitnessVersion() byte {
+	return TaprootWitnessVersion
+}
+
+// WitnessProgram returns the witness program of the address.
+func (addr *AddressTaproot) WitnessProgram() []byte {
+	return addr.witnessProgram[:]
+}
+
+// TaprootOutputKey returns the 32 byte x-only output key for a taproot output
+// that can only be spent using the key-path (there is no script tree). The
+// internal key must be a 32 byte x-only public key. See BIP-86.
+func TaprootOutputKey(internalKey []byte) ([]byte, error) {
+	if len(internalKey) != 32 {
+		return nil, fmt.Errorf("invalid internal key length: expected 32, got %v", len(internalKey))
+	}
+	curve := btcec.S256()
+	pubKey, err := btcec.ParsePubKey(append([]byte{0x02}, internalKey...), curve)
+	if err != nil {
+		return nil, fmt.Errorf("invalid internal key: %v", err)
+	}
+	tweak := taggedHash("TapTweak", internalKey)
+	if new(big.Int).SetBytes(tweak[:]).Cmp(curve.N) >= 0 {
+		return nil, fmt.Errorf("invalid tweak")
+	}
+	tweakX, tweakY := curve.ScalarBaseMult(tweak[:])
+	outputX, _ := curve.Add(pubKey.X, pubKey.Y, tweakX, tweakY)
+	return outputX.FillBytes(make([]byte, 32)), nil
+}
+
+// IsPayToTaproot returns true if the script is in the standard P2TR format.
+func IsPayToTaproot(script []byte) bool {
+	return len(script) == 34 && script[0] == txscript.OP_1 && script[1] == txscript.OP_DATA_32
+}
+
+// decodeAddress decodes an address for the given network. It supports all of
+// the address types supported by btcutil, and P2TR addresses.
+func decodeAddress(addr string, params *chaincfg.Params) (btcutil.Address, error) {
+	decodedAddr, err := btcutil.DecodeAddress(addr, params)
+	if err == nil {
+		return decodedAddr, nil
+	}
+	if taprootAddr, taprootErr := DecodeAddressTaproot(addr, params); taprootErr == nil {
+		return taprootAddr, nil
+	}
+	return nil, err
+}
+
+// payToAddrScript returns the pubkey script that pays to the given address. It
+// supports all of the address types supported by txscript, and P2TR
+// addresses.
+func payToAddrScript(addr btcutil.Address) ([]byte, error) {
+	if taprootAddr, ok := addr.(*AddressTaproot); ok {
+		return append([]byte{txscript.OP_1, txscript.OP_DATA_32}, taprootAddr.witnessProgram[:]...), nil
+	}
+	return txscript.PayToAddrScript(addr)
+}
+
+// calcTaprootSignatureHash returns the BIP-341 signature hash for a key-path
+// spend of the input at the given index, using SIGHASH_DEFAULT. Unlike BIP-143,
+// the signature hash commits to the values and pubkey scripts of all of the
+// outputs being spent, so every input must be known.
+func calcTaprootSignatureHash(msgTx *wire.MsgTx, inputs []utxo.Input, index int) ([]byte, error) {
+	if len(inputs) != len(msgTx.TxIn) {
+		return nil, fmt.Errorf("expected %v inputs, got %v inputs", len(msgTx.TxIn), len(inputs))
+	}
+
+	prevouts, amounts, pubKeyScripts, sequences := new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)
+	for i, txIn := range msgTx.TxIn {
+		value := inputs[i].Value.Int().Int64()
+		if value < 0 {
+			return nil, fmt.Errorf("bad input %v: expected value >= 0, got value %v", i, value)
+		}
+		prevouts.Write(txIn.PreviousOutPoint.Hash[:])
+		binary.Write(prevouts, binary.LittleEndian, txIn.PreviousOutPoint.Index)
+		binary.Write(amounts, binary.LittleEndian, value)
+		if err := wire.WriteVarBytes(pubKeyScripts, 0, inputs[i].PubKeyScript); err != nil {
+			return nil, fmt.Errorf("bad input %v: %v", i, err)
+		}
+		binary.Write(sequences, binary.LittleEndian, txIn.Sequence)
+	}
+	outputs := new(bytes.Buffer)
+	for i, txOut := range msgTx.TxOut {
+		if err := wire.WriteTxOut(outputs, 0, 0, txOut); err != nil {
+			return nil, fmt.Errorf("bad output %v: %v", i, err)
+		}
+	}
+
+	sigMsg := new(bytes.Buffer)
+	// Sighash epoch and hash type (SIGHASH_DEFAULT).
+	sigMsg.Write([]byte{0x00, 0x00})
+	binary.Write(sigMsg, binary.LittleEndian, msgTx.Version)
+	binary.Write(sigMsg, binary.LittleEndian, msgTx.LockTime)
+	for _, buf := range []*bytes.Buffer{prevouts, amounts, pubKeyScripts, sequences, outputs} {
+		hash := sha256.Sum256(buf.Bytes())
+		sigMsg.Write(hash[:])
+	}
+	// Spend type: key-path spend without an annex.
+	sigMsg.WriteByte(0x00)
+	binary.Write(sigMsg, binary.LittleEndian, uint32(index))
+
+	sighash := taggedHash("TapSighash", sigMsg.Bytes())
+	return sighash[:], nil
+}
+
+// taggedHash implements the tagged hash function defined by BIP-340.
+func taggedHash(tag string, msg []byte) [32]byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	preimage := make([]byte, 0, 64+len(msg))
+	preimage = append(preimage, tagHash[:]...)
+	preimage = append(preimage, tagHash[:]...)
+	preimage = append(preimage, msg...)
+	return sha256.Sum256(preimage)
+}
+
+// encodeBech32m encodes 5 bit groups of data into a bech32m string.
+func encodeBech32m(hrp string, data []byte) string {
+	values := append(bech32HRPExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ bech32mConst
+
+	builder := strings.Builder{}
+	builder.WriteString(hrp)
+	builder.WriteByte('1')
+	for _, b := range data {
+		builder.WriteByte(bech32Charset[b])
+	}
+	for i := 0; i < 6; i++ {
+		builder.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+	return builder.String()
+}
+
+// decodeBech32m decodes a bech32m string into its hrp and 5 bit groups of
+// data. The checksum is verified and removed.
+func decodeBech32m(str string) (string, []byte, error) {
+	if len(str) < 8 || len(str) > 90 {
+		return "", nil, fmt.Errorf("invalid bech32m string length %v", len(str))
+	}
+	if strings.ToLower(str) != str && strings.ToUpper(str) != str {
+		return "", nil, fmt.Errorf("invalid bech32m string: mixed case")
+	}
+	str = strings.ToLower(str)
+
+	sep := strings.LastIndexByte(str, '1')
+	if sep < 1 || sep+7 > len(str) {
+		return "", nil, fmt.Errorf("invalid bech32m string: bad separator index %v", sep)
+	}
+	hrp := str[:sep]
+	for _, c := range hrp {
+		if c < 33 || c > 126 {
+			return "", nil, fmt.Errorf("invalid bech32m string: bad hrp character %v", c)
+		}
+	}
+	data := make([]byte, len(str)-sep-1)
+	for i, c := range str[sep+1:] {
+		index := strings.IndexRune(bech32Charset, c)
+		if index < 0 {
+			return "", nil, fmt.Errorf("invalid bech32m string: bad data character %q", c)
+		}
+		data[i] = byte(index)
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), data...)) != bech32mConst {
+		return "", nil, fmt.Errorf("invalid bech32m string: bad checksum")
+	}
+	return hrp, data[:len(data)-6], nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
@@ -0,0 +1,180 @@
+package bitcoin_test
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Taproot", func() {
+	// Test vectors from BIP-86.
+	internalKey, _ := hex.DecodeString("cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115")
+	outputKey, _ := hex.DecodeString("a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c")
+	taprootAddr := "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"
+
+	Context("when computing the output key", func() {
+		It("should tweak the internal key", func() {
+			key, err := bitcoin.TaprootOutputKey(internalKey)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(key).To(Equal(outputKey))
+		})
+	})
+
+	Context("when encoding and decoding addresses", func() {
+		It("should use bech32m", func() {
+			addr, err := bitcoin.NewAddressTaproot(outputKey, &chaincfg.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.EncodeAddress()).To(Equal(taprootAddr))
+
+			decodedAddr, err := bitcoin.DecodeAddressTaproot(taprootAddr, &chaincfg.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decodedAddr.WitnessProgram()).To(Equal(outputKey))
+		})
+
+		It("should round-trip through the address encode decoder", func() {
+			encodeDecoder := bitcoin.NewAddressEncodeDecoder(&chaincfg.MainNetParams)
+			rawAddr, err := encodeDecoder.DecodeAddress(address.Address(taprootAddr))
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(rawAddr)).To(Equal(append([]byte{bitcoin.TaprootWitnessVersion}, outputKey...)))
+
+			addr, err := encodeDecoder.EncodeAddress(rawAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr).To(Equal(address.Address(taprootAddr)))
+		})
+
+		It("should reject bech32 encoded witness v1 addresses", func() {
+			// Witness v1 encoded with a bech32 (instead of bech32m) checksum,
+			// from BIP-350.
+			_, err := bitcoin.DecodeAddressTaproot("bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", &chaincfg.MainNetParams)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject addresses for other networks", func() {
+			_, err := bitcoin.DecodeAddressTaproot(taprootAddr, &chaincfg.TestNet3Params)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when computing signature hashes", func() {
+		It("should match the BIP-341 key path spending vector", func() {
+			// Test vector from BIP-341 (keyPathSpending, input 4), which is
+			// the only input that is signed using SIGHASH_DEFAULT.
+			rawTx, err := hex.DecodeString(
+				"02000000097de20cbff686da83a54981d2b9bab3586f4ca7e48f57f5b55963115f3b334e9c010000000000000000d7b7" +
+					"cab57b1393ace2d064f4d4a2cb8af6def61273e127517d44759b6dafdd990000000000fffffffff8e1f5833843336892" +
+					"28c5d28eac13366be082dc57441760d957275419a418420000000000fffffffff0689180aa63b30cb162a73c6d2a38b7" +
+					"eeda2a83ece74310fda0843ad604853b0100000000feffffffaa5202bdf6d8ccd2ee0f0202afbbb7461d9264a25e5bfd" +
+					"3c5a52ee1239e0ba6c0000000000feffffff956149bdc66faa968eb2be2d2faa29718acbfe3941215893a2a3446d32ac" +
+					"d050000000000000000000e664b9773b88c09c32cb70a2a3e4da0ced63b7ba3b22f848531bbb1d5d5f4c940100000000" +
+					"00000000e9aa6b8e6c9de67619e6a3924ae25696bb7b694bb677a632a74ef7eadfd4eabf0000000000ffffffffa778eb" +
+					"6a263dc090464cd125c466b5a99667720b1c110468831d058aa1b82af10100000000ffffffff0200ca9a3b0000000019" +
+					"76a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac807840cb0000000020ac9a87f5594be208f8532db38cff" +
+					"670c450ed2fea8fcdefcc9a663f78bab962b0065cd1d")
+			Expect(err).ToNot(HaveOccurred())
+			msgTx := wire.NewMsgTx(wire.TxVersion)
+			Expect(msgTx.Deserialize(bytes.NewReader(rawTx))).To(Succeed())
+
+			utxosSpent := []struct {
+				pubKeyScript string
+				value        uint64
+			}{
+				{"512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343", 420000000},
+				{"5120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3", 462000000},
+				{"76a914751e76e8199196d454941c45d1b3a323f1433bd688ac", 294000000},
+				{"5120e4d810fd50586274face62b8a807eb9719cef49c04177cc6b76a9a4251d5450e", 504000000},
+				{"512091b64d5324723a985170e4dc5a0f84c041804f2cd12660fa5dec09fc21783605", 630000000},
+				{"00147dd65592d0ab2fe0d0257d571abf032cd9db93dc", 378000000},
+				{"512075169f4001aa68f15bbed28b218df1d0a62cbbcf1188c6665110c293c907b831", 672000000},
+				{"5120712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5", 546000000},
+				{"512077e30a5522dd9f894c3f8b8bd4c4b2cf82ca7da8a3ea6a239655c39c050ab220", 588000000},
+			}
+			inputs := make([]utxo.Input, len(utxosSpent))
+			for i, spent := range utxosSpent {
+				pubKeyScript, err := hex.DecodeString(spent.pubKeyScript)
+				Expect(err).ToNot(HaveOccurred())
+				inputs[i] = utxo.Input{Output: utxo.Output{
+					Value:        pack.NewU256FromU64(pack.NewU64(spent.value)),
+					PubKeyScript: pack.NewBytes(pubKeyScript),
+				}}
+			}
+
+			sighash, err := bitcoin.CalcTaprootSignatureHash(msgTx, inputs, 4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hex.EncodeToString(sighash)).To(Equal("4f900a0bae3f1446fd48490c2958b5a023228f01661cda3496a11da502a7f7ef"))
+		})
+
+		It("should commit to the values of all of the inputs", func() {
+			msgTx := wire.NewMsgTx(wire.TxVersion)
+			msgTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0}, nil, nil))
+			msgTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+			msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+			pubKeyScript := pack.NewBytes(append([]byte{0x51, 0x20}, outputKey...))
+			inputs := []utxo.Input{
+				{Output: utxo.Output{Value: pack.NewU256FromU64(pack.NewU64(1000)), PubKeyScript: pubKeyScript}},
+				{Output: utxo.Output{Value: pack.NewU256FromU64(pack.NewU64(2000)), PubKeyScript: pubKeyScript}},
+			}
+
+			sighash, err := bitcoin.CalcTaprootSignatureHash(msgTx, inputs, 0)
+			Expect(err).ToNot(HaveOccurred())
+			inputs[1].Value = pack.NewU256FromU64(pack.NewU64(2001))
+			changed, err := bitcoin.CalcTaprootSignatureHash(msgTx, inputs, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changed).ToNot(Equal(sighash))
+
+			_, err = bitcoin.CalcTaprootSignatureHash(msgTx, inputs[:1], 0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when building and signing a transaction", func() {
+		It("should pay to and spend from taproot outputs", func() {
+			pubKeyScript := append([]byte{0x51, 0x20}, outputKey...)
+			tx, err := bitcoin.NewTxBuilder(&chaincfg.MainNetParams).BuildTx(
+				[]utxo.Input{{
+					Output: utxo.Output{
+						Outpoint: utxo.Outpoint{
+							Hash:  pack.NewBytes(make([]byte, 32)),
+							Index: pack.NewU32(0),
+						},
+						Value:        pack.NewU256FromU64(pack.NewU64(100000)),
+						PubKeyScript: pack.NewBytes(pubKeyScript),
+					},
+				}},
+				[]utxo.Recipient{{
+					To:    address.Address(taprootAddr),
+					Value: pack.NewU256FromU64(pack.NewU64(90000)),
+				}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			outputs, err := tx.Outputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(outputs[0].PubKeyScript)).To(Equal(pubKeyScript))
+			Expect(bitcoin.IsPayToTaproot(outputs[0].PubKeyScript)).To(BeTrue())
+
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sighashes).To(HaveLen(1))
+
+			signature := pack.Bytes65{}
+			for i := range signature {
+				signature[i] = byte(i)
+			}
+			Expect(tx.Sign([]pack.Bytes65{signature}, nil)).To(Succeed())
+			serialized, err := tx.Serialize()
+			Expect(err).ToNot(HaveOccurred())
+			// The witness is a single 64 byte signature, followed by the
+			// locktime.
+			Expect([]byte(serialized[len(serialized)-70 : len(serialized)-4])).To(Equal(append([]byte{0x01, 0x40}, signature[:64]...)))
+		})
+	})
+})
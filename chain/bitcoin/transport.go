@@ -0,0 +1,200 @@
+package bitcoin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RPC error codes returned by Bitcoin nodes (and nodes for forks of Bitcoin)
+// that indicate the request may succeed if it is retried. All other RPC error
+// codes indicate that the request is invalid, and will never succeed.
+const (
+	// RPCErrorInWarmup is returned while the node is still starting.
+	RPCErrorInWarmup = -28
+	// RPCErrorClientNotConnected is returned when the node has no peers.
+	RPCErrorClientNotConnected = -9
+	// RPCErrorClientInInitialDownload is returned while the node is still
+	// downloading blocks.
+	RPCErrorClientInInitialDownload = -10
+)
+
+// RPCErrorInvalidAddressOrKey is returned by Bitcoin nodes (and nodes for forks
+// of Bitcoin) when a transaction, block, or address is not known to the node.
+const RPCErrorInvalidAddressOrKey = -5
+
+// A Transport sends raw JSON-RPC requests to a node, and returns the raw
+// responses. Implementations should not retry requests (this is done by the
+// Client).
+type Transport interface {
+	Send(ctx context.Context, req []byte) ([]byte, error)
+}
+
+// HTTPTransport sends JSON-RPC requests to a node over HTTP, using basic
+// authentication.
+type HTTPTransport struct {
+	host       string
+	user       string
+	password   string
+	httpClient *http.Client
+}
+
+// NewHTTPTransport returns a Transport that sends requests to the given host.
+// Every request is given the timeout duration to complete.
+func NewHTTPTransport(host, user, password string, timeout time.Duration) HTTPTransport {
+	return HTTPTransport{
+		host:       host,
+		user:       user,
+		password:   password,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Send the request over HTTP. If the node responds with a status other than
+// 200 OK, the body of the response is returned along with an HTTPError (Bitcoin
+// nodes respond with a non-200 status when a JSON-RPC error is returned).
+func (transport HTTPTransport) Send(ctx context.Context, req []byte) ([]byte, error) {
+	// Create request and add basic authentication headers. The context is not
+	// attached to the request, and instead we allow each attempt to run for the
+	// timeout duration.
+	httpReq, err := http.NewRequest("POST", transport.host, bytes.NewBuffer(req))
+	if err != nil {
+		return nil, fmt.Errorf("building http request: %v", err)
+	}
+	httpReq.SetBasicAuth(transport.user, transport.password)
+
+	res, err := transport.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("sending http request: %v", err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading http response: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return body, &HTTPError{StatusCode: res.StatusCode, Body: string(body)}
+	}
+	return body, nil
+}
+
+// An HTTPError is returned by the HTTPTransport when the node responds with a
+// status other than 200 OK.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface.
+func (err *HTTPError) Error() string {
+	return fmt.Sprintf("http status %v: %v", err.StatusCode, err.Body)
+}
+
+// An RPCError is returned by a node when it fails to handle a JSON-RPC
+// request.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (err *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %v: %v", err.Code, err.Message)
+}
+
+// IsTransientError returns true if the error is likely to be temporary, and
+// the request that caused it should be retried. RPC errors are permanent,
+// unless the node is still starting or syncing. HTTP errors are permanent,
+// unless they are server errors or the request was rate limited. All other
+// errors (for example, network errors) are assumed to be transient.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	rpcErr := new(RPCError)
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case RPCErrorInWarmup, RPCErrorClientNotConnected, RPCErrorClientInInitialDownload:
+			return true
+		default:
+			return false
+		}
+	}
+	httpErr := new(HTTPError)
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode == http.StatusRequestTimeout
+	}
+	var decodeErr *decodeError
+	if errors.As(err, &decodeErr) {
+		return false
+	}
+	return true
+}
+
+// decodeError is returned when a response has been received, but its result
+// cannot be decoded. Retrying will not change the result.
+type decodeError struct {
+	err error
+}
+
+func (err *decodeError) Error() string {
+	return err.err.Error()
+}
+
+// retry calls the function until it succeeds, it returns a permanent error,
+// the maximum number of attempts is reached, or the context is done. The delay
+// between attempts grows exponentially (with jitter) from the retry timeout,
+// up to the maximum retry timeout.
+func retry(ctx context.Context, opts ClientOptions, method string, f func() error) error {
+	backoff := opts.TimeoutRetry
+	if backoff < MinClientTimeoutRetry {
+		backoff = MinClientTimeoutRetry
+	}
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		if !IsTransientError(err) {
+			return err
+		}
+		if opts.MaxAttempts > 0 && attempt >= opts.MaxAttempts {
+			return fmt.Errorf("%v attempts: %w", attempt, err)
+		}
+
+		// Use "equal jitter": wait for at least half of the backoff.
+		delay := backoff / 2
+		if backoff > 1 {
+			delay += time.Duration(rand.Int63n(int64(backoff / 2)))
+		}
+		if opts.Logger != nil {
+			opts.Logger.Warn("retrying", zap.String("method", method), zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%v: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+
+		if backoff < math.MaxInt64/2 {
+			backoff *= 2
+		}
+		if opts.MaxTimeoutRetry > 0 && backoff > opts.MaxTimeoutRetry {
+			backoff = opts.MaxTimeoutRetry
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package bitcoin_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/renproject/multichain/chain/bitcoin"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mockTransport returns the given errors (one per attempt), and then returns
+// the result.
+type mockTransport struct {
+	errs     []error
+	result   interface{}
+	attempts int
+}
+
+func (transport *mockTransport) Send(ctx context.Context, req []byte) ([]byte, error) {
+	transport.attempts++
+	if len(transport.errs) > 0 {
+		err := transport.errs[0]
+		transport.errs = transport.errs[1:]
+		if rpcErr, ok := err.(*bitcoin.RPCError); ok {
+			return json.Marshal(map[string]interface{}{"result": nil, "error": rpcErr})
+		}
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{"result": transport.result, "error": nil})
+}
+
+var _ = Describe("Transport", func() {
+	opts := bitcoin.DefaultClientOptions().
+		WithTimeoutRetry(time.Millisecond).
+		WithLogger(nil)
+
+	Context("when the transport returns transient errors", func() {
+		It("should retry until it succeeds", func() {
+			transport := &mockTransport{
+				errs:   []error{errors.New("connection refused"), &bitcoin.HTTPError{StatusCode: 503}, &bitcoin.RPCError{Code: bitcoin.RPCErrorInWarmup}},
+				result: 100,
+			}
+			client := bitcoin.NewClient(opts.WithTransport(transport))
+			height, err := client.LatestBlock(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(height).To(BeEquivalentTo(100))
+			Expect(transport.attempts).To(Equal(4))
+		})
+
+		It("should stop after the maximum number of attempts", func() {
+			transport := &mockTransport{
+				errs: []error{errors.New("connection refused"), errors.New("connection refused"), errors.New("connection refused")},
+			}
+			client := bitcoin.NewClient(opts.WithTransport(transport).WithMaxAttempts(2))
+			_, err := client.LatestBlock(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(transport.attempts).To(Equal(2))
+		})
+
+		It("should wait between attempts when the retry timeout is zero", func() {
+			transport := &mockTransport{
+				errs: []error{errors.New("connection refused"), errors.New("connection refused"), errors.New("connection refused")},
+			}
+			client := bitcoin.NewClient(opts.WithTransport(transport).WithTimeoutRetry(0).WithMaxAttempts(3))
+			start := time.Now()
+			_, err := client.LatestBlock(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(transport.attempts).To(Equal(3))
+			// The delays are at least half of the minimum backoff, and then
+			// half of double the minimum backoff.
+			Expect(time.Since(start)).To(BeNumerically(">=", bitcoin.MinClientTimeoutRetry/2+bitcoin.MinClientTimeoutRetry))
+		})
+	})
+
+	Context("when the transport returns permanent errors", func() {
+		It("should not retry", func() {
+			transport := &mockTransport{
+				errs: []error{&bitcoin.RPCError{Code: -5, Message: "No such mempool or blockchain transaction"}},
+			}
+			client := bitcoin.NewClient(opts.WithTransport(transport))
+			_, err := client.LatestBlock(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("rpc error -5"))
+			Expect(transport.attempts).To(Equal(1))
+		})
+	})
+
+	Context("when classifying errors", func() {
+		It("should treat cancelled contexts as permanent", func() {
+			Expect(bitcoin.IsTransientError(context.Canceled)).To(BeFalse())
+			Expect(bitcoin.IsTransientError(&bitcoin.HTTPError{StatusCode: 401})).To(BeFalse())
+			Expect(bitcoin.IsTransientError(&bitcoin.HTTPError{StatusCode: 429})).To(BeTrue())
+		})
+	})
+})
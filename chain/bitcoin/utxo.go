@@ -10,7 +10,6 @@ import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
-	"github.com/btcsuite/btcutil"
 	"github.com/renproject/multichain/api/utxo"
 	"github.com/renproject/pack"
 )
@@ -21,7 +20,8 @@ const Version int32 = 2
 // The TxBuilder is an implementation of a UTXO-compatible transaction builder
 // for Bitcoin.
 type TxBuilder struct {
-	params *chaincfg.Params
+	params   *chaincfg.Params
+	sequence uint32
 }
 
 // NewTxBuilder returns a transaction builder that builds UTXO-compatible
@@ -29,16 +29,24 @@ type TxBuilder struct {
 // can be used for regnet, testnet, and mainnet, but also for networks that are
 // minimally modified forks of the Bitcoin network).
 func NewTxBuilder(params *chaincfg.Params) TxBuilder {
-	return TxBuilder{params: params}
+	return TxBuilder{params: params, sequence: wire.MaxTxInSequenceNum}
+}
+
+// WithRBF returns a copy of the transaction builder that builds transactions
+// that signal opt-in replace-by-fee, as defined by BIP-125. These transactions
+// can be replaced by transactions that pay a higher fee (see BumpFee).
+func (txBuilder TxBuilder) WithRBF() TxBuilder {
+	txBuilder.sequence = SequenceRBF
+	return txBuilder
 }
 
 // BuildTx returns a Bitcoin transaction that consumes funds from the given
 // inputs, and sends them to the given recipients. The difference in the sum
 // value of the inputs and the sum value of the recipients is paid as a fee to
 // the Bitcoin network. This fee must be calculated independently of this
-// function. Outputs produced for recipients will use P2PKH, P2SH, P2WPKH, or
-// P2WSH scripts as the pubkey script, based on the format of the recipient
-// address.
+// function. Outputs produced for recipients will use P2PKH, P2SH, P2WPKH,
+// P2WSH, or P2TR scripts as the pubkey script, based on the format of the
+// recipient address.
 func (txBuilder TxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipient) (utxo.Tx, error) {
 	msgTx := wire.NewMsgTx(Version)
 
@@ -47,19 +55,21 @@ func (txBuilder TxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipi
 		hash := chainhash.Hash{}
 		copy(hash[:], input.Hash)
 		index := input.Index.Uint32()
-		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&hash, index), nil, nil))
+		txIn := wire.NewTxIn(wire.NewOutPoint(&hash, index), nil, nil)
+		txIn.Sequence = txBuilder.sequence
+		msgTx.AddTxIn(txIn)
 	}
 
 	// Outputs
 	for _, recipient := range recipients {
-		addr, err := btcutil.DecodeAddress(string(recipient.To), txBuilder.params)
+		addr, err := decodeAddress(string(recipient.To), txBuilder.params)
 		if err != nil {
 			return nil, err
 		}
 		if !addr.IsForNet(txBuilder.params) {
 			return nil, fmt.Errorf("addr of a different network")
 		}
-		script, err := txscript.PayToAddrScript(addr)
+		script, err := payToAddrScript(addr)
 		if err != nil {
 			return nil, err
 		}
@@ -131,7 +141,9 @@ func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
 
 		var hash []byte
 		var err error
-		if sigScript == nil {
+		if IsPayToTaproot(pubKeyScript) {
+			hash, err = calcTaprootSignatureHash(tx.msgTx, tx.inputs, i)
+		} else if sigScript == nil {
 			if txscript.IsPayToWitnessPubKeyHash(pubKeyScript) {
 				hash, err = txscript.CalcWitnessSigHash(pubKeyScript, txscript.NewTxSigHashes(tx.msgTx), txscript.SigHashAll, tx.msgTx, i, value)
 			} else {
@@ -157,7 +169,9 @@ func (tx *Tx) Sighashes() ([]pack.Bytes32, error) {
 }
 
 // Sign consumes a list of signatures, and adds them to the list of UTXOs in
-// the underlying transactions.
+// the underlying transactions. Signatures for P2TR inputs must be 64 byte
+// BIP-340 Schnorr signatures (by the tweaked output key) stored in the first 64
+// bytes, and the last byte is ignored.
 func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
 	if tx.signed {
 		return fmt.Errorf("already signed")
@@ -169,6 +183,13 @@ func (tx *Tx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
 	for i, rsv := range signatures {
 		var err error
 
+		// Support taproot key-path spends. The signature uses SIGHASH_DEFAULT,
+		// so no sighash type is appended.
+		if IsPayToTaproot(tx.inputs[i].Output.PubKeyScript) {
+			tx.msgTx.TxIn[i].Witness = wire.TxWitness([][]byte{append([]byte{}, rsv[:64]...)})
+			continue
+		}
+
 		// Decode the signature and the pubkey script.
 		r := new(big.Int).SetBytes(rsv[:32])
 		s := new(big.Int).SetBytes(rsv[32:64])
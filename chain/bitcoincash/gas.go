@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/renproject/multichain/api/utxo"
 	"github.com/renproject/pack"
 )
 
@@ -13,6 +14,10 @@ const (
 	kilobyteToByte = 1024
 )
 
+// SizeParams are the sizes of transactions that spend P2PKH outputs. Output
+// sizes are large enough to pay to any address type.
+var SizeParams = utxo.SizeParams{BaseSize: 10, InputSize: 148, OutputSize: 34}
+
 // A GasEstimator returns the SATs-per-byte that is needed in order to confirm
 // transactions with an estimated maximum delay of one block. In distributed
 // networks that collectively build, sign, and submit transactions, it is
@@ -33,12 +38,12 @@ func NewGasEstimator(client Client, fallbackGas pack.U256) GasEstimator {
 }
 
 // EstimateGas returns the number of SATs-per-byte (for both price and cap) that
-// is needed in order to confirm transactions with a minimal delay. It is the
-// responsibility of the caller to know the number of bytes in their
-// transaction. This method calls the `estimatefee` RPC call to the node, which
-// based on a conservative (considering longer history) strategy returns the
-// estimated BCH per kilobyte of data in the transaction. An error will be
-// returned if the node hasn't observed enough blocks to make an estimate.
+// is needed in order to confirm transactions with a minimal delay. The number
+// of bytes in the transaction can be computed using the SizeEstimator. This
+// method calls the `estimatefee` RPC call to the node, which based on a
+// conservative (considering longer history) strategy returns the estimated BCH
+// per kilobyte of data in the transaction. An error will be returned if the
+// node hasn't observed enough blocks to make an estimate.
 func (gasEstimator GasEstimator) EstimateGas(ctx context.Context) (pack.U256, pack.U256, error) {
 	feeRate, err := gasEstimator.client.EstimateFeeLegacy(ctx, int64(0))
 	if err != nil {
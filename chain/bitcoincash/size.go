@@ -0,0 +1,79 @@
+package bitcoincash
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/bitcoin"
+	"github.com/renproject/pack"
+)
+
+// The SizeEstimator computes the size of Bitcoin Cash transactions. It
+// implements the utxo.SizeEstimator interface. Estimates assume that inputs
+// are signed in the same way as Tx.Sign, using a compressed public key.
+type SizeEstimator struct {
+	params *chaincfg.Params
+}
+
+// NewSizeEstimator returns a SizeEstimator for the given chain configuration.
+func NewSizeEstimator(params *chaincfg.Params) SizeEstimator {
+	return SizeEstimator{params: params}
+}
+
+// EstimateSize returns an upper bound on the size of the signed transaction
+// that consumes the given inputs and sends to the given recipients.
+func (estimator SizeEstimator) EstimateSize(inputs []utxo.Input, recipients []utxo.Recipient) (pack.U64, error) {
+	addrEncodeDecoder := NewAddressEncodeDecoder(estimator.params)
+	txOuts := make([]*wire.TxOut, len(recipients))
+	for i, recipient := range recipients {
+		addrBytes, err := addrEncodeDecoder.DecodeAddress(recipient.To)
+		if err != nil {
+			return pack.U64(0), fmt.Errorf("bad recipient %v: %v", i, err)
+		}
+		addr, err := addressFromRawBytes(addrBytes, estimator.params)
+		if err != nil {
+			return pack.U64(0), fmt.Errorf("bad recipient %v: %v", i, err)
+		}
+		script, err := txscript.PayToAddrScript(addr.BitcoinAddress())
+		if err != nil {
+			return pack.U64(0), fmt.Errorf("bad recipient %v: %v", i, err)
+		}
+		txOuts[i] = wire.NewTxOut(0, script)
+	}
+	return estimateSize(inputs, txOuts), nil
+}
+
+// TxSize returns the size of the given transaction. If the transaction has not
+// been signed, an upper bound on its size once it has been signed is returned.
+func (estimator SizeEstimator) TxSize(tx utxo.Tx) (pack.U64, error) {
+	bchTx, ok := tx.(*Tx)
+	if !ok {
+		return pack.U64(0), fmt.Errorf("expected type %T, got type %T", new(Tx), tx)
+	}
+	if bchTx.signed {
+		return pack.NewU64(uint64(bchTx.msgTx.SerializeSize())), nil
+	}
+	return estimateSize(bchTx.inputs, bchTx.msgTx.TxOut), nil
+}
+
+// estimateSize returns an upper bound on the size of a signed transaction with
+// the given inputs and outputs. Every sig script contains a signature, a
+// public key, and (when it is defined by the input) the sig script of the
+// input.
+func estimateSize(inputs []utxo.Input, txOuts []*wire.TxOut) pack.U64 {
+	size := 8 + wire.VarIntSerializeSize(uint64(len(inputs))) + wire.VarIntSerializeSize(uint64(len(txOuts)))
+	for _, input := range inputs {
+		sigScriptSize := bitcoin.PushDataSize(bitcoin.MaxSignatureSize) + bitcoin.PushDataSize(bitcoin.CompressedPubKeySize)
+		if input.SigScript != nil {
+			sigScriptSize += bitcoin.PushDataSize(len(input.SigScript))
+		}
+		size += 40 + wire.VarIntSerializeSize(uint64(sigScriptSize)) + sigScriptSize
+	}
+	for _, txOut := range txOuts {
+		size += txOut.SerializeSize()
+	}
+	return pack.NewU64(uint64(size))
+}
@@ -9,3 +9,32 @@ type GasEstimator = evm.GasEstimator
 
 // NewGasEstimator re-exports evm.NewGasEstimator.
 var NewGasEstimator = evm.NewGasEstimator
+
+const (
+	// FeeMarketLegacy re-exports evm.FeeMarketLegacy.
+	FeeMarketLegacy = evm.FeeMarketLegacy
+	// FeeMarketDynamic re-exports evm.FeeMarketDynamic.
+	FeeMarketDynamic = evm.FeeMarketDynamic
+	// FeeMarketAuto re-exports evm.FeeMarketAuto.
+	FeeMarketAuto = evm.FeeMarketAuto
+)
+
+type (
+	// FeeMarket re-exports evm.FeeMarket.
+	FeeMarket = evm.FeeMarket
+
+	// FeeOptions re-exports evm.FeeOptions.
+	FeeOptions = evm.FeeOptions
+)
+
+// DefaultFeeOptions re-exports evm.DefaultFeeOptions.
+var DefaultFeeOptions = evm.DefaultFeeOptions
+
+// DefaultGasLimitMultiplier re-exports evm.DefaultGasLimitMultiplier.
+const DefaultGasLimitMultiplier = evm.DefaultGasLimitMultiplier
+
+// GasLimitOptions re-exports evm.GasLimitOptions.
+type GasLimitOptions = evm.GasLimitOptions
+
+// DefaultGasLimitOptions re-exports evm.DefaultGasLimitOptions.
+var DefaultGasLimitOptions = evm.DefaultGasLimitOptions
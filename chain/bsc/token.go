@@ -0,0 +1,45 @@
+package bsc
+
+import (
+	"github.com/renproject/multichain"
+	"github.com/renproject/multichain/chain/evm"
+)
+
+type (
+	// TokenRegistry re-exports evm.TokenRegistry.
+	TokenRegistry = evm.TokenRegistry
+
+	// TransferEvent re-exports evm.TransferEvent.
+	TransferEvent = evm.TransferEvent
+)
+
+var (
+	// TransferEventSignature re-exports evm.TransferEventSignature.
+	TransferEventSignature = evm.TransferEventSignature
+
+	// TokenBalance re-exports evm.TokenBalance.
+	TokenBalance = evm.TokenBalance
+
+	// Allowance re-exports evm.Allowance.
+	Allowance = evm.Allowance
+
+	// BuildTransfer re-exports evm.BuildTransfer.
+	BuildTransfer = evm.BuildTransfer
+
+	// BuildApprove re-exports evm.BuildApprove.
+	BuildApprove = evm.BuildApprove
+
+	// DecodeTransferLog re-exports evm.DecodeTransferLog.
+	DecodeTransferLog = evm.DecodeTransferLog
+)
+
+// NewTokenRegistry returns an empty TokenRegistry for BinanceSmartChain.
+func NewTokenRegistry() *TokenRegistry {
+	return evm.NewTokenRegistry(multichain.BinanceSmartChain)
+}
+
+// DefaultTokenRegistry returns a TokenRegistry with the well-known addresses
+// of token contracts on BinanceSmartChain mainnet.
+func DefaultTokenRegistry() *TokenRegistry {
+	return evm.DefaultTokenRegistry(multichain.BinanceSmartChain)
+}
@@ -3,7 +3,9 @@ package cosmos
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"time"
@@ -12,16 +14,20 @@ import (
 	authTypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 	"github.com/renproject/multichain/api/account"
 	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/multichain/api/contract"
 	"github.com/renproject/pack"
 
 	cosmClient "github.com/cosmos/cosmos-sdk/client"
 	cliRpc "github.com/cosmos/cosmos-sdk/client/rpc"
 	"github.com/cosmos/cosmos-sdk/codec"
 	"github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 	"github.com/cosmos/cosmos-sdk/types/tx"
 	cosmTx "github.com/cosmos/cosmos-sdk/x/auth/tx"
 	bankType "github.com/cosmos/cosmos-sdk/x/bank/types"
 	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	rpctypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
 )
 
 const (
@@ -153,16 +159,111 @@ func (client *Client) LatestBlock(ctx context.Context) (pack.U64, error) {
 func (client *Client) Tx(ctx context.Context, txHash pack.Bytes) (account.Tx, pack.U64, error) {
 	res, err := cosmTx.QueryTx(client.ctx, hex.EncodeToString(txHash[:]))
 	if err != nil {
+		if isTxNotFound(err, txHash) {
+			return &Tx{}, pack.NewU64(0), fmt.Errorf("query fail: %v: %w", err, confirmation.ErrTxNotFound)
+		}
 		return &Tx{}, pack.NewU64(0), fmt.Errorf("query fail: %v", err)
 	}
 
 	authStdTx := res.Tx.GetCachedValue().(*tx.Tx)
 	if res.Code != 0 {
-		return &Tx{}, pack.NewU64(0), fmt.Errorf("tx failed code: %v, log: %v", res.Code, res.RawLog)
+		return &Tx{}, pack.NewU64(0), fmt.Errorf("tx failed code: %v, log: %v: %w", res.Code, res.RawLog, confirmation.ErrTxFailed)
 	}
 	return &Tx{originalTx: authStdTx, encoder: client.ctx.TxConfig.TxEncoder(), denom: string(client.opts.CoinDenom)}, pack.NewU64(1), nil
 }
 
+// isTxNotFound returns true if the error was returned because the node has
+// not indexed the transaction with the given hash. Tendermint reports unknown
+// transactions as an internal JSON-RPC error, with the hash of the transaction
+// in its data, and queries served by the application use the not found error.
+func isTxNotFound(err error, txHash pack.Bytes) bool {
+	if errors.Is(err, sdkerrors.ErrNotFound) {
+		return true
+	}
+	rpcErr := new(rpctypes.RPCError)
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Data == fmt.Sprintf("tx (%X) not found", []byte(txHash))
+	}
+	return false
+}
+
+// BlockHash returns the hash of the block at the given height.
+func (client *Client) BlockHash(ctx context.Context, height pack.U64) (pack.Bytes, error) {
+	h := int64(height.Uint64())
+	res, err := client.ctx.Client.Block(ctx, &h)
+	if err != nil {
+		return nil, fmt.Errorf("query block %v: %v", height, err)
+	}
+	return pack.NewBytes(res.BlockID.Hash), nil
+}
+
+// TxInclusion returns the block in which the transaction with the given hash
+// was included. Transactions that have not been committed are not indexed by
+// the node, so they are reported as unknown.
+func (client *Client) TxInclusion(ctx context.Context, txHash pack.Bytes) (confirmation.Inclusion, error) {
+	res, err := cosmTx.QueryTx(client.ctx, hex.EncodeToString(txHash[:]))
+	if err != nil {
+		if isTxNotFound(err, txHash) {
+			return confirmation.Inclusion{Status: confirmation.TxStatusUnknown}, nil
+		}
+		return confirmation.Inclusion{}, fmt.Errorf("query fail: %v", err)
+	}
+	if res.Height < 0 {
+		return confirmation.Inclusion{}, fmt.Errorf("unexpected tx height, expected > 0, got: %v", res.Height)
+	}
+	blockHash, err := client.BlockHash(ctx, pack.NewU64(uint64(res.Height)))
+	if err != nil {
+		return confirmation.Inclusion{}, err
+	}
+	return confirmation.Inclusion{
+		Status:    confirmation.TxStatusConfirmed,
+		BlockHash: blockHash,
+		Height:    pack.NewU64(uint64(res.Height)),
+	}, nil
+}
+
+// TxReceipt returns the outcome of the transaction uniquely identified by the
+// given transaction hash. Failed transactions use their log as the revert
+// reason.
+func (client *Client) TxReceipt(ctx context.Context, txHash pack.Bytes) (confirmation.TxReceipt, error) {
+	res, err := cosmTx.QueryTx(client.ctx, hex.EncodeToString(txHash[:]))
+	if err != nil {
+		if isTxNotFound(err, txHash) {
+			return confirmation.TxReceipt{TxHash: txHash, Status: confirmation.TxStatusUnknown}, nil
+		}
+		return confirmation.TxReceipt{}, fmt.Errorf("query fail: %v", err)
+	}
+	if res.Height < 0 {
+		return confirmation.TxReceipt{}, fmt.Errorf("unexpected tx height, expected > 0, got: %v", res.Height)
+	}
+	if res.GasUsed < 0 {
+		return confirmation.TxReceipt{}, fmt.Errorf("unexpected gas used, expected > 0, got: %v", res.GasUsed)
+	}
+	height := pack.NewU64(uint64(res.Height))
+	blockHash, err := client.BlockHash(ctx, height)
+	if err != nil {
+		return confirmation.TxReceipt{}, err
+	}
+	chainHeight, err := client.LatestBlock(ctx)
+	if err != nil {
+		return confirmation.TxReceipt{}, err
+	}
+
+	receipt := confirmation.TxReceipt{
+		TxHash:        txHash,
+		Status:        confirmation.TxStatusConfirmed,
+		BlockHash:     blockHash,
+		Height:        height,
+		Confirmations: confirmation.Confirmations(height, chainHeight),
+		GasUsed:       pack.NewU256FromU64(pack.NewU64(uint64(res.GasUsed))),
+	}
+	if res.Code != 0 {
+		receipt.Status = confirmation.TxStatusFailed
+		receipt.RevertReason = pack.String(res.RawLog)
+	}
+	return receipt, nil
+}
+
 // SubmitTx to the Cosmos based network.
 func (client *Client) SubmitTx(ctx context.Context, tx account.Tx) error {
 	txBytes, err := tx.Serialize()
@@ -182,17 +283,54 @@ func (client *Client) SubmitTx(ctx context.Context, tx account.Tx) error {
 	return nil
 }
 
+// Simulate the execution of the transaction using the Simulate method of the
+// tx service, and return the gas used. Transactions that are rejected by the
+// node (for example, because they have a bad sequence number) return an
+// unsuccessful simulation with the log of the error.
+func (client *Client) Simulate(ctx context.Context, t account.Tx) (confirmation.Simulation, error) {
+	txBytes, err := t.Serialize()
+	if err != nil {
+		return confirmation.Simulation{}, fmt.Errorf("bad \"simulate\": %v", err)
+	}
+
+	res, err := tx.NewServiceClient(client.ctx).Simulate(ctx, &tx.SimulateRequest{TxBytes: txBytes})
+	if err != nil {
+		var abciErr *sdkerrors.Error
+		if errors.As(err, &abciErr) {
+			return confirmation.Simulation{
+				Success:      false,
+				RevertReason: pack.String(err.Error()),
+			}, nil
+		}
+		return confirmation.Simulation{}, fmt.Errorf("failed to simulate tx : %v", err)
+	}
+
+	return confirmation.Simulation{
+		Success: true,
+		GasUsed: pack.NewU256FromUint64(res.GetGasInfo().GetGasUsed()),
+	}, nil
+}
+
 // AccountNonce returns the current nonce of the account. This is the nonce to
 // be used while building a new transaction.
 func (client *Client) AccountNonce(ctx context.Context, addr address.Address) (pack.U256, error) {
+	return client.AccountNonceAt(ctx, addr, contract.LatestBlock())
+}
+
+// AccountNonceAt returns the nonce of the account at the given block.
+func (client *Client) AccountNonceAt(ctx context.Context, addr address.Address, block contract.BlockRef) (pack.U256, error) {
 	cosmosAddr, err := types.AccAddressFromBech32(string(addr))
 	if err != nil {
 		return pack.U256{}, fmt.Errorf("bad address: '%v': %v", addr, err)
 	}
+	queryCtx, err := client.queryContext(block)
+	if err != nil {
+		return pack.U256{}, err
+	}
 
-	acc, err := client.ctx.AccountRetriever.GetAccount(client.ctx, Address(cosmosAddr).AccAddress())
+	acc, err := queryCtx.AccountRetriever.GetAccount(queryCtx, Address(cosmosAddr).AccAddress())
 	if err != nil {
-		return pack.U256{}, fmt.Errorf("failed to get account nonce : '%v': %v", addr, err)
+		return pack.U256{}, fmt.Errorf("failed to get account nonce : '%v' at %v: %v", addr, block, err)
 	}
 
 	return pack.NewU256FromU64(pack.NewU64(acc.GetSequence())), nil
@@ -214,14 +352,23 @@ func (client *Client) AccountNumber(ctx context.Context, addr address.Address) (
 
 // AccountBalance returns the account balancee for a given address.
 func (client *Client) AccountBalance(ctx context.Context, addr address.Address) (pack.U256, error) {
+	return client.AccountBalanceAt(ctx, addr, contract.LatestBlock())
+}
+
+// AccountBalanceAt returns the balance of the account at the given block.
+func (client *Client) AccountBalanceAt(ctx context.Context, addr address.Address, block contract.BlockRef) (pack.U256, error) {
 	cosmosAddr, err := types.AccAddressFromBech32(string(addr))
 	if err != nil {
 		return pack.U256{}, fmt.Errorf("bad address: '%v': %v", addr, err)
 	}
+	queryCtx, err := client.queryContext(block)
+	if err != nil {
+		return pack.U256{}, err
+	}
 
-	balResp, err := bankType.NewQueryClient(client.ctx).Balance(ctx, bankType.NewQueryBalanceRequest(Address(cosmosAddr).AccAddress(), string(client.opts.CoinDenom)))
+	balResp, err := bankType.NewQueryClient(queryCtx).Balance(ctx, bankType.NewQueryBalanceRequest(Address(cosmosAddr).AccAddress(), string(client.opts.CoinDenom)))
 	if err != nil {
-		return pack.U256{}, fmt.Errorf("failed to get account balance : '%v': %v", addr, err)
+		return pack.U256{}, fmt.Errorf("failed to get account balance : '%v' at %v: %v", addr, block, err)
 	}
 	balance := balResp.GetBalance().Amount.BigInt()
 
@@ -233,6 +380,23 @@ func (client *Client) AccountBalance(ctx context.Context, addr address.Address)
 	return pack.NewU256FromInt(balance), nil
 }
 
+// queryContext returns the client context used to query the state at the given
+// block. Tendermint has instant finality, so safe and finalized blocks are the
+// latest block.
+func (client *Client) queryContext(block contract.BlockRef) (cosmClient.Context, error) {
+	switch block.Tag {
+	case contract.BlockTagLatest, contract.BlockTagSafe, contract.BlockTagFinalized:
+		return client.ctx, nil
+	case contract.BlockTagHeight:
+		if block.Height.Uint64() > math.MaxInt64 {
+			return cosmClient.Context{}, fmt.Errorf("bad height: %v", block)
+		}
+		return client.ctx.WithHeight(int64(block.Height.Uint64())), nil
+	default:
+		return cosmClient.Context{}, fmt.Errorf("unsupported block tag %v", block.Tag)
+	}
+}
+
 type transport struct {
 	remote string
 	proxy  http.RoundTripper
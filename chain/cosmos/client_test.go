@@ -0,0 +1,121 @@
+package cosmos_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/multichain/chain/cosmos"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// tendermintNode is a fake Tendermint JSON-RPC node. Every request is answered
+// by the handler for its method, which returns either a result or an error
+// object.
+type tendermintNode map[string]func(params json.RawMessage) (interface{}, map[string]interface{})
+
+func (node tendermintNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	handler, ok := node[req.Method]
+	if !ok {
+		res["error"] = map[string]interface{}{"code": -32601, "message": "Method not found"}
+	} else if result, rpcErr := handler(req.Params); rpcErr != nil {
+		res["error"] = rpcErr
+	} else {
+		res["result"] = result
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+// dialTendermint returns a client that is connected to the fake node, and a
+// function that stops the node.
+func dialTendermint(node tendermintNode) (*cosmos.Client, func()) {
+	server := httptest.NewServer(node)
+	interfaceRegistry := codectypes.NewInterfaceRegistry()
+	cdc := codec.NewProtoCodec(interfaceRegistry)
+	txConfig := authtx.NewTxConfig(cdc, authtx.DefaultSignModes)
+	opts := cosmos.DefaultClientOptions().WithHost(pack.String(server.URL))
+	return cosmos.NewClient(opts, cdc, txConfig, interfaceRegistry, codec.NewLegacyAmino(), "terra"), server.Close
+}
+
+// internalError returns the error object that is returned by Tendermint when a
+// request fails, with the given data.
+func internalError(data string) map[string]interface{} {
+	return map[string]interface{}{"code": -32603, "message": "Internal error", "data": data}
+}
+
+var _ = Describe("Client", func() {
+	ctx := context.Background()
+	txHash := pack.Bytes{0xde, 0xad, 0xbe, 0xef}
+
+	Context("when the transaction is not found", func() {
+		node := tendermintNode{
+			"tx": func(json.RawMessage) (interface{}, map[string]interface{}) {
+				return nil, internalError(fmt.Sprintf("tx (%X) not found", []byte(txHash)))
+			},
+		}
+
+		It("should return the not found error from Tx", func() {
+			client, done := dialTendermint(node)
+			defer done()
+
+			_, _, err := client.Tx(ctx, txHash)
+			Expect(errors.Is(err, confirmation.ErrTxNotFound)).To(BeTrue())
+		})
+
+		It("should return an unknown receipt and inclusion", func() {
+			client, done := dialTendermint(node)
+			defer done()
+
+			receipt, err := client.TxReceipt(ctx, txHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(receipt.Status).To(Equal(confirmation.TxStatusUnknown))
+
+			inclusion, err := client.TxInclusion(ctx, txHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inclusion.Status).To(Equal(confirmation.TxStatusUnknown))
+		})
+	})
+
+	Context("when the node fails for another reason", func() {
+		node := tendermintNode{
+			"tx": func(json.RawMessage) (interface{}, map[string]interface{}) {
+				return nil, internalError("block not found: the node is still syncing")
+			},
+		}
+
+		It("should return the error", func() {
+			client, done := dialTendermint(node)
+			defer done()
+
+			_, _, err := client.Tx(ctx, txHash)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, confirmation.ErrTxNotFound)).To(BeFalse())
+
+			_, err = client.TxReceipt(ctx, txHash)
+			Expect(err).To(HaveOccurred())
+
+			_, err = client.TxInclusion(ctx, txHash)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
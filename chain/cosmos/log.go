@@ -0,0 +1,154 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+	abci "github.com/tendermint/tendermint/abci/types"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// DefaultTxSearchPerPage is the number of transactions requested in each page
+// of a transaction search.
+const DefaultTxSearchPerPage = 100
+
+// ContractAddressAttributes are the keys of the event attributes that are
+// used as the address of a log. CosmWasm uses "_contract_address" in recent
+// versions, and "contract_address" in older versions.
+var ContractAddressAttributes = []string{"_contract_address", "contract_address"}
+
+// ContractAddressQueryKeys are the event keys that are used to search for the
+// transactions of the contracts in a filter. Transactions are only found if
+// they emitted an event with one of these keys for a contract.
+var ContractAddressQueryKeys = []string{"wasm._contract_address", "from_contract.contract_address"}
+
+// An EventAttribute is a key-value pair of an event. The data of a log is the
+// JSON-encoded list of the attributes of its event.
+type EventAttribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// DecodeEventAttributes returns the attributes of the event of the log.
+func DecodeEventAttributes(log contract.Log) ([]EventAttribute, error) {
+	attrs := []EventAttribute{}
+	if err := json.Unmarshal(log.Data, &attrs); err != nil {
+		return nil, fmt.Errorf("decoding attributes: %v", err)
+	}
+	return attrs, nil
+}
+
+// FilterLogs returns the events that match the filter. A to block of zero is
+// the latest block. The only topic of a log is the type of its event (for
+// example, "transfer" or "wasm"), and the address of a log is the contract
+// that emitted it (see ContractAddressAttributes). Events without a contract
+// address have an empty address. The index of a log is the position of its
+// event in its transaction. The events of failed transactions are ignored.
+//
+// If the filter has addresses, only the transactions that emitted an event
+// for one of the contracts are searched (see ContractAddressQueryKeys).
+// Otherwise, every transaction in the block range is searched.
+func (client *Client) FilterLogs(ctx context.Context, filter contract.LogFilter) ([]contract.Log, error) {
+	query := fmt.Sprintf("tx.height>=%v", filter.FromBlock)
+	if filter.ToBlock != 0 {
+		query = fmt.Sprintf("%v AND tx.height<=%v", query, filter.ToBlock)
+	}
+	queries := []string{query}
+	if len(filter.Addresses) > 0 {
+		// Queries cannot match one of several values, so there is one query
+		// for each address and key.
+		queries = make([]string, 0, len(filter.Addresses)*len(ContractAddressQueryKeys))
+		for _, addr := range filter.Addresses {
+			for _, key := range ContractAddressQueryKeys {
+				queries = append(queries, fmt.Sprintf("%v AND %v='%v'", query, key, addr))
+			}
+		}
+	}
+
+	// A transaction can be found by more than one query, so transactions are
+	// collected by hash, and then ordered by their position in the chain.
+	txsByHash := map[string]*coretypes.ResultTx{}
+	for _, query := range queries {
+		if err := client.searchTxs(ctx, query, txsByHash); err != nil {
+			return nil, err
+		}
+	}
+	txs := make([]*coretypes.ResultTx, 0, len(txsByHash))
+	for _, tx := range txsByHash {
+		txs = append(txs, tx)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].Height != txs[j].Height {
+			return txs[i].Height < txs[j].Height
+		}
+		return txs[i].Index < txs[j].Index
+	})
+
+	logs := []contract.Log{}
+	for _, tx := range txs {
+		if tx.TxResult.Code != 0 {
+			continue
+		}
+		if tx.Height < 0 {
+			return nil, fmt.Errorf("unexpected tx height, expected > 0, got: %v", tx.Height)
+		}
+		for i, event := range tx.TxResult.Events {
+			log, err := newEventLog(event)
+			if err != nil {
+				return nil, err
+			}
+			if !filter.Match(log) {
+				continue
+			}
+			log.BlockNumber = pack.NewU64(uint64(tx.Height))
+			log.TxHash = pack.NewBytes(tx.Hash)
+			log.Index = pack.NewU32(uint32(i))
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// searchTxs adds every page of the transactions that match the query to the
+// transactions, by hash.
+func (client *Client) searchTxs(ctx context.Context, query string, txs map[string]*coretypes.ResultTx) error {
+	perPage := DefaultTxSearchPerPage
+	for page, fetched := 1, 0; ; page++ {
+		res, err := client.ctx.Client.TxSearch(ctx, query, false, &page, &perPage, "asc")
+		if err != nil {
+			return fmt.Errorf("searching txs: %v", err)
+		}
+		for _, tx := range res.Txs {
+			txs[string(tx.Hash)] = tx
+		}
+		fetched += len(res.Txs)
+		if len(res.Txs) == 0 || fetched >= res.TotalCount {
+			return nil
+		}
+	}
+}
+
+// newEventLog converts an event into a log, without its position.
+func newEventLog(event abci.Event) (contract.Log, error) {
+	log := contract.Log{Topics: []pack.Bytes{pack.Bytes(event.Type)}}
+	attrs := make([]EventAttribute, len(event.Attributes))
+	for i, attr := range event.Attributes {
+		attrs[i] = EventAttribute{Key: string(attr.Key), Value: string(attr.Value)}
+		for _, key := range ContractAddressAttributes {
+			if attrs[i].Key == key && log.Address == "" {
+				log.Address = address.Address(attrs[i].Value)
+			}
+		}
+	}
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return contract.Log{}, fmt.Errorf("encoding attributes: %v", err)
+	}
+	log.Data = pack.NewBytes(data)
+	return log, nil
+}
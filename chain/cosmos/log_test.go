@@ -0,0 +1,214 @@
+package cosmos_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/multichain/chain/cosmos"
+	"github.com/renproject/pack"
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// event returns an event with the attributes, given as keys and values.
+func event(ty string, attrs ...string) abci.Event {
+	event := abci.Event{Type: ty}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		event.Attributes = append(event.Attributes, abci.EventAttribute{Key: []byte(attrs[i]), Value: []byte(attrs[i+1])})
+	}
+	return event
+}
+
+// txSearchNode returns a fake node that returns each page of transactions in
+// response to tx_search.
+func txSearchNode(pages ...[]*coretypes.ResultTx) tendermintNode {
+	total := 0
+	for _, page := range pages {
+		total += len(page)
+	}
+	return tendermintNode{
+		"tx_search": func(params json.RawMessage) (interface{}, map[string]interface{}) {
+			req := map[string]interface{}{}
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, internalError(err.Error())
+			}
+			page := 0
+			fmt.Sscan(fmt.Sprint(req["page"]), &page)
+			if page < 1 || page > len(pages) {
+				return nil, internalError(fmt.Sprintf("page should be within [1, %v] range, given %v", len(pages), page))
+			}
+			result, err := tmjson.Marshal(coretypes.ResultTxSearch{Txs: pages[page-1], TotalCount: total})
+			if err != nil {
+				return nil, internalError(err.Error())
+			}
+			return json.RawMessage(result), nil
+		},
+	}
+}
+
+// keys returns the keys of the transactions by query.
+func keys(txsByQuery map[string][]*coretypes.ResultTx) []string {
+	queries := make([]string, 0, len(txsByQuery))
+	for query := range txsByQuery {
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+// attributesData returns the data of a log with the attributes, given as keys
+// and values.
+func attributesData(attrs ...string) pack.Bytes {
+	decoded := []cosmos.EventAttribute{}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		decoded = append(decoded, cosmos.EventAttribute{Key: attrs[i], Value: attrs[i+1]})
+	}
+	data, err := json.Marshal(decoded)
+	Expect(err).ToNot(HaveOccurred())
+	return pack.NewBytes(data)
+}
+
+var _ = Describe("Logs", func() {
+	const (
+		contractAddr = "terra14hj2tavq8fpesdwxxcu44rty3hh90vhujrvcmstl4zr3txmfvw9ssrc8au"
+		txHash       = "Log test tx hash"
+	)
+
+	tx := func(height int64, code uint32, events ...abci.Event) *coretypes.ResultTx {
+		return &coretypes.ResultTx{
+			Hash:     []byte(txHash),
+			Height:   height,
+			TxResult: abci.ResponseDeliverTx{Code: code, Events: events},
+		}
+	}
+
+	DescribeTable("when filtering logs",
+		func(filter contract.LogFilter, txs []*coretypes.ResultTx, expected []contract.Log) {
+			client, done := dialTendermint(txSearchNode(txs))
+			defer done()
+
+			logs, err := client.FilterLogs(context.Background(), filter)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logs).To(Equal(expected))
+		},
+		Entry("should use the contract address attribute as the address",
+			contract.LogFilter{FromBlock: 1},
+			[]*coretypes.ResultTx{tx(10, 0, event("wasm", "_contract_address", contractAddr, "action", "transfer"))},
+			[]contract.Log{{
+				Address:     address.Address(contractAddr),
+				Topics:      []pack.Bytes{pack.Bytes("wasm")},
+				Data:        attributesData("_contract_address", contractAddr, "action", "transfer"),
+				BlockNumber: pack.NewU64(10),
+				TxHash:      pack.Bytes(txHash),
+				Index:       pack.NewU32(0),
+			}}),
+		Entry("should use the legacy contract address attribute as the address",
+			contract.LogFilter{FromBlock: 1},
+			[]*coretypes.ResultTx{tx(10, 0, event("from_contract", "contract_address", contractAddr))},
+			[]contract.Log{{
+				Address:     address.Address(contractAddr),
+				Topics:      []pack.Bytes{pack.Bytes("from_contract")},
+				Data:        attributesData("contract_address", contractAddr),
+				BlockNumber: pack.NewU64(10),
+				TxHash:      pack.Bytes(txHash),
+				Index:       pack.NewU32(0),
+			}}),
+		Entry("should use an empty address for events without a contract",
+			contract.LogFilter{FromBlock: 1},
+			[]*coretypes.ResultTx{tx(10, 0, event("transfer", "recipient", "terra1x", "amount", "1uluna"))},
+			[]contract.Log{{
+				Topics:      []pack.Bytes{pack.Bytes("transfer")},
+				Data:        attributesData("recipient", "terra1x", "amount", "1uluna"),
+				BlockNumber: pack.NewU64(10),
+				TxHash:      pack.Bytes(txHash),
+				Index:       pack.NewU32(0),
+			}}),
+		Entry("should match the event type and keep the position of the event",
+			contract.LogFilter{FromBlock: 1, Topics: [][]pack.Bytes{{pack.Bytes("wasm")}}},
+			[]*coretypes.ResultTx{tx(10, 0, event("message", "action", "execute"), event("wasm", "_contract_address", contractAddr))},
+			[]contract.Log{{
+				Address:     address.Address(contractAddr),
+				Topics:      []pack.Bytes{pack.Bytes("wasm")},
+				Data:        attributesData("_contract_address", contractAddr),
+				BlockNumber: pack.NewU64(10),
+				TxHash:      pack.Bytes(txHash),
+				Index:       pack.NewU32(1),
+			}}),
+		Entry("should ignore the events of failed transactions",
+			contract.LogFilter{FromBlock: 1},
+			[]*coretypes.ResultTx{tx(10, 5, event("wasm", "_contract_address", contractAddr))},
+			[]contract.Log{}),
+	)
+
+	Context("when the transactions do not fit in one page", func() {
+		It("should request every page", func() {
+			first := tx(10, 0, event("transfer"))
+			second := tx(11, 0, event("transfer"))
+			second.Hash = []byte("Second log test tx hash")
+			client, done := dialTendermint(txSearchNode([]*coretypes.ResultTx{first}, []*coretypes.ResultTx{second}))
+			defer done()
+
+			logs, err := client.FilterLogs(context.Background(), contract.LogFilter{FromBlock: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logs).To(HaveLen(2))
+			Expect(logs[0].BlockNumber).To(Equal(pack.NewU64(10)))
+			Expect(logs[1].BlockNumber).To(Equal(pack.NewU64(11)))
+		})
+	})
+
+	Context("when filtering the logs of contracts", func() {
+		It("should only search the transactions of the contracts", func() {
+			const otherAddr = "terra1qxxlalvsdjd07p07y3rc5fu6ll8k4tmetpha8n"
+
+			// The first transaction is found by two queries, and the others
+			// are found by one query each.
+			first := tx(10, 0, event("wasm", "_contract_address", contractAddr), event("wasm", "_contract_address", otherAddr))
+			second := tx(10, 0, event("from_contract", "contract_address", otherAddr))
+			second.Hash, second.Index = []byte("Second log test tx hash"), 1
+			third := tx(12, 0, event("wasm", "_contract_address", contractAddr))
+			third.Hash = []byte("Third log test tx hash")
+			txsByQuery := map[string][]*coretypes.ResultTx{
+				"tx.height>=1 AND tx.height<=20 AND wasm._contract_address='" + contractAddr + "'":         {first, third},
+				"tx.height>=1 AND tx.height<=20 AND from_contract.contract_address='" + contractAddr + "'": {},
+				"tx.height>=1 AND tx.height<=20 AND wasm._contract_address='" + otherAddr + "'":            {first},
+				"tx.height>=1 AND tx.height<=20 AND from_contract.contract_address='" + otherAddr + "'":    {second},
+			}
+			queries := []string{}
+			client, done := dialTendermint(tendermintNode{
+				"tx_search": func(params json.RawMessage) (interface{}, map[string]interface{}) {
+					req := struct {
+						Query string `json:"query"`
+					}{}
+					if err := json.Unmarshal(params, &req); err != nil {
+						return nil, internalError(err.Error())
+					}
+					queries = append(queries, req.Query)
+					txs, ok := txsByQuery[req.Query]
+					if !ok {
+						return nil, internalError(fmt.Sprintf("unexpected query %v", req.Query))
+					}
+					result, err := tmjson.Marshal(coretypes.ResultTxSearch{Txs: txs, TotalCount: len(txs)})
+					if err != nil {
+						return nil, internalError(err.Error())
+					}
+					return json.RawMessage(result), nil
+				},
+			})
+			defer done()
+
+			logs, err := client.FilterLogs(context.Background(), contract.LogFilter{FromBlock: 1, ToBlock: 20, Addresses: []address.Address{contractAddr, otherAddr}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queries).To(ConsistOf(keys(txsByQuery)))
+			Expect(logs).To(HaveLen(4))
+			Expect([]pack.Bytes{logs[0].TxHash, logs[1].TxHash, logs[2].TxHash, logs[3].TxHash}).To(Equal([]pack.Bytes{pack.NewBytes(first.Hash), pack.NewBytes(first.Hash), pack.NewBytes(second.Hash), pack.NewBytes(third.Hash)}))
+			Expect([]address.Address{logs[0].Address, logs[1].Address, logs[2].Address, logs[3].Address}).To(Equal([]address.Address{contractAddr, otherAddr, otherAddr, contractAddr}))
+		})
+	})
+})
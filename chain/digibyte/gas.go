@@ -7,3 +7,15 @@ type GasEstimator = bitcoin.GasEstimator
 
 // NewGasEstimator re-exports bitcoin.NewGasEstimator.
 var NewGasEstimator = bitcoin.NewGasEstimator
+
+// P2PKHSizeParams re-exports bitcoin.P2PKHSizeParams.
+var P2PKHSizeParams = bitcoin.P2PKHSizeParams
+
+// P2WPKHSizeParams re-exports bitcoin.P2WPKHSizeParams.
+var P2WPKHSizeParams = bitcoin.P2WPKHSizeParams
+
+// SizeEstimator re-exports bitcoin.SizeEstimator.
+type SizeEstimator = bitcoin.SizeEstimator
+
+// NewSizeEstimator re-exports bitcoin.NewSizeEstimator.
+var NewSizeEstimator = bitcoin.NewSizeEstimator
@@ -7,3 +7,13 @@ type GasEstimator = bitcoin.GasEstimator
 
 // NewGasEstimator re-exports bitcoin.NewGasEstimator.
 var NewGasEstimator = bitcoin.NewGasEstimator
+
+// SizeParams are the sizes of transactions that spend P2PKH outputs. Dogecoin
+// does not support segwit.
+var SizeParams = bitcoin.P2PKHSizeParams
+
+// SizeEstimator re-exports bitcoin.SizeEstimator.
+type SizeEstimator = bitcoin.SizeEstimator
+
+// NewSizeEstimator re-exports bitcoin.NewSizeEstimator.
+var NewSizeEstimator = bitcoin.NewSizeEstimator
@@ -13,5 +13,10 @@ const (
 // Client re-exports evm.Client.
 type Client = evm.Client
 
-// NewClient re-exports evm.NewClient.
-var NewClient = evm.NewClient
+var (
+	// NewClient re-exports evm.NewClient.
+	NewClient = evm.NewClient
+
+	// NewClientFromRPC re-exports evm.NewClientFromRPC.
+	NewClientFromRPC = evm.NewClientFromRPC
+)
@@ -7,5 +7,13 @@ import (
 // Payload re-exports evm.Payload.
 type Payload = evm.Payload
 
-// Encode re-exports evm.Encode.
-var Encode = evm.Encode
+var (
+	// Encode re-exports evm.Encode.
+	Encode = evm.Encode
+
+	// EncodeArgs re-exports evm.EncodeArgs.
+	EncodeArgs = evm.EncodeArgs
+
+	// Decode re-exports evm.Decode.
+	Decode = evm.Decode
+)
@@ -6,6 +6,7 @@ import (
 	"math/big"
 	"sort"
 
+	"github.com/renproject/multichain/chain/evm"
 	"github.com/renproject/pack"
 )
 
@@ -168,3 +169,12 @@ func (gasEstimator *GasEstimator) estimatePriorityFee(ctx context.Context, baseF
 	}
 	return rewards[len(rewards)/2], nil
 }
+
+// DefaultGasLimitMultiplier re-exports evm.DefaultGasLimitMultiplier.
+const DefaultGasLimitMultiplier = evm.DefaultGasLimitMultiplier
+
+// GasLimitOptions re-exports evm.GasLimitOptions.
+type GasLimitOptions = evm.GasLimitOptions
+
+// DefaultGasLimitOptions re-exports evm.DefaultGasLimitOptions.
+var DefaultGasLimitOptions = evm.DefaultGasLimitOptions
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"math/big"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/renproject/id"
 	"github.com/renproject/multichain/api/account"
@@ -19,31 +18,54 @@ import (
 // chain id.
 type TxBuilder struct {
 	ChainID *big.Int
+
+	client          *evm.Client
+	gasLimitOptions *evm.GasLimitOptions
 }
 
 // NewTxBuilder creates a new transaction builder.
 func NewTxBuilder(chainID *big.Int) TxBuilder {
-	return TxBuilder{chainID}
+	return TxBuilder{ChainID: chainID}
+}
+
+// WithGasLimitOptions returns a copy of the transaction builder that uses the
+// client to estimate the gas limit of transactions that are built with a zero
+// gas limit, and attaches their access lists if enabled (see
+// evm.Client.EstimateGasLimit).
+func (txBuilder TxBuilder) WithGasLimitOptions(client *evm.Client, gasLimitOptions evm.GasLimitOptions) TxBuilder {
+	txBuilder.client = client
+	txBuilder.gasLimitOptions = &gasLimitOptions
+	return txBuilder
 }
 
-// BuildTx receives transaction fields and constructs a new transaction.
+// BuildTx receives transaction fields and constructs a new transaction. If
+// the to address is empty, a contract-creation transaction is built, and the
+// payload must be the init code of the contract.
 func (txBuilder TxBuilder) BuildTx(ctx context.Context, fromPubKey *id.PubKey, to address.Address, value, nonce, gas, gasTipCap, gasFeeCap pack.U256, payload pack.Bytes) (account.Tx, error) {
-	toAddr, err := NewAddressFromHex(string(pack.String(to)))
+	toAddr, err := evm.NewRecipient(to)
 	if err != nil {
-		return nil, fmt.Errorf("bad to address '%v': %v", to, err)
+		return nil, err
+	}
+	var accessList types.AccessList
+	if txBuilder.gasLimitOptions != nil && gas.Int().Sign() == 0 {
+		if txBuilder.client == nil {
+			return nil, fmt.Errorf("estimating gas limit: nil client")
+		}
+		gas, accessList, err = txBuilder.client.EstimateGasLimit(ctx, fromPubKey, to, value, payload, *txBuilder.gasLimitOptions)
+		if err != nil {
+			return nil, err
+		}
 	}
-	addr := common.Address(toAddr)
-	return &evm.Tx{
-		EthTx: types.NewTx(&types.DynamicFeeTx{
-			ChainID:   txBuilder.ChainID,
-			Nonce:     nonce.Int().Uint64(),
-			GasTipCap: gasTipCap.Int(),
-			GasFeeCap: gasFeeCap.Int(),
-			Gas:       gas.Int().Uint64(),
-			To:        &addr,
-			Value:     value.Int(),
-			Data:      payload,
-		}),
-		Signer: types.LatestSignerForChainID(txBuilder.ChainID),
-	}, nil
+	ethTx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:    txBuilder.ChainID,
+		Nonce:      nonce.Int().Uint64(),
+		GasTipCap:  gasTipCap.Int(),
+		GasFeeCap:  gasFeeCap.Int(),
+		Gas:        gas.Int().Uint64(),
+		To:         toAddr,
+		Value:      value.Int(),
+		Data:       payload,
+		AccessList: accessList,
+	})
+	return evm.NewTx(ethTx, types.LatestSignerForChainID(txBuilder.ChainID), fromPubKey), nil
 }
@@ -6,11 +6,15 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/renproject/multichain/api/account"
 	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
 	"github.com/renproject/multichain/api/contract"
 	"github.com/renproject/pack"
 )
@@ -21,28 +25,44 @@ const (
 	DefaultClientRPCURL = "http://127.0.0.1:8545/"
 )
 
-// Client holds the underlying RPC client instance.
+// Client holds the underlying RPC client instance. The raw RPC client is only
+// used for methods that are not exposed by the Ethereum client (for example,
+// eth_createAccessList), and is nil unless the Client is created by NewClient.
 type Client struct {
 	EthClient *ethclient.Client
 	ChainID   *big.Int
+
+	rpcClient *rpc.Client
 }
 
 // NewClient creates and returns a new JSON-RPC client to the Ethereum node
 func NewClient(rpcURL string, chainID *big.Int) (*Client, error) {
-	client, err := ethclient.Dial(rpcURL)
+	rpcClient, err := rpc.Dial(rpcURL)
 	if err != nil {
 		return nil, fmt.Errorf("dialing url: %v", rpcURL)
 	}
+	client := ethclient.NewClient(rpcClient)
 	clientChainID, err := client.ChainID(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("mismatched chain id: expected %v, got %v", chainID, clientChainID)
 	}
 	return &Client{
-		client,
-		chainID,
+		EthClient: client,
+		ChainID:   chainID,
+		rpcClient: rpcClient,
 	}, nil
 }
 
+// NewClientFromRPC returns a Client that uses an existing RPC client. Unlike
+// NewClient, it does not check the chain id of the node.
+func NewClientFromRPC(rpcClient *rpc.Client, chainID *big.Int) *Client {
+	return &Client{
+		EthClient: ethclient.NewClient(rpcClient),
+		ChainID:   chainID,
+		rpcClient: rpcClient,
+	}
+}
+
 // LatestBlock returns the block number at the current chain head.
 func (client *Client) LatestBlock(ctx context.Context) (pack.U64, error) {
 	header, err := client.EthClient.HeaderByNumber(ctx, nil)
@@ -57,6 +77,9 @@ func (client *Client) LatestBlock(ctx context.Context) (pack.U64, error) {
 func (client *Client) Tx(ctx context.Context, txID pack.Bytes) (account.Tx, pack.U64, error) {
 	tx, pending, err := client.EthClient.TransactionByHash(ctx, common.BytesToHash(txID))
 	if err != nil {
+		if err == ethereum.NotFound {
+			return nil, pack.NewU64(0), fmt.Errorf("fetching tx by hash '%v': %w", txID, confirmation.ErrTxNotFound)
+		}
 		return nil, pack.NewU64(0), fmt.Errorf(fmt.Sprintf("fetching tx by hash '%v': %v", txID, err))
 	}
 
@@ -80,7 +103,7 @@ func (client *Client) Tx(ctx context.Context, txID pack.Bytes) (account.Tx, pack
 	}
 	if pending {
 		// Transaction has not been included in a block yet.
-		return nil, 0, fmt.Errorf("tx %v is pending", txID)
+		return nil, 0, fmt.Errorf("tx %v: %w", txID, confirmation.ErrTxPending)
 	}
 
 	receipt, err := client.EthClient.TransactionReceipt(ctx, common.BytesToHash(txID))
@@ -95,13 +118,13 @@ func (client *Client) Tx(ctx context.Context, txID pack.Bytes) (account.Tx, pack
 
 	if receipt.Status == 0 {
 		// Transaction has been reverted.
-		return nil, pack.NewU64(0), fmt.Errorf("tx %v reverted, reciept status 0", txID)
+		return nil, pack.NewU64(0), fmt.Errorf("tx %v reverted, reciept status 0: %w", txID, confirmation.ErrTxFailed)
 	}
 
 	// Transaction has been confirmed.
 	confirmedTx := Tx{
-		tx,
-		types.LatestSignerForChainID(client.ChainID),
+		EthTx:  tx,
+		Signer: types.LatestSignerForChainID(client.ChainID),
 	}
 
 	header, err := client.EthClient.HeaderByNumber(ctx, nil)
@@ -112,6 +135,125 @@ func (client *Client) Tx(ctx context.Context, txID pack.Bytes) (account.Tx, pack
 	return &confirmedTx, pack.NewU64(header.Number.Uint64() - receipt.BlockNumber.Uint64()), nil
 }
 
+// BlockHash returns the hash of the block at the given height in the longest
+// blockchain.
+func (client *Client) BlockHash(ctx context.Context, height pack.U64) (pack.Bytes, error) {
+	header, err := client.EthClient.HeaderByNumber(ctx, new(big.Int).SetUint64(height.Uint64()))
+	if err != nil {
+		return nil, fmt.Errorf("fetching header %v: %v", height, err)
+	}
+	return pack.NewBytes(header.Hash().Bytes()), nil
+}
+
+// TxInclusion returns the block in which the transaction with the given hash
+// was included.
+func (client *Client) TxInclusion(ctx context.Context, txID pack.Bytes) (confirmation.Inclusion, error) {
+	_, pending, err := client.EthClient.TransactionByHash(ctx, common.BytesToHash(txID))
+	if err != nil {
+		if err == ethereum.NotFound {
+			return confirmation.Inclusion{Status: confirmation.TxStatusUnknown}, nil
+		}
+		return confirmation.Inclusion{}, fmt.Errorf("fetching tx by hash '%v': %v", txID, err)
+	}
+	if pending {
+		return confirmation.Inclusion{Status: confirmation.TxStatusPending}, nil
+	}
+
+	receipt, err := client.EthClient.TransactionReceipt(ctx, common.BytesToHash(txID))
+	if err != nil {
+		if err == ethereum.NotFound {
+			return confirmation.Inclusion{Status: confirmation.TxStatusPending}, nil
+		}
+		return confirmation.Inclusion{}, fmt.Errorf("fetching recipt for tx %v : %v", txID, err)
+	}
+	return confirmation.Inclusion{
+		Status:    confirmation.TxStatusConfirmed,
+		BlockHash: pack.NewBytes(receipt.BlockHash.Bytes()),
+		Height:    pack.NewU64(receipt.BlockNumber.Uint64()),
+	}, nil
+}
+
+// TxReceipt returns the outcome of the transaction uniquely identified by the
+// given transaction hash. The revert reason of a failed transaction is found
+// by replaying the transaction at the block in which it was included.
+func (client *Client) TxReceipt(ctx context.Context, txID pack.Bytes) (confirmation.TxReceipt, error) {
+	tx, pending, err := client.EthClient.TransactionByHash(ctx, common.BytesToHash(txID))
+	if err != nil {
+		if err == ethereum.NotFound {
+			return confirmation.TxReceipt{TxHash: txID, Status: confirmation.TxStatusUnknown}, nil
+		}
+		return confirmation.TxReceipt{}, fmt.Errorf("fetching tx by hash '%v': %v", txID, err)
+	}
+	if pending {
+		return confirmation.TxReceipt{TxHash: txID, Status: confirmation.TxStatusPending}, nil
+	}
+
+	receipt, err := client.EthClient.TransactionReceipt(ctx, common.BytesToHash(txID))
+	if err != nil {
+		if err == ethereum.NotFound {
+			return confirmation.TxReceipt{TxHash: txID, Status: confirmation.TxStatusPending}, nil
+		}
+		return confirmation.TxReceipt{}, fmt.Errorf("fetching recipt for tx %v : %v", txID, err)
+	}
+	header, err := client.EthClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return confirmation.TxReceipt{}, fmt.Errorf("fetching header : %v", err)
+	}
+
+	height := pack.NewU64(receipt.BlockNumber.Uint64())
+	txReceipt := confirmation.TxReceipt{
+		TxHash:        txID,
+		Status:        confirmation.TxStatusConfirmed,
+		BlockHash:     pack.NewBytes(receipt.BlockHash.Bytes()),
+		Height:        height,
+		Confirmations: confirmation.Confirmations(height, pack.NewU64(header.Number.Uint64())),
+		GasUsed:       pack.NewU256FromU64(pack.NewU64(receipt.GasUsed)),
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		txReceipt.Status = confirmation.TxStatusFailed
+		txReceipt.RevertReason = client.revertReason(ctx, tx, receipt.BlockNumber)
+	} else if tx.To() == nil {
+		txReceipt.ContractAddress = address.Address(receipt.ContractAddress.Hex())
+	}
+	return txReceipt, nil
+}
+
+// revertReason replays the transaction at the given block, and returns the
+// reason for which it reverted. If the reason cannot be decoded, the error
+// returned by the node is used instead.
+func (client *Client) revertReason(ctx context.Context, tx *types.Transaction, blockNumber *big.Int) pack.String {
+	from, err := types.Sender(types.LatestSignerForChainID(client.ChainID), tx)
+	if err != nil {
+		return pack.String("")
+	}
+	callMsg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+	_, err = client.EthClient.CallContract(ctx, callMsg, blockNumber)
+	if err == nil {
+		return pack.String("")
+	}
+	return decodeRevertReason(err)
+}
+
+// decodeRevertReason returns the reason for which a call reverted, using the
+// error returned by the node. If the reason cannot be decoded, the error is
+// used instead.
+func decodeRevertReason(err error) pack.String {
+	if dataErr, ok := err.(rpc.DataError); ok {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if reason, err := abi.UnpackRevert(common.FromHex(data)); err == nil {
+				return pack.String(reason)
+			}
+		}
+	}
+	return pack.String(err.Error())
+}
+
 // SubmitTx to the underlying blockchain network.
 func (client *Client) SubmitTx(ctx context.Context, tx account.Tx) error {
 	switch tx := tx.(type) {
@@ -129,13 +271,29 @@ func (client *Client) SubmitTx(ctx context.Context, tx account.Tx) error {
 // AccountNonce returns the current nonce of the account. This is the nonce to
 // be used while building a new transaction.
 func (client *Client) AccountNonce(ctx context.Context, addr address.Address) (pack.U256, error) {
+	return client.AccountNonceAt(ctx, addr, contract.LatestBlock())
+}
+
+// AccountNonceAt returns the nonce of the account at the given block.
+func (client *Client) AccountNonceAt(ctx context.Context, addr address.Address, block contract.BlockRef) (pack.U256, error) {
 	targetAddr, err := NewAddressFromHex(string(pack.String(addr)))
 	if err != nil {
 		return pack.U256{}, fmt.Errorf("bad to address '%v': %v", addr, err)
 	}
-	nonce, err := client.EthClient.NonceAt(ctx, common.Address(targetAddr), nil)
+	if isBlockTagged(block) {
+		var nonce hexutil.Uint64
+		if err := client.callAtBlock(ctx, &nonce, "eth_getTransactionCount", block, common.Address(targetAddr)); err != nil {
+			return pack.U256{}, fmt.Errorf("failed to get nonce for '%v' at %v: %v", addr, block, err)
+		}
+		return pack.NewU256FromU64(pack.NewU64(uint64(nonce))), nil
+	}
+	blockNumber, err := blockNumberArg(block)
+	if err != nil {
+		return pack.U256{}, err
+	}
+	nonce, err := client.EthClient.NonceAt(ctx, common.Address(targetAddr), blockNumber)
 	if err != nil {
-		return pack.U256{}, fmt.Errorf("failed to get nonce for '%v': %v", addr, err)
+		return pack.U256{}, fmt.Errorf("failed to get nonce for '%v' at %v: %v", addr, block, err)
 	}
 
 	return pack.NewU256FromU64(pack.NewU64(nonce)), nil
@@ -143,13 +301,29 @@ func (client *Client) AccountNonce(ctx context.Context, addr address.Address) (p
 
 // AccountBalance returns the account balancee for a given address.
 func (client *Client) AccountBalance(ctx context.Context, addr address.Address) (pack.U256, error) {
+	return client.AccountBalanceAt(ctx, addr, contract.LatestBlock())
+}
+
+// AccountBalanceAt returns the balance of the account at the given block.
+func (client *Client) AccountBalanceAt(ctx context.Context, addr address.Address, block contract.BlockRef) (pack.U256, error) {
 	targetAddr, err := NewAddressFromHex(string(pack.String(addr)))
 	if err != nil {
 		return pack.U256{}, fmt.Errorf("bad to address '%v': %v", addr, err)
 	}
-	balance, err := client.EthClient.BalanceAt(ctx, common.Address(targetAddr), nil)
+	if isBlockTagged(block) {
+		var balance hexutil.Big
+		if err := client.callAtBlock(ctx, &balance, "eth_getBalance", block, common.Address(targetAddr)); err != nil {
+			return pack.U256{}, fmt.Errorf("failed to get balance for '%v' at %v: %v", addr, block, err)
+		}
+		return pack.NewU256FromInt(balance.ToInt()), nil
+	}
+	blockNumber, err := blockNumberArg(block)
+	if err != nil {
+		return pack.U256{}, err
+	}
+	balance, err := client.EthClient.BalanceAt(ctx, common.Address(targetAddr), blockNumber)
 	if err != nil {
-		return pack.U256{}, fmt.Errorf("failed to get balance for '%v': %v", addr, err)
+		return pack.U256{}, fmt.Errorf("failed to get balance for '%v' at %v: %v", addr, block, err)
 	}
 
 	return pack.NewU256FromInt(balance), nil
@@ -157,15 +331,92 @@ func (client *Client) AccountBalance(ctx context.Context, addr address.Address)
 
 // CallContract implements the multichain Contract API.
 func (client *Client) CallContract(ctx context.Context, program address.Address, calldata contract.CallData) (pack.Bytes, error) {
+	return client.CallContractAt(ctx, program, calldata, contract.LatestBlock())
+}
+
+// CallContractAt calls the contract using the state at the given block.
+func (client *Client) CallContractAt(ctx context.Context, program address.Address, calldata contract.CallData, block contract.BlockRef) (pack.Bytes, error) {
 	targetAddr, err := NewAddressFromHex(string(pack.String(program)))
 	if err != nil {
 		return nil, fmt.Errorf("bad to address '%v': %v", program, err)
 	}
 	addr := common.Address(targetAddr)
+	if isBlockTagged(block) {
+		var result hexutil.Bytes
+		callArg := map[string]interface{}{
+			"to":   addr,
+			"data": hexutil.Bytes(calldata),
+		}
+		if err := client.callAtBlock(ctx, &result, "eth_call", block, callArg); err != nil {
+			return nil, err
+		}
+		return pack.Bytes(result), nil
+	}
+	blockNumber, err := blockNumberArg(block)
+	if err != nil {
+		return nil, err
+	}
 
 	callMsg := ethereum.CallMsg{
 		To:   &addr,
 		Data: calldata,
 	}
-	return client.EthClient.CallContract(ctx, callMsg, nil)
+	return client.EthClient.CallContract(ctx, callMsg, blockNumber)
+}
+
+// callAtBlock calls the JSON-RPC method with the block appended to its
+// arguments. The Ethereum client encodes every block as a number, so the safe
+// and finalized blocks are requested through the raw RPC client instead.
+func (client *Client) callAtBlock(ctx context.Context, result interface{}, method string, block contract.BlockRef, args ...interface{}) error {
+	if client.rpcClient == nil {
+		return fmt.Errorf("reading at the %v block requires an rpc client", block)
+	}
+	blockArg, err := blockNumberString(block)
+	if err != nil {
+		return err
+	}
+	return client.rpcClient.CallContext(ctx, result, method, append(args, blockArg)...)
+}
+
+// isBlockTagged returns true if the block can only be passed to the node as a
+// tag.
+func isBlockTagged(block contract.BlockRef) bool {
+	return block.Tag == contract.BlockTagSafe || block.Tag == contract.BlockTagFinalized
+}
+
+// blockNumberArg returns the block number that is passed to the Ethereum
+// client for the block. The latest block is nil, and the safe and finalized
+// blocks use the negative numbers that go-ethereum reserves for these tags.
+func blockNumberArg(block contract.BlockRef) (*big.Int, error) {
+	switch block.Tag {
+	case contract.BlockTagLatest:
+		return nil, nil
+	case contract.BlockTagSafe:
+		return big.NewInt(int64(rpc.SafeBlockNumber)), nil
+	case contract.BlockTagFinalized:
+		return big.NewInt(int64(rpc.FinalizedBlockNumber)), nil
+	case contract.BlockTagHeight:
+		return new(big.Int).SetUint64(block.Height.Uint64()), nil
+	default:
+		return nil, fmt.Errorf("unsupported block tag %v", block.Tag)
+	}
+}
+
+// blockNumberString returns the block parameter of a JSON-RPC request for the
+// block.
+func blockNumberString(block contract.BlockRef) (string, error) {
+	blockNumber, err := blockNumberArg(block)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case blockNumber == nil:
+		return "latest", nil
+	case blockNumber.Int64() == int64(rpc.SafeBlockNumber):
+		return "safe", nil
+	case blockNumber.Int64() == int64(rpc.FinalizedBlockNumber):
+		return "finalized", nil
+	default:
+		return hexutil.EncodeBig(blockNumber), nil
+	}
 }
@@ -0,0 +1,59 @@
+package evm_test
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/multichain/chain/evm/evmtest"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	ctx := context.Background()
+	addr := address.Address("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+
+	// block returns the block argument of the last request for the method.
+	block := func(node *evmtest.Node, method string) string {
+		reqs := node.Requests(method)
+		Expect(reqs).ToNot(BeEmpty())
+		Expect(reqs[len(reqs)-1].Params).To(HaveLen(2))
+		var block string
+		Expect(json.Unmarshal(reqs[len(reqs)-1].Params[1], &block)).To(Succeed())
+		return block
+	}
+
+	Context("when reading state at a block", func() {
+		It("should pass the block to the node", func() {
+			node := evmtest.NewNode().
+				Result("eth_getBalance", "0x3e8").
+				Result("eth_getTransactionCount", "0x7").
+				Result("eth_call", "0x01")
+			client, closeServer := dial(node)
+			defer closeServer()
+
+			balance, err := client.AccountBalance(ctx, addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance).To(Equal(pack.NewU256FromUint64(1000)))
+			Expect(block(node, "eth_getBalance")).To(Equal("latest"))
+
+			_, err = client.AccountBalanceAt(ctx, addr, contract.BlockAtHeight(100))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(block(node, "eth_getBalance")).To(Equal("0x64"))
+
+			nonce, err := client.AccountNonceAt(ctx, addr, contract.FinalizedBlock())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nonce).To(Equal(pack.NewU256FromUint64(7)))
+			Expect(block(node, "eth_getTransactionCount")).To(Equal("finalized"))
+
+			result, err := client.CallContractAt(ctx, addr, nil, contract.SafeBlock())
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(result)).To(Equal([]byte{1}))
+			Expect(block(node, "eth_call")).To(Equal("safe"))
+		})
+	})
+})
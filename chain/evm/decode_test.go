@@ -0,0 +1,149 @@
+package evm_test
+
+import (
+	"math/big"
+	"testing/quick"
+
+	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// position is a tuple that is nested in another tuple.
+type position struct {
+	X int32
+	Y int32
+}
+
+// order is a tuple with fields of different types. Unexported fields are
+// ignored.
+type order struct {
+	Owner    evm.Address
+	Amount   pack.U256
+	Filled   bool
+	Memo     string
+	Tags     [2]pack.Bytes32
+	Position position
+	Levels   []uint16
+
+	ignored uint8
+}
+
+var _ = Describe("Decoding", func() {
+	Context("when decoding values that were encoded", func() {
+		It("should return the same values", func() {
+			f := func(u256 [32]byte, u64 uint64, i64 int64, i8 int8, b bool, s string, bs []byte, b4 [4]byte, addr [20]byte) bool {
+				encoded, err := evm.EncodeArgs(pack.NewU256(u256), pack.NewU64(u64), i64, i8, b, pack.String(s), pack.Bytes(bs), b4, evm.Address(addr))
+				Expect(err).ToNot(HaveOccurred())
+
+				var (
+					decodedU256 pack.U256
+					decodedU64  pack.U64
+					decodedI64  int64
+					decodedI8   int8
+					decodedB    bool
+					decodedS    pack.String
+					decodedBs   pack.Bytes
+					decodedB4   [4]byte
+					decodedAddr evm.Address
+				)
+				err = evm.Decode(encoded, &decodedU256, &decodedU64, &decodedI64, &decodedI8, &decodedB, &decodedS, &decodedBs, &decodedB4, &decodedAddr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decodedU256.String()).To(Equal(pack.NewU256(u256).String()))
+				Expect(decodedU64).To(Equal(pack.NewU64(u64)))
+				Expect(decodedI64).To(Equal(i64))
+				Expect(decodedI8).To(Equal(i8))
+				Expect(decodedB).To(Equal(b))
+				Expect(decodedS).To(Equal(pack.String(s)))
+				Expect([]byte(decodedBs)).To(Equal(append([]byte{}, bs...)))
+				Expect(decodedB4).To(Equal(b4))
+				Expect(decodedAddr).To(Equal(evm.Address(addr)))
+				return true
+			}
+
+			err := quick.Check(f, nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should return the same nested tuples and arrays", func() {
+			in := []order{{
+				Owner:    evm.Address{1},
+				Amount:   pack.NewU256FromUint64(1000),
+				Filled:   true,
+				Memo:     "memo",
+				Tags:     [2]pack.Bytes32{{1}, {2}},
+				Position: position{X: -1, Y: 1},
+				Levels:   []uint16{1, 2, 3},
+			}, {
+				Owner:  evm.Address{2},
+				Amount: pack.NewU256FromUint64(0),
+				Levels: []uint16{},
+			}}
+			encoded, err := evm.EncodeArgs(in)
+			Expect(err).ToNot(HaveOccurred())
+
+			var out []order
+			Expect(evm.Decode(encoded, &out)).To(Succeed())
+			Expect(out).To(HaveLen(len(in)))
+			for i := range out {
+				// Integers are compared by value, because the same value can
+				// have different internal representations.
+				Expect(out[i].Amount.String()).To(Equal(in[i].Amount.String()))
+				out[i].Amount, in[i].Amount = pack.U256{}, pack.U256{}
+			}
+			Expect(out).To(Equal(in))
+		})
+
+		It("should decode signed integers into big integers", func() {
+			encoded, err := evm.EncodeArgs(big.NewInt(-100))
+			Expect(err).ToNot(HaveOccurred())
+
+			var out *big.Int
+			Expect(evm.Decode(encoded, &out)).To(Succeed())
+			Expect(out.Cmp(big.NewInt(-100))).To(Equal(0))
+		})
+	})
+
+	Context("when decoding malformed data", func() {
+		It("should return an error", func() {
+			f := func(data []byte) bool {
+				var s pack.String
+				var levels []uint16
+				var tags [3]pack.Bytes32
+				err := evm.Decode(data, &s, &levels, &tags)
+				if len(data) < 32*5 {
+					Expect(err).To(HaveOccurred())
+				}
+				return true
+			}
+
+			err := quick.Check(f, nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should return an error when an integer overflows", func() {
+			encoded, err := evm.EncodeArgs(pack.NewU256FromUint64(256))
+			Expect(err).ToNot(HaveOccurred())
+
+			var out pack.U8
+			Expect(evm.Decode(encoded, &out)).ToNot(Succeed())
+		})
+	})
+
+	Context("when decoding into values that are not pointers", func() {
+		It("should return an error", func() {
+			Expect(evm.Decode(make([]byte, 32), pack.NewU64(0))).ToNot(Succeed())
+		})
+	})
+
+	Context("when encoding an unsupported type", func() {
+		It("should return an error", func() {
+			_, err := evm.EncodeArgs(map[string]int{})
+			Expect(err).To(HaveOccurred())
+			_, err = evm.EncodeArgs(struct{ unexported int }{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
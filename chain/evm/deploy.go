@@ -0,0 +1,50 @@
+package evm
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/pack"
+)
+
+// DeployCode returns the init code that deploys a contract: the creation
+// bytecode of the contract, followed by the ABI encoding of the constructor
+// arguments. It is used as the payload of a contract-creation transaction,
+// which is built by passing an empty to address to the TxBuilder.
+func DeployCode(bytecode pack.Bytes, args ...interface{}) (pack.Bytes, error) {
+	encoded, err := EncodeArgs(args...)
+	if err != nil {
+		return nil, fmt.Errorf("encoding constructor arguments: %v", err)
+	}
+	initCode := make(pack.Bytes, 0, len(bytecode)+len(encoded))
+	initCode = append(initCode, bytecode...)
+	return append(initCode, encoded...), nil
+}
+
+// CreateAddress returns the address of the contract that is deployed by a
+// contract-creation transaction from the deployer, with the given nonce. This
+// is also the address of a contract that is deployed by a contract using the
+// CREATE opcode, where the nonce is the nonce of the deploying contract.
+func CreateAddress(deployer address.Address, nonce pack.U256) (address.Address, error) {
+	deployerAddr, err := NewAddressFromHex(string(pack.String(deployer)))
+	if err != nil {
+		return address.Address(""), fmt.Errorf("bad deployer address '%v': %v", deployer, err)
+	}
+	if !nonce.Int().IsUint64() {
+		return address.Address(""), fmt.Errorf("bad nonce %v: expected 64-bit nonce", nonce)
+	}
+	return address.Address(crypto.CreateAddress(common.Address(deployerAddr), nonce.Int().Uint64()).Hex()), nil
+}
+
+// Create2Address returns the address of the contract that is deployed by the
+// deployer using the CREATE2 opcode, with the given salt and init code. Unlike
+// CreateAddress, the address does not depend on the nonce of the deployer.
+func Create2Address(deployer address.Address, salt pack.Bytes32, initCode pack.Bytes) (address.Address, error) {
+	deployerAddr, err := NewAddressFromHex(string(pack.String(deployer)))
+	if err != nil {
+		return address.Address(""), fmt.Errorf("bad deployer address '%v': %v", deployer, err)
+	}
+	return address.Address(crypto.CreateAddress2(common.Address(deployerAddr), salt, crypto.Keccak256(initCode)).Hex()), nil
+}
@@ -0,0 +1,74 @@
+package evm_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Deployment", func() {
+	ctx := context.Background()
+
+	Context("when predicting contract addresses", func() {
+		It("should return the CREATE address", func() {
+			deployer := address.Address("0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0")
+			addr, err := evm.CreateAddress(deployer, pack.NewU256FromUint64(0))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strings.ToLower(string(addr))).To(Equal("0xcd234a471b72ba2f1ccf0a70fcaba648a5eecd8d"))
+
+			addr, err = evm.CreateAddress(deployer, pack.NewU256FromUint64(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strings.ToLower(string(addr))).To(Equal("0x343c43a37d37dff08ae8c4a11544c718abb4fcf8"))
+		})
+
+		It("should return the CREATE2 address", func() {
+			addr, err := evm.Create2Address(address.Address("0x0000000000000000000000000000000000000000"), pack.Bytes32{}, pack.Bytes{0x00})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strings.ToLower(string(addr))).To(Equal("0x4d1a2e2bb4f88f0250f26ffff098b0b30b26bf38"))
+
+			addr, err = evm.Create2Address(address.Address("0xdeadbeef00000000000000000000000000000000"), pack.Bytes32{}, pack.Bytes{0x00})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strings.ToLower(string(addr))).To(Equal("0xb928f69bb1d91cd65274e3c79d8986362984fda3"))
+		})
+	})
+
+	Context("when building contract-creation transactions", func() {
+		It("should build, sign and predict the deployed address", func() {
+			key, err := crypto.GenerateKey()
+			Expect(err).ToNot(HaveOccurred())
+			from := address.Address(crypto.PubkeyToAddress(key.PublicKey).Hex())
+
+			initCode, err := evm.DeployCode(pack.Bytes{0x60, 0x80}, pack.NewU256FromUint64(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(initCode).To(HaveLen(2 + 32))
+
+			txBuilder := evm.NewTxBuilder(big.NewInt(1))
+			tx, err := txBuilder.BuildTx(ctx, nil, address.Address(""), pack.NewU256FromUint64(0), pack.NewU256FromUint64(3), pack.NewU256FromUint64(100000), pack.NewU256FromUint64(1), pack.NewU256FromUint64(1), initCode)
+			Expect(err).ToNot(HaveOccurred())
+			ethTx := tx.(*evm.Tx)
+			Expect(ethTx.IsContractCreation()).To(BeTrue())
+			Expect(tx.To()).To(Equal(address.Address("")))
+			Expect(ethTx.ContractAddress()).To(Equal(address.Address("")))
+
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			sig, err := crypto.Sign(sighashes[0][:], key)
+			Expect(err).ToNot(HaveOccurred())
+			var signature pack.Bytes65
+			copy(signature[:], sig)
+			Expect(tx.Sign([]pack.Bytes65{signature}, nil)).To(Succeed())
+
+			expected, err := evm.CreateAddress(from, pack.NewU256FromUint64(3))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ethTx.ContractAddress()).To(Equal(expected))
+		})
+	})
+})
@@ -0,0 +1,268 @@
+// Package eip712 implements hashing and signing of EIP-712 typed data. Typed
+// data is signed using the same Sighashes/Sign pattern as transactions, so
+// that signatures can be produced externally (for example, by a threshold
+// signer) and injected into the message. See
+// https://eips.ethereum.org/EIPS/eip-712 for more information.
+package eip712
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/pack"
+)
+
+// DomainType is the name of the type of the domain.
+const DomainType = "EIP712Domain"
+
+type (
+	// TypedData is the typed data that is signed. It is the same as the JSON
+	// object used by eth_signTypedData_v4.
+	TypedData = apitypes.TypedData
+
+	// Types maps the names of types to their fields.
+	Types = apitypes.Types
+
+	// Type is a field of a type.
+	Type = apitypes.Type
+
+	// Domain is the domain of the typed data.
+	Domain = apitypes.TypedDataDomain
+)
+
+// Parse typed data from the JSON object used by eth_signTypedData_v4.
+func Parse(data []byte) (TypedData, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return TypedData{}, fmt.Errorf("decoding typed data: %v", err)
+	}
+	if domain, ok := fields["domain"]; ok {
+		normalized, err := normalizeDomain(domain)
+		if err != nil {
+			return TypedData{}, fmt.Errorf("decoding domain: %v", err)
+		}
+		fields["domain"] = normalized
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return TypedData{}, fmt.Errorf("encoding typed data: %v", err)
+	}
+	typedData := TypedData{}
+	if err := json.Unmarshal(data, &typedData); err != nil {
+		return TypedData{}, fmt.Errorf("decoding typed data: %v", err)
+	}
+	return typedData, nil
+}
+
+// ParseParts parses typed data from its parts: the JSON-encoded types, the
+// name of the primary type, the JSON-encoded domain, and the JSON-encoded
+// message. The types must include the domain type.
+func ParseParts(types []byte, primaryType string, domain []byte, message []byte) (TypedData, error) {
+	typedData := TypedData{PrimaryType: primaryType}
+	if err := json.Unmarshal(types, &typedData.Types); err != nil {
+		return TypedData{}, fmt.Errorf("decoding types: %v", err)
+	}
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return TypedData{}, fmt.Errorf("decoding domain: %v", err)
+	}
+	if err := json.Unmarshal(domain, &typedData.Domain); err != nil {
+		return TypedData{}, fmt.Errorf("decoding domain: %v", err)
+	}
+	if err := json.Unmarshal(message, &typedData.Message); err != nil {
+		return TypedData{}, fmt.Errorf("decoding message: %v", err)
+	}
+	return typedData, nil
+}
+
+// normalizeDomain rewrites a numeric chain ID in the JSON-encoded domain as a
+// string. Wallets usually send the chain ID as a number, but the domain only
+// decodes it from a decimal or hex string.
+func normalizeDomain(domain []byte) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(domain, &fields); err != nil {
+		return nil, err
+	}
+	chainID, ok := fields["chainId"]
+	if !ok {
+		return domain, nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(chainID, &number); err != nil {
+		// The chain ID is not a number, so it is decoded as it is.
+		return domain, nil
+	}
+	normalized, err := json.Marshal(number.String())
+	if err != nil {
+		return nil, err
+	}
+	fields["chainId"] = normalized
+	return json.Marshal(fields)
+}
+
+// DomainSeparator returns the hash of the domain of the typed data.
+func DomainSeparator(typedData TypedData) (pack.Bytes32, error) {
+	if _, ok := typedData.Types[DomainType]; !ok {
+		return pack.Bytes32{}, fmt.Errorf("bad types: expected %v", DomainType)
+	}
+	hash, err := typedData.HashStruct(DomainType, typedData.Domain.Map())
+	if err != nil {
+		return pack.Bytes32{}, fmt.Errorf("hashing domain: %v", err)
+	}
+	return pack.NewBytes32(common.BytesToHash(hash)), nil
+}
+
+// Digest returns the digest of the typed data that must be signed. This is
+// the hash of the domain separator and the hash of the message.
+func Digest(typedData TypedData) (pack.Bytes32, error) {
+	domainSeparator, err := DomainSeparator(typedData)
+	if err != nil {
+		return pack.Bytes32{}, err
+	}
+	if _, ok := typedData.Types[typedData.PrimaryType]; !ok {
+		return pack.Bytes32{}, fmt.Errorf("bad types: expected primary type %v", typedData.PrimaryType)
+	}
+	messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return pack.Bytes32{}, fmt.Errorf("hashing message: %v", err)
+	}
+	digest := crypto.Keccak256([]byte{0x19, 0x01}, domainSeparator[:], messageHash)
+	return pack.NewBytes32(common.BytesToHash(digest)), nil
+}
+
+// RecoverSigner returns the address that produced the signature over the
+// digest. The recovery id of the signature can be 0 or 1, or 27 or 28.
+func RecoverSigner(digest pack.Bytes32, signature pack.Bytes65) (address.Address, error) {
+	sig := normalize(signature)
+	pubKey, err := crypto.SigToPub(digest[:], sig[:])
+	if err != nil {
+		return address.Address(""), fmt.Errorf("recovering public key: %v", err)
+	}
+	return address.Address(crypto.PubkeyToAddress(*pubKey).Hex()), nil
+}
+
+// VerifySignature returns an error if the signature over the digest was not
+// produced by the signer.
+func VerifySignature(digest pack.Bytes32, signature pack.Bytes65, signer address.Address) error {
+	recovered, err := RecoverSigner(digest, signature)
+	if err != nil {
+		return err
+	}
+	if !common.IsHexAddress(string(signer)) {
+		return fmt.Errorf("bad signer address '%v'", signer)
+	}
+	if common.HexToAddress(string(recovered)) != common.HexToAddress(string(signer)) {
+		return fmt.Errorf("bad signature: expected signer %v, got %v", signer, recovered)
+	}
+	return nil
+}
+
+// A Message is typed data that can be signed. It exposes the same Sighashes
+// and Sign methods as transactions, so that the signature can be produced
+// externally.
+type Message struct {
+	TypedData TypedData
+
+	digest    pack.Bytes32
+	signature *pack.Bytes65
+}
+
+// NewMessage returns a message for the typed data. An error is returned if the
+// typed data cannot be hashed.
+func NewMessage(typedData TypedData) (*Message, error) {
+	digest, err := Digest(typedData)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{TypedData: typedData, digest: digest}, nil
+}
+
+// Digest returns the digest of the message that must be signed.
+func (msg Message) Digest() pack.Bytes32 {
+	return msg.digest
+}
+
+// Sighashes returns the digests that must be signed before the message is
+// signed. There is always exactly one digest.
+func (msg Message) Sighashes() ([]pack.Bytes32, error) {
+	return []pack.Bytes32{msg.digest}, nil
+}
+
+// Sign the message by injecting the signature for the digest. If the
+// serialized public key is specified (compressed or uncompressed), an error is
+// returned if the signature was not produced by the public key.
+func (msg *Message) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
+	if len(signatures) != 1 {
+		return fmt.Errorf("bad signatures: expected 1 signature, got %v signatures", len(signatures))
+	}
+	signature := normalize(signatures[0])
+	recovered, err := crypto.Ecrecover(msg.digest[:], signature[:])
+	if err != nil {
+		return fmt.Errorf("recovering public key: %v", err)
+	}
+	if len(pubKey) > 0 {
+		expected, err := decodePubKey(pubKey)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(recovered, expected) {
+			return fmt.Errorf("bad signature: signed by a different public key")
+		}
+	}
+	msg.signature = &signature
+	return nil
+}
+
+// Signature returns the signature of the message, and false if the message
+// has not been signed. The recovery id of the signature is 27 or 28, which is
+// the format expected by ecrecover in contracts.
+func (msg Message) Signature() (pack.Bytes65, bool) {
+	if msg.signature == nil {
+		return pack.Bytes65{}, false
+	}
+	signature := *msg.signature
+	signature[64] += 27
+	return signature, true
+}
+
+// Signer returns the address that signed the message. An error is returned if
+// the message has not been signed.
+func (msg Message) Signer() (address.Address, error) {
+	if msg.signature == nil {
+		return address.Address(""), fmt.Errorf("message is not signed")
+	}
+	return RecoverSigner(msg.digest, *msg.signature)
+}
+
+// normalize returns the signature with a recovery id of 0 or 1.
+func normalize(signature pack.Bytes65) pack.Bytes65 {
+	if signature[64] >= 27 {
+		signature[64] -= 27
+	}
+	return signature
+}
+
+// decodePubKey returns the uncompressed serialization of the public key.
+func decodePubKey(pubKey pack.Bytes) ([]byte, error) {
+	switch len(pubKey) {
+	case 33:
+		key, err := crypto.DecompressPubkey(pubKey)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing public key: %v", err)
+		}
+		return crypto.FromECDSAPub(key), nil
+	case 65:
+		key, err := crypto.UnmarshalPubkey(pubKey)
+		if err != nil {
+			return nil, fmt.Errorf("decoding public key: %v", err)
+		}
+		return crypto.FromECDSAPub(key), nil
+	default:
+		return nil, fmt.Errorf("bad public key: expected 33 or 65 bytes, got %v bytes", len(pubKey))
+	}
+}
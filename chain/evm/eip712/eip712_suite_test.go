@@ -0,0 +1,13 @@
+package eip712_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEIP712(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "EIP712 Suite")
+}
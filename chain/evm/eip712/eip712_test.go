@@ -0,0 +1,127 @@
+package eip712_test
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/evm/eip712"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mail is the example typed data from the EIP-712 specification.
+const mail = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+var _ = Describe("EIP-712", func() {
+	privKey := crypto.Keccak256([]byte("cow"))
+
+	Context("when hashing typed data", func() {
+		It("should return the digest from the specification", func() {
+			typedData, err := eip712.Parse([]byte(mail))
+			Expect(err).ToNot(HaveOccurred())
+
+			domainSeparator, err := eip712.DomainSeparator(typedData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hexutil.Encode(domainSeparator[:])).To(Equal("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"))
+
+			digest, err := eip712.Digest(typedData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hexutil.Encode(digest[:])).To(Equal("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"))
+		})
+
+		It("should return an error for unknown primary types", func() {
+			typedData, err := eip712.Parse([]byte(mail))
+			Expect(err).ToNot(HaveOccurred())
+			typedData.PrimaryType = "Letter"
+			_, err = eip712.Digest(typedData)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when signing typed data", func() {
+		It("should accept external signatures and recover the signer", func() {
+			key, err := crypto.ToECDSA(privKey)
+			Expect(err).ToNot(HaveOccurred())
+			signer := address.Address(crypto.PubkeyToAddress(key.PublicKey).Hex())
+
+			typedData, err := eip712.Parse([]byte(mail))
+			Expect(err).ToNot(HaveOccurred())
+			msg, err := eip712.NewMessage(typedData)
+			Expect(err).ToNot(HaveOccurred())
+			_, ok := msg.Signature()
+			Expect(ok).To(BeFalse())
+
+			sighashes, err := msg.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sighashes).To(Equal([]pack.Bytes32{msg.Digest()}))
+			sig, err := crypto.Sign(sighashes[0][:], key)
+			Expect(err).ToNot(HaveOccurred())
+			var signature pack.Bytes65
+			copy(signature[:], sig)
+
+			Expect(msg.Sign([]pack.Bytes65{signature}, pack.Bytes(crypto.CompressPubkey(&key.PublicKey)))).To(Succeed())
+			recovered, err := msg.Signer()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recovered).To(Equal(signer))
+			Expect(common.HexToAddress(string(recovered))).To(Equal(common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")))
+
+			signed, ok := msg.Signature()
+			Expect(ok).To(BeTrue())
+			Expect(signed[64]).To(Equal(signature[64] + 27))
+			Expect(eip712.VerifySignature(msg.Digest(), signed, signer)).To(Succeed())
+		})
+
+		It("should reject signatures from other keys", func() {
+			key, err := crypto.ToECDSA(privKey)
+			Expect(err).ToNot(HaveOccurred())
+			other, err := crypto.GenerateKey()
+			Expect(err).ToNot(HaveOccurred())
+
+			typedData, err := eip712.Parse([]byte(mail))
+			Expect(err).ToNot(HaveOccurred())
+			msg, err := eip712.NewMessage(typedData)
+			Expect(err).ToNot(HaveOccurred())
+			digest := msg.Digest()
+			sig, err := crypto.Sign(digest[:], other)
+			Expect(err).ToNot(HaveOccurred())
+			var signature pack.Bytes65
+			copy(signature[:], sig)
+
+			Expect(msg.Sign([]pack.Bytes65{signature}, pack.Bytes(crypto.FromECDSAPub(&key.PublicKey)))).ToNot(Succeed())
+			Expect(eip712.VerifySignature(digest, signature, address.Address(crypto.PubkeyToAddress(key.PublicKey).Hex()))).ToNot(Succeed())
+		})
+	})
+})
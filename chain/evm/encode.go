@@ -3,8 +3,10 @@ package evm
 import (
 	"fmt"
 	"math/big"
+	"reflect"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/renproject/pack"
 )
 
@@ -17,78 +19,372 @@ type Payload struct {
 	Data pack.Bytes `json:"data"`
 }
 
-// Encode values into an Ethereum ABI compatible byte slice.
+// maxFixedBytes is the largest size of the bytesN types.
+const maxFixedBytes = 32
+
+// bigIntConverter is implemented by pack.U128 and pack.U256.
+type bigIntConverter interface {
+	Int() *big.Int
+}
+
+var (
+	packU8Type          = reflect.TypeOf(pack.U8(0))
+	packU16Type         = reflect.TypeOf(pack.U16(0))
+	packU32Type         = reflect.TypeOf(pack.U32(0))
+	packU64Type         = reflect.TypeOf(pack.U64(0))
+	packU128Type        = reflect.TypeOf(pack.U128{})
+	packU256Type        = reflect.TypeOf(pack.U256{})
+	addressType         = reflect.TypeOf(Address{})
+	ethAddressType      = reflect.TypeOf(common.Address{})
+	bigIntPtrType       = reflect.TypeOf(new(big.Int))
+	bigIntConverterType = reflect.TypeOf((*bigIntConverter)(nil)).Elem()
+)
+
+// Encode values into an Ethereum ABI compatible byte slice. It panics if a
+// value cannot be encoded, so EncodeArgs should be used for values that are
+// not known to be valid.
 func Encode(vals ...interface{}) []byte {
+	packed, err := EncodeArgs(vals...)
+	if err != nil {
+		panic(err)
+	}
+	return packed
+}
+
+// EncodeArgs encodes values into an Ethereum ABI compatible byte slice. The ABI
+// type of each value is defined by its Go type:
+//
+//   - pack.U8, pack.U16, pack.U32, pack.U64, pack.U128, and pack.U256 are
+//     encoded as uint256,
+//   - uint8, uint16, uint32, and uint64 are encoded as uintN,
+//   - int8, int16, int32, and int64 are encoded as intN, and *big.Int is
+//     encoded as int256,
+//   - bool and pack.Bool are encoded as bool, and string and pack.String are
+//     encoded as string,
+//   - Address and common.Address are encoded as address,
+//   - byte slices (including pack.Bytes) are encoded as bytes, and byte arrays
+//     with at most 32 bytes (including pack.Bytes32) are encoded as bytesN,
+//   - other slices and arrays are encoded as T[] and T[N], and
+//   - structs are encoded as tuples of their exported fields.
+//
+// An error is returned if a value has a type that is not supported.
+func EncodeArgs(vals ...interface{}) ([]byte, error) {
 	ethargs := make(abi.Arguments, 0, len(vals))
 	ethvals := make([]interface{}, 0, len(vals))
 
-	for _, val := range vals {
-		var ethval interface{}
-		var ty abi.Type
-		var err error
-
-		switch val := val.(type) {
-		case pack.Bytes:
-			ethval = val
-			ty, err = abi.NewType("bytes", "", nil)
-		case pack.Bytes32:
-			ethval = val
-			ty, err = abi.NewType("bytes32", "", nil)
-
-		case pack.U8:
-			ethval = big.NewInt(0).SetUint64(uint64(val.Uint8()))
-			ty, err = abi.NewType("uint256", "", nil)
-		case pack.U16:
-			ethval = big.NewInt(0).SetUint64(uint64(val.Uint16()))
-			ty, err = abi.NewType("uint256", "", nil)
-		case pack.U32:
-			ethval = big.NewInt(0).SetUint64(uint64(val.Uint32()))
-			ty, err = abi.NewType("uint256", "", nil)
-		case pack.U64:
-			ethval = big.NewInt(0).SetUint64(uint64(val.Uint64()))
-			ty, err = abi.NewType("uint256", "", nil)
-		case pack.U128:
-			ethval = val.Int()
-			ty, err = abi.NewType("uint256", "", nil)
-		case pack.U256:
-			ethval = val.Int()
-			ty, err = abi.NewType("uint256", "", nil)
-
-		case Address:
-			ethval = val
-			ty, err = abi.NewType("address", "", nil)
-
-		case []pack.U256:
-			v := make([]*big.Int, len(val))
-			for i := range val {
-				elem, ok := big.NewInt(0).SetString(val[i].String(), 10)
-				if !ok {
-					panic(fmt.Errorf("encoding list item: %v", err))
-				}
-				v[i] = elem
-			}
-			ethval = v
-			ty, err = abi.NewType("uint256[]", "", nil)
-		case []Address:
-			ethval = val
-			ty, err = abi.NewType("address[]", "", nil)
-
-		default:
-			panic(fmt.Errorf("non-exhaustive pattern: %T", val))
+	for i, val := range vals {
+		if val == nil {
+			return nil, fmt.Errorf("encoding arg %v: nil value", i)
 		}
-
+		ty, err := abiType(reflect.TypeOf(val))
+		if err != nil {
+			return nil, fmt.Errorf("encoding arg %v: %v", i, err)
+		}
+		ethval, err := toEthValue(ty, reflect.ValueOf(val))
 		if err != nil {
-			panic(fmt.Errorf("error encoding: %v", err))
+			return nil, fmt.Errorf("encoding arg %v: %v", i, err)
 		}
 		ethargs = append(ethargs, abi.Argument{
 			Type: ty,
 		})
-		ethvals = append(ethvals, ethval)
+		ethvals = append(ethvals, ethval.Interface())
 	}
 
 	packed, err := ethargs.Pack(ethvals...)
 	if err != nil {
-		panic(fmt.Errorf("error packing: %v", err))
+		return nil, fmt.Errorf("error packing: %v", err)
 	}
-	return packed
+	return packed, nil
+}
+
+// Decode Ethereum ABI compatible bytes, such as the output of a contract call,
+// into values. Every value must be a non-nil pointer, and the ABI type that is
+// decoded is defined by the type to which it points, in the same way as
+// EncodeArgs. An error is returned if the bytes cannot be decoded into the
+// values, including when the bytes are malformed, or when a decoded integer
+// does not fit into its value.
+func Decode(data []byte, vals ...interface{}) (err error) {
+	ethargs := make(abi.Arguments, 0, len(vals))
+	for i, val := range vals {
+		rv := reflect.ValueOf(val)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() {
+			return fmt.Errorf("decoding arg %v: expected non-nil pointer, got %T", i, val)
+		}
+		ty, err := abiType(rv.Type().Elem())
+		if err != nil {
+			return fmt.Errorf("decoding arg %v: %v", i, err)
+		}
+		ethargs = append(ethargs, abi.Argument{
+			Type: ty,
+		})
+	}
+
+	// Unpacking malformed data is not guaranteed to return an error, so
+	// panics are recovered to make sure that it can never crash the caller.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("error unpacking: %v", r)
+		}
+	}()
+	ethvals, err := ethargs.Unpack(data)
+	if err != nil {
+		return fmt.Errorf("error unpacking: %v", err)
+	}
+	if len(ethvals) != len(vals) {
+		return fmt.Errorf("error unpacking: expected %v values, got %v", len(vals), len(ethvals))
+	}
+	for i := range vals {
+		if err := fromEthValue(ethargs[i].Type, reflect.ValueOf(ethvals[i]), reflect.ValueOf(vals[i]).Elem()); err != nil {
+			return fmt.Errorf("decoding arg %v: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// abiType returns the ABI type that is used to encode and decode values of the
+// Go type.
+func abiType(t reflect.Type) (abi.Type, error) {
+	marshaling, err := abiArgument(t, "")
+	if err != nil {
+		return abi.Type{}, err
+	}
+	return abi.NewType(marshaling.Type, "", marshaling.Components)
+}
+
+// abiArgument returns the ABI type, and the ABI types of the components of
+// tuples, that is used to encode and decode values of the Go type.
+func abiArgument(t reflect.Type, name string) (abi.ArgumentMarshaling, error) {
+	arg := abi.ArgumentMarshaling{Name: name}
+	switch t {
+	case packU8Type, packU16Type, packU32Type, packU64Type, packU128Type, packU256Type:
+		arg.Type = "uint256"
+		return arg, nil
+	case addressType, ethAddressType:
+		arg.Type = "address"
+		return arg, nil
+	case bigIntPtrType:
+		arg.Type = "int256"
+		return arg, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		arg.Type = "bool"
+	case reflect.String:
+		arg.Type = "string"
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		arg.Type = fmt.Sprintf("uint%d", t.Bits())
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		arg.Type = fmt.Sprintf("int%d", t.Bits())
+	case reflect.Slice:
+		if isByte(t.Elem()) {
+			arg.Type = "bytes"
+			return arg, nil
+		}
+		elem, err := abiArgument(t.Elem(), name)
+		if err != nil {
+			return arg, err
+		}
+		arg.Type = elem.Type + "[]"
+		arg.Components = elem.Components
+	case reflect.Array:
+		if isByte(t.Elem()) && t.Len() > 0 && t.Len() <= maxFixedBytes {
+			arg.Type = fmt.Sprintf("bytes%d", t.Len())
+			return arg, nil
+		}
+		elem, err := abiArgument(t.Elem(), name)
+		if err != nil {
+			return arg, err
+		}
+		arg.Type = fmt.Sprintf("%v[%d]", elem.Type, t.Len())
+		arg.Components = elem.Components
+	case reflect.Struct:
+		fields := exportedFields(t)
+		if len(fields) == 0 {
+			return arg, fmt.Errorf("unsupported type %v: no exported fields", t)
+		}
+		arg.Type = "tuple"
+		arg.Components = make([]abi.ArgumentMarshaling, len(fields))
+		for i, field := range fields {
+			component, err := abiArgument(t.Field(field).Type, t.Field(field).Name)
+			if err != nil {
+				return arg, err
+			}
+			arg.Components[i] = component
+		}
+	default:
+		return arg, fmt.Errorf("unsupported type %v", t)
+	}
+	return arg, nil
+}
+
+// toEthValue converts a value into the Go type that is used by go-ethereum to
+// encode the ABI type.
+func toEthValue(ty abi.Type, val reflect.Value) (reflect.Value, error) {
+	out := reflect.New(ty.GetType()).Elem()
+	switch ty.T {
+	case abi.UintTy, abi.IntTy:
+		x, err := bigIntValue(val)
+		if err != nil {
+			return out, err
+		}
+		if ty.Size > 64 {
+			out.Set(reflect.ValueOf(x))
+		} else if ty.T == abi.UintTy {
+			out.SetUint(x.Uint64())
+		} else {
+			out.SetInt(x.Int64())
+		}
+	case abi.BoolTy:
+		out.SetBool(val.Bool())
+	case abi.StringTy:
+		out.SetString(val.String())
+	case abi.BytesTy:
+		out.SetBytes(append([]byte{}, val.Bytes()...))
+	case abi.FixedBytesTy, abi.AddressTy:
+		reflect.Copy(out, val)
+	case abi.SliceTy:
+		out.Set(reflect.MakeSlice(out.Type(), val.Len(), val.Len()))
+		fallthrough
+	case abi.ArrayTy:
+		for i := 0; i < val.Len(); i++ {
+			elem, err := toEthValue(*ty.Elem, val.Index(i))
+			if err != nil {
+				return out, err
+			}
+			out.Index(i).Set(elem)
+		}
+	case abi.TupleTy:
+		for i, field := range exportedFields(val.Type()) {
+			elem, err := toEthValue(*ty.TupleElems[i], val.Field(field))
+			if err != nil {
+				return out, err
+			}
+			out.Field(i).Set(elem)
+		}
+	default:
+		return out, fmt.Errorf("unsupported abi type %v", ty)
+	}
+	return out, nil
+}
+
+// fromEthValue sets a value from the Go type that is used by go-ethereum to
+// decode the ABI type.
+func fromEthValue(ty abi.Type, ethval reflect.Value, out reflect.Value) error {
+	switch ty.T {
+	case abi.UintTy, abi.IntTy:
+		x, err := bigIntValue(ethval)
+		if err != nil {
+			return err
+		}
+		return setBigIntValue(out, x)
+	case abi.BoolTy:
+		out.SetBool(ethval.Bool())
+	case abi.StringTy:
+		out.SetString(ethval.String())
+	case abi.BytesTy:
+		out.SetBytes(append([]byte{}, ethval.Bytes()...))
+	case abi.FixedBytesTy, abi.AddressTy:
+		if out.Len() != ethval.Len() {
+			return fmt.Errorf("expected %v bytes, got %v bytes", out.Len(), ethval.Len())
+		}
+		reflect.Copy(out, ethval)
+	case abi.SliceTy:
+		out.Set(reflect.MakeSlice(out.Type(), ethval.Len(), ethval.Len()))
+		fallthrough
+	case abi.ArrayTy:
+		if out.Len() != ethval.Len() {
+			return fmt.Errorf("expected %v elements, got %v elements", out.Len(), ethval.Len())
+		}
+		for i := 0; i < ethval.Len(); i++ {
+			if err := fromEthValue(*ty.Elem, ethval.Index(i), out.Index(i)); err != nil {
+				return fmt.Errorf("element %v: %v", i, err)
+			}
+		}
+	case abi.TupleTy:
+		for i, field := range exportedFields(out.Type()) {
+			if err := fromEthValue(*ty.TupleElems[i], ethval.Field(i), out.Field(field)); err != nil {
+				return fmt.Errorf("field %v: %v", out.Type().Field(field).Name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported abi type %v", ty)
+	}
+	return nil
+}
+
+// bigIntValue returns the integer value of a Go integer, *big.Int, or pack
+// unsigned integer.
+func bigIntValue(val reflect.Value) (*big.Int, error) {
+	if val.Type().Implements(bigIntConverterType) {
+		return new(big.Int).Set(val.Interface().(bigIntConverter).Int()), nil
+	}
+	switch val.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(val.Uint()), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(val.Int()), nil
+	case reflect.Ptr:
+		if x, ok := val.Interface().(*big.Int); ok && x != nil {
+			return new(big.Int).Set(x), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported integer type %v", val.Type())
+}
+
+// setBigIntValue sets a Go integer, *big.Int, or pack unsigned integer to the
+// integer value. It returns an error if the integer value does not fit.
+func setBigIntValue(out reflect.Value, x *big.Int) error {
+	switch out.Type() {
+	case packU128Type:
+		if x.Sign() < 0 || x.BitLen() > 128 {
+			return fmt.Errorf("%v overflows %v", x, out.Type())
+		}
+		buf := [16]byte{}
+		x.FillBytes(buf[:])
+		out.Set(reflect.ValueOf(pack.NewU128(buf)))
+		return nil
+	case packU256Type:
+		if x.Sign() < 0 || x.BitLen() > 256 {
+			return fmt.Errorf("%v overflows %v", x, out.Type())
+		}
+		out.Set(reflect.ValueOf(pack.NewU256FromInt(x)))
+		return nil
+	case bigIntPtrType:
+		out.Set(reflect.ValueOf(x))
+		return nil
+	}
+	switch out.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if x.Sign() < 0 || !x.IsUint64() || out.OverflowUint(x.Uint64()) {
+			return fmt.Errorf("%v overflows %v", x, out.Type())
+		}
+		out.SetUint(x.Uint64())
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !x.IsInt64() || out.OverflowInt(x.Int64()) {
+			return fmt.Errorf("%v overflows %v", x, out.Type())
+		}
+		out.SetInt(x.Int64())
+	default:
+		return fmt.Errorf("unsupported integer type %v", out.Type())
+	}
+	return nil
+}
+
+// isByte returns true if the type is a byte. Elements of type pack.U8 are not
+// bytes, because pack.U8 is encoded as uint256.
+func isByte(t reflect.Type) bool {
+	return t.Kind() == reflect.Uint8 && t != packU8Type
+}
+
+// exportedFields returns the indices of the exported fields of the struct
+// type. These are the fields that are encoded as the components of a tuple.
+func exportedFields(t reflect.Type) []int {
+	fields := []int{}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			fields = append(fields, i)
+		}
+	}
+	return fields
 }
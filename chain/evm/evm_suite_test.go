@@ -0,0 +1,25 @@
+package evm_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/multichain/chain/evm/evmtest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEVM(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "EVM Suite")
+}
+
+// dial returns a client that is connected to the fake node, and a function
+// that stops the node.
+func dial(node *evmtest.Node) (*evm.Client, func()) {
+	client, closeNode, err := node.Dial(big.NewInt(1337))
+	Expect(err).ToNot(HaveOccurred())
+	return client, closeNode
+}
@@ -0,0 +1,220 @@
+// Package evmtest provides a fake EVM JSON-RPC node, for testing clients and
+// utilities that talk to EVM chains without running a real node.
+package evmtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/renproject/multichain/chain/evm"
+)
+
+// A Handler returns the result of a JSON-RPC request, given its parameters.
+// Returning an *Error responds with that JSON-RPC error, and returning any
+// other error responds with a generic server error.
+type Handler func(params []json.RawMessage) (interface{}, error)
+
+// An Error is a JSON-RPC error, with an optional data field (for example, the
+// return data of a call that reverted).
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Error implements the error interface.
+func (err *Error) Error() string {
+	return fmt.Sprintf("json-rpc error %v: %v", err.Code, err.Message)
+}
+
+// Revert returns the error that is returned by a node when a call reverts
+// with the given return data.
+func Revert(message string, data []byte) *Error {
+	return &Error{Code: 3, Message: message, Data: hexutil.Encode(data)}
+}
+
+// A Request is a JSON-RPC request that was received by a Node.
+type Request struct {
+	Method string
+	Params []json.RawMessage
+}
+
+// A Node is a fake JSON-RPC node. It responds to the methods for which it
+// has a handler, and responds to other methods with a method not found error.
+// It supports batch requests, records all of the requests that it receives,
+// and is safe for concurrent use.
+type Node struct {
+	mu       *sync.Mutex
+	handlers map[string]Handler
+	requests []Request
+}
+
+// NewNode returns a Node without any handlers.
+func NewNode() *Node {
+	return &Node{
+		mu:       new(sync.Mutex),
+		handlers: map[string]Handler{},
+	}
+}
+
+// Handle sets the handler for the method, and returns the node.
+func (node *Node) Handle(method string, handler Handler) *Node {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.handlers[method] = handler
+	return node
+}
+
+// Result sets a handler for the method that always returns the result, and
+// returns the node.
+func (node *Node) Result(method string, result interface{}) *Node {
+	return node.Handle(method, func([]json.RawMessage) (interface{}, error) {
+		return result, nil
+	})
+}
+
+// Requests returns the requests for the method that the node has received, in
+// the order in which they were received.
+func (node *Node) Requests(method string) []Request {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	requests := []Request{}
+	for _, req := range node.requests {
+		if req.Method == method {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+// Dial starts a server for the node, and returns a client that is connected
+// to it, and a function that stops the server.
+func (node *Node) Dial(chainID *big.Int) (*evm.Client, func(), error) {
+	server := httptest.NewServer(node)
+	rpcClient, err := rpc.Dial(server.URL)
+	if err != nil {
+		server.Close()
+		return nil, nil, fmt.Errorf("dialing %v: %v", server.URL, err)
+	}
+	return evm.NewClientFromRPC(rpcClient, chainID), server.Close, nil
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (node *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body := new(bytes.Buffer)
+	if _, err := body.ReadFrom(r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if data := bytes.TrimSpace(body.Bytes()); len(data) > 0 && data[0] == '[' {
+		reqs := []json.RawMessage{}
+		if err := json.Unmarshal(data, &reqs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		res := make([]map[string]interface{}, len(reqs))
+		for i, req := range reqs {
+			res[i] = node.handle(req)
+		}
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+	json.NewEncoder(w).Encode(node.handle(body.Bytes()))
+}
+
+func (node *Node) handle(data []byte) map[string]interface{} {
+	req := struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}{}
+	res := map[string]interface{}{"jsonrpc": "2.0"}
+	if err := json.Unmarshal(data, &req); err != nil {
+		res["error"] = &Error{Code: -32700, Message: err.Error()}
+		return res
+	}
+	res["id"] = req.ID
+
+	node.mu.Lock()
+	node.requests = append(node.requests, Request{Method: req.Method, Params: req.Params})
+	handler, ok := node.handlers[req.Method]
+	node.mu.Unlock()
+	if !ok {
+		res["error"] = &Error{Code: -32601, Message: fmt.Sprintf("method %v does not exist", req.Method)}
+		return res
+	}
+
+	result, err := handler(req.Params)
+	if err != nil {
+		if rpcErr, ok := err.(*Error); ok {
+			res["error"] = rpcErr
+		} else {
+			res["error"] = &Error{Code: -32000, Message: err.Error()}
+		}
+		return res
+	}
+	res["result"] = result
+	return res
+}
+
+// Header returns a block header with the given number and base fee, that can
+// be returned by the eth_getBlockByNumber method. Blocks before London do not
+// have a base fee, and should use a nil base fee.
+func Header(number uint64, baseFee *big.Int) *types.Header {
+	return &types.Header{
+		Difficulty: big.NewInt(0),
+		Number:     new(big.Int).SetUint64(number),
+		GasLimit:   30000000,
+		BaseFee:    baseFee,
+	}
+}
+
+// Quantity returns the hex encoding of the integer, that can be returned by
+// methods that return quantities (for example, eth_estimateGas).
+func Quantity(x uint64) string {
+	return hexutil.EncodeUint64(x)
+}
+
+// CallMsg is the call object that is the first parameter of eth_call and
+// eth_estimateGas.
+type CallMsg struct {
+	From  string        `json:"from"`
+	To    string        `json:"to"`
+	Value *hexutil.Big  `json:"value"`
+	Data  hexutil.Bytes `json:"data"`
+	Input hexutil.Bytes `json:"input"`
+}
+
+// CallData returns the calldata of the call, which is sent using either the
+// data or the input field.
+func (msg CallMsg) CallData() []byte {
+	if len(msg.Data) > 0 {
+		return msg.Data
+	}
+	return msg.Input
+}
+
+// DecodeCallMsg decodes the call object of an eth_call or eth_estimateGas
+// request.
+func DecodeCallMsg(params []json.RawMessage) (CallMsg, error) {
+	msg := CallMsg{}
+	if len(params) == 0 {
+		return msg, fmt.Errorf("missing call object")
+	}
+	if err := json.Unmarshal(params[0], &msg); err != nil {
+		return msg, fmt.Errorf("decoding call object: %v", err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,147 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultFeeHistoryBlocks is the number of blocks used by default to
+	// estimate the priority fee.
+	DefaultFeeHistoryBlocks = 10
+	// DefaultFeeHistoryPercentile is the percentile of the priority fees paid
+	// in each block that is used by default to estimate the priority fee.
+	DefaultFeeHistoryPercentile = 50
+	// DefaultBaseFeeMultiplier is the multiplier, as a percentage, applied to
+	// the base fee by default when estimating the fee cap. The base fee can
+	// increase by 12.5% every block, so the default allows for roughly six
+	// full blocks in a row before the fee cap is exceeded.
+	DefaultBaseFeeMultiplier = 200
+)
+
+// FeeMarket defines the types of transaction fees that are supported by a
+// chain.
+type FeeMarket uint8
+
+const (
+	// FeeMarketLegacy is used for chains that only support legacy
+	// transactions, with a single gas price.
+	FeeMarketLegacy = FeeMarket(iota)
+	// FeeMarketDynamic is used for chains that support EIP-1559 dynamic fee
+	// transactions, with a priority fee and a fee cap.
+	FeeMarketDynamic
+	// FeeMarketAuto is used for chains that might support EIP-1559. Dynamic
+	// fee transactions are used if the latest block has a base fee (the chain
+	// has activated the London upgrade), and legacy transactions are used
+	// otherwise.
+	FeeMarketAuto
+)
+
+// String implements the Stringer interface.
+func (feeMarket FeeMarket) String() string {
+	switch feeMarket {
+	case FeeMarketLegacy:
+		return "legacy"
+	case FeeMarketDynamic:
+		return "dynamic"
+	case FeeMarketAuto:
+		return "auto"
+	default:
+		return fmt.Sprintf("FeeMarket(%d)", uint8(feeMarket))
+	}
+}
+
+// FeeOptions are used to parameterise the fees used by the TxBuilder and the
+// GasEstimator. The same options should be used by both, so that the
+// estimated fees are used for the right type of transaction. The zero value
+// only uses legacy transactions.
+type FeeOptions struct {
+	// FeeMarket is the type of transaction fees used.
+	FeeMarket FeeMarket
+	// FeeHistoryBlocks is the number of blocks used to estimate the priority
+	// fee.
+	FeeHistoryBlocks uint64
+	// FeeHistoryPercentile is the percentile of the priority fees paid in each
+	// block that is used to estimate the priority fee.
+	FeeHistoryPercentile float64
+	// BaseFeeMultiplier is the multiplier, as a percentage, applied to the
+	// base fee when estimating the fee cap.
+	BaseFeeMultiplier uint64
+	// MinPriorityFee is the smallest priority fee that is estimated. Some
+	// chains do not accept transactions with a priority fee below a minimum.
+	MinPriorityFee pack.U256
+}
+
+// DefaultFeeOptions returns FeeOptions with the default settings. Dynamic fee
+// transactions are used if the chain has activated the London upgrade.
+func DefaultFeeOptions() FeeOptions {
+	return FeeOptions{
+		FeeMarket:            FeeMarketAuto,
+		FeeHistoryBlocks:     DefaultFeeHistoryBlocks,
+		FeeHistoryPercentile: DefaultFeeHistoryPercentile,
+		BaseFeeMultiplier:    DefaultBaseFeeMultiplier,
+		MinPriorityFee:       pack.NewU256FromUint64(0),
+	}
+}
+
+// WithFeeMarket sets the type of transaction fees used.
+func (opts FeeOptions) WithFeeMarket(feeMarket FeeMarket) FeeOptions {
+	opts.FeeMarket = feeMarket
+	return opts
+}
+
+// WithFeeHistory sets the number of blocks, and the percentile of the
+// priority fees paid in each block, used to estimate the priority fee.
+func (opts FeeOptions) WithFeeHistory(blocks uint64, percentile float64) FeeOptions {
+	opts.FeeHistoryBlocks = blocks
+	opts.FeeHistoryPercentile = percentile
+	return opts
+}
+
+// WithBaseFeeMultiplier sets the multiplier, as a percentage, applied to the
+// base fee when estimating the fee cap.
+func (opts FeeOptions) WithBaseFeeMultiplier(baseFeeMultiplier uint64) FeeOptions {
+	opts.BaseFeeMultiplier = baseFeeMultiplier
+	return opts
+}
+
+// WithMinPriorityFee sets the smallest priority fee that is estimated.
+func (opts FeeOptions) WithMinPriorityFee(minPriorityFee pack.U256) FeeOptions {
+	opts.MinPriorityFee = minPriorityFee
+	return opts
+}
+
+// BaseFee returns the base fee of the latest block. It returns nil if the
+// chain has not activated the London upgrade.
+func (client *Client) BaseFee(ctx context.Context) (*big.Int, error) {
+	header, err := client.EthClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching header: %v", err)
+	}
+	return header.BaseFee, nil
+}
+
+// dynamicFee returns true if dynamic fee transactions should be used with the
+// given fee options.
+func dynamicFee(ctx context.Context, client *Client, opts FeeOptions) (bool, error) {
+	switch opts.FeeMarket {
+	case FeeMarketLegacy:
+		return false, nil
+	case FeeMarketDynamic:
+		return true, nil
+	case FeeMarketAuto:
+		if client == nil {
+			return false, fmt.Errorf("detecting fee market: nil client")
+		}
+		baseFee, err := client.BaseFee(ctx)
+		if err != nil {
+			return false, fmt.Errorf("detecting fee market: %v", err)
+		}
+		return baseFee != nil, nil
+	default:
+		return false, fmt.Errorf("unsupported fee market %v", opts.FeeMarket)
+	}
+}
@@ -0,0 +1,149 @@
+package evm_test
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/multichain/chain/evm/evmtest"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// feeNode configures a fake node that responds to the methods used to
+// estimate fees. Blocks only have a base fee if the base fee is not nil.
+type feeNode struct {
+	baseFee     *big.Int
+	rewards     []int64
+	nextBaseFee int64
+	tipCap      int64
+	gasPrice    int64
+}
+
+func (fees feeNode) node() *evmtest.Node {
+	baseFee := fees.baseFee
+	if baseFee == nil {
+		baseFee = big.NewInt(0)
+	}
+	rewards := make([][]string, len(fees.rewards))
+	for i, reward := range fees.rewards {
+		rewards[i] = []string{evmtest.Quantity(uint64(reward))}
+	}
+	return evmtest.NewNode().
+		Result("eth_getBlockByNumber", evmtest.Header(100, fees.baseFee)).
+		Result("eth_feeHistory", map[string]interface{}{
+			"oldestBlock":   evmtest.Quantity(91),
+			"reward":        rewards,
+			"baseFeePerGas": []string{evmtest.Quantity(baseFee.Uint64()), evmtest.Quantity(uint64(fees.nextBaseFee))},
+			"gasUsedRatio":  []float64{0.5},
+		}).
+		Result("eth_maxPriorityFeePerGas", evmtest.Quantity(uint64(fees.tipCap))).
+		Result("eth_gasPrice", evmtest.Quantity(uint64(fees.gasPrice)))
+}
+
+var _ = Describe("Fees", func() {
+	ctx := context.Background()
+	chainID := big.NewInt(1337)
+	to := address.Address("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+
+	build := func(txBuilder evm.TxBuilder) *types.Transaction {
+		tx, err := txBuilder.BuildTx(ctx, nil, to, pack.NewU256FromUint64(1), pack.NewU256FromUint64(0), pack.NewU256FromUint64(21000), pack.NewU256FromUint64(2), pack.NewU256FromUint64(30), nil)
+		Expect(err).ToNot(HaveOccurred())
+		return tx.(*evm.Tx).EthTx
+	}
+
+	Context("when building transactions", func() {
+		It("should build legacy transactions by default", func() {
+			tx := build(evm.NewTxBuilder(chainID))
+			Expect(tx.Type()).To(Equal(uint8(types.LegacyTxType)))
+			Expect(tx.GasPrice()).To(Equal(big.NewInt(2)))
+		})
+
+		It("should build dynamic fee transactions when configured", func() {
+			tx := build(evm.NewTxBuilder(chainID).WithFeeOptions(nil, evm.DefaultFeeOptions().WithFeeMarket(evm.FeeMarketDynamic)))
+			Expect(tx.Type()).To(Equal(uint8(types.DynamicFeeTxType)))
+			Expect(tx.GasTipCap()).To(Equal(big.NewInt(2)))
+			Expect(tx.GasFeeCap()).To(Equal(big.NewInt(30)))
+			Expect(tx.ChainId()).To(Equal(chainID))
+		})
+
+		It("should detect whether the chain has activated london", func() {
+			client, done := dial(feeNode{baseFee: big.NewInt(10)}.node())
+			defer done()
+			tx := build(evm.NewTxBuilder(chainID).WithFeeOptions(client, evm.DefaultFeeOptions()))
+			Expect(tx.Type()).To(Equal(uint8(types.DynamicFeeTxType)))
+
+			client, done = dial(feeNode{}.node())
+			defer done()
+			tx = build(evm.NewTxBuilder(chainID).WithFeeOptions(client, evm.DefaultFeeOptions()))
+			Expect(tx.Type()).To(Equal(uint8(types.LegacyTxType)))
+		})
+	})
+
+	Context("when estimating fees", func() {
+		It("should use the median priority fee from the fee history", func() {
+			client, done := dial(feeNode{baseFee: big.NewInt(100), nextBaseFee: 110, rewards: []int64{3, 1, 2}, tipCap: 50}.node())
+			defer done()
+
+			priorityFee, feeCap, err := evm.NewGasEstimator(client).WithFeeOptions(evm.DefaultFeeOptions()).EstimateGas(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(priorityFee).To(Equal(pack.NewU256FromUint64(2)))
+			Expect(feeCap).To(Equal(pack.NewU256FromUint64(2*110 + 2)))
+		})
+
+		It("should use the suggested priority fee when there is no fee history", func() {
+			client, done := dial(feeNode{baseFee: big.NewInt(100), nextBaseFee: 100, tipCap: 5}.node())
+			defer done()
+
+			priorityFee, feeCap, err := evm.NewGasEstimator(client).WithFeeOptions(evm.DefaultFeeOptions().WithBaseFeeMultiplier(150)).EstimateGas(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(priorityFee).To(Equal(pack.NewU256FromUint64(5)))
+			Expect(feeCap).To(Equal(pack.NewU256FromUint64(150 + 5)))
+		})
+
+		It("should not estimate a priority fee below the minimum", func() {
+			client, done := dial(feeNode{baseFee: big.NewInt(100), nextBaseFee: 100, rewards: []int64{1}}.node())
+			defer done()
+
+			priorityFee, _, err := evm.NewGasEstimator(client).WithFeeOptions(evm.DefaultFeeOptions().WithMinPriorityFee(pack.NewU256FromUint64(30))).EstimateGas(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(priorityFee).To(Equal(pack.NewU256FromUint64(30)))
+		})
+
+		It("should fall back to the gas price when the chain has not activated london", func() {
+			client, done := dial(feeNode{gasPrice: 7}.node())
+			defer done()
+
+			gasPrice, gasCap, err := evm.NewGasEstimator(client).WithFeeOptions(evm.DefaultFeeOptions()).EstimateGas(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gasPrice).To(Equal(pack.NewU256FromUint64(7)))
+			Expect(gasCap).To(Equal(pack.NewU256FromUint64(7)))
+
+			_, _, err = evm.NewGasEstimator(client).WithFeeOptions(evm.DefaultFeeOptions().WithFeeMarket(evm.FeeMarketDynamic)).EstimateGas(ctx)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should estimate the fee paid by a transaction", func() {
+			client, done := dial(feeNode{baseFee: big.NewInt(10)}.node())
+			defer done()
+			gasEstimator := evm.NewGasEstimator(client)
+
+			tx, err := evm.NewTxBuilder(chainID).BuildTx(ctx, nil, to, pack.NewU256FromUint64(1), pack.NewU256FromUint64(0), pack.NewU256FromUint64(21000), pack.NewU256FromUint64(2), pack.NewU256FromUint64(30), nil)
+			Expect(err).ToNot(HaveOccurred())
+			fee, err := gasEstimator.EstimateTxFee(ctx, tx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(Equal(pack.NewU256FromUint64(2 * 21000)))
+
+			// The base fee plus the priority fee is less than the fee cap.
+			tx, err = evm.NewTxBuilder(chainID).WithFeeOptions(client, evm.DefaultFeeOptions()).BuildTx(ctx, nil, to, pack.NewU256FromUint64(1), pack.NewU256FromUint64(0), pack.NewU256FromUint64(21000), pack.NewU256FromUint64(2), pack.NewU256FromUint64(30), nil)
+			Expect(err).ToNot(HaveOccurred())
+			fee, err = gasEstimator.EstimateTxFee(ctx, tx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(Equal(pack.NewU256FromUint64(12 * 21000)))
+		})
+	})
+})
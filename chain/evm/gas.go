@@ -3,14 +3,27 @@ package evm
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"sort"
 
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/renproject/multichain/api/account"
 	"github.com/renproject/pack"
 )
 
+// A TxFeeEstimator estimates the total fee that will be paid by a transaction,
+// once it is included in a block. This includes fees that are not paid for
+// gas on the chain itself, such as the fees paid by layer 2 chains to post
+// transactions to layer 1.
+type TxFeeEstimator interface {
+	EstimateTxFee(context.Context, account.Tx) (pack.U256, error)
+}
+
 // A GasEstimator returns the gas price and the provide gas limit that is needed in
 // order to confirm transactions with an estimated maximum delay of one block.
 type GasEstimator struct {
-	client *Client
+	client     *Client
+	feeOptions FeeOptions
 }
 
 // NewGasEstimator returns a simple gas estimator that fetches the ideal gas
@@ -22,13 +35,123 @@ func NewGasEstimator(client *Client) *GasEstimator {
 	}
 }
 
+// WithFeeOptions returns a copy of the gas estimator that estimates fees for
+// the type of transaction defined by the fee options. The same fee options
+// should be used by the TxBuilder.
+func (gasEstimator *GasEstimator) WithFeeOptions(feeOptions FeeOptions) *GasEstimator {
+	return &GasEstimator{
+		client:     gasEstimator.client,
+		feeOptions: feeOptions,
+	}
+}
+
 // EstimateGas returns an estimate of the current gas price
 // and returns the gas limit provided. These numbers change with congestion. These estimates
 // are often a little bit off, and this should be considered when using them.
+//
+// For dynamic fee transactions, the gas price is the priority fee and the gas
+// cap is the fee cap. The priority fee is estimated using the fee history of
+// recent blocks, falling back to the priority fee suggested by the node, and
+// the fee cap allows for the base fee to increase before the transaction is
+// included in a block.
 func (gasEstimator *GasEstimator) EstimateGas(ctx context.Context) (pack.U256, pack.U256, error) {
+	if gasEstimator.feeOptions.FeeMarket == FeeMarketLegacy {
+		return gasEstimator.estimateGasPrice(ctx)
+	}
+
+	header, err := gasEstimator.client.EthClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return pack.NewU256([32]byte{}), pack.NewU256([32]byte{}), fmt.Errorf("fetching header: %v", err)
+	}
+	if header.BaseFee == nil {
+		if gasEstimator.feeOptions.FeeMarket == FeeMarketDynamic {
+			return pack.NewU256([32]byte{}), pack.NewU256([32]byte{}), fmt.Errorf("block %v has no base fee", header.Number)
+		}
+		// The chain has not activated the London upgrade, so legacy
+		// transactions are used.
+		return gasEstimator.estimateGasPrice(ctx)
+	}
+
+	baseFee, priorityFee, err := gasEstimator.estimateFeeHistory(ctx, header.Number)
+	if err != nil || priorityFee == nil {
+		priorityFee, err = gasEstimator.client.EthClient.SuggestGasTipCap(ctx)
+		if err != nil {
+			return pack.NewU256([32]byte{}), pack.NewU256([32]byte{}), fmt.Errorf("failed to get eth suggested priority fee: %v", err)
+		}
+	}
+	if baseFee == nil || baseFee.Cmp(header.BaseFee) < 0 {
+		baseFee = header.BaseFee
+	}
+	if minPriorityFee := gasEstimator.feeOptions.MinPriorityFee.Int(); priorityFee.Cmp(minPriorityFee) < 0 {
+		priorityFee = minPriorityFee
+	}
+
+	feeCap := new(big.Int).Mul(baseFee, new(big.Int).SetUint64(gasEstimator.feeOptions.BaseFeeMultiplier))
+	feeCap.Div(feeCap, big.NewInt(100))
+	feeCap.Add(feeCap, priorityFee)
+	return pack.NewU256FromInt(priorityFee), pack.NewU256FromInt(feeCap), nil
+}
+
+// estimateGasPrice returns the gas price suggested by the node, for legacy
+// transactions.
+func (gasEstimator *GasEstimator) estimateGasPrice(ctx context.Context) (pack.U256, pack.U256, error) {
 	gasPrice, err := gasEstimator.client.EthClient.SuggestGasPrice(ctx)
 	if err != nil {
 		return pack.NewU256([32]byte{}), pack.NewU256([32]byte{}), fmt.Errorf("failed to get eth suggested gas price: %v", err)
 	}
 	return pack.NewU256FromInt(gasPrice), pack.NewU256FromInt(gasPrice), nil
 }
+
+// estimateFeeHistory returns the base fee of the next block, and the median
+// of the priority fees paid in recent blocks, at the configured percentile.
+// The priority fee is nil if there are no recent blocks.
+func (gasEstimator *GasEstimator) estimateFeeHistory(ctx context.Context, blockNumber *big.Int) (*big.Int, *big.Int, error) {
+	feeHistory, err := gasEstimator.client.EthClient.FeeHistory(ctx, gasEstimator.feeOptions.FeeHistoryBlocks, blockNumber, []float64{gasEstimator.feeOptions.FeeHistoryPercentile})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get eth fee history: %v", err)
+	}
+
+	// The last base fee is the base fee of the block after the newest block
+	// in the history.
+	var baseFee *big.Int
+	if len(feeHistory.BaseFee) > 0 {
+		baseFee = feeHistory.BaseFee[len(feeHistory.BaseFee)-1]
+	}
+
+	rewards := make([]*big.Int, 0, len(feeHistory.Reward))
+	for _, r := range feeHistory.Reward {
+		if len(r) > 0 && r[0] != nil {
+			rewards = append(rewards, r[0])
+		}
+	}
+	if len(rewards) == 0 {
+		return baseFee, nil, nil
+	}
+	sort.Slice(rewards, func(i, j int) bool { return rewards[i].Cmp(rewards[j]) < 0 })
+	return baseFee, rewards[len(rewards)/2], nil
+}
+
+// EstimateTxFee returns the fee that will be paid by the transaction if it
+// uses all of its gas. For dynamic fee transactions, the gas price is the
+// base fee of the latest block plus the priority fee, up to the fee cap.
+func (gasEstimator *GasEstimator) EstimateTxFee(ctx context.Context, tx account.Tx) (pack.U256, error) {
+	ethTx, ok := tx.(*Tx)
+	if !ok {
+		return pack.U256{}, fmt.Errorf("expected type %T, got type %T", new(Tx), tx)
+	}
+	gasPrice := ethTx.EthTx.GasPrice()
+	if ethTx.EthTx.Type() == types.DynamicFeeTxType {
+		baseFee, err := gasEstimator.client.BaseFee(ctx)
+		if err != nil {
+			return pack.U256{}, err
+		}
+		gasPrice = ethTx.EthTx.GasFeeCap()
+		if baseFee != nil {
+			if effective := new(big.Int).Add(baseFee, ethTx.EthTx.GasTipCap()); effective.Cmp(gasPrice) < 0 {
+				gasPrice = effective
+			}
+		}
+	}
+	fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(ethTx.EthTx.Gas()))
+	return pack.NewU256FromInt(fee), nil
+}
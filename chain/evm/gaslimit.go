@@ -0,0 +1,120 @@
+package evm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultGasLimitMultiplier is the percentage of the estimated gas that is
+	// used as the gas limit by default. The extra gas protects against changes
+	// in state between the estimate and the execution of the transaction.
+	DefaultGasLimitMultiplier = 120
+)
+
+// GasLimitOptions are used to parameterise the estimation of gas limits.
+type GasLimitOptions struct {
+	// Multiplier is the percentage of the estimated gas that is used as the
+	// gas limit. Multipliers less than 100 are treated as 100.
+	Multiplier uint64
+	// AccessList enables the creation of EIP-2930 access lists, for nodes
+	// that support eth_createAccessList.
+	AccessList bool
+}
+
+// DefaultGasLimitOptions returns GasLimitOptions with the default settings.
+// Access lists are created by default.
+func DefaultGasLimitOptions() GasLimitOptions {
+	return GasLimitOptions{
+		Multiplier: DefaultGasLimitMultiplier,
+		AccessList: true,
+	}
+}
+
+// WithGasLimitMultiplier sets the percentage of the estimated gas that is used
+// as the gas limit.
+func (opts GasLimitOptions) WithGasLimitMultiplier(multiplier uint64) GasLimitOptions {
+	opts.Multiplier = multiplier
+	return opts
+}
+
+// WithAccessList sets whether access lists are created.
+func (opts GasLimitOptions) WithAccessList(accessList bool) GasLimitOptions {
+	opts.AccessList = accessList
+	return opts
+}
+
+// CreateAccessList returns the EIP-2930 access list of the storage that is
+// accessed by the call, and the gas used by the call when it uses the access
+// list. An error is returned if the node does not support
+// eth_createAccessList, or if the call reverts.
+func (client *Client) CreateAccessList(ctx context.Context, msg ethereum.CallMsg) (types.AccessList, uint64, error) {
+	if client.rpcClient == nil {
+		return nil, 0, fmt.Errorf("creating access list: rpc client is not available")
+	}
+	accessList, gasUsed, vmErr, err := gethclient.New(client.rpcClient).CreateAccessList(ctx, msg)
+	if err != nil {
+		return nil, 0, fmt.Errorf("creating access list: %v", err)
+	}
+	if vmErr != "" {
+		return nil, 0, fmt.Errorf("creating access list: execution reverted: %v", vmErr)
+	}
+	if accessList == nil {
+		return types.AccessList{}, gasUsed, nil
+	}
+	return *accessList, gasUsed, nil
+}
+
+// EstimateGasLimit returns the gas limit of a prospective transaction, and its
+// access list (if enabled by the options). The gas limit is the estimate
+// returned by eth_estimateGas, multiplied by the multiplier. The estimate is
+// done without the access list, which is an upper bound on the gas used with
+// the access list. If the access list cannot be created, or is empty, then a
+// nil access list is returned. An empty to address estimates the deployment of
+// a contract. The sender is the address of the public key, or the zero address
+// if the public key is nil.
+func (client *Client) EstimateGasLimit(ctx context.Context, fromPubKey *id.PubKey, to address.Address, value pack.U256, payload pack.Bytes, opts GasLimitOptions) (pack.U256, types.AccessList, error) {
+	toAddr, err := NewRecipient(to)
+	if err != nil {
+		return pack.U256{}, nil, err
+	}
+	msg := ethereum.CallMsg{
+		To:    toAddr,
+		Value: value.Int(),
+		Data:  payload,
+	}
+	if fromPubKey != nil {
+		msg.From = crypto.PubkeyToAddress(ecdsa.PublicKey(*fromPubKey))
+	}
+
+	gas, err := client.EthClient.EstimateGas(ctx, msg)
+	if err != nil {
+		return pack.U256{}, nil, fmt.Errorf("estimating gas: %v", err)
+	}
+	multiplier := opts.Multiplier
+	if multiplier < 100 {
+		multiplier = 100
+	}
+	gasLimit := new(big.Int).Mul(new(big.Int).SetUint64(gas), new(big.Int).SetUint64(multiplier))
+	gasLimit.Div(gasLimit, big.NewInt(100))
+
+	var accessList types.AccessList
+	if opts.AccessList {
+		// Nodes that do not support access lists are expected, so errors are
+		// ignored and the transaction is built without an access list.
+		if list, _, err := client.CreateAccessList(ctx, msg); err == nil && len(list) > 0 {
+			accessList = list
+		}
+	}
+	return pack.NewU256FromInt(gasLimit), accessList, nil
+}
@@ -0,0 +1,87 @@
+package evm_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/multichain/chain/evm/evmtest"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// gasLimitNode returns a fake node that responds to the methods used to
+// estimate gas limits. Access lists are only supported if the storage key is
+// not empty.
+func gasLimitNode(gas uint64, storageKey string) *evmtest.Node {
+	node := evmtest.NewNode().Result("eth_estimateGas", evmtest.Quantity(gas))
+	if storageKey != "" {
+		node.Result("eth_createAccessList", map[string]interface{}{
+			"accessList": []map[string]interface{}{{
+				"address":     "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+				"storageKeys": []string{storageKey},
+			}},
+			"gasUsed": evmtest.Quantity(gas),
+		})
+	}
+	return node
+}
+
+var _ = Describe("Gas limits", func() {
+	ctx := context.Background()
+	chainID := big.NewInt(1337)
+	to := address.Address("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+	storageKey := "0x" + strings.Repeat("00", 31) + "01"
+
+	build := func(txBuilder evm.TxBuilder, gasLimit uint64) *types.Transaction {
+		tx, err := txBuilder.BuildTx(ctx, nil, to, pack.NewU256FromUint64(1), pack.NewU256FromUint64(0), pack.NewU256FromUint64(gasLimit), pack.NewU256FromUint64(2), pack.NewU256FromUint64(30), pack.Bytes{1, 2, 3, 4})
+		Expect(err).ToNot(HaveOccurred())
+		return tx.(*evm.Tx).EthTx
+	}
+
+	Context("when building transactions without a gas limit", func() {
+		It("should estimate the gas limit and attach the access list", func() {
+			client, closeServer := dial(gasLimitNode(50000, storageKey))
+			defer closeServer()
+
+			tx := build(evm.NewTxBuilder(chainID).WithGasLimitOptions(client, evm.DefaultGasLimitOptions()), 0)
+			Expect(tx.Type()).To(Equal(uint8(types.AccessListTxType)))
+			Expect(tx.Gas()).To(Equal(uint64(60000)))
+			Expect(tx.AccessList()).To(Equal(types.AccessList{{
+				Address:     common.HexToAddress(string(to)),
+				StorageKeys: []common.Hash{common.HexToHash(storageKey)},
+			}}))
+
+			tx = build(evm.NewTxBuilder(chainID).WithFeeOptions(client, evm.DefaultFeeOptions().WithFeeMarket(evm.FeeMarketDynamic)).WithGasLimitOptions(client, evm.DefaultGasLimitOptions().WithGasLimitMultiplier(150)), 0)
+			Expect(tx.Type()).To(Equal(uint8(types.DynamicFeeTxType)))
+			Expect(tx.Gas()).To(Equal(uint64(75000)))
+			Expect(tx.AccessList()).To(HaveLen(1))
+		})
+
+		It("should not attach access lists if they are not supported", func() {
+			client, closeServer := dial(gasLimitNode(50000, ""))
+			defer closeServer()
+
+			tx := build(evm.NewTxBuilder(chainID).WithGasLimitOptions(client, evm.DefaultGasLimitOptions()), 0)
+			Expect(tx.Type()).To(Equal(uint8(types.LegacyTxType)))
+			Expect(tx.Gas()).To(Equal(uint64(60000)))
+		})
+	})
+
+	Context("when building transactions with a gas limit", func() {
+		It("should use the gas limit", func() {
+			client, closeServer := dial(gasLimitNode(50000, storageKey))
+			defer closeServer()
+
+			tx := build(evm.NewTxBuilder(chainID).WithGasLimitOptions(client, evm.DefaultGasLimitOptions()), 21000)
+			Expect(tx.Type()).To(Equal(uint8(types.LegacyTxType)))
+			Expect(tx.Gas()).To(Equal(uint64(21000)))
+		})
+	})
+})
@@ -0,0 +1,205 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+)
+
+// FilterLogs returns the logs that match the filter. Removed logs are ignored.
+// A to block of zero is the latest block. The filter is sent to the node in a
+// single request, so filters over large block ranges should be done using
+// contract.NewLogFilterer, which splits the range into chunks and waits for
+// confirmations.
+func (client *Client) FilterLogs(ctx context.Context, filter contract.LogFilter) ([]contract.Log, error) {
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(filter.FromBlock.Uint64()),
+		Addresses: make([]common.Address, len(filter.Addresses)),
+		Topics:    make([][]common.Hash, len(filter.Topics)),
+	}
+	if filter.ToBlock != 0 {
+		query.ToBlock = new(big.Int).SetUint64(filter.ToBlock.Uint64())
+	}
+	for i, addr := range filter.Addresses {
+		ethAddr, err := NewAddressFromHex(string(pack.String(addr)))
+		if err != nil {
+			return nil, fmt.Errorf("bad address '%v': %v", addr, err)
+		}
+		query.Addresses[i] = common.Address(ethAddr)
+	}
+	for i, topics := range filter.Topics {
+		for _, topic := range topics {
+			if len(topic) != common.HashLength {
+				return nil, fmt.Errorf("bad topic %v: expected %v bytes, got %v bytes", topic, common.HashLength, len(topic))
+			}
+			query.Topics[i] = append(query.Topics[i], common.BytesToHash(topic))
+		}
+	}
+
+	logs, err := client.EthClient.FilterLogs(ctx, query)
+	if err != nil {
+		if isLogRangeTooLarge(err) {
+			return nil, fmt.Errorf("filtering logs: %v: %w", err, contract.ErrLogRangeTooLarge)
+		}
+		return nil, fmt.Errorf("filtering logs: %v", err)
+	}
+	result := make([]contract.Log, 0, len(logs))
+	for _, log := range logs {
+		if log.Removed {
+			continue
+		}
+		result = append(result, NewLog(log))
+	}
+	return result, nil
+}
+
+// logRangeTooLargeMessages are the messages used by nodes and providers when
+// they reject a log filter because its range is too large, or because it
+// matches too many logs.
+var logRangeTooLargeMessages = []string{
+	"query returned more than",
+	"response size exceeded",
+	"block range",
+	"range too large",
+	"too many logs",
+}
+
+// isLogRangeTooLarge returns true if the node rejected a log filter because
+// its range is too large. The error code cannot be used, because providers
+// use the limit exceeded code of EIP-1474 for rate limits too.
+func isLogRangeTooLarge(err error) bool {
+	rpcErr, ok := err.(rpc.Error)
+	if !ok {
+		return false
+	}
+	message := strings.ToLower(rpcErr.Error())
+	for _, tooLarge := range logRangeTooLargeMessages {
+		if strings.Contains(message, tooLarge) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewLog converts an Ethereum log into a contract log.
+func NewLog(log types.Log) contract.Log {
+	topics := make([]pack.Bytes, len(log.Topics))
+	for i, topic := range log.Topics {
+		topics[i] = pack.NewBytes(topic.Bytes())
+	}
+	return contract.Log{
+		Address:     address.Address(log.Address.Hex()),
+		Topics:      topics,
+		Data:        pack.NewBytes(log.Data),
+		BlockNumber: pack.NewU64(log.BlockNumber),
+		BlockHash:   pack.NewBytes(log.BlockHash.Bytes()),
+		TxHash:      pack.NewBytes(log.TxHash.Bytes()),
+		Index:       pack.NewU32(uint32(log.Index)),
+	}
+}
+
+// A DecodedLog is a log that has been decoded using the ABI of the contract
+// that emitted it. Args maps the names of the event arguments to their values.
+// Indexed arguments of dynamic types (strings, bytes, arrays, and tuples) are
+// only stored as their hash in the log, so their values are the hash.
+type DecodedLog struct {
+	Name string
+	Args map[string]interface{}
+}
+
+// A LogDecoder decodes the logs emitted by a contract, using the ABI of the
+// contract.
+type LogDecoder struct {
+	abi abi.ABI
+}
+
+// NewLogDecoder returns a LogDecoder for the contract with the given ABI, as
+// JSON.
+func NewLogDecoder(abiJSON string) (LogDecoder, error) {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return LogDecoder{}, fmt.Errorf("parsing abi: %v", err)
+	}
+	return LogDecoder{abi: parsed}, nil
+}
+
+// Decode the log into the name and arguments of its event. An error is
+// returned if the event is not in the ABI. Anonymous events cannot be decoded.
+func (decoder LogDecoder) Decode(log contract.Log) (DecodedLog, error) {
+	event, topics, err := decoder.event(log)
+	if err != nil {
+		return DecodedLog{}, err
+	}
+	args := map[string]interface{}{}
+	if err := event.Inputs.NonIndexed().UnpackIntoMap(args, log.Data); err != nil {
+		return DecodedLog{}, fmt.Errorf("unpacking %v data: %v", event.Name, err)
+	}
+	if err := abi.ParseTopicsIntoMap(args, indexed(event.Inputs), topics); err != nil {
+		return DecodedLog{}, fmt.Errorf("parsing %v topics: %v", event.Name, err)
+	}
+	return DecodedLog{Name: event.Name, Args: args}, nil
+}
+
+// DecodeInto decodes the arguments of the log into a pointer to a struct. The
+// fields of the struct are matched to the arguments by name, or by their "abi"
+// tag. An error is returned if the log is not an event with the given name.
+func (decoder LogDecoder) DecodeInto(log contract.Log, name string, out interface{}) error {
+	event, topics, err := decoder.event(log)
+	if err != nil {
+		return err
+	}
+	if event.Name != name {
+		return fmt.Errorf("bad event: expected %v, got %v", name, event.Name)
+	}
+	values, err := event.Inputs.Unpack(log.Data)
+	if err != nil {
+		return fmt.Errorf("unpacking %v data: %v", event.Name, err)
+	}
+	if err := event.Inputs.Copy(out, values); err != nil {
+		return fmt.Errorf("copying %v data: %v", event.Name, err)
+	}
+	if err := abi.ParseTopics(out, indexed(event.Inputs), topics); err != nil {
+		return fmt.Errorf("parsing %v topics: %v", event.Name, err)
+	}
+	return nil
+}
+
+// event returns the event of the log, and the topics of its indexed arguments.
+func (decoder LogDecoder) event(log contract.Log) (*abi.Event, []common.Hash, error) {
+	if len(log.Topics) == 0 {
+		return nil, nil, fmt.Errorf("bad log: expected event topic")
+	}
+	topics := make([]common.Hash, len(log.Topics))
+	for i, topic := range log.Topics {
+		if len(topic) != common.HashLength {
+			return nil, nil, fmt.Errorf("bad topic %v: expected %v bytes, got %v bytes", topic, common.HashLength, len(topic))
+		}
+		topics[i] = common.BytesToHash(topic)
+	}
+	event, err := decoder.abi.EventByID(topics[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown event %v: %v", topics[0].Hex(), err)
+	}
+	return event, topics[1:], nil
+}
+
+// indexed returns the indexed arguments.
+func indexed(args abi.Arguments) abi.Arguments {
+	indexedArgs := abi.Arguments{}
+	for _, arg := range args {
+		if arg.Indexed {
+			indexedArgs = append(indexedArgs, arg)
+		}
+	}
+	return indexedArgs
+}
@@ -0,0 +1,126 @@
+package evm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/multichain/chain/evm/evmtest"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+const logABI = `[
+	{"anonymous":false,"name":"Transfer","type":"event","inputs":[
+		{"indexed":true,"name":"from","type":"address"},
+		{"indexed":true,"name":"to","type":"address"},
+		{"indexed":false,"name":"value","type":"uint256"}
+	]},
+	{"anonymous":false,"name":"Paused","type":"event","inputs":[]}
+]`
+
+// transfer is the struct that Transfer events are decoded into.
+type transfer struct {
+	From  common.Address
+	To    common.Address
+	Value *big.Int
+}
+
+var _ = Describe("Logs", func() {
+	from := common.HexToAddress("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+	to := common.HexToAddress("0xAb8483F64d9C6d1EcF9b849Ae677dD3315835cb2")
+	log := evm.NewLog(types.Log{
+		Address:     common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"),
+		Topics:      []common.Hash{evm.TransferEventSignature, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:        common.LeftPadBytes(big.NewInt(1000).Bytes(), 32),
+		BlockNumber: 10,
+		Index:       2,
+	})
+
+	Context("when converting logs", func() {
+		It("should keep the topics, data and position", func() {
+			Expect(log.Topics).To(HaveLen(3))
+			Expect([]byte(log.Topics[0])).To(Equal(evm.TransferEventSignature.Bytes()))
+			Expect(log.BlockNumber).To(Equal(pack.NewU64(10)))
+			Expect(log.Index).To(Equal(pack.NewU32(2)))
+		})
+	})
+
+	Context("when decoding logs using an abi", func() {
+		It("should decode the arguments into a map", func() {
+			decoder, err := evm.NewLogDecoder(logABI)
+			Expect(err).ToNot(HaveOccurred())
+
+			decoded, err := decoder.Decode(log)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded.Name).To(Equal("Transfer"))
+			Expect(decoded.Args["from"]).To(Equal(from))
+			Expect(decoded.Args["to"]).To(Equal(to))
+			Expect(decoded.Args["value"].(*big.Int).Cmp(big.NewInt(1000))).To(Equal(0))
+		})
+
+		It("should decode the arguments into a struct", func() {
+			decoder, err := evm.NewLogDecoder(logABI)
+			Expect(err).ToNot(HaveOccurred())
+
+			var out transfer
+			Expect(decoder.DecodeInto(log, "Transfer", &out)).To(Succeed())
+			Expect(out.From).To(Equal(from))
+			Expect(out.To).To(Equal(to))
+			Expect(out.Value.Cmp(big.NewInt(1000))).To(Equal(0))
+
+			Expect(decoder.DecodeInto(log, "Paused", &out)).ToNot(Succeed())
+		})
+
+		It("should return an error for unknown events", func() {
+			decoder, err := evm.NewLogDecoder(logABI)
+			Expect(err).ToNot(HaveOccurred())
+
+			unknown := contract.Log{Topics: []pack.Bytes{crypto.Keccak256([]byte("Unknown()"))}}
+			_, err = decoder.Decode(unknown)
+			Expect(err).To(HaveOccurred())
+			_, err = decoder.Decode(contract.Log{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the node rejects a filter", func() {
+		filterLogs := func(rpcErr *evmtest.Error) error {
+			node := evmtest.NewNode().Handle("eth_getLogs", func([]json.RawMessage) (interface{}, error) {
+				return nil, rpcErr
+			})
+			client, done := dial(node)
+			defer done()
+			_, err := client.FilterLogs(context.Background(), contract.LogFilter{FromBlock: 1, ToBlock: 100000})
+			Expect(err).To(HaveOccurred())
+			return err
+		}
+
+		DescribeTable("should only report errors about the size of the range as too large",
+			func(rpcErr *evmtest.Error, tooLarge bool) {
+				Expect(errors.Is(filterLogs(rpcErr), contract.ErrLogRangeTooLarge)).To(Equal(tooLarge))
+			},
+			Entry("too many results", &evmtest.Error{Code: -32005, Message: "query returned more than 10000 results"}, true),
+			Entry("response too large", &evmtest.Error{Code: -32602, Message: "Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"}, true),
+			Entry("block range too wide", &evmtest.Error{Code: -32000, Message: "exceed maximum block range: 5000"}, true),
+			Entry("missing header", &evmtest.Error{Code: -32000, Message: "header not found"}, false),
+			Entry("rate limited", &evmtest.Error{Code: -32005, Message: "daily request count exceeded, request rate limited"}, false),
+		)
+	})
+
+	Context("when parsing a bad abi", func() {
+		It("should return an error", func() {
+			_, err := evm.NewLogDecoder("{")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,415 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultMulticallAddress is the address of the Multicall3 contract, which
+	// is deployed at the same address on most chains. Local nodes (for
+	// example, hardhat) must deploy the contract themselves, and configure its
+	// address using WithAddress.
+	DefaultMulticallAddress = address.Address("0xcA11bde05977b3631167028862bE2a173976CA11")
+	// DefaultMulticallBatchSize is the largest number of calls that are
+	// aggregated into one request by default.
+	DefaultMulticallBatchSize = 500
+	// DefaultMulticallBatchInterval is the duration for which calls to
+	// CallContract are collected before they are aggregated, by default.
+	DefaultMulticallBatchInterval = 10 * time.Millisecond
+	// DefaultMulticallTimeout is the longest duration for which calls to
+	// CallContract are aggregated, by default.
+	DefaultMulticallTimeout = 30 * time.Second
+)
+
+var (
+	// aggregate3Selector is the selector of the aggregate3 function of the
+	// Multicall3 contract.
+	aggregate3Selector = crypto.Keccak256([]byte("aggregate3((address,bool,bytes)[])"))[:4]
+
+	// errMulticallNotDeployed is returned when a call to the Multicall3
+	// contract does not return any output, because the contract was not
+	// deployed at the block of the call.
+	errMulticallNotDeployed = errors.New("multicall contract is not deployed")
+)
+
+// MulticallerOptions are used to parameterise the behaviour of the
+// Multicaller.
+type MulticallerOptions struct {
+	// Address of the Multicall3 contract. If no contract is deployed at the
+	// address, calls are sent as a JSON-RPC batch instead.
+	Address address.Address
+	// BatchSize is the largest number of calls aggregated into one request.
+	BatchSize int
+	// BatchInterval is the duration for which calls to CallContract are
+	// collected before they are aggregated.
+	BatchInterval time.Duration
+	// Timeout is the longest duration for which calls to CallContract are
+	// aggregated. The calls are also cancelled once all of their callers
+	// have stopped waiting for them.
+	Timeout time.Duration
+}
+
+// DefaultMulticallerOptions returns MulticallerOptions with the default
+// settings.
+func DefaultMulticallerOptions() MulticallerOptions {
+	return MulticallerOptions{
+		Address:       DefaultMulticallAddress,
+		BatchSize:     DefaultMulticallBatchSize,
+		BatchInterval: DefaultMulticallBatchInterval,
+		Timeout:       DefaultMulticallTimeout,
+	}
+}
+
+// WithAddress sets the address of the Multicall3 contract.
+func (opts MulticallerOptions) WithAddress(addr address.Address) MulticallerOptions {
+	opts.Address = addr
+	return opts
+}
+
+// WithBatchSize sets the largest number of calls aggregated into one request.
+func (opts MulticallerOptions) WithBatchSize(batchSize int) MulticallerOptions {
+	opts.BatchSize = batchSize
+	return opts
+}
+
+// WithBatchInterval sets the duration for which calls to CallContract are
+// collected before they are aggregated.
+func (opts MulticallerOptions) WithBatchInterval(batchInterval time.Duration) MulticallerOptions {
+	opts.BatchInterval = batchInterval
+	return opts
+}
+
+// WithTimeout sets the longest duration for which calls to CallContract are
+// aggregated.
+func (opts MulticallerOptions) WithTimeout(timeout time.Duration) MulticallerOptions {
+	opts.Timeout = timeout
+	return opts
+}
+
+// A Call is a readonly call to a contract.
+type Call struct {
+	Target   address.Address
+	CallData contract.CallData
+}
+
+// A CallResult is the outcome of a Call. If the call reverted, success is
+// false and the return data is the revert data (if any). Err is set if the
+// call could not be made, or reverted without returning data.
+type CallResult struct {
+	Success    bool
+	ReturnData pack.Bytes
+	Err        error
+}
+
+// multicall3Call is the Call3 struct of the Multicall3 contract.
+type multicall3Call struct {
+	Target       Address
+	AllowFailure bool
+	CallData     pack.Bytes
+}
+
+// multicall3Result is the Result struct of the Multicall3 contract.
+type multicall3Result struct {
+	Success    bool
+	ReturnData pack.Bytes
+}
+
+// pendingCall is a call to CallContract that has not been aggregated.
+type pendingCall struct {
+	ctx    context.Context
+	call   Call
+	result chan CallResult
+}
+
+// A Multicaller aggregates readonly calls to contracts into a small number of
+// requests, using the Multicall3 contract. If the contract is not deployed,
+// calls are sent as a JSON-RPC batch instead (or one at a time, if the client
+// was not created with an RPC client). The Multicaller implements the
+// contract.Caller interface: concurrent calls to CallContract are collected
+// for the batch interval, and then aggregated. It is safe for concurrent use.
+type Multicaller struct {
+	opts   MulticallerOptions
+	client *Client
+
+	mu       *sync.Mutex
+	deployed *bool
+	pending  []pendingCall
+}
+
+// NewMulticaller returns a Multicaller that uses the client to make calls.
+func NewMulticaller(client *Client, opts MulticallerOptions) *Multicaller {
+	return &Multicaller{
+		opts:   opts,
+		client: client,
+
+		mu: new(sync.Mutex),
+	}
+}
+
+// CallContract implements the contract.Caller interface. The call is
+// aggregated with other concurrent calls. An error is returned if the call
+// reverts.
+func (multicaller *Multicaller) CallContract(ctx context.Context, program address.Address, calldata contract.CallData) (pack.Bytes, error) {
+	pending := pendingCall{
+		ctx:    ctx,
+		call:   Call{Target: program, CallData: calldata},
+		result: make(chan CallResult, 1),
+	}
+
+	multicaller.mu.Lock()
+	multicaller.pending = append(multicaller.pending, pending)
+	if len(multicaller.pending) == 1 {
+		time.AfterFunc(multicaller.opts.BatchInterval, multicaller.flush)
+	}
+	if len(multicaller.pending) >= multicaller.batchSize() {
+		batch := multicaller.pending
+		multicaller.pending = nil
+		go multicaller.run(batch)
+	}
+	multicaller.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-pending.result:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		if !result.Success {
+			return nil, fmt.Errorf("call to %v reverted: %v", program, hexutil.Encode(result.ReturnData))
+		}
+		return result.ReturnData, nil
+	}
+}
+
+// Aggregate the calls, and return their results in the same order. Calls that
+// revert do not cause an error to be returned, and must be checked using the
+// success of their result.
+func (multicaller *Multicaller) Aggregate(ctx context.Context, calls []Call) ([]CallResult, error) {
+	return multicaller.AggregateAt(ctx, calls, contract.LatestBlock())
+}
+
+// AggregateAt is the same as Aggregate, but reads the state of the chain at
+// the given block. If the Multicall3 contract was deployed after the block,
+// the calls are sent as a JSON-RPC batch instead.
+func (multicaller *Multicaller) AggregateAt(ctx context.Context, calls []Call, block contract.BlockRef) ([]CallResult, error) {
+	deployed, err := multicaller.isDeployed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]CallResult, 0, len(calls))
+	batchSize := multicaller.batchSize()
+	for begin := 0; begin < len(calls); begin += batchSize {
+		end := begin + batchSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+		var batchResults []CallResult
+		if deployed {
+			batchResults, err = multicaller.aggregate3(ctx, calls[begin:end], block)
+			if errors.Is(err, errMulticallNotDeployed) {
+				batchResults, err = multicaller.batch(ctx, calls[begin:end], block)
+			}
+		} else {
+			batchResults, err = multicaller.batch(ctx, calls[begin:end], block)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batchResults...)
+	}
+	return results, nil
+}
+
+// flush aggregates the pending calls.
+func (multicaller *Multicaller) flush() {
+	multicaller.mu.Lock()
+	batch := multicaller.pending
+	multicaller.pending = nil
+	multicaller.mu.Unlock()
+
+	if len(batch) > 0 {
+		multicaller.run(batch)
+	}
+}
+
+// run aggregates the calls, and sends each result to its caller. The calls
+// are cancelled after the timeout, or once every caller has stopped waiting.
+func (multicaller *Multicaller) run(batch []pendingCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), multicaller.timeout())
+	defer cancel()
+	go func() {
+		for _, pending := range batch {
+			select {
+			case <-pending.ctx.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		cancel()
+	}()
+
+	calls := make([]Call, len(batch))
+	for i, pending := range batch {
+		calls[i] = pending.call
+	}
+	results, err := multicaller.Aggregate(ctx, calls)
+	for i, pending := range batch {
+		if err != nil {
+			pending.result <- CallResult{Err: err}
+			continue
+		}
+		pending.result <- results[i]
+	}
+}
+
+// isDeployed returns true if the Multicall3 contract is deployed at the latest
+// block. The result is cached once it is known. Calls at earlier blocks must
+// still check that the contract was deployed (see errMulticallNotDeployed).
+func (multicaller *Multicaller) isDeployed(ctx context.Context) (bool, error) {
+	multicaller.mu.Lock()
+	deployed := multicaller.deployed
+	multicaller.mu.Unlock()
+	if deployed != nil {
+		return *deployed, nil
+	}
+
+	addr, err := NewAddressFromHex(string(pack.String(multicaller.opts.Address)))
+	if err != nil {
+		return false, fmt.Errorf("bad multicall address '%v': %v", multicaller.opts.Address, err)
+	}
+	code, err := multicaller.client.EthClient.CodeAt(ctx, common.Address(addr), nil)
+	if err != nil {
+		return false, fmt.Errorf("fetching multicall code: %v", err)
+	}
+	isDeployed := len(code) > 0
+
+	multicaller.mu.Lock()
+	multicaller.deployed = &isDeployed
+	multicaller.mu.Unlock()
+	return isDeployed, nil
+}
+
+// aggregate3 makes the calls using the aggregate3 function of the Multicall3
+// contract, allowing all calls to fail.
+func (multicaller *Multicaller) aggregate3(ctx context.Context, calls []Call, block contract.BlockRef) ([]CallResult, error) {
+	args := make([]multicall3Call, len(calls))
+	for i, call := range calls {
+		target, err := NewAddressFromHex(string(pack.String(call.Target)))
+		if err != nil {
+			return nil, fmt.Errorf("bad target address '%v': %v", call.Target, err)
+		}
+		args[i] = multicall3Call{
+			Target:       target,
+			AllowFailure: true,
+			CallData:     pack.Bytes(call.CallData),
+		}
+	}
+	encoded, err := EncodeArgs(args)
+	if err != nil {
+		return nil, fmt.Errorf("encoding calls: %v", err)
+	}
+	calldata := append(append([]byte{}, aggregate3Selector...), encoded...)
+
+	output, err := multicaller.client.CallContractAt(ctx, multicaller.opts.Address, calldata, block)
+	if err != nil {
+		return nil, fmt.Errorf("calling multicall: %v", err)
+	}
+	if len(output) == 0 {
+		return nil, errMulticallNotDeployed
+	}
+	var returned []multicall3Result
+	if err := Decode(output, &returned); err != nil {
+		return nil, fmt.Errorf("decoding multicall results: %v", err)
+	}
+	if len(returned) != len(calls) {
+		return nil, fmt.Errorf("bad multicall results: expected %v results, got %v results", len(calls), len(returned))
+	}
+	results := make([]CallResult, len(returned))
+	for i, result := range returned {
+		results[i] = CallResult{Success: result.Success, ReturnData: result.ReturnData}
+	}
+	return results, nil
+}
+
+// batch makes the calls using a JSON-RPC batch. If the client was not created
+// with an RPC client, the calls are made one at a time.
+func (multicaller *Multicaller) batch(ctx context.Context, calls []Call, block contract.BlockRef) ([]CallResult, error) {
+	results := make([]CallResult, len(calls))
+	if multicaller.client.rpcClient == nil {
+		for i, call := range calls {
+			output, err := multicaller.client.CallContractAt(ctx, call.Target, call.CallData, block)
+			if err != nil {
+				results[i] = CallResult{Err: err}
+				continue
+			}
+			results[i] = CallResult{Success: true, ReturnData: output}
+		}
+		return results, nil
+	}
+
+	blockArg, err := blockNumberString(block)
+	if err != nil {
+		return nil, err
+	}
+	elems := make([]rpc.BatchElem, len(calls))
+	outputs := make([]hexutil.Bytes, len(calls))
+	for i, call := range calls {
+		target, err := NewAddressFromHex(string(pack.String(call.Target)))
+		if err != nil {
+			return nil, fmt.Errorf("bad target address '%v': %v", call.Target, err)
+		}
+		elems[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args: []interface{}{
+				map[string]interface{}{
+					"to":   common.Address(target),
+					"data": hexutil.Bytes(call.CallData),
+				},
+				blockArg,
+			},
+			Result: &outputs[i],
+		}
+	}
+	if err := multicaller.client.rpcClient.BatchCallContext(ctx, elems); err != nil {
+		return nil, fmt.Errorf("sending batch: %v", err)
+	}
+	for i, elem := range elems {
+		if elem.Error != nil {
+			results[i] = CallResult{Err: elem.Error}
+			continue
+		}
+		results[i] = CallResult{Success: true, ReturnData: pack.Bytes(outputs[i])}
+	}
+	return results, nil
+}
+
+// timeout returns the timeout, or the default timeout if it is not positive.
+func (multicaller *Multicaller) timeout() time.Duration {
+	if multicaller.opts.Timeout <= 0 {
+		return DefaultMulticallTimeout
+	}
+	return multicaller.opts.Timeout
+}
+
+// batchSize returns the batch size, or the default batch size if it is not
+// positive.
+func (multicaller *Multicaller) batchSize() int {
+	if multicaller.opts.BatchSize <= 0 {
+		return DefaultMulticallBatchSize
+	}
+	return multicaller.opts.BatchSize
+}
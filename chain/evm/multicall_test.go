@@ -0,0 +1,193 @@
+package evm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/multichain/chain/evm/evmtest"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// multicallTarget is the Call3 struct of the Multicall3 contract.
+type multicallTarget struct {
+	Target       evm.Address
+	AllowFailure bool
+	CallData     pack.Bytes
+}
+
+// multicallResult is the Result struct of the Multicall3 contract.
+type multicallResult struct {
+	Success    bool
+	ReturnData pack.Bytes
+}
+
+// multicallDeployedAt is the block at which the fake node deploys the
+// Multicall3 contract, when it is deployed.
+const multicallDeployedAt = 100
+
+// multicallNode returns a fake node with contracts that return their
+// calldata, and revert if the calldata is 0xff. The Multicall3 contract is
+// only deployed if deployed is true, and calls to it before the block at
+// which it was deployed do not return any output.
+func multicallNode(deployed bool) *evmtest.Node {
+	code := "0x"
+	if deployed {
+		code = "0x01"
+	}
+	return evmtest.NewNode().
+		Result("eth_getCode", code).
+		Handle("eth_call", func(params []json.RawMessage) (interface{}, error) {
+			msg, err := evmtest.DecodeCallMsg(params)
+			if err != nil {
+				return nil, err
+			}
+			calldata := msg.CallData()
+			if strings.EqualFold(msg.To, string(evm.DefaultMulticallAddress)) {
+				var block string
+				if err := json.Unmarshal(params[1], &block); err != nil {
+					return nil, err
+				}
+				if height, err := hexutil.DecodeUint64(block); err == nil && height < multicallDeployedAt {
+					return "0x", nil
+				}
+				targets := []multicallTarget{}
+				if err := evm.Decode(calldata[4:], &targets); err != nil {
+					return nil, err
+				}
+				results := make([]multicallResult, len(targets))
+				for i, target := range targets {
+					results[i] = multicallResult{Success: !bytes.Equal(target.CallData, []byte{0xff}), ReturnData: target.CallData}
+				}
+				encoded, err := evm.EncodeArgs(results)
+				if err != nil {
+					return nil, err
+				}
+				return hexutil.Encode(encoded), nil
+			}
+			if bytes.Equal(calldata, []byte{0xff}) {
+				return nil, evmtest.Revert("execution reverted", calldata)
+			}
+			return hexutil.Encode(calldata), nil
+		})
+}
+
+var _ = Describe("Multicall", func() {
+	ctx := context.Background()
+	target := address.Address("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+	calls := []evm.Call{
+		{Target: target, CallData: contract.CallData{1, 2, 3}},
+		{Target: target, CallData: contract.CallData{0xff}},
+		{Target: target, CallData: contract.CallData{4}},
+	}
+
+	check := func(results []evm.CallResult) {
+		Expect(results).To(HaveLen(3))
+		Expect(results[0].Success).To(BeTrue())
+		Expect([]byte(results[0].ReturnData)).To(Equal([]byte{1, 2, 3}))
+		Expect(results[1].Success).To(BeFalse())
+		Expect(results[2].Success).To(BeTrue())
+		Expect([]byte(results[2].ReturnData)).To(Equal([]byte{4}))
+	}
+
+	Context("when the multicall contract is deployed", func() {
+		It("should aggregate calls into batches", func() {
+			node := multicallNode(true)
+			client, closeServer := dial(node)
+			defer closeServer()
+
+			multicaller := evm.NewMulticaller(client, evm.DefaultMulticallerOptions().WithBatchSize(2))
+			results, err := multicaller.Aggregate(ctx, calls)
+			Expect(err).ToNot(HaveOccurred())
+			check(results)
+			Expect(len(node.Requests("eth_call"))).To(Equal(2))
+		})
+
+		It("should aggregate concurrent calls to CallContract", func() {
+			node := multicallNode(true)
+			client, closeServer := dial(node)
+			defer closeServer()
+
+			multicaller := evm.NewMulticaller(client, evm.DefaultMulticallerOptions().WithBatchInterval(50*time.Millisecond))
+			wg := new(sync.WaitGroup)
+			outputs := make([]pack.Bytes, 10)
+			errs := make([]error, 10)
+			for i := range outputs {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					outputs[i], errs[i] = multicaller.CallContract(ctx, target, contract.CallData{byte(i)})
+				}(i)
+			}
+			wg.Wait()
+			for i := range outputs {
+				Expect(errs[i]).ToNot(HaveOccurred())
+				Expect([]byte(outputs[i])).To(Equal([]byte{byte(i)}))
+			}
+			Expect(len(node.Requests("eth_call"))).To(Equal(1))
+
+			_, err := multicaller.CallContract(ctx, target, contract.CallData{0xff})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should send the calls as a JSON-RPC batch at blocks before the contract was deployed", func() {
+			node := multicallNode(true)
+			client, closeServer := dial(node)
+			defer closeServer()
+
+			multicaller := evm.NewMulticaller(client, evm.DefaultMulticallerOptions())
+			results, err := multicaller.AggregateAt(ctx, calls, contract.BlockAtHeight(multicallDeployedAt-1))
+			Expect(err).ToNot(HaveOccurred())
+			check(results)
+			Expect(results[1].Err).To(HaveOccurred())
+			Expect(len(node.Requests("eth_call"))).To(Equal(4))
+
+			results, err = multicaller.AggregateAt(ctx, calls, contract.BlockAtHeight(multicallDeployedAt))
+			Expect(err).ToNot(HaveOccurred())
+			check(results)
+			Expect(len(node.Requests("eth_call"))).To(Equal(5))
+		})
+
+		It("should stop aggregating calls to CallContract after the timeout", func() {
+			release := make(chan struct{})
+			node := multicallNode(true).Handle("eth_call", func([]json.RawMessage) (interface{}, error) {
+				<-release
+				return nil, fmt.Errorf("released")
+			})
+			client, closeServer := dial(node)
+			defer closeServer()
+			defer close(release)
+
+			multicaller := evm.NewMulticaller(client, evm.DefaultMulticallerOptions().WithTimeout(50*time.Millisecond))
+			_, err := multicaller.CallContract(ctx, target, contract.CallData{1})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(context.DeadlineExceeded.Error()))
+		})
+	})
+
+	Context("when the multicall contract is not deployed", func() {
+		It("should send the calls as a JSON-RPC batch", func() {
+			node := multicallNode(false)
+			client, closeServer := dial(node)
+			defer closeServer()
+
+			multicaller := evm.NewMulticaller(client, evm.DefaultMulticallerOptions())
+			results, err := multicaller.Aggregate(ctx, calls)
+			Expect(err).ToNot(HaveOccurred())
+			check(results)
+			Expect(results[1].Err).To(HaveOccurred())
+			Expect(len(node.Requests("eth_call"))).To(Equal(3))
+		})
+	})
+})
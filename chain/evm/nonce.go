@@ -0,0 +1,511 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultNonceManagerReservationTimeout used by the NonceManager.
+	DefaultNonceManagerReservationTimeout = 5 * time.Minute
+)
+
+// NonceState is the state of the nonces of an address, as tracked by the
+// NonceManager. It is persisted in a NonceStore, so that nonces can be
+// recovered after restarts.
+type NonceState struct {
+	// Next is the next nonce that will be reserved, unless a released nonce
+	// can be reused.
+	Next uint64 `json:"next"`
+	// Reserved nonces that have not been submitted, and the time at which
+	// they were reserved.
+	Reserved map[uint64]time.Time `json:"reserved"`
+	// Released nonces that were reserved, but whose transactions failed to be
+	// submitted. They are reused by the next reservations, or filled by
+	// cancellation transactions.
+	Released []uint64 `json:"released"`
+	// Filling nonces are being filled by cancellation transactions. They are
+	// not reused. When the nonce state is loaded from the store, they are
+	// released, because the cancellation transactions might not have been
+	// submitted.
+	Filling []uint64 `json:"filling"`
+}
+
+// The NonceStore interface defines the functionality required to persist the
+// state of the nonces of addresses.
+type NonceStore interface {
+	// Get the nonce state of the address. If the address does not have a
+	// nonce state, then an empty state must be returned without an error.
+	Get(address.Address) (NonceState, error)
+
+	// Put the nonce state of the address.
+	Put(address.Address, NonceState) error
+}
+
+type memoryNonceStore struct {
+	mu     *sync.Mutex
+	states map[address.Address]NonceState
+}
+
+// NewMemoryNonceStore returns a NonceStore that keeps nonce states in memory.
+// Nonce states are lost when the process restarts, so it should only be used
+// for testing, or when nothing else sends transactions from the addresses.
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{
+		mu:     new(sync.Mutex),
+		states: map[address.Address]NonceState{},
+	}
+}
+
+func (store *memoryNonceStore) Get(addr address.Address) (NonceState, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return copyNonceState(store.states[addr]), nil
+}
+
+func (store *memoryNonceStore) Put(addr address.Address, state NonceState) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.states[addr] = copyNonceState(state)
+	return nil
+}
+
+// The PendingNonceClient interface is implemented by clients that can return
+// the nonce of an account, including the transactions in the mempool of the
+// node.
+type PendingNonceClient interface {
+	PendingNonce(context.Context, address.Address) (pack.U256, error)
+}
+
+// PendingNonce returns the nonce of the account, including the transactions in
+// the mempool of the node. Unlike AccountNonce, this is the nonce that must be
+// used to build a new transaction when other transactions from the account
+// are still pending.
+func (client *Client) PendingNonce(ctx context.Context, addr address.Address) (pack.U256, error) {
+	targetAddr, err := NewAddressFromHex(string(pack.String(addr)))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad to address '%v': %v", addr, err)
+	}
+	nonce, err := client.EthClient.PendingNonceAt(ctx, common.Address(targetAddr))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("failed to get pending nonce for '%v': %v", addr, err)
+	}
+	return pack.NewU256FromU64(pack.NewU64(nonce)), nil
+}
+
+// A CancelTxBuilder returns a signed transaction from the address that uses
+// the given nonce, but that otherwise does nothing (usually, by sending zero
+// value from the address to itself). It is used to fill gaps in the nonces of
+// the address.
+type CancelTxBuilder func(ctx context.Context, from address.Address, nonce pack.U256) (account.Tx, error)
+
+// NonceManagerOptions are used to parameterise the behaviour of the
+// NonceManager.
+type NonceManagerOptions struct {
+	// ReservationTimeout is the time after which a reserved nonce that has
+	// not been submitted is considered to be a gap.
+	ReservationTimeout time.Duration
+}
+
+// DefaultNonceManagerOptions returns NonceManagerOptions with the default
+// settings.
+func DefaultNonceManagerOptions() NonceManagerOptions {
+	return NonceManagerOptions{
+		ReservationTimeout: DefaultNonceManagerReservationTimeout,
+	}
+}
+
+// WithReservationTimeout sets the time after which a reserved nonce that has
+// not been submitted is considered to be a gap.
+func (opts NonceManagerOptions) WithReservationTimeout(reservationTimeout time.Duration) NonceManagerOptions {
+	opts.ReservationTimeout = reservationTimeout
+	return opts
+}
+
+// The NonceManager wraps an account.Client, so that concurrent senders can
+// use the same address without colliding. Nonces are reserved locally, on
+// top of the pending nonce reported by the chain, and reserved nonces that
+// are never submitted are reused, or filled with cancellation transactions
+// so that later transactions are not stuck behind a gap. It implements the
+// account.Client interface, and is safe for concurrent use.
+//
+// AccountNonce reserves a nonce, and SubmitTx marks the nonce of the
+// transaction as submitted (or releases it, if the transaction cannot be
+// submitted), so the NonceManager can be used in place of the client that it
+// wraps. Reserved nonces that are not going to be used should be released
+// using Release.
+type NonceManager struct {
+	account.Client
+
+	opts   NonceManagerOptions
+	store  NonceStore
+	cancel CancelTxBuilder
+
+	mu     *sync.Mutex
+	states map[address.Address]*NonceState
+}
+
+// NewNonceManager returns a NonceManager that wraps the client, and persists
+// the nonce states of addresses in the store. If the client implements the
+// PendingNonceClient interface, pending nonces are used. Otherwise, the nonces
+// returned by AccountNonce are used.
+func NewNonceManager(client account.Client, store NonceStore, cancel CancelTxBuilder, opts NonceManagerOptions) *NonceManager {
+	return &NonceManager{
+		Client: client,
+
+		opts:   opts,
+		store:  store,
+		cancel: cancel,
+
+		mu:     new(sync.Mutex),
+		states: map[address.Address]*NonceState{},
+	}
+}
+
+// AccountNonce reserves the next nonce for the address. It is the same as
+// Reserve.
+func (manager *NonceManager) AccountNonce(ctx context.Context, addr address.Address) (pack.U256, error) {
+	return manager.Reserve(ctx, addr)
+}
+
+// Reserve the next nonce for the address. Released nonces that have not been
+// used are reserved first. Otherwise, the next nonce is the larger of the
+// pending nonce reported by the chain, and the nonce after the last one that
+// was reserved.
+func (manager *NonceManager) Reserve(ctx context.Context, addr address.Address) (pack.U256, error) {
+	key, err := nonceKey(addr)
+	if err != nil {
+		return pack.U256{}, err
+	}
+	pendingNonce, err := manager.pendingNonce(ctx, key)
+	if err != nil {
+		return pack.U256{}, err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	state, err := manager.state(key)
+	if err != nil {
+		return pack.U256{}, err
+	}
+	prev := copyNonceState(*state)
+	prune(state, pendingNonce)
+
+	var nonce uint64
+	if len(state.Released) > 0 {
+		nonce = state.Released[0]
+		state.Released = state.Released[1:]
+	} else {
+		nonce = state.Next
+		if nonce < pendingNonce {
+			nonce = pendingNonce
+		}
+		state.Next = nonce + 1
+	}
+	state.Reserved[nonce] = time.Now()
+	if err := manager.store.Put(key, *state); err != nil {
+		*state = prev
+		return pack.U256{}, fmt.Errorf("storing nonce state for '%v': %v", key, err)
+	}
+	return pack.NewU256FromU64(pack.NewU64(nonce)), nil
+}
+
+// Release a reserved nonce that is not going to be used, so that it can be
+// reused by the next reservation.
+func (manager *NonceManager) Release(addr address.Address, nonce pack.U256) error {
+	key, err := nonceKey(addr)
+	if err != nil {
+		return err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	return manager.update(key, func(state *NonceState) {
+		release(state, nonce.Int().Uint64())
+	})
+}
+
+// SubmitTx to the underlying client. If the transaction is submitted, its
+// nonce is no longer reserved. Otherwise, its nonce is released so that it
+// can be reused.
+func (manager *NonceManager) SubmitTx(ctx context.Context, tx account.Tx) error {
+	submitErr := manager.Client.SubmitTx(ctx, tx)
+	key, err := nonceKey(tx.From())
+	if err != nil {
+		if submitErr != nil {
+			return submitErr
+		}
+		return err
+	}
+	nonce := tx.Nonce().Int().Uint64()
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	err = manager.update(key, func(state *NonceState) {
+		if submitErr != nil {
+			release(state, nonce)
+			return
+		}
+		delete(state.Reserved, nonce)
+	})
+	if submitErr != nil {
+		return submitErr
+	}
+	return err
+}
+
+// Gaps returns the nonces of the address that have been reserved, but that
+// have not been (and will likely never be) used. These are nonces that have
+// been released, and nonces that have been reserved for longer than the
+// reservation timeout without being submitted. Transactions with larger
+// nonces cannot be included in a block until the gaps are filled.
+func (manager *NonceManager) Gaps(ctx context.Context, addr address.Address) ([]pack.U256, error) {
+	key, err := nonceKey(addr)
+	if err != nil {
+		return nil, err
+	}
+	pendingNonce, err := manager.pendingNonce(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	gaps := []pack.U256{}
+	err = manager.update(key, func(state *NonceState) {
+		prune(state, pendingNonce)
+		for _, nonce := range manager.gaps(state) {
+			gaps = append(gaps, pack.NewU256FromU64(pack.NewU64(nonce)))
+		}
+	})
+	return gaps, err
+}
+
+// FillGaps submits a cancellation transaction for every gap in the nonces of
+// the address, and returns the hashes of the cancellation transactions. The
+// gaps are claimed before the cancellation transactions are built, so they
+// cannot be reserved by concurrent calls to Reserve. If a cancellation
+// transaction cannot be built or submitted, the remaining gaps are released
+// and an error is returned.
+func (manager *NonceManager) FillGaps(ctx context.Context, addr address.Address) ([]pack.Bytes, error) {
+	if manager.cancel == nil {
+		return nil, fmt.Errorf("filling gaps: no cancel tx builder")
+	}
+	key, err := nonceKey(addr)
+	if err != nil {
+		return nil, err
+	}
+	pendingNonce, err := manager.pendingNonce(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var gaps []uint64
+	manager.mu.Lock()
+	err = manager.update(key, func(state *NonceState) {
+		prune(state, pendingNonce)
+		gaps = manager.gaps(state)
+		for _, nonce := range gaps {
+			delete(state.Reserved, nonce)
+			state.Released = removeNonce(state.Released, nonce)
+			state.Filling = append(state.Filling, nonce)
+		}
+	})
+	manager.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	txHashes := make([]pack.Bytes, 0, len(gaps))
+	for i, nonce := range gaps {
+		txHash, err := manager.fillGap(ctx, key, nonce)
+		if err != nil {
+			manager.mu.Lock()
+			if releaseErr := manager.update(key, func(state *NonceState) {
+				for _, gap := range gaps[i:] {
+					state.Filling = removeNonce(state.Filling, gap)
+					release(state, gap)
+				}
+			}); releaseErr != nil {
+				err = fmt.Errorf("%v: %v", err, releaseErr)
+			}
+			manager.mu.Unlock()
+			return txHashes, err
+		}
+		txHashes = append(txHashes, txHash)
+
+		manager.mu.Lock()
+		err = manager.update(key, func(state *NonceState) {
+			state.Filling = removeNonce(state.Filling, nonce)
+		})
+		manager.mu.Unlock()
+		if err != nil {
+			return txHashes, err
+		}
+	}
+	return txHashes, nil
+}
+
+// fillGap builds and submits a cancellation transaction for the nonce, and
+// returns its hash.
+func (manager *NonceManager) fillGap(ctx context.Context, addr address.Address, nonce uint64) (pack.Bytes, error) {
+	tx, err := manager.cancel(ctx, addr, pack.NewU256FromU64(pack.NewU64(nonce)))
+	if err != nil {
+		return nil, fmt.Errorf("building cancel tx for nonce %v: %v", nonce, err)
+	}
+	if err := manager.Client.SubmitTx(ctx, tx); err != nil {
+		return nil, fmt.Errorf("submitting cancel tx for nonce %v: %v", nonce, err)
+	}
+	return tx.Hash(), nil
+}
+
+// pendingNonce returns the pending nonce of the address, if the client
+// supports it, and the account nonce otherwise.
+func (manager *NonceManager) pendingNonce(ctx context.Context, addr address.Address) (uint64, error) {
+	var nonce pack.U256
+	var err error
+	if client, ok := manager.Client.(PendingNonceClient); ok {
+		nonce, err = client.PendingNonce(ctx, addr)
+	} else {
+		nonce, err = manager.Client.AccountNonce(ctx, addr)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return nonce.Int().Uint64(), nil
+}
+
+// state returns the nonce state of the address, loading it from the store if
+// it has not been loaded yet. This must be called while holding the mutex.
+func (manager *NonceManager) state(addr address.Address) (*NonceState, error) {
+	if state, ok := manager.states[addr]; ok {
+		return state, nil
+	}
+	state, err := manager.store.Get(addr)
+	if err != nil {
+		return nil, fmt.Errorf("loading nonce state for '%v': %v", addr, err)
+	}
+	state = copyNonceState(state)
+	for _, nonce := range state.Filling {
+		release(&state, nonce)
+	}
+	state.Filling = []uint64{}
+	manager.states[addr] = &state
+	return &state, nil
+}
+
+// update the nonce state of the address, and store it. If it cannot be
+// stored, the update is reverted. This must be called while holding the
+// mutex.
+func (manager *NonceManager) update(addr address.Address, f func(state *NonceState)) error {
+	state, err := manager.state(addr)
+	if err != nil {
+		return err
+	}
+	prev := copyNonceState(*state)
+	f(state)
+	if err := manager.store.Put(addr, *state); err != nil {
+		*state = prev
+		return fmt.Errorf("storing nonce state for '%v': %v", addr, err)
+	}
+	return nil
+}
+
+// gaps returns the released nonces, and the reserved nonces that have expired,
+// in ascending order.
+func (manager *NonceManager) gaps(state *NonceState) []uint64 {
+	gaps := append([]uint64{}, state.Released...)
+	for nonce, reservedAt := range state.Reserved {
+		if time.Since(reservedAt) >= manager.opts.ReservationTimeout {
+			gaps = append(gaps, nonce)
+		}
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	return gaps
+}
+
+// release the reserved nonce. If it is the last nonce that was reserved, the
+// next nonce is moved back instead, so that releasing does not create a gap.
+func release(state *NonceState, nonce uint64) {
+	delete(state.Reserved, nonce)
+	if nonce >= state.Next {
+		return
+	}
+	state.Released = append(removeNonce(state.Released, nonce), nonce)
+	sort.Slice(state.Released, func(i, j int) bool { return state.Released[i] < state.Released[j] })
+	for len(state.Released) > 0 && state.Released[len(state.Released)-1] == state.Next-1 {
+		state.Released = state.Released[:len(state.Released)-1]
+		state.Next--
+	}
+}
+
+// prune the nonces that have already been used, according to the pending
+// nonce reported by the chain.
+func prune(state *NonceState, pendingNonce uint64) {
+	for nonce := range state.Reserved {
+		if nonce < pendingNonce {
+			delete(state.Reserved, nonce)
+		}
+	}
+	released := state.Released[:0]
+	for _, nonce := range state.Released {
+		if nonce >= pendingNonce {
+			released = append(released, nonce)
+		}
+	}
+	state.Released = released
+	filling := state.Filling[:0]
+	for _, nonce := range state.Filling {
+		if nonce >= pendingNonce {
+			filling = append(filling, nonce)
+		}
+	}
+	state.Filling = filling
+}
+
+func removeNonce(nonces []uint64, nonce uint64) []uint64 {
+	filtered := make([]uint64, 0, len(nonces))
+	for _, n := range nonces {
+		if n != nonce {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
+
+func copyNonceState(state NonceState) NonceState {
+	reserved := make(map[uint64]time.Time, len(state.Reserved))
+	for nonce, reservedAt := range state.Reserved {
+		reserved[nonce] = reservedAt
+	}
+	return NonceState{
+		Next:     state.Next,
+		Reserved: reserved,
+		Released: append([]uint64{}, state.Released...),
+		Filling:  append([]uint64{}, state.Filling...),
+	}
+}
+
+// nonceKey returns the checksummed form of the address, so that the same
+// address always has the same nonce state.
+func nonceKey(addr address.Address) (address.Address, error) {
+	ethAddr, err := NewAddressFromHex(string(addr))
+	if err != nil {
+		return address.Address(""), fmt.Errorf("bad address '%v': %v", addr, err)
+	}
+	return address.Address(common.Address(ethAddr).Hex()), nil
+}
@@ -0,0 +1,232 @@
+package evm_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// nonceTx is a transaction that only has a sender and a nonce.
+type nonceTx struct {
+	account.Tx
+	from  address.Address
+	nonce uint64
+}
+
+func (tx nonceTx) From() address.Address {
+	return tx.from
+}
+
+func (tx nonceTx) Nonce() pack.U256 {
+	return pack.NewU256FromU64(pack.NewU64(tx.nonce))
+}
+
+func (tx nonceTx) Hash() pack.Bytes {
+	return pack.Bytes(fmt.Sprintf("%v/%v", tx.from, tx.nonce))
+}
+
+// nonceClient is an account.Client that reports a fixed pending nonce, and
+// records the nonces of submitted transactions.
+type nonceClient struct {
+	account.Client
+
+	mu           *sync.Mutex
+	pendingNonce uint64
+	fail         bool
+	submitted    []uint64
+}
+
+func (client *nonceClient) PendingNonce(ctx context.Context, addr address.Address) (pack.U256, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	return pack.NewU256FromU64(pack.NewU64(client.pendingNonce)), nil
+}
+
+func (client *nonceClient) SubmitTx(ctx context.Context, tx account.Tx) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.fail {
+		return fmt.Errorf("unavailable")
+	}
+	client.submitted = append(client.submitted, tx.Nonce().Int().Uint64())
+	return nil
+}
+
+var _ = Describe("Nonce manager", func() {
+	ctx := context.Background()
+
+	addr := address.Address("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+	cancel := func(ctx context.Context, from address.Address, nonce pack.U256) (account.Tx, error) {
+		return nonceTx{from: from, nonce: nonce.Int().Uint64()}, nil
+	}
+	newClient := func(pendingNonce uint64) *nonceClient {
+		return &nonceClient{mu: new(sync.Mutex), pendingNonce: pendingNonce}
+	}
+	reserve := func(manager *evm.NonceManager) uint64 {
+		nonce, err := manager.AccountNonce(ctx, addr)
+		Expect(err).ToNot(HaveOccurred())
+		return nonce.Int().Uint64()
+	}
+
+	Context("when reserving nonces concurrently", func() {
+		It("should reserve distinct nonces, starting from the pending nonce", func() {
+			manager := evm.NewNonceManager(newClient(3), evm.NewMemoryNonceStore(), cancel, evm.DefaultNonceManagerOptions())
+
+			nonces := make(chan uint64, 10)
+			wg := new(sync.WaitGroup)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					nonces <- reserve(manager)
+				}()
+			}
+			wg.Wait()
+			close(nonces)
+
+			seen := map[uint64]bool{}
+			for nonce := range nonces {
+				Expect(nonce).To(BeNumerically(">=", 3))
+				Expect(nonce).To(BeNumerically("<", 13))
+				Expect(seen[nonce]).To(BeFalse())
+				seen[nonce] = true
+			}
+		})
+	})
+
+	Context("when a transaction cannot be submitted", func() {
+		It("should reuse its nonce", func() {
+			client := newClient(0)
+			manager := evm.NewNonceManager(client, evm.NewMemoryNonceStore(), cancel, evm.DefaultNonceManagerOptions())
+			Expect(reserve(manager)).To(Equal(uint64(0)))
+			Expect(reserve(manager)).To(Equal(uint64(1)))
+
+			client.fail = true
+			Expect(manager.SubmitTx(ctx, nonceTx{from: addr, nonce: 0})).ToNot(Succeed())
+			client.fail = false
+			Expect(manager.SubmitTx(ctx, nonceTx{from: addr, nonce: 1})).To(Succeed())
+
+			Expect(reserve(manager)).To(Equal(uint64(0)))
+			Expect(reserve(manager)).To(Equal(uint64(2)))
+		})
+	})
+
+	Context("when there are gaps", func() {
+		It("should fill them with cancellation transactions", func() {
+			client := newClient(0)
+			manager := evm.NewNonceManager(client, evm.NewMemoryNonceStore(), cancel, evm.DefaultNonceManagerOptions())
+			for i := 0; i < 3; i++ {
+				reserve(manager)
+			}
+			Expect(manager.Release(addr, pack.NewU256FromUint64(0))).To(Succeed())
+			Expect(manager.SubmitTx(ctx, nonceTx{from: addr, nonce: 1})).To(Succeed())
+			Expect(manager.SubmitTx(ctx, nonceTx{from: addr, nonce: 2})).To(Succeed())
+
+			gaps, err := manager.Gaps(ctx, addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gaps).To(Equal([]pack.U256{pack.NewU256FromUint64(0)}))
+
+			txHashes, err := manager.FillGaps(ctx, addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txHashes).To(HaveLen(1))
+			Expect(client.submitted).To(Equal([]uint64{1, 2, 0}))
+
+			gaps, err = manager.Gaps(ctx, addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gaps).To(BeEmpty())
+		})
+
+		It("should not reserve gaps that are being filled", func() {
+			client := newClient(0)
+			started, unblock := make(chan struct{}), make(chan struct{})
+			blockingCancel := func(ctx context.Context, from address.Address, nonce pack.U256) (account.Tx, error) {
+				close(started)
+				<-unblock
+				return cancel(ctx, from, nonce)
+			}
+			manager := evm.NewNonceManager(client, evm.NewMemoryNonceStore(), blockingCancel, evm.DefaultNonceManagerOptions())
+			for i := 0; i < 3; i++ {
+				reserve(manager)
+			}
+			Expect(manager.Release(addr, pack.NewU256FromUint64(0))).To(Succeed())
+			Expect(manager.SubmitTx(ctx, nonceTx{from: addr, nonce: 1})).To(Succeed())
+			Expect(manager.SubmitTx(ctx, nonceTx{from: addr, nonce: 2})).To(Succeed())
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := manager.FillGaps(ctx, addr)
+				done <- err
+			}()
+			<-started
+			Expect(reserve(manager)).To(Equal(uint64(3)))
+			close(unblock)
+			Expect(<-done).ToNot(HaveOccurred())
+
+			Expect(manager.SubmitTx(ctx, nonceTx{from: addr, nonce: 3})).To(Succeed())
+			Expect(client.submitted).To(Equal([]uint64{1, 2, 0, 3}))
+		})
+
+		It("should release the gaps if they cannot be filled", func() {
+			client := newClient(0)
+			manager := evm.NewNonceManager(client, evm.NewMemoryNonceStore(), cancel, evm.DefaultNonceManagerOptions())
+			for i := 0; i < 3; i++ {
+				reserve(manager)
+			}
+			Expect(manager.Release(addr, pack.NewU256FromUint64(0))).To(Succeed())
+			Expect(manager.Release(addr, pack.NewU256FromUint64(1))).To(Succeed())
+
+			client.fail = true
+			_, err := manager.FillGaps(ctx, addr)
+			Expect(err).To(HaveOccurred())
+			client.fail = false
+
+			gaps, err := manager.Gaps(ctx, addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gaps).To(Equal([]pack.U256{pack.NewU256FromUint64(0), pack.NewU256FromUint64(1)}))
+			Expect(reserve(manager)).To(Equal(uint64(0)))
+		})
+
+		It("should not create a gap when the last nonce is released", func() {
+			manager := evm.NewNonceManager(newClient(0), evm.NewMemoryNonceStore(), cancel, evm.DefaultNonceManagerOptions())
+			Expect(reserve(manager)).To(Equal(uint64(0)))
+			Expect(manager.Release(addr, pack.NewU256FromUint64(0))).To(Succeed())
+
+			gaps, err := manager.Gaps(ctx, addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gaps).To(BeEmpty())
+			Expect(reserve(manager)).To(Equal(uint64(0)))
+		})
+	})
+
+	Context("when restarting", func() {
+		It("should recover reserved nonces from the store, and treat expired reservations as gaps", func() {
+			client := newClient(0)
+			store := evm.NewMemoryNonceStore()
+			manager := evm.NewNonceManager(client, store, cancel, evm.DefaultNonceManagerOptions())
+			Expect(reserve(manager)).To(Equal(uint64(0)))
+			Expect(reserve(manager)).To(Equal(uint64(1)))
+			Expect(manager.SubmitTx(ctx, nonceTx{from: addr, nonce: 1})).To(Succeed())
+
+			restarted := evm.NewNonceManager(client, store, cancel, evm.DefaultNonceManagerOptions().WithReservationTimeout(50*time.Millisecond))
+			time.Sleep(50 * time.Millisecond)
+			Expect(reserve(restarted)).To(Equal(uint64(2)))
+
+			gaps, err := restarted.Gaps(ctx, addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gaps).To(HaveLen(1))
+			Expect(gaps[0]).To(Equal(pack.NewU256FromUint64(0)))
+		})
+	})
+})
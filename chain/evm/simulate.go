@@ -0,0 +1,87 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/pack"
+)
+
+// Simulate implements the account.Simulator interface. The transaction is
+// executed using eth_call at the latest block, and its gas is estimated using
+// eth_estimateGas. The sender of the transaction is its signer, or the public
+// key that was given when building the transaction if it has not been signed.
+// Transactions that revert (or that are rejected by the node, for example,
+// because the sender has insufficient funds) return an unsuccessful
+// simulation with the revert reason. Transactions whose nonce is below the
+// pending nonce of the sender can never be executed, so they return an
+// unsuccessful simulation without being executed. Transactions whose nonce is
+// above the pending nonce (for example, nonces reserved by a NonceManager for
+// concurrent transactions) are executed as if they were the next transaction
+// of the sender.
+func (client *Client) Simulate(ctx context.Context, tx account.Tx) (confirmation.Simulation, error) {
+	ethTx, ok := tx.(*Tx)
+	if !ok {
+		return confirmation.Simulation{}, fmt.Errorf("expected type %T, got type %T", new(Tx), tx)
+	}
+	from := ethTx.From()
+	if from == "" {
+		return confirmation.Simulation{}, fmt.Errorf("simulating tx: unknown sender")
+	}
+	pendingNonce, err := client.EthClient.PendingNonceAt(ctx, common.HexToAddress(string(from)))
+	if err != nil {
+		return confirmation.Simulation{}, fmt.Errorf("simulating tx: getting pending nonce: %v", err)
+	}
+	if nonce := ethTx.EthTx.Nonce(); nonce < pendingNonce {
+		return confirmation.Simulation{
+			Success:      false,
+			RevertReason: pack.String(fmt.Sprintf("nonce too low: tx nonce %v, pending nonce %v", nonce, pendingNonce)),
+		}, nil
+	}
+
+	msg := ethereum.CallMsg{
+		From:       common.HexToAddress(string(from)),
+		To:         ethTx.EthTx.To(),
+		Gas:        ethTx.EthTx.Gas(),
+		Value:      ethTx.EthTx.Value(),
+		Data:       ethTx.EthTx.Data(),
+		AccessList: ethTx.EthTx.AccessList(),
+	}
+	if ethTx.EthTx.Type() == types.DynamicFeeTxType {
+		msg.GasFeeCap = ethTx.EthTx.GasFeeCap()
+		msg.GasTipCap = ethTx.EthTx.GasTipCap()
+	} else {
+		msg.GasPrice = ethTx.EthTx.GasPrice()
+	}
+
+	if _, err = client.EthClient.CallContract(ctx, msg, nil); err != nil {
+		return simulationError(err)
+	}
+	gas, err := client.EthClient.EstimateGas(ctx, msg)
+	if err != nil {
+		return simulationError(err)
+	}
+	return confirmation.Simulation{
+		Success: true,
+		GasUsed: pack.NewU256FromUint64(gas),
+	}, nil
+}
+
+// simulationError returns an unsuccessful simulation if the error was returned
+// by the node while executing the transaction, and returns the error
+// otherwise.
+func simulationError(err error) (confirmation.Simulation, error) {
+	if _, ok := err.(rpc.Error); !ok {
+		return confirmation.Simulation{}, fmt.Errorf("simulating tx: %v", err)
+	}
+	return confirmation.Simulation{
+		Success:      false,
+		RevertReason: decodeRevertReason(err),
+	}, nil
+}
@@ -0,0 +1,129 @@
+package evm_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/multichain/chain/evm/evmtest"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// simulateNode returns a fake node that responds to the methods used to
+// simulate transactions, for a sender whose pending nonce is zero. Calls
+// revert with the revert reason if it is not empty.
+func simulateNode(gas uint64, revertReason string) *evmtest.Node {
+	node := evmtest.NewNode().Result("eth_getTransactionCount", evmtest.Quantity(0))
+	if revertReason != "" {
+		// Revert reasons are encoded as calls to Error(string).
+		encoded, _ := evm.EncodeArgs(pack.String(revertReason))
+		err := evmtest.Revert("execution reverted: "+revertReason, append([]byte{0x08, 0xc3, 0x79, 0xa0}, encoded...))
+		revert := func([]json.RawMessage) (interface{}, error) {
+			return nil, err
+		}
+		return node.Handle("eth_call", revert).Handle("eth_estimateGas", revert)
+	}
+	return node.
+		Result("eth_call", "0x").
+		Result("eth_estimateGas", evmtest.Quantity(gas))
+}
+
+var _ = Describe("Simulation", func() {
+	ctx := context.Background()
+	chainID := big.NewInt(1337)
+	to := address.Address("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+
+	build := func(fromPubKey *id.PubKey) *evm.Tx {
+		tx, err := evm.NewTxBuilder(chainID).BuildTx(ctx, fromPubKey, to, pack.NewU256FromUint64(1), pack.NewU256FromUint64(0), pack.NewU256FromUint64(21000), pack.NewU256FromUint64(2), pack.NewU256FromUint64(30), pack.Bytes{1, 2, 3, 4})
+		Expect(err).ToNot(HaveOccurred())
+		return tx.(*evm.Tx)
+	}
+
+	Context("when simulating an unsigned transaction", func() {
+		It("should use the public key that built the transaction as the sender", func() {
+			client, closeServer := dial(simulateNode(50000, ""))
+			defer closeServer()
+
+			tx := build(id.NewPrivKey().PubKey())
+			Expect(tx.From()).ToNot(BeEmpty())
+			sim, err := client.Simulate(ctx, tx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sim.Success).To(BeTrue())
+			Expect(sim.GasUsed.String()).To(Equal(pack.NewU256FromUint64(50000).String()))
+		})
+
+		It("should return an error if the sender is unknown", func() {
+			client, closeServer := dial(simulateNode(50000, ""))
+			defer closeServer()
+
+			_, err := client.Simulate(ctx, build(nil))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the nonce is not the pending nonce", func() {
+		It("should not execute transactions whose nonce is too low", func() {
+			node := simulateNode(50000, "")
+			client, closeServer := dial(node)
+			defer closeServer()
+
+			node.Result("eth_getTransactionCount", evmtest.Quantity(1))
+			sim, err := client.Simulate(ctx, build(id.NewPrivKey().PubKey()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sim.Success).To(BeFalse())
+			Expect(sim.RevertReason).To(Equal(pack.String("nonce too low: tx nonce 0, pending nonce 1")))
+			Expect(node.Requests("eth_call")).To(BeEmpty())
+		})
+
+		It("should execute transactions whose nonce is above the pending nonce", func() {
+			node := simulateNode(50000, "")
+			client, closeServer := dial(node)
+			defer closeServer()
+
+			tx, err := evm.NewTxBuilder(chainID).BuildTx(ctx, id.NewPrivKey().PubKey(), to, pack.NewU256FromUint64(1), pack.NewU256FromUint64(2), pack.NewU256FromUint64(21000), pack.NewU256FromUint64(2), pack.NewU256FromUint64(30), nil)
+			Expect(err).ToNot(HaveOccurred())
+			sim, err := client.Simulate(ctx, tx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sim.Success).To(BeTrue())
+			Expect(sim.GasUsed.String()).To(Equal(pack.NewU256FromUint64(50000).String()))
+			Expect(node.Requests("eth_call")).To(HaveLen(1))
+			Expect(node.Requests("eth_estimateGas")).To(HaveLen(1))
+		})
+
+		It("should check the nonce against the pending nonce of the sender", func() {
+			node := simulateNode(50000, "")
+			client, closeServer := dial(node)
+			defer closeServer()
+
+			tx := build(id.NewPrivKey().PubKey())
+			_, err := client.Simulate(ctx, tx)
+			Expect(err).ToNot(HaveOccurred())
+			reqs := node.Requests("eth_getTransactionCount")
+			Expect(reqs).To(HaveLen(1))
+			var from, block string
+			Expect(json.Unmarshal(reqs[0].Params[0], &from)).To(Succeed())
+			Expect(json.Unmarshal(reqs[0].Params[1], &block)).To(Succeed())
+			Expect(strings.EqualFold(from, string(tx.From()))).To(BeTrue())
+			Expect(block).To(Equal("pending"))
+		})
+	})
+
+	Context("when the transaction reverts", func() {
+		It("should return the revert reason", func() {
+			client, closeServer := dial(simulateNode(0, "insufficient balance"))
+			defer closeServer()
+
+			sim, err := client.Simulate(ctx, build(id.NewPrivKey().PubKey()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sim.Success).To(BeFalse())
+			Expect(sim.RevertReason).To(Equal(pack.String("insufficient balance")))
+		})
+	})
+})
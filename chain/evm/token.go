@@ -0,0 +1,252 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/id"
+	"github.com/renproject/multichain"
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+)
+
+// TransferEventSignature is the topic of the ERC-20 Transfer event.
+var TransferEventSignature = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// defaultTokens are the well-known addresses of token contracts on the
+// mainnet of each chain.
+var defaultTokens = map[multichain.Chain]map[multichain.Asset]address.Address{
+	multichain.Ethereum: {
+		multichain.DAI:  address.Address("0x6B175474E89094C44Da98b954EedeAC495271d0F"),
+		multichain.REN:  address.Address("0x408e41876cCCDC0F92210600ef50372656052a38"),
+		multichain.USDC: address.Address("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"),
+		multichain.USDT: address.Address("0xdAC17F958D2ee523a2206206994597C13D831ec7"),
+	},
+	multichain.Avalanche: {
+		multichain.USDC_Avalanche: address.Address("0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E"),
+		multichain.USDT_Avalanche: address.Address("0x9702230A8Ea53601f5cD2dc00fDBc13d4dF4A8c7"),
+	},
+	multichain.Polygon: {
+		multichain.USDC_Polygon: address.Address("0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174"),
+		multichain.USDT_Polygon: address.Address("0xc2132D05D31c914a87C6611C10748AEb04B58e8F"),
+	},
+}
+
+// A TokenRegistry maps token assets to the addresses of their contracts on
+// one chain, for each network of the chain. It is safe for concurrent use.
+type TokenRegistry struct {
+	chain multichain.Chain
+
+	mu     *sync.RWMutex
+	tokens map[multichain.Network]map[multichain.Asset]address.Address
+}
+
+// NewTokenRegistry returns an empty TokenRegistry for the chain.
+func NewTokenRegistry(chain multichain.Chain) *TokenRegistry {
+	return &TokenRegistry{
+		chain: chain,
+
+		mu:     new(sync.RWMutex),
+		tokens: map[multichain.Network]map[multichain.Asset]address.Address{},
+	}
+}
+
+// DefaultTokenRegistry returns a TokenRegistry for the chain, with the
+// well-known addresses of token contracts on the mainnet of the chain. Token
+// contracts on other networks must be registered.
+func DefaultTokenRegistry(chain multichain.Chain) *TokenRegistry {
+	registry := NewTokenRegistry(chain)
+	for asset, addr := range defaultTokens[chain] {
+		registry.Register(multichain.NetworkMainnet, asset, addr)
+	}
+	return registry
+}
+
+// Chain returns the chain of the token contracts in the registry.
+func (registry *TokenRegistry) Chain() multichain.Chain {
+	return registry.chain
+}
+
+// Register the address of the contract for the token asset on the network of
+// the chain. Registering an asset that is already registered replaces its
+// address.
+func (registry *TokenRegistry) Register(network multichain.Network, asset multichain.Asset, addr address.Address) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.tokens[network]; !ok {
+		registry.tokens[network] = map[multichain.Asset]address.Address{}
+	}
+	registry.tokens[network][asset] = addr
+}
+
+// TokenAddress returns the address of the contract for the token asset on the
+// network of the chain.
+func (registry *TokenRegistry) TokenAddress(network multichain.Network, asset multichain.Asset) (address.Address, error) {
+	if asset.Type() != multichain.AssetTypeToken {
+		return address.Address(""), fmt.Errorf("asset %v is not a token", asset)
+	}
+
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	addr, ok := registry.tokens[network][asset]
+	if !ok {
+		return address.Address(""), fmt.Errorf("token %v is not registered on %v %v", asset, registry.chain, network)
+	}
+	return addr, nil
+}
+
+// TokenBalance returns the balance of the owner, as reported by the balanceOf
+// function of the token contract.
+func TokenBalance(ctx context.Context, caller contract.Caller, token, owner address.Address) (pack.U256, error) {
+	ownerAddr, err := NewAddressFromHex(string(pack.String(owner)))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad owner address '%v': %v", owner, err)
+	}
+	return callToken(ctx, caller, token, "balanceOf(address)", ownerAddr)
+}
+
+// Allowance returns the amount that the spender is allowed to transfer on
+// behalf of the owner, as reported by the allowance function of the token
+// contract.
+func Allowance(ctx context.Context, caller contract.Caller, token, owner, spender address.Address) (pack.U256, error) {
+	ownerAddr, err := NewAddressFromHex(string(pack.String(owner)))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad owner address '%v': %v", owner, err)
+	}
+	spenderAddr, err := NewAddressFromHex(string(pack.String(spender)))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad spender address '%v': %v", spender, err)
+	}
+	return callToken(ctx, caller, token, "allowance(address,address)", ownerAddr, spenderAddr)
+}
+
+// BuildTransfer returns a transaction that calls the transfer function of the
+// token contract, to send the amount of tokens to the recipient.
+func BuildTransfer(ctx context.Context, txBuilder account.TxBuilder, fromPubKey *id.PubKey, token, to address.Address, amount, nonce, gasLimit, gasPrice, gasCap pack.U256) (account.Tx, error) {
+	toAddr, err := NewAddressFromHex(string(pack.String(to)))
+	if err != nil {
+		return nil, fmt.Errorf("bad to address '%v': %v", to, err)
+	}
+	calldata := tokenCallData("transfer(address,uint256)", toAddr, amount)
+	return txBuilder.BuildTx(ctx, fromPubKey, token, pack.NewU256FromUint64(0), nonce, gasLimit, gasPrice, gasCap, pack.Bytes(calldata))
+}
+
+// BuildApprove returns a transaction that calls the approve function of the
+// token contract, to allow the spender to transfer the amount of tokens on
+// behalf of the sender.
+func BuildApprove(ctx context.Context, txBuilder account.TxBuilder, fromPubKey *id.PubKey, token, spender address.Address, amount, nonce, gasLimit, gasPrice, gasCap pack.U256) (account.Tx, error) {
+	spenderAddr, err := NewAddressFromHex(string(pack.String(spender)))
+	if err != nil {
+		return nil, fmt.Errorf("bad spender address '%v': %v", spender, err)
+	}
+	calldata := tokenCallData("approve(address,uint256)", spenderAddr, amount)
+	return txBuilder.BuildTx(ctx, fromPubKey, token, pack.NewU256FromUint64(0), nonce, gasLimit, gasPrice, gasCap, pack.Bytes(calldata))
+}
+
+// A TransferEvent is an ERC-20 Transfer event, emitted by a token contract
+// when tokens are transferred.
+type TransferEvent struct {
+	Token       address.Address
+	From        address.Address
+	To          address.Address
+	Amount      pack.U256
+	TxHash      pack.Bytes
+	BlockNumber pack.U64
+	LogIndex    pack.U32
+}
+
+// DecodeTransferLog returns the Transfer event in the log. It returns an error
+// if the log is not a Transfer event.
+func DecodeTransferLog(log types.Log) (TransferEvent, error) {
+	if len(log.Topics) != 3 || log.Topics[0] != TransferEventSignature {
+		return TransferEvent{}, fmt.Errorf("log %v in tx %v is not a transfer event", log.Index, log.TxHash.Hex())
+	}
+	if len(log.Data) != 32 {
+		return TransferEvent{}, fmt.Errorf("bad transfer event data: expected 32 bytes, got %v bytes", len(log.Data))
+	}
+	return TransferEvent{
+		Token:       address.Address(log.Address.Hex()),
+		From:        address.Address(common.BytesToAddress(log.Topics[1].Bytes()).Hex()),
+		To:          address.Address(common.BytesToAddress(log.Topics[2].Bytes()).Hex()),
+		Amount:      pack.NewU256FromInt(new(big.Int).SetBytes(log.Data)),
+		TxHash:      pack.NewBytes(log.TxHash.Bytes()),
+		BlockNumber: pack.NewU64(log.BlockNumber),
+		LogIndex:    pack.NewU32(uint32(log.Index)),
+	}, nil
+}
+
+// TokenTransfers returns the transfers of the token between the given blocks
+// (inclusive). If recipients are given, only transfers to the recipients are
+// returned. Transfers in removed logs are ignored.
+func (client *Client) TokenTransfers(ctx context.Context, token address.Address, fromBlock, toBlock pack.U64, recipients ...address.Address) ([]TransferEvent, error) {
+	tokenAddr, err := NewAddressFromHex(string(pack.String(token)))
+	if err != nil {
+		return nil, fmt.Errorf("bad token address '%v': %v", token, err)
+	}
+	topics := [][]common.Hash{{TransferEventSignature}}
+	if len(recipients) > 0 {
+		recipientTopics := make([]common.Hash, len(recipients))
+		for i, recipient := range recipients {
+			recipientAddr, err := NewAddressFromHex(string(pack.String(recipient)))
+			if err != nil {
+				return nil, fmt.Errorf("bad recipient address '%v': %v", recipient, err)
+			}
+			recipientTopics[i] = common.BytesToHash(common.Address(recipientAddr).Bytes())
+		}
+		topics = append(topics, nil, recipientTopics)
+	}
+
+	logs, err := client.EthClient.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock.Uint64()),
+		ToBlock:   new(big.Int).SetUint64(toBlock.Uint64()),
+		Addresses: []common.Address{common.Address(tokenAddr)},
+		Topics:    topics,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filtering logs: %v", err)
+	}
+	transfers := make([]TransferEvent, 0, len(logs))
+	for _, log := range logs {
+		if log.Removed {
+			continue
+		}
+		transfer, err := DecodeTransferLog(log)
+		if err != nil {
+			// Non-standard tokens can emit events with the same signature,
+			// but with different indexed fields.
+			continue
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, nil
+}
+
+// callToken calls a function of the token contract that returns a uint256.
+func callToken(ctx context.Context, caller contract.Caller, token address.Address, signature string, args ...interface{}) (pack.U256, error) {
+	result, err := caller.CallContract(ctx, token, tokenCallData(signature, args...))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("calling %v on '%v': %v", signature, token, err)
+	}
+	var value pack.U256
+	if err := Decode(result, &value); err != nil {
+		return pack.U256{}, fmt.Errorf("decoding %v result: %v", signature, err)
+	}
+	return value, nil
+}
+
+// tokenCallData returns the calldata for calling the function with the given
+// signature, and the given arguments.
+func tokenCallData(signature string, args ...interface{}) contract.CallData {
+	calldata := append([]byte{}, crypto.Keccak256([]byte(signature))[:4]...)
+	return contract.CallData(append(calldata, Encode(args...)...))
+}
@@ -0,0 +1,143 @@
+package evm_test
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/multichain"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// tokenCaller is a contract.Caller that records the calldata of the last
+// call, and returns a fixed result.
+type tokenCaller struct {
+	program  address.Address
+	calldata contract.CallData
+	result   pack.Bytes
+}
+
+func (caller *tokenCaller) CallContract(ctx context.Context, program address.Address, calldata contract.CallData) (pack.Bytes, error) {
+	caller.program = program
+	caller.calldata = calldata
+	return caller.result, nil
+}
+
+var _ = Describe("Tokens", func() {
+	ctx := context.Background()
+	token := address.Address("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	owner := address.Address("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+	spender := address.Address("0xAb8483F64d9C6d1EcF9b849Ae677dD3315835cb2")
+
+	selector := func(signature string) []byte {
+		return crypto.Keccak256([]byte(signature))[:4]
+	}
+	word := func(addr address.Address) []byte {
+		return common.LeftPadBytes(common.HexToAddress(string(addr)).Bytes(), 32)
+	}
+
+	Context("when looking up token addresses", func() {
+		It("should return registered tokens", func() {
+			registry := evm.DefaultTokenRegistry(multichain.Ethereum)
+			addr, err := registry.TokenAddress(multichain.NetworkMainnet, multichain.USDC)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr).To(Equal(token))
+
+			_, err = registry.TokenAddress(multichain.NetworkTestnet, multichain.USDC)
+			Expect(err).To(HaveOccurred())
+			registry.Register(multichain.NetworkTestnet, multichain.USDC, spender)
+			addr, err = registry.TokenAddress(multichain.NetworkTestnet, multichain.USDC)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr).To(Equal(spender))
+		})
+
+		It("should only return tokens registered on the chain", func() {
+			for _, chain := range []multichain.Chain{multichain.BinanceSmartChain, multichain.Polygon, multichain.Avalanche} {
+				_, err := evm.DefaultTokenRegistry(chain).TokenAddress(multichain.NetworkMainnet, multichain.USDC)
+				Expect(err).To(HaveOccurred())
+			}
+			addr, err := evm.DefaultTokenRegistry(multichain.Polygon).TokenAddress(multichain.NetworkMainnet, multichain.USDC_Polygon)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr).To(Equal(address.Address("0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174")))
+		})
+
+		It("should not return native assets", func() {
+			_, err := evm.DefaultTokenRegistry(multichain.Ethereum).TokenAddress(multichain.NetworkMainnet, multichain.ETH)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when reading token state", func() {
+		It("should call balanceOf and allowance", func() {
+			caller := &tokenCaller{result: common.LeftPadBytes(big.NewInt(1000).Bytes(), 32)}
+
+			balance, err := evm.TokenBalance(ctx, caller, token, owner)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance).To(Equal(pack.NewU256FromUint64(1000)))
+			Expect(caller.program).To(Equal(token))
+			Expect([]byte(caller.calldata)).To(Equal(append(selector("balanceOf(address)"), word(owner)...)))
+
+			allowance, err := evm.Allowance(ctx, caller, token, owner, spender)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(allowance).To(Equal(pack.NewU256FromUint64(1000)))
+			Expect([]byte(caller.calldata)).To(Equal(append(append(selector("allowance(address,address)"), word(owner)...), word(spender)...)))
+		})
+
+		It("should return an error for short results", func() {
+			_, err := evm.TokenBalance(ctx, &tokenCaller{result: pack.Bytes{1}}, token, owner)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when building token transactions", func() {
+		It("should build transfers and approvals to the token contract", func() {
+			txBuilder := evm.NewTxBuilder(big.NewInt(1))
+			amount := pack.NewU256FromUint64(1000)
+			zero := pack.NewU256FromUint64(0)
+
+			tx, err := evm.BuildTransfer(ctx, txBuilder, nil, token, spender, amount, zero, pack.NewU256FromUint64(60000), pack.NewU256FromUint64(1), pack.NewU256FromUint64(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx.To()).To(Equal(token))
+			Expect(tx.Value()).To(Equal(zero))
+			Expect([]byte(tx.Payload())).To(Equal(append(append(selector("transfer(address,uint256)"), word(spender)...), common.LeftPadBytes(big.NewInt(1000).Bytes(), 32)...)))
+
+			tx, err = evm.BuildApprove(ctx, txBuilder, nil, token, spender, amount, zero, pack.NewU256FromUint64(60000), pack.NewU256FromUint64(1), pack.NewU256FromUint64(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]byte(tx.Payload())[:4]).To(Equal(selector("approve(address,uint256)")))
+		})
+	})
+
+	Context("when decoding transfer logs", func() {
+		It("should decode transfer events", func() {
+			log := types.Log{
+				Address:     common.HexToAddress(string(token)),
+				Topics:      []common.Hash{evm.TransferEventSignature, common.BytesToHash(word(owner)), common.BytesToHash(word(spender))},
+				Data:        common.LeftPadBytes(big.NewInt(1000).Bytes(), 32),
+				BlockNumber: 10,
+				Index:       2,
+			}
+			transfer, err := evm.DecodeTransferLog(log)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transfer.Token).To(Equal(token))
+			Expect(transfer.From).To(Equal(owner))
+			Expect(transfer.To).To(Equal(spender))
+			Expect(transfer.Amount).To(Equal(pack.NewU256FromUint64(1000)))
+			Expect(transfer.BlockNumber).To(Equal(pack.NewU64(10)))
+			Expect(transfer.LogIndex).To(Equal(pack.NewU32(2)))
+		})
+
+		It("should not decode other events", func() {
+			log := types.Log{Topics: []common.Hash{crypto.Keccak256Hash([]byte("Approval(address,address,uint256)")), {}, {}}, Data: make([]byte, 32)}
+			_, err := evm.DecodeTransferLog(log)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
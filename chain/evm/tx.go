@@ -2,11 +2,13 @@ package evm
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/renproject/id"
 	"github.com/renproject/multichain/api/account"
 	"github.com/renproject/multichain/api/address"
@@ -16,32 +18,123 @@ import (
 
 // TxBuilder represents a transaction builder that builds transactions to be
 // broadcasted to the ethereum network. The TxBuilder is configured using a
-// chain id.
+// chain id, and builds legacy transactions unless it is configured with fee
+// options that use dynamic fees.
 type TxBuilder struct {
 	ChainID *big.Int
+
+	client          *Client
+	feeOptions      FeeOptions
+	gasLimitOptions *GasLimitOptions
 }
 
 // NewTxBuilder creates a new transaction builder.
 func NewTxBuilder(chainID *big.Int) TxBuilder {
-	return TxBuilder{chainID}
+	return TxBuilder{ChainID: chainID}
+}
+
+// WithFeeOptions returns a copy of the transaction builder that builds the
+// type of transaction defined by the fee options. The client is used to
+// detect whether the chain has activated the London upgrade, and is only
+// required when using FeeMarketAuto. The same fee options should be used by
+// the GasEstimator.
+func (txBuilder TxBuilder) WithFeeOptions(client *Client, feeOptions FeeOptions) TxBuilder {
+	if client != nil {
+		txBuilder.client = client
+	}
+	txBuilder.feeOptions = feeOptions
+	return txBuilder
 }
 
-// BuildTx receives transaction fields and constructs a new transaction.
+// WithGasLimitOptions returns a copy of the transaction builder that uses the
+// client to estimate the gas limit of transactions that are built with a zero
+// gas limit (see Client.EstimateGasLimit). If access lists are enabled, they
+// are attached to the transactions: dynamic fee transactions include the
+// access list, and legacy transactions are built as access list transactions.
+func (txBuilder TxBuilder) WithGasLimitOptions(client *Client, gasLimitOptions GasLimitOptions) TxBuilder {
+	if client != nil {
+		txBuilder.client = client
+	}
+	txBuilder.gasLimitOptions = &gasLimitOptions
+	return txBuilder
+}
+
+// BuildTx receives transaction fields and constructs a new transaction. For
+// legacy transactions, the gas price is used and the gas cap is ignored. For
+// dynamic fee transactions, the gas price is used as the priority fee (the
+// gas tip cap) and the gas cap is used as the fee cap. If the to address is
+// empty, a contract-creation transaction is built, and the payload must be
+// the init code of the contract (see DeployCode).
 func (txBuilder TxBuilder) BuildTx(ctx context.Context, fromPubKey *id.PubKey, to address.Address, value, nonce, gasLimit, gasPrice, gasCap pack.U256, payload pack.Bytes) (account.Tx, error) {
+	toAddr, err := NewRecipient(to)
+	if err != nil {
+		return nil, err
+	}
+	var accessList types.AccessList
+	if txBuilder.gasLimitOptions != nil && gasLimit.Int().Sign() == 0 {
+		if txBuilder.client == nil {
+			return nil, fmt.Errorf("estimating gas limit: nil client")
+		}
+		gasLimit, accessList, err = txBuilder.client.EstimateGasLimit(ctx, fromPubKey, to, value, payload, *txBuilder.gasLimitOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dynamic, err := dynamicFee(ctx, txBuilder.client, txBuilder.feeOptions)
+	if err != nil {
+		return nil, err
+	}
+	var txData types.TxData
+	switch {
+	case dynamic:
+		txData = &types.DynamicFeeTx{
+			ChainID:    txBuilder.ChainID,
+			Nonce:      nonce.Int().Uint64(),
+			GasTipCap:  gasPrice.Int(),
+			GasFeeCap:  gasCap.Int(),
+			Gas:        gasLimit.Int().Uint64(),
+			To:         toAddr,
+			Value:      value.Int(),
+			Data:       payload,
+			AccessList: accessList,
+		}
+	case len(accessList) > 0:
+		txData = &types.AccessListTx{
+			ChainID:    txBuilder.ChainID,
+			Nonce:      nonce.Int().Uint64(),
+			GasPrice:   gasPrice.Int(),
+			Gas:        gasLimit.Int().Uint64(),
+			To:         toAddr,
+			Value:      value.Int(),
+			Data:       payload,
+			AccessList: accessList,
+		}
+	default:
+		txData = &types.LegacyTx{
+			Nonce:    nonce.Int().Uint64(),
+			GasPrice: gasPrice.Int(),
+			Gas:      gasLimit.Int().Uint64(),
+			To:       toAddr,
+			Value:    value.Int(),
+			Data:     payload,
+		}
+	}
+	return NewTx(types.NewTx(txData), types.LatestSignerForChainID(txBuilder.ChainID), fromPubKey), nil
+}
+
+// NewRecipient returns the recipient of a transaction to the given address.
+// The recipient of a contract-creation transaction is nil, and is used when
+// the address is empty.
+func NewRecipient(to address.Address) (*common.Address, error) {
+	if to == "" {
+		return nil, nil
+	}
 	toAddr, err := NewAddressFromHex(string(pack.String(to)))
 	if err != nil {
 		return nil, fmt.Errorf("bad to address '%v': %v", to, err)
 	}
 	addr := common.Address(toAddr)
-	return &Tx{
-		EthTx: types.NewTransaction(nonce.Int().Uint64(),
-			addr, value.Int(),
-			gasLimit.Int().Uint64(),
-			gasPrice.Int(),
-			payload,
-		),
-		Signer: types.LatestSignerForChainID(txBuilder.ChainID),
-	}, nil
+	return &addr, nil
 }
 
 // Tx represents a ethereum transaction, encapsulating a payload/data and its
@@ -49,6 +142,20 @@ func (txBuilder TxBuilder) BuildTx(ctx context.Context, fromPubKey *id.PubKey, t
 type Tx struct {
 	EthTx  *types.Transaction
 	Signer types.Signer
+
+	sender *common.Address
+}
+
+// NewTx returns a transaction that will be signed by the given public key.
+// The public key is used as the sender of the transaction before it is signed
+// (for example, when it is simulated), and can be nil.
+func NewTx(ethTx *types.Transaction, signer types.Signer, fromPubKey *id.PubKey) *Tx {
+	tx := &Tx{EthTx: ethTx, Signer: signer}
+	if fromPubKey != nil {
+		sender := crypto.PubkeyToAddress(ecdsa.PublicKey(*fromPubKey))
+		tx.sender = &sender
+	}
+	return tx
 }
 
 // Hash returns the hash that uniquely identifies the transaction.
@@ -59,10 +166,15 @@ func (tx Tx) Hash() pack.Bytes {
 }
 
 // From returns the address that is sending the transaction. Generally,
-// this is also the address that must sign the transaction.
+// this is also the address that must sign the transaction. Before the
+// transaction is signed, this is the address of the public key that was given
+// when building the transaction (if any).
 func (tx Tx) From() address.Address {
 	addr, err := types.Sender(tx.Signer, tx.EthTx)
 	if err != nil {
+		if tx.sender != nil {
+			return address.Address(tx.sender.Hex())
+		}
 		return address.Address("")
 	}
 	return address.Address(addr.Hex())
@@ -72,9 +184,32 @@ func (tx Tx) From() address.Address {
 // address of an external account, controlled by a private key, or it can be
 // the address of a contract.
 func (tx Tx) To() address.Address {
+	if tx.EthTx.To() == nil {
+		return address.Address("")
+	}
 	return address.Address(tx.EthTx.To().Hex())
 }
 
+// IsContractCreation returns true if the transaction deploys a contract.
+func (tx Tx) IsContractCreation() bool {
+	return tx.EthTx.To() == nil
+}
+
+// ContractAddress returns the address of the contract that is deployed by the
+// transaction. It is only known once the transaction has been signed, because
+// it depends on the sender. An empty address is returned if the transaction
+// does not deploy a contract, or has not been signed.
+func (tx Tx) ContractAddress() address.Address {
+	if !tx.IsContractCreation() {
+		return address.Address("")
+	}
+	from, err := types.Sender(tx.Signer, tx.EthTx)
+	if err != nil {
+		return address.Address("")
+	}
+	return address.Address(crypto.CreateAddress(from, tx.EthTx.Nonce()).Hex())
+}
+
 // Value being sent from the sender to the receiver.
 func (tx Tx) Value() pack.U256 {
 	return pack.NewU256FromInt(tx.EthTx.Value())
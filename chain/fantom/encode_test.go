@@ -188,9 +188,8 @@ var _ = Describe("Encoding", func() {
 
 	Context("when encoding an unsupported type", func() {
 		It("should panic", func() {
-			f := func(x bool) bool {
-				arg := pack.NewBool(x)
-				Expect(func() { fantom.Encode(arg) }).To(Panic())
+			f := func(x float64) bool {
+				Expect(func() { fantom.Encode(x) }).To(Panic())
 				return true
 			}
 
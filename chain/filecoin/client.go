@@ -3,10 +3,12 @@ package filecoin
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
 
 	filaddress "github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/crypto"
 	filclient "github.com/filecoin-project/lotus/api/client"
 	"github.com/filecoin-project/lotus/api/v0api"
@@ -15,6 +17,8 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/renproject/multichain/api/account"
 	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/multichain/api/contract"
 	"github.com/renproject/pack"
 )
 
@@ -30,6 +34,10 @@ const (
 	// client. A valid lotus auth token is required to write messages to the
 	// filecoin storage. To do read-only queries, auth token is not required.
 	DefaultClientAuthToken = ""
+
+	// FinalityEpochs is the number of epochs after which a tipset cannot be
+	// reorged.
+	FinalityEpochs = 900
 )
 
 // ClientOptions are used to parameterise the behaviour of the Client.
@@ -114,10 +122,10 @@ func (client *Client) Tx(ctx context.Context, txID pack.Bytes) (account.Tx, pack
 		return nil, pack.NewU64(0), fmt.Errorf("searching state for txid: %v", err)
 	}
 	if messageLookup == nil {
-		return nil, pack.NewU64(0), fmt.Errorf("searching state for txid %v: not found", msgID)
+		return nil, pack.NewU64(0), fmt.Errorf("searching state for txid %v: %w", msgID, confirmation.ErrTxNotFound)
 	}
 	if messageLookup.Receipt.ExitCode.IsError() {
-		return nil, pack.NewU64(0), fmt.Errorf("executing transaction: %v", messageLookup.Receipt.ExitCode.String())
+		return nil, pack.NewU64(0), fmt.Errorf("executing transaction: %v: %w", messageLookup.Receipt.ExitCode.String(), confirmation.ErrTxFailed)
 	}
 	if !messageLookup.Message.Equals(msgID) {
 		return nil, pack.U64(0), fmt.Errorf("searching state for txid: expected %v, got %v", msgID, messageLookup.Message)
@@ -147,6 +155,91 @@ func (client *Client) Tx(ctx context.Context, txID pack.Bytes) (account.Tx, pack
 	return &Tx{msg: *msg}, pack.NewU64(confs), nil
 }
 
+// BlockHash returns the key of the tipset at the given height in the longest
+// blockchain. If there is no tipset at the height (because it is a null
+// round), then the key of the previous tipset is returned.
+func (client *Client) BlockHash(ctx context.Context, height pack.U64) (pack.Bytes, error) {
+	tipset, err := client.node.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(height.Uint64()), types.EmptyTSK)
+	if err != nil {
+		return nil, fmt.Errorf("get tipset %v: %v", height, err)
+	}
+	return pack.NewBytes(tipset.Key().Bytes()), nil
+}
+
+// TxInclusion returns the tipset in which the transaction with the given hash
+// was executed. Messages that have not been executed, but that are known to
+// the node, are pending.
+func (client *Client) TxInclusion(ctx context.Context, txID pack.Bytes) (confirmation.Inclusion, error) {
+	msgID, err := cid.Parse([]byte(txID))
+	if err != nil {
+		return confirmation.Inclusion{}, fmt.Errorf("parsing txid: %v", err)
+	}
+	messageLookup, err := client.node.StateSearchMsg(ctx, msgID)
+	if err != nil {
+		return confirmation.Inclusion{}, fmt.Errorf("searching state for txid: %v", err)
+	}
+	if messageLookup == nil {
+		if _, err := client.node.ChainGetMessage(ctx, msgID); err != nil {
+			return confirmation.Inclusion{Status: confirmation.TxStatusUnknown}, nil
+		}
+		return confirmation.Inclusion{Status: confirmation.TxStatusPending}, nil
+	}
+	if messageLookup.Height < 0 {
+		return confirmation.Inclusion{}, fmt.Errorf("searching state for txid %v: negative height", msgID)
+	}
+	return confirmation.Inclusion{
+		Status:    confirmation.TxStatusConfirmed,
+		BlockHash: pack.NewBytes(messageLookup.TipSet.Bytes()),
+		Height:    pack.NewU64(uint64(messageLookup.Height)),
+	}, nil
+}
+
+// TxReceipt returns the outcome of the transaction uniquely identified by the
+// given transaction hash. The block of a transaction is the tipset in which it
+// was executed, and failed transactions use their exit code as the revert
+// reason.
+func (client *Client) TxReceipt(ctx context.Context, txID pack.Bytes) (confirmation.TxReceipt, error) {
+	msgID, err := cid.Parse([]byte(txID))
+	if err != nil {
+		return confirmation.TxReceipt{}, fmt.Errorf("parsing txid: %v", err)
+	}
+	messageLookup, err := client.node.StateSearchMsg(ctx, msgID)
+	if err != nil {
+		return confirmation.TxReceipt{}, fmt.Errorf("searching state for txid: %v", err)
+	}
+	if messageLookup == nil {
+		if _, err := client.node.ChainGetMessage(ctx, msgID); err != nil {
+			return confirmation.TxReceipt{TxHash: txID, Status: confirmation.TxStatusUnknown}, nil
+		}
+		return confirmation.TxReceipt{TxHash: txID, Status: confirmation.TxStatusPending}, nil
+	}
+	if messageLookup.Height < 0 {
+		return confirmation.TxReceipt{}, fmt.Errorf("searching state for txid %v: negative height", msgID)
+	}
+	if messageLookup.Receipt.GasUsed < 0 {
+		return confirmation.TxReceipt{}, fmt.Errorf("searching state for txid %v: negative gas used", msgID)
+	}
+	chainHead, err := client.LatestBlock(ctx)
+	if err != nil {
+		return confirmation.TxReceipt{}, err
+	}
+
+	height := pack.NewU64(uint64(messageLookup.Height))
+	receipt := confirmation.TxReceipt{
+		TxHash:        txID,
+		Status:        confirmation.TxStatusConfirmed,
+		BlockHash:     pack.NewBytes(messageLookup.TipSet.Bytes()),
+		Height:        height,
+		Confirmations: confirmation.Confirmations(height, chainHead),
+		GasUsed:       pack.NewU256FromU64(pack.NewU64(uint64(messageLookup.Receipt.GasUsed))),
+	}
+	if messageLookup.Receipt.ExitCode.IsError() {
+		receipt.Status = confirmation.TxStatusFailed
+		receipt.RevertReason = pack.String(messageLookup.Receipt.ExitCode.String())
+	}
+	return receipt, nil
+}
+
 // SubmitTx to the underlying blockchain network.
 func (client *Client) SubmitTx(ctx context.Context, tx account.Tx) error {
 	switch tx := tx.(type) {
@@ -174,17 +267,60 @@ func (client *Client) SubmitTx(ctx context.Context, tx account.Tx) error {
 	}
 }
 
+// Simulate the execution of the transaction at the current chain head using
+// StateCall, and return the gas used. The transaction does not need to be
+// signed. Transactions that exit with an error code return an unsuccessful
+// simulation with the execution error.
+func (client *Client) Simulate(ctx context.Context, tx account.Tx) (confirmation.Simulation, error) {
+	switch tx := tx.(type) {
+	case *Tx:
+		res, err := client.node.StateCall(ctx, &tx.msg, types.EmptyTSK)
+		if err != nil {
+			return confirmation.Simulation{}, fmt.Errorf("calling state: %v", err)
+		}
+		if res == nil || res.MsgRct == nil {
+			return confirmation.Simulation{}, fmt.Errorf("calling state: nil receipt")
+		}
+		if res.MsgRct.GasUsed < 0 {
+			return confirmation.Simulation{}, fmt.Errorf("calling state: negative gas used")
+		}
+		simulation := confirmation.Simulation{
+			Success: true,
+			GasUsed: pack.NewU256FromU64(pack.NewU64(uint64(res.MsgRct.GasUsed))),
+		}
+		if res.MsgRct.ExitCode.IsError() {
+			simulation.Success = false
+			simulation.RevertReason = pack.String(res.MsgRct.ExitCode.String())
+			if res.Error != "" {
+				simulation.RevertReason = pack.String(res.Error)
+			}
+		}
+		return simulation, nil
+	default:
+		return confirmation.Simulation{}, fmt.Errorf("expected type %T, got type %T", new(Tx), tx)
+	}
+}
+
 // AccountNonce returns the current nonce of the account. This is the nonce to
 // be used while building a new transaction.
 func (client *Client) AccountNonce(ctx context.Context, addr address.Address) (pack.U256, error) {
+	return client.AccountNonceAt(ctx, addr, contract.LatestBlock())
+}
+
+// AccountNonceAt returns the nonce of the account at the given block.
+func (client *Client) AccountNonceAt(ctx context.Context, addr address.Address, block contract.BlockRef) (pack.U256, error) {
 	filAddr, err := filaddress.NewFromString(string(addr))
 	if err != nil {
 		return pack.U256{}, fmt.Errorf("bad address '%v': %v", addr, err)
 	}
+	tsk, err := client.tipSetKey(ctx, block)
+	if err != nil {
+		return pack.U256{}, err
+	}
 
-	actor, err := client.node.StateGetActor(ctx, filAddr, types.NewTipSetKey(cid.Undef))
+	actor, err := client.node.StateGetActor(ctx, filAddr, tsk)
 	if err != nil {
-		return pack.U256{}, fmt.Errorf("searching state for addr %v: %v", addr, err)
+		return pack.U256{}, fmt.Errorf("searching state for addr %v at %v: %v", addr, block, err)
 	}
 
 	return pack.NewU256FromU64(pack.NewU64(actor.Nonce)), nil
@@ -192,14 +328,23 @@ func (client *Client) AccountNonce(ctx context.Context, addr address.Address) (p
 
 // AccountBalance returns the account balancee for a given address.
 func (client *Client) AccountBalance(ctx context.Context, addr address.Address) (pack.U256, error) {
+	return client.AccountBalanceAt(ctx, addr, contract.LatestBlock())
+}
+
+// AccountBalanceAt returns the balance of the account at the given block.
+func (client *Client) AccountBalanceAt(ctx context.Context, addr address.Address, block contract.BlockRef) (pack.U256, error) {
 	filAddr, err := filaddress.NewFromString(string(addr))
 	if err != nil {
 		return pack.U256{}, fmt.Errorf("bad address '%v': %v", addr, err)
 	}
+	tsk, err := client.tipSetKey(ctx, block)
+	if err != nil {
+		return pack.U256{}, err
+	}
 
-	actor, err := client.node.StateGetActor(ctx, filAddr, types.NewTipSetKey(cid.Undef))
+	actor, err := client.node.StateGetActor(ctx, filAddr, tsk)
 	if err != nil {
-		return pack.U256{}, fmt.Errorf("searching state for addr %v: %v", addr, err)
+		return pack.U256{}, fmt.Errorf("searching state for addr %v at %v: %v", addr, block, err)
 	}
 
 	balance := actor.Balance.Int
@@ -211,3 +356,48 @@ func (client *Client) AccountBalance(ctx context.Context, addr address.Address)
 
 	return pack.NewU256FromInt(balance), nil
 }
+
+// tipSetKey returns the key of the tipset from which the node reads the state
+// after the given block. The latest block is the empty key, which the node
+// interprets as the head of the chain. Filecoin does not distinguish safe
+// blocks, so safe and finalized blocks are both the tipset that is
+// FinalityEpochs behind the head.
+func (client *Client) tipSetKey(ctx context.Context, block contract.BlockRef) (types.TipSetKey, error) {
+	if block.Tag == contract.BlockTagLatest {
+		return types.NewTipSetKey(cid.Undef), nil
+	}
+	head, err := client.node.ChainHead(ctx)
+	if err != nil {
+		return types.EmptyTSK, fmt.Errorf("get chain head: %v", err)
+	}
+
+	var height abi.ChainEpoch
+	switch block.Tag {
+	case contract.BlockTagSafe, contract.BlockTagFinalized:
+		height = head.Height() - FinalityEpochs
+		if height < 0 {
+			height = 0
+		}
+	case contract.BlockTagHeight:
+		if block.Height.Uint64() > math.MaxInt64 {
+			return types.EmptyTSK, fmt.Errorf("bad height: expected at most %v, got %v", int64(math.MaxInt64), block.Height)
+		}
+		height = abi.ChainEpoch(block.Height.Uint64())
+	default:
+		return types.EmptyTSK, fmt.Errorf("unsupported block tag %v", block.Tag)
+	}
+
+	// The node reads state from the parent state of a tipset, which does not
+	// include the messages in the tipset itself. The state after the height is
+	// the parent state of the first tipset after it, skipping null rounds.
+	for next := height + 1; next <= head.Height(); next++ {
+		tipset, err := client.node.ChainGetTipSetByHeight(ctx, next, head.Key())
+		if err != nil {
+			return types.EmptyTSK, fmt.Errorf("get tipset at height %v: %v", next, err)
+		}
+		if tipset.Height() > height {
+			return tipset.Key(), nil
+		}
+	}
+	return types.EmptyTSK, fmt.Errorf("state after height %v is not available: head is at height %v", height, head.Height())
+}
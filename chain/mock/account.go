@@ -0,0 +1,391 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sync"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+	"github.com/renproject/surge"
+)
+
+// The AccountTxBuilder is an implementation of an account-based transaction
+// builder for mock account-based chains.
+type AccountTxBuilder struct{}
+
+// NewAccountTxBuilder returns a transaction builder that builds transactions
+// for mock account-based chains.
+func NewAccountTxBuilder() AccountTxBuilder {
+	return AccountTxBuilder{}
+}
+
+// BuildTx returns an unsigned transaction that sends value from the address
+// controlled by the given public key to the given recipient. The fee paid by
+// the sender is the gas limit multiplied by the gas price.
+func (txBuilder AccountTxBuilder) BuildTx(ctx context.Context, fromPubKey *id.PubKey, to address.Address, value, nonce, gasLimit, gasPrice, gasCap pack.U256, payload pack.Bytes) (account.Tx, error) {
+	if fromPubKey == nil {
+		return nil, fmt.Errorf("bad from pubkey: nil")
+	}
+	toAddr, err := normalizeAccountAddress(to)
+	if err != nil {
+		return nil, fmt.Errorf("bad to address '%v': %v", to, err)
+	}
+	return &AccountTx{
+		from:     AccountAddressFromPubKey(fromPubKey),
+		to:       toAddr,
+		value:    value,
+		nonce:    nonce,
+		gasLimit: gasLimit,
+		gasPrice: gasPrice,
+		gasCap:   gasCap,
+		payload:  contract.CallData(payload),
+	}, nil
+}
+
+// AccountTx represents a transaction on a mock account-based chain.
+type AccountTx struct {
+	from     address.Address
+	to       address.Address
+	value    pack.U256
+	nonce    pack.U256
+	gasLimit pack.U256
+	gasPrice pack.U256
+	gasCap   pack.U256
+	payload  contract.CallData
+
+	signature pack.Bytes65
+	signed    bool
+}
+
+// Hash returns the hash that uniquely identifies the transaction. Like most
+// account-based chains, the hash of a signed transaction commits to its
+// signature.
+func (tx AccountTx) Hash() pack.Bytes {
+	preimage := tx.preimage()
+	if tx.signed {
+		preimage = append(preimage, tx.signature[:]...)
+	}
+	return pack.NewBytes(ethcrypto.Keccak256(preimage))
+}
+
+// From returns the address from which value is being sent.
+func (tx AccountTx) From() address.Address {
+	return tx.from
+}
+
+// To returns the address to which value is being sent.
+func (tx AccountTx) To() address.Address {
+	return tx.to
+}
+
+// Value being sent from the sender to the receiver.
+func (tx AccountTx) Value() pack.U256 {
+	return tx.value
+}
+
+// Nonce returns the nonce used to order the transaction with respect to all
+// other transactions signed and submitted by the sender.
+func (tx AccountTx) Nonce() pack.U256 {
+	return tx.nonce
+}
+
+// Payload returns arbitrary data that is associated with the transaction.
+func (tx AccountTx) Payload() contract.CallData {
+	return tx.payload
+}
+
+// Fee returns the maximum amount that will be paid by the sender for including
+// the transaction in a block.
+func (tx AccountTx) Fee() pack.U256 {
+	return pack.NewU256FromInt(new(big.Int).Mul(tx.gasLimit.Int(), tx.gasPrice.Int()))
+}
+
+// Sighashes returns the digests that must be signed before the transaction
+// can be submitted by the client.
+func (tx AccountTx) Sighashes() ([]pack.Bytes32, error) {
+	sighash := [32]byte{}
+	copy(sighash[:], ethcrypto.Keccak256(tx.preimage()))
+	return []pack.Bytes32{pack.NewBytes32(sighash)}, nil
+}
+
+// Sign the transaction by injecting signatures for the required sighashes.
+// The signature is not verified until the transaction is submitted.
+func (tx *AccountTx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
+	if tx.signed {
+		return fmt.Errorf("already signed")
+	}
+	if len(signatures) != 1 {
+		return fmt.Errorf("expected 1 signature, got %v signatures", len(signatures))
+	}
+	tx.signature = signatures[0]
+	tx.signed = true
+	return nil
+}
+
+// Serialize the transaction into bytes.
+func (tx AccountTx) Serialize() (pack.Bytes, error) {
+	serial := tx.preimage()
+	if tx.signed {
+		serial = append(serial, tx.signature[:]...)
+	}
+	return pack.NewBytes(serial), nil
+}
+
+func (tx AccountTx) preimage() []byte {
+	buf := new(bytes.Buffer)
+	for _, v := range []surge.Marshaler{tx.from, tx.to, tx.value, tx.nonce, tx.gasLimit, tx.gasPrice, tx.gasCap, tx.payload} {
+		data, err := surge.ToBinary(v)
+		if err != nil {
+			// All of the fields have a bounded size, so marshaling can only
+			// fail as a result of a programmer error.
+			panic(fmt.Errorf("marshaling tx: %v", err))
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes()
+}
+
+// verify that the transaction has been signed by the private key that controls
+// the sender address.
+func (tx AccountTx) verify() error {
+	if !tx.signed {
+		return fmt.Errorf("not signed")
+	}
+	sighashes, err := tx.Sighashes()
+	if err != nil {
+		return fmt.Errorf("computing sighash: %v", err)
+	}
+	pubKey, err := ethcrypto.SigToPub(sighashes[0][:], tx.signature[:])
+	if err != nil {
+		return fmt.Errorf("recovering pubkey: %v", err)
+	}
+	if signer := AccountAddressFromPubKey((*id.PubKey)(pubKey)); signer != tx.from {
+		return fmt.Errorf("bad signature: expected signer %v, got %v", tx.from, signer)
+	}
+	return nil
+}
+
+type accountTxEntry struct {
+	tx     AccountTx
+	height uint64
+}
+
+// AccountClient is an in-memory implementation of an account-based chain. It
+// implements the account.Client interface.
+type AccountClient struct {
+	opts ClientOptions
+
+	mu       *sync.RWMutex
+	head     uint64
+	balances map[address.Address]*big.Int
+	nonces   map[address.Address]uint64
+	mempool  []string
+	txs      map[string]*accountTxEntry
+}
+
+// NewAccountClient returns an empty mock account-based chain. Use Fund to
+// give accounts an initial balance.
+func NewAccountClient(opts ClientOptions) *AccountClient {
+	return &AccountClient{
+		opts: opts,
+
+		mu:       new(sync.RWMutex),
+		head:     0,
+		balances: map[address.Address]*big.Int{},
+		nonces:   map[address.Address]uint64{},
+		mempool:  []string{},
+		txs:      map[string]*accountTxEntry{},
+	}
+}
+
+// Fund credits the given address with the given value. The value is available
+// immediately, and does not require a block to be mined.
+func (client *AccountClient) Fund(addr address.Address, value pack.U256) error {
+	targetAddr, err := normalizeAccountAddress(addr)
+	if err != nil {
+		return fmt.Errorf("bad address '%v': %v", addr, err)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.credit(targetAddr, value.Int())
+	return nil
+}
+
+// Mine the given number of blocks. All transactions in the mempool are
+// included in the first block that is mined.
+func (client *AccountClient) Mine(numBlocks uint64) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.mine(numBlocks)
+}
+
+// LatestBlock returns the height of the latest mined block.
+func (client *AccountClient) LatestBlock(ctx context.Context) (pack.U64, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	return pack.NewU64(client.head), nil
+}
+
+// AccountBalance returns the confirmed balance of the given address.
+func (client *AccountClient) AccountBalance(ctx context.Context, addr address.Address) (pack.U256, error) {
+	targetAddr, err := normalizeAccountAddress(addr)
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad address '%v': %v", addr, err)
+	}
+
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	return pack.NewU256FromInt(client.balance(targetAddr)), nil
+}
+
+// AccountNonce returns the confirmed nonce of the given address. This is the
+// nonce that must be used to build the next transaction, assuming that there
+// are no transactions from the address waiting in the mempool.
+func (client *AccountClient) AccountNonce(ctx context.Context, addr address.Address) (pack.U256, error) {
+	targetAddr, err := normalizeAccountAddress(addr)
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("bad address '%v': %v", addr, err)
+	}
+
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	return pack.NewU256FromU64(pack.NewU64(client.nonces[targetAddr])), nil
+}
+
+// Tx returns the transaction uniquely identified by the given transaction
+// hash, and its number of confirmations. Transactions that are in the mempool
+// have zero confirmations.
+func (client *AccountClient) Tx(ctx context.Context, txHash pack.Bytes) (account.Tx, pack.U64, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	entry, ok := client.txs[hex.EncodeToString(txHash)]
+	if !ok {
+		return nil, pack.NewU64(0), fmt.Errorf("tx %v: %w", txHash, confirmation.ErrTxNotFound)
+	}
+	tx := entry.tx
+	return &tx, confirmations(client.head, entry.height), nil
+}
+
+// TxReceipt returns the outcome of the transaction uniquely identified by the
+// given transaction hash. The gas used by a transaction is its gas limit.
+func (client *AccountClient) TxReceipt(ctx context.Context, txHash pack.Bytes) (confirmation.TxReceipt, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	entry, ok := client.txs[hex.EncodeToString(txHash)]
+	if !ok {
+		return confirmation.TxReceipt{TxHash: txHash, Status: confirmation.TxStatusUnknown}, nil
+	}
+	receipt := txReceipt(txHash, client.head, entry.height)
+	if receipt.Status == confirmation.TxStatusConfirmed {
+		receipt.GasUsed = entry.tx.gasLimit
+	}
+	return receipt, nil
+}
+
+// SubmitTx to the mempool. The transaction is rejected if it is not signed by
+// the sender, if its nonce is not the next nonce of the sender, or if the
+// sender cannot afford to pay for the transaction.
+func (client *AccountClient) SubmitTx(ctx context.Context, tx account.Tx) error {
+	mockTx, ok := tx.(*AccountTx)
+	if !ok {
+		return fmt.Errorf("expected type %T, got type %T", new(AccountTx), tx)
+	}
+	if err := mockTx.verify(); err != nil {
+		return fmt.Errorf("verifying tx: %v", err)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	txHash := hex.EncodeToString(mockTx.Hash())
+	if _, ok := client.txs[txHash]; ok {
+		return fmt.Errorf("tx %v: already submitted", txHash)
+	}
+
+	// Compute the nonce and balance of the sender, assuming that everything in
+	// the mempool will be included in the next block.
+	pendingNonce := client.nonces[mockTx.from]
+	pendingBalance := new(big.Int).Set(client.balance(mockTx.from))
+	for _, pendingTxHash := range client.mempool {
+		pendingTx := client.txs[pendingTxHash].tx
+		if pendingTx.from == mockTx.from {
+			pendingNonce++
+			pendingBalance.Sub(pendingBalance, pendingTx.value.Int())
+			pendingBalance.Sub(pendingBalance, pendingTx.Fee().Int())
+		}
+		if pendingTx.to == mockTx.from {
+			pendingBalance.Add(pendingBalance, pendingTx.value.Int())
+		}
+	}
+
+	if mockTx.nonce.Int().Cmp(new(big.Int).SetUint64(pendingNonce)) != 0 {
+		return fmt.Errorf("bad nonce: expected %v, got %v", pendingNonce, mockTx.nonce)
+	}
+	cost := new(big.Int).Add(mockTx.value.Int(), mockTx.Fee().Int())
+	if pendingBalance.Cmp(cost) < 0 {
+		return fmt.Errorf("insufficient balance: expected >= %v, got %v", cost, pendingBalance)
+	}
+
+	client.txs[txHash] = &accountTxEntry{tx: *mockTx}
+	client.mempool = append(client.mempool, txHash)
+	client.mine(client.opts.AutoMine)
+	return nil
+}
+
+func (client *AccountClient) mine(numBlocks uint64) {
+	for i := uint64(0); i < numBlocks; i++ {
+		client.head++
+		for _, txHash := range client.mempool {
+			entry := client.txs[txHash]
+			client.debit(entry.tx.from, new(big.Int).Add(entry.tx.value.Int(), entry.tx.Fee().Int()))
+			client.credit(entry.tx.to, entry.tx.value.Int())
+			client.nonces[entry.tx.from]++
+			entry.height = client.head
+		}
+		client.mempool = []string{}
+	}
+}
+
+func (client *AccountClient) balance(addr address.Address) *big.Int {
+	balance, ok := client.balances[addr]
+	if !ok {
+		return new(big.Int)
+	}
+	return balance
+}
+
+func (client *AccountClient) credit(addr address.Address, value *big.Int) {
+	client.balances[addr] = new(big.Int).Add(client.balance(addr), value)
+}
+
+func (client *AccountClient) debit(addr address.Address, value *big.Int) {
+	client.balances[addr] = new(big.Int).Sub(client.balance(addr), value)
+}
+
+// normalizeAccountAddress returns the checksummed form of the given address,
+// so that it can be used as a key when looking up balances and nonces.
+func normalizeAccountAddress(addr address.Address) (address.Address, error) {
+	encodeDecoder := NewAccountAddressEncodeDecoder()
+	rawAddr, err := encodeDecoder.DecodeAddress(addr)
+	if err != nil {
+		return address.Address(""), err
+	}
+	return encodeDecoder.EncodeAddress(rawAddr)
+}
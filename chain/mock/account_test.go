@@ -0,0 +1,126 @@
+package mock_test
+
+import (
+	"context"
+
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/chain/mock"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Account", func() {
+	ctx := context.Background()
+
+	signAccountTx := func(tx account.Tx, privKey *id.PrivKey) {
+		sighashes, err := tx.Sighashes()
+		Expect(err).ToNot(HaveOccurred())
+		hash := id.Hash(sighashes[0])
+		signature, err := privKey.Sign(&hash)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tx.Sign([]pack.Bytes65{pack.NewBytes65(signature)}, nil)).To(Succeed())
+	}
+
+	buildAccountTx := func(privKey *id.PrivKey, payload pack.String, value, nonce uint64) account.Tx {
+		tx, err := mock.NewAccountTxBuilder().BuildTx(
+			ctx,
+			privKey.PubKey(),
+			mock.AccountAddressFromPubKey(id.NewPrivKey().PubKey()),
+			pack.NewU256FromUint64(value),
+			pack.NewU256FromUint64(nonce),
+			pack.NewU256FromUint64(21000),
+			pack.NewU256FromUint64(1),
+			pack.NewU256FromUint64(1),
+			pack.Bytes(payload),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		return tx
+	}
+
+	Context("when submitting a signed transaction", func() {
+		It("should update balances and nonces once mined", func() {
+			client := mock.NewAccountClient(mock.DefaultClientOptions())
+			privKey := id.NewPrivKey()
+			from := mock.AccountAddressFromPubKey(privKey.PubKey())
+			Expect(client.Fund(from, pack.NewU256FromUint64(100000))).To(Succeed())
+
+			tx := buildAccountTx(privKey, "multichain", 1000, 0)
+			signAccountTx(tx, privKey)
+			Expect(client.SubmitTx(ctx, tx)).To(Succeed())
+
+			// The transaction is in the mempool.
+			_, confs, err := client.Tx(ctx, tx.Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(pack.NewU64(0)))
+			nonce, err := client.AccountNonce(ctx, from)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nonce).To(Equal(pack.NewU256FromUint64(0)))
+
+			// The transaction is confirmed.
+			client.Mine(3)
+			_, confs, err = client.Tx(ctx, tx.Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(pack.NewU64(3)))
+			nonce, err = client.AccountNonce(ctx, from)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nonce).To(Equal(pack.NewU256FromUint64(1)))
+			balance, err := client.AccountBalance(ctx, from)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance).To(Equal(pack.NewU256FromUint64(100000 - 1000 - 21000)))
+			balance, err = client.AccountBalance(ctx, tx.To())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance).To(Equal(pack.NewU256FromUint64(1000)))
+		})
+	})
+
+	Context("when auto-mining is enabled", func() {
+		It("should confirm transactions immediately", func() {
+			client := mock.NewAccountClient(mock.DefaultClientOptions().WithAutoMine(6))
+			privKey := id.NewPrivKey()
+			Expect(client.Fund(mock.AccountAddressFromPubKey(privKey.PubKey()), pack.NewU256FromUint64(100000))).To(Succeed())
+
+			tx := buildAccountTx(privKey, "", 1000, 0)
+			signAccountTx(tx, privKey)
+			Expect(client.SubmitTx(ctx, tx)).To(Succeed())
+
+			_, confs, err := client.Tx(ctx, tx.Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(pack.NewU64(6)))
+		})
+	})
+
+	Context("when submitting an invalid transaction", func() {
+		It("should reject a transaction signed by the wrong key", func() {
+			client := mock.NewAccountClient(mock.DefaultClientOptions())
+			privKey := id.NewPrivKey()
+			Expect(client.Fund(mock.AccountAddressFromPubKey(privKey.PubKey()), pack.NewU256FromUint64(100000))).To(Succeed())
+
+			tx := buildAccountTx(privKey, "", 1000, 0)
+			signAccountTx(tx, id.NewPrivKey())
+			Expect(client.SubmitTx(ctx, tx)).ToNot(Succeed())
+		})
+
+		It("should reject a transaction with the wrong nonce", func() {
+			client := mock.NewAccountClient(mock.DefaultClientOptions())
+			privKey := id.NewPrivKey()
+			Expect(client.Fund(mock.AccountAddressFromPubKey(privKey.PubKey()), pack.NewU256FromUint64(100000))).To(Succeed())
+
+			tx := buildAccountTx(privKey, "", 1000, 1)
+			signAccountTx(tx, privKey)
+			Expect(client.SubmitTx(ctx, tx)).ToNot(Succeed())
+		})
+
+		It("should reject a transaction that cannot be afforded", func() {
+			client := mock.NewAccountClient(mock.DefaultClientOptions())
+			privKey := id.NewPrivKey()
+			Expect(client.Fund(mock.AccountAddressFromPubKey(privKey.PubKey()), pack.NewU256FromUint64(21999))).To(Succeed())
+
+			tx := buildAccountTx(privKey, "", 1000, 0)
+			signAccountTx(tx, privKey)
+			Expect(client.SubmitTx(ctx, tx)).ToNot(Succeed())
+		})
+	})
+})
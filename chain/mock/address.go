@@ -0,0 +1,88 @@
+package mock
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/address"
+)
+
+// AccountAddressEncodeDecoder implements the address.EncodeDecoder interface
+// for mock account-based chains. Addresses are 20 bytes, and are encoded as
+// 0x-prefixed hex strings (the same as Ethereum addresses).
+type AccountAddressEncodeDecoder struct{}
+
+// NewAccountAddressEncodeDecoder constructs a new AccountAddressEncodeDecoder.
+func NewAccountAddressEncodeDecoder() address.EncodeDecoder {
+	return AccountAddressEncodeDecoder{}
+}
+
+// EncodeAddress implements the address.Encoder interface.
+func (AccountAddressEncodeDecoder) EncodeAddress(rawAddr address.RawAddress) (address.Address, error) {
+	if len(rawAddr) != common.AddressLength {
+		return address.Address(""), fmt.Errorf("invalid address length: expected %v, got %v", common.AddressLength, len(rawAddr))
+	}
+	return address.Address(common.BytesToAddress(rawAddr).Hex()), nil
+}
+
+// DecodeAddress implements the address.Decoder interface.
+func (AccountAddressEncodeDecoder) DecodeAddress(addr address.Address) (address.RawAddress, error) {
+	str := strings.TrimPrefix(string(addr), "0x")
+	if len(str) != 2*common.AddressLength {
+		return nil, fmt.Errorf("invalid address %v: bad length", addr)
+	}
+	rawAddr, err := hex.DecodeString(str)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %v: %v", addr, err)
+	}
+	return address.RawAddress(rawAddr), nil
+}
+
+// AccountAddressFromPubKey returns the mock account-based address that is
+// controlled by the given public key.
+func AccountAddressFromPubKey(pubKey *id.PubKey) address.Address {
+	return address.Address(ethcrypto.PubkeyToAddress(ecdsa.PublicKey(*pubKey)).Hex())
+}
+
+// UTXOAddressEncodeDecoder implements the address.EncodeDecoder interface for
+// mock utxo-based chains. Addresses are the 20 byte HASH160 of the compressed
+// public key, and are encoded as hex strings.
+type UTXOAddressEncodeDecoder struct{}
+
+// NewUTXOAddressEncodeDecoder constructs a new UTXOAddressEncodeDecoder.
+func NewUTXOAddressEncodeDecoder() address.EncodeDecoder {
+	return UTXOAddressEncodeDecoder{}
+}
+
+// EncodeAddress implements the address.Encoder interface.
+func (UTXOAddressEncodeDecoder) EncodeAddress(rawAddr address.RawAddress) (address.Address, error) {
+	if len(rawAddr) != 20 {
+		return address.Address(""), fmt.Errorf("invalid address length: expected 20, got %v", len(rawAddr))
+	}
+	return address.Address(hex.EncodeToString(rawAddr)), nil
+}
+
+// DecodeAddress implements the address.Decoder interface.
+func (UTXOAddressEncodeDecoder) DecodeAddress(addr address.Address) (address.RawAddress, error) {
+	rawAddr, err := hex.DecodeString(string(addr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %v: %v", addr, err)
+	}
+	if len(rawAddr) != 20 {
+		return nil, fmt.Errorf("invalid address %v: bad length", addr)
+	}
+	return address.RawAddress(rawAddr), nil
+}
+
+// UTXOAddressFromPubKey returns the mock utxo-based address that is controlled
+// by the given public key.
+func UTXOAddressFromPubKey(pubKey *id.PubKey) address.Address {
+	serializedPubKey := ethcrypto.CompressPubkey((*ecdsa.PublicKey)(pubKey))
+	return address.Address(hex.EncodeToString(btcutil.Hash160(serializedPubKey)))
+}
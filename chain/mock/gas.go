@@ -0,0 +1,43 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/renproject/multichain/api/gas"
+	"github.com/renproject/pack"
+)
+
+var (
+	// DefaultGasPrice returned by the GasEstimator when no price is specified.
+	DefaultGasPrice = pack.NewU256FromU64(pack.NewU64(1))
+	// DefaultGasCap returned by the GasEstimator when no cap is specified.
+	DefaultGasCap = pack.NewU256FromU64(pack.NewU64(1))
+)
+
+// A GasEstimator returns a fixed gas price and gas cap. Mock chains do not
+// have congestion, so there is no need to estimate anything.
+type GasEstimator struct {
+	gasPrice pack.U256
+	gasCap   pack.U256
+}
+
+// NewGasEstimator returns a simple gas estimator that always returns the given
+// gas price and gas cap.
+func NewGasEstimator(gasPrice, gasCap pack.U256) gas.Estimator {
+	return &GasEstimator{
+		gasPrice: gasPrice,
+		gasCap:   gasCap,
+	}
+}
+
+// NewDefaultGasEstimator returns a gas estimator that always returns the
+// default gas price and gas cap.
+func NewDefaultGasEstimator() gas.Estimator {
+	return NewGasEstimator(DefaultGasPrice, DefaultGasCap)
+}
+
+// EstimateGas returns the gas price and gas cap that were given to the
+// estimator when it was constructed.
+func (gasEstimator *GasEstimator) EstimateGas(ctx context.Context) (pack.U256, pack.U256, error) {
+	return gasEstimator.gasPrice, gasEstimator.gasCap, nil
+}
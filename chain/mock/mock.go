@@ -0,0 +1,81 @@
+// Package mock implements in-memory account-based and utxo-based chains. The
+// chains keep their entire ledger in memory, and blocks are only produced when
+// they are explicitly mined (or automatically mined after each submission, if
+// configured to do so). Signatures are verified in the same way that they
+// would be verified by a real chain, so the mock chains can be used to test
+// applications that build, sign, and submit transactions using the multichain
+// APIs, without needing to run any of the nodes in the infra directory.
+//
+// The AccountMocker1 and AccountMocker2 chains should be served by an
+// AccountClient, and the UTXOMocker chain should be served by a UTXOClient.
+package mock
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultAutoMine used by the clients. By default, submitted transactions
+	// remain in the mempool until blocks are explicitly mined.
+	DefaultAutoMine = 0
+)
+
+// ClientOptions are used to parameterise the behaviour of the mock clients.
+type ClientOptions struct {
+	// AutoMine is the number of blocks that are mined immediately after a
+	// transaction is successfully submitted. This can be used to control the
+	// confirmation depth that submitted transactions will have when they are
+	// first queried.
+	AutoMine uint64
+}
+
+// DefaultClientOptions returns ClientOptions with the default settings.
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		AutoMine: DefaultAutoMine,
+	}
+}
+
+// WithAutoMine sets the number of blocks that are mined after every successful
+// transaction submission.
+func (opts ClientOptions) WithAutoMine(autoMine uint64) ClientOptions {
+	opts.AutoMine = autoMine
+	return opts
+}
+
+// confirmations returns the number of confirmations of something that was
+// included at the given height, when the chain is at the given head. Something
+// that has not been included yet (height of zero) has no confirmations.
+func confirmations(head, height uint64) pack.U64 {
+	if height == 0 || height > head {
+		return pack.NewU64(0)
+	}
+	return pack.NewU64(head - height + 1)
+}
+
+// blockHash returns the hash of the block at the given height. Mock chains
+// never reorg, so the hash only depends on the height.
+func blockHash(height uint64) pack.Bytes {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("block %v", height)))
+	return pack.NewBytes(hash[:])
+}
+
+// txReceipt returns the receipt for a transaction that was included at the
+// given height, when the chain is at the given head. Transactions on mock
+// chains never fail.
+func txReceipt(txHash pack.Bytes, head, height uint64) confirmation.TxReceipt {
+	if height == 0 {
+		return confirmation.TxReceipt{TxHash: txHash, Status: confirmation.TxStatusPending}
+	}
+	return confirmation.TxReceipt{
+		TxHash:        txHash,
+		Status:        confirmation.TxStatusConfirmed,
+		BlockHash:     blockHash(height),
+		Height:        pack.NewU64(height),
+		Confirmations: confirmations(head, height),
+	}
+}
@@ -0,0 +1,478 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/btcsuite/btcutil"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// The UTXOTxBuilder is an implementation of a utxo-based transaction builder
+// for mock utxo-based chains.
+type UTXOTxBuilder struct{}
+
+// NewUTXOTxBuilder returns a transaction builder that builds transactions for
+// mock utxo-based chains.
+func NewUTXOTxBuilder() UTXOTxBuilder {
+	return UTXOTxBuilder{}
+}
+
+// BuildTx returns a transaction that consumes funds from the given inputs, and
+// sends them to the given recipients. The difference in the sum value of the
+// inputs and the sum value of the recipients is paid as a fee. The pubkey
+// script of every output is the raw address of its recipient.
+func (txBuilder UTXOTxBuilder) BuildTx(inputs []utxo.Input, recipients []utxo.Recipient) (utxo.Tx, error) {
+	addrDecoder := NewUTXOAddressEncodeDecoder()
+	outputs := make([]utxo.Output, len(recipients))
+	for i, recipient := range recipients {
+		rawAddr, err := addrDecoder.DecodeAddress(recipient.To)
+		if err != nil {
+			return nil, fmt.Errorf("bad recipient %v: %v", i, err)
+		}
+		outputs[i] = utxo.Output{
+			Outpoint: utxo.Outpoint{
+				Index: pack.NewU32(uint32(i)),
+			},
+			Value:        recipient.Value,
+			PubKeyScript: pack.NewBytes(rawAddr),
+		}
+	}
+	tx := &UTXOTx{
+		inputs:  inputs,
+		outputs: outputs,
+	}
+
+	// Now that the transaction is fully defined, the outpoints of the outputs
+	// can be filled in.
+	hash, err := tx.Hash()
+	if err != nil {
+		return nil, err
+	}
+	for i := range tx.outputs {
+		tx.outputs[i].Outpoint.Hash = hash
+	}
+	return tx, nil
+}
+
+// UTXOTx represents a transaction on a mock utxo-based chain.
+type UTXOTx struct {
+	inputs  []utxo.Input
+	outputs []utxo.Output
+
+	signatures []pack.Bytes65
+	pubKey     pack.Bytes
+	signed     bool
+}
+
+// Hash returns the hash that uniquely identifies the transaction. The hash does
+// not commit to the signatures (similar to a segwit transaction), so it is
+// known before the transaction is signed.
+func (tx *UTXOTx) Hash() (pack.Bytes, error) {
+	first := sha256.Sum256(tx.preimage())
+	second := sha256.Sum256(first[:])
+	return pack.NewBytes(second[:]), nil
+}
+
+// Inputs consumed by the transaction.
+func (tx *UTXOTx) Inputs() ([]utxo.Input, error) {
+	return tx.inputs, nil
+}
+
+// Outputs produced by the transaction.
+func (tx *UTXOTx) Outputs() ([]utxo.Output, error) {
+	return tx.outputs, nil
+}
+
+// Sighashes returns the digests that must be signed before the transaction
+// can be submitted by the client. There is one sighash per input, and each
+// sighash commits to the value and pubkey script of the output being spent.
+func (tx *UTXOTx) Sighashes() ([]pack.Bytes32, error) {
+	sighashes := make([]pack.Bytes32, len(tx.inputs))
+	for i, input := range tx.inputs {
+		buf := bytes.NewBuffer(tx.preimage())
+		if err := binary.Write(buf, binary.LittleEndian, uint32(i)); err != nil {
+			return nil, fmt.Errorf("writing index: %v", err)
+		}
+		buf.Write(u256Bytes(input.Value))
+		buf.Write(input.PubKeyScript)
+		sighashes[i] = pack.NewBytes32(sha256.Sum256(buf.Bytes()))
+	}
+	return sighashes, nil
+}
+
+// Sign the transaction by injecting signatures for the required sighashes. All
+// inputs must be spendable by the given public key. The signatures are not
+// verified until the transaction is submitted.
+func (tx *UTXOTx) Sign(signatures []pack.Bytes65, pubKey pack.Bytes) error {
+	if tx.signed {
+		return fmt.Errorf("already signed")
+	}
+	if len(signatures) != len(tx.inputs) {
+		return fmt.Errorf("expected %v signatures, got %v signatures", len(tx.inputs), len(signatures))
+	}
+	tx.signatures = signatures
+	tx.pubKey = pubKey
+	tx.signed = true
+	return nil
+}
+
+// Serialize the transaction into bytes.
+func (tx *UTXOTx) Serialize() (pack.Bytes, error) {
+	return json.Marshal(struct {
+		Inputs     []utxo.Input   `json:"inputs"`
+		Outputs    []utxo.Output  `json:"outputs"`
+		Signatures []pack.Bytes65 `json:"signatures"`
+		PubKey     pack.Bytes     `json:"pubKey"`
+	}{
+		Inputs:     tx.inputs,
+		Outputs:    tx.outputs,
+		Signatures: tx.signatures,
+		PubKey:     tx.pubKey,
+	})
+}
+
+func (tx *UTXOTx) preimage() []byte {
+	buf := new(bytes.Buffer)
+	for _, input := range tx.inputs {
+		buf.Write(input.Hash)
+		binary.Write(buf, binary.LittleEndian, input.Index.Uint32())
+	}
+	for _, output := range tx.outputs {
+		buf.Write(u256Bytes(output.Value))
+		buf.Write(output.PubKeyScript)
+	}
+	return buf.Bytes()
+}
+
+// verify that every input has been signed by the private key that controls the
+// output being spent.
+func (tx *UTXOTx) verify() error {
+	if !tx.signed {
+		return fmt.Errorf("not signed")
+	}
+	pubKey, err := pubKeyFromBytes(tx.pubKey)
+	if err != nil {
+		return fmt.Errorf("bad pubkey: %v", err)
+	}
+	pubKeyHash := btcutil.Hash160(ethcrypto.CompressPubkey(pubKey))
+	sighashes, err := tx.Sighashes()
+	if err != nil {
+		return fmt.Errorf("computing sighashes: %v", err)
+	}
+	for i, input := range tx.inputs {
+		if !bytes.Equal(input.PubKeyScript, pubKeyHash) {
+			return fmt.Errorf("bad input %v: not spendable by pubkey", i)
+		}
+		if !ethcrypto.VerifySignature(tx.pubKey, sighashes[i][:], tx.signatures[i][:64]) {
+			return fmt.Errorf("bad input %v: invalid signature", i)
+		}
+	}
+	return nil
+}
+
+type utxoEntry struct {
+	output utxo.Output
+	txHash string
+	spent  bool
+}
+
+type utxoTxEntry struct {
+	tx     *UTXOTx
+	height uint64
+}
+
+// UTXOClient is an in-memory implementation of a utxo-based chain. It
+// implements the utxo.Client interface.
+type UTXOClient struct {
+	opts ClientOptions
+
+	mu      *sync.RWMutex
+	head    uint64
+	outputs map[string]*utxoEntry
+	mempool []string
+	txs     map[string]*utxoTxEntry
+	funded  uint64
+}
+
+// NewUTXOClient returns an empty mock utxo-based chain. Use Fund to create
+// outputs that can be spent.
+func NewUTXOClient(opts ClientOptions) *UTXOClient {
+	return &UTXOClient{
+		opts: opts,
+
+		mu:      new(sync.RWMutex),
+		head:    0,
+		outputs: map[string]*utxoEntry{},
+		mempool: []string{},
+		txs:     map[string]*utxoTxEntry{},
+		funded:  0,
+	}
+}
+
+// Fund mines a new block that contains a coinbase transaction sending the given
+// value to the given address. The output produced by the coinbase transaction
+// is returned.
+func (client *UTXOClient) Fund(addr address.Address, value pack.U256) (utxo.Output, error) {
+	rawAddr, err := NewUTXOAddressEncodeDecoder().DecodeAddress(addr)
+	if err != nil {
+		return utxo.Output{}, fmt.Errorf("bad address '%v': %v", addr, err)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// Coinbase transactions have no inputs, so a unique input is used to make
+	// sure that every coinbase transaction has a unique hash.
+	client.funded++
+	coinbaseHash := sha256.Sum256([]byte(fmt.Sprintf("coinbase %v", client.funded)))
+	tx := &UTXOTx{
+		inputs: []utxo.Input{{
+			Output: utxo.Output{
+				Outpoint: utxo.Outpoint{
+					Hash:  pack.NewBytes(coinbaseHash[:]),
+					Index: pack.NewU32(^uint32(0)),
+				},
+			},
+		}},
+		outputs: []utxo.Output{{
+			Outpoint: utxo.Outpoint{
+				Index: pack.NewU32(0),
+			},
+			Value:        value,
+			PubKeyScript: pack.NewBytes(rawAddr),
+		}},
+		signed: true,
+	}
+	txHash, err := tx.Hash()
+	if err != nil {
+		return utxo.Output{}, err
+	}
+	tx.outputs[0].Outpoint.Hash = txHash
+
+	client.insertTx(tx)
+	client.mine(1)
+	return tx.outputs[0], nil
+}
+
+// Mine the given number of blocks. All transactions in the mempool are
+// included in the first block that is mined.
+func (client *UTXOClient) Mine(numBlocks uint64) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.mine(numBlocks)
+}
+
+// LatestBlock returns the height of the latest mined block.
+func (client *UTXOClient) LatestBlock(ctx context.Context) (pack.U64, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	return pack.NewU64(client.head), nil
+}
+
+// Output returns the output identified by the given outpoint, and its number
+// of confirmations. Outputs are returned even if they have been spent.
+func (client *UTXOClient) Output(ctx context.Context, outpoint utxo.Outpoint) (utxo.Output, pack.U64, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	entry, ok := client.outputs[outpointKey(outpoint)]
+	if !ok {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("output %v:%v: not found", outpoint.Hash, outpoint.Index)
+	}
+	return entry.output, confirmations(client.head, client.txs[entry.txHash].height), nil
+}
+
+// UnspentOutput returns the output identified by the given outpoint, and its
+// number of confirmations. An error is returned if the output has been spent,
+// even if the spending transaction is still in the mempool.
+func (client *UTXOClient) UnspentOutput(ctx context.Context, outpoint utxo.Outpoint) (utxo.Output, pack.U64, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	entry, ok := client.outputs[outpointKey(outpoint)]
+	if !ok {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("output %v:%v: not found", outpoint.Hash, outpoint.Index)
+	}
+	if entry.spent {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("output %v:%v: spent", outpoint.Hash, outpoint.Index)
+	}
+	return entry.output, confirmations(client.head, client.txs[entry.txHash].height), nil
+}
+
+// UnspentOutputs returns all unspent outputs that are spendable by the given
+// address, and that have at least the given number of confirmations.
+func (client *UTXOClient) UnspentOutputs(ctx context.Context, minConf uint64, addr address.Address) ([]utxo.Output, error) {
+	rawAddr, err := NewUTXOAddressEncodeDecoder().DecodeAddress(addr)
+	if err != nil {
+		return nil, fmt.Errorf("bad address '%v': %v", addr, err)
+	}
+
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	outputs := []utxo.Output{}
+	for _, entry := range client.outputs {
+		if entry.spent || !bytes.Equal(entry.output.PubKeyScript, rawAddr) {
+			continue
+		}
+		if uint64(confirmations(client.head, client.txs[entry.txHash].height)) < minConf {
+			continue
+		}
+		outputs = append(outputs, entry.output)
+	}
+	return outputs, nil
+}
+
+// SubmitTx to the mempool. The transaction is rejected if any of its inputs do
+// not exist, have already been spent, or are not correctly signed. It is also
+// rejected if the sum value of its outputs exceeds the sum value of its
+// inputs. Outputs in the mempool can be spent before they are mined.
+func (client *UTXOClient) SubmitTx(ctx context.Context, tx utxo.Tx) error {
+	mockTx, ok := tx.(*UTXOTx)
+	if !ok {
+		return fmt.Errorf("expected type %T, got type %T", new(UTXOTx), tx)
+	}
+	if err := mockTx.verify(); err != nil {
+		return fmt.Errorf("verifying tx: %v", err)
+	}
+	txHash, err := mockTx.Hash()
+	if err != nil {
+		return fmt.Errorf("bad hash: %v", err)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if _, ok := client.txs[hex.EncodeToString(txHash)]; ok {
+		return fmt.Errorf("tx %v: already submitted", txHash)
+	}
+
+	inputValue := new(big.Int)
+	spending := map[string]bool{}
+	for i, input := range mockTx.inputs {
+		key := outpointKey(input.Outpoint)
+		entry, ok := client.outputs[key]
+		if !ok {
+			return fmt.Errorf("bad input %v: not found", i)
+		}
+		if entry.spent || spending[key] {
+			return fmt.Errorf("bad input %v: already spent", i)
+		}
+		if entry.output.Value.Int().Cmp(input.Value.Int()) != 0 || !bytes.Equal(entry.output.PubKeyScript, input.PubKeyScript) {
+			return fmt.Errorf("bad input %v: does not match output", i)
+		}
+		spending[key] = true
+		inputValue.Add(inputValue, entry.output.Value.Int())
+	}
+	outputValue := new(big.Int)
+	for i, output := range mockTx.outputs {
+		if !bytes.Equal(output.Hash, txHash) || output.Index.Uint32() != uint32(i) {
+			return fmt.Errorf("bad output %v: bad outpoint", i)
+		}
+		outputValue.Add(outputValue, output.Value.Int())
+	}
+	if inputValue.Cmp(outputValue) < 0 {
+		return fmt.Errorf("insufficient input value: expected >= %v, got %v", outputValue, inputValue)
+	}
+
+	client.insertTx(mockTx)
+	client.mine(client.opts.AutoMine)
+	return nil
+}
+
+// TxSenders returns the addresses of the outputs spent by the transaction.
+func (client *UTXOClient) TxSenders(ctx context.Context, txHash pack.Bytes) ([]pack.String, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	entry, ok := client.txs[hex.EncodeToString(txHash)]
+	if !ok {
+		return nil, fmt.Errorf("tx %v: not found", txHash)
+	}
+	addrEncoder := NewUTXOAddressEncodeDecoder()
+	senders := make([]pack.String, 0, len(entry.tx.inputs))
+	for _, input := range entry.tx.inputs {
+		spent, ok := client.outputs[outpointKey(input.Outpoint)]
+		if !ok {
+			// Coinbase transactions do not have any senders.
+			continue
+		}
+		addr, err := addrEncoder.EncodeAddress(address.RawAddress(spent.output.PubKeyScript))
+		if err != nil {
+			return nil, fmt.Errorf("encoding sender: %v", err)
+		}
+		senders = append(senders, pack.String(addr))
+	}
+	return senders, nil
+}
+
+// TxReceipt returns the outcome of the transaction uniquely identified by the
+// given transaction hash.
+func (client *UTXOClient) TxReceipt(ctx context.Context, txHash pack.Bytes) (confirmation.TxReceipt, error) {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	entry, ok := client.txs[hex.EncodeToString(txHash)]
+	if !ok {
+		return confirmation.TxReceipt{TxHash: txHash, Status: confirmation.TxStatusUnknown}, nil
+	}
+	return txReceipt(txHash, client.head, entry.height), nil
+}
+
+func (client *UTXOClient) insertTx(tx *UTXOTx) {
+	txHash, _ := tx.Hash()
+	key := hex.EncodeToString(txHash)
+	for _, input := range tx.inputs {
+		if entry, ok := client.outputs[outpointKey(input.Outpoint)]; ok {
+			entry.spent = true
+		}
+	}
+	for _, output := range tx.outputs {
+		client.outputs[outpointKey(output.Outpoint)] = &utxoEntry{output: output, txHash: key}
+	}
+	client.txs[key] = &utxoTxEntry{tx: tx}
+	client.mempool = append(client.mempool, key)
+}
+
+func (client *UTXOClient) mine(numBlocks uint64) {
+	for i := uint64(0); i < numBlocks; i++ {
+		client.head++
+		for _, txHash := range client.mempool {
+			client.txs[txHash].height = client.head
+		}
+		client.mempool = []string{}
+	}
+}
+
+func outpointKey(outpoint utxo.Outpoint) string {
+	return fmt.Sprintf("%v:%v", hex.EncodeToString(outpoint.Hash), outpoint.Index.Uint32())
+}
+
+// u256Bytes returns the 32 byte big-endian representation of the given value.
+func u256Bytes(value pack.U256) []byte {
+	return value.Int().FillBytes(make([]byte, 32))
+}
+
+// pubKeyFromBytes parses a serialized public key, in either compressed or
+// uncompressed form.
+func pubKeyFromBytes(pubKey []byte) (*ecdsa.PublicKey, error) {
+	if len(pubKey) == 33 {
+		return ethcrypto.DecompressPubkey(pubKey)
+	}
+	return ethcrypto.UnmarshalPubkey(pubKey)
+}
@@ -0,0 +1,166 @@
+package mock_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/mock"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UTXO", func() {
+	ctx := context.Background()
+
+	signUTXOTx := func(tx utxo.Tx, privKey *id.PrivKey) {
+		sighashes, err := tx.Sighashes()
+		Expect(err).ToNot(HaveOccurred())
+		signatures := make([]pack.Bytes65, len(sighashes))
+		for i := range sighashes {
+			hash := id.Hash(sighashes[i])
+			signature, err := privKey.Sign(&hash)
+			Expect(err).ToNot(HaveOccurred())
+			signatures[i] = pack.NewBytes65(signature)
+		}
+		pubKey := ethcrypto.CompressPubkey((*ecdsa.PublicKey)(privKey.PubKey()))
+		Expect(tx.Sign(signatures, pack.NewBytes(pubKey))).To(Succeed())
+	}
+
+	Context("when submitting a signed transaction", func() {
+		It("should spend the inputs and create the outputs", func() {
+			client := mock.NewUTXOClient(mock.DefaultClientOptions())
+			privKey := id.NewPrivKey()
+			sender := mock.UTXOAddressFromPubKey(privKey.PubKey())
+			recipient := mock.UTXOAddressFromPubKey(id.NewPrivKey().PubKey())
+
+			output, err := client.Fund(sender, pack.NewU256FromUint64(100000))
+			Expect(err).ToNot(HaveOccurred())
+			_, confs, err := client.UnspentOutput(ctx, output.Outpoint)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(pack.NewU64(1)))
+
+			tx, err := mock.NewUTXOTxBuilder().BuildTx(
+				[]utxo.Input{{Output: output}},
+				[]utxo.Recipient{
+					{To: recipient, Value: pack.NewU256FromUint64(60000)},
+					{To: sender, Value: pack.NewU256FromUint64(39000)},
+				},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			signUTXOTx(tx, privKey)
+			Expect(client.SubmitTx(ctx, tx)).To(Succeed())
+
+			// The input is spent as soon as the transaction is in the mempool.
+			_, _, err = client.UnspentOutput(ctx, output.Outpoint)
+			Expect(err).To(HaveOccurred())
+
+			outputs, err := tx.Outputs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputs).To(HaveLen(2))
+			_, confs, err = client.UnspentOutput(ctx, outputs[0].Outpoint)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(pack.NewU64(0)))
+
+			client.Mine(2)
+			_, confs, err = client.UnspentOutput(ctx, outputs[0].Outpoint)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(pack.NewU64(2)))
+
+			unspent, err := client.UnspentOutputs(ctx, 1, recipient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unspent).To(Equal([]utxo.Output{outputs[0]}))
+
+			txHash, err := tx.Hash()
+			Expect(err).ToNot(HaveOccurred())
+			senders, err := client.TxSenders(ctx, txHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(senders).To(Equal([]pack.String{pack.String(sender)}))
+		})
+	})
+
+	Context("when submitting an invalid transaction", func() {
+		It("should reject a transaction signed by the wrong key", func() {
+			client := mock.NewUTXOClient(mock.DefaultClientOptions())
+			privKey := id.NewPrivKey()
+			output, err := client.Fund(mock.UTXOAddressFromPubKey(privKey.PubKey()), pack.NewU256FromUint64(100000))
+			Expect(err).ToNot(HaveOccurred())
+
+			tx, err := mock.NewUTXOTxBuilder().BuildTx(
+				[]utxo.Input{{Output: output}},
+				[]utxo.Recipient{{To: mock.UTXOAddressFromPubKey(privKey.PubKey()), Value: pack.NewU256FromUint64(1000)}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			signUTXOTx(tx, id.NewPrivKey())
+			Expect(client.SubmitTx(ctx, tx)).ToNot(Succeed())
+		})
+
+		It("should reject a transaction that double spends an input", func() {
+			client := mock.NewUTXOClient(mock.DefaultClientOptions().WithAutoMine(1))
+			privKey := id.NewPrivKey()
+			addr := mock.UTXOAddressFromPubKey(privKey.PubKey())
+			output, err := client.Fund(addr, pack.NewU256FromUint64(100000))
+			Expect(err).ToNot(HaveOccurred())
+
+			tx, err := mock.NewUTXOTxBuilder().BuildTx(
+				[]utxo.Input{{Output: output}},
+				[]utxo.Recipient{{To: addr, Value: pack.NewU256FromUint64(1000)}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			signUTXOTx(tx, privKey)
+			Expect(client.SubmitTx(ctx, tx)).To(Succeed())
+
+			tx, err = mock.NewUTXOTxBuilder().BuildTx(
+				[]utxo.Input{{Output: output}},
+				[]utxo.Recipient{{To: addr, Value: pack.NewU256FromUint64(2000)}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			signUTXOTx(tx, privKey)
+			Expect(client.SubmitTx(ctx, tx)).ToNot(Succeed())
+		})
+
+		It("should reject a transaction that creates value", func() {
+			client := mock.NewUTXOClient(mock.DefaultClientOptions())
+			privKey := id.NewPrivKey()
+			addr := mock.UTXOAddressFromPubKey(privKey.PubKey())
+			output, err := client.Fund(addr, pack.NewU256FromUint64(100000))
+			Expect(err).ToNot(HaveOccurred())
+
+			tx, err := mock.NewUTXOTxBuilder().BuildTx(
+				[]utxo.Input{{Output: output}},
+				[]utxo.Recipient{{To: addr, Value: pack.NewU256FromUint64(100001)}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			signUTXOTx(tx, privKey)
+			Expect(client.SubmitTx(ctx, tx)).ToNot(Succeed())
+		})
+	})
+
+	Context("when querying the receipt of a transaction", func() {
+		It("should return its status and confirmations", func() {
+			client := mock.NewUTXOClient(mock.DefaultClientOptions())
+			addr := mock.UTXOAddressFromPubKey(id.NewPrivKey().PubKey())
+
+			output, err := client.Fund(addr, pack.NewU256FromUint64(100000))
+			Expect(err).ToNot(HaveOccurred())
+			client.Mine(1)
+			receipt, err := client.TxReceipt(ctx, output.Outpoint.Hash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(receipt.Status).To(Equal(confirmation.TxStatusConfirmed))
+			Expect(receipt.Height).To(Equal(pack.NewU64(1)))
+			Expect(receipt.Confirmations).To(Equal(pack.NewU64(2)))
+			Expect(receipt.Err()).ToNot(HaveOccurred())
+
+			receipt, err = client.TxReceipt(ctx, pack.Bytes("unknown"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(receipt.Status).To(Equal(confirmation.TxStatusUnknown))
+			Expect(errors.Is(receipt.Err(), confirmation.ErrTxNotFound)).To(BeTrue())
+		})
+	})
+})
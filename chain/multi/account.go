@@ -0,0 +1,139 @@
+package multi
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/renproject/multichain/api/account"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/pack"
+)
+
+// AccountClient is an account-based client that is backed by several
+// underlying account-based clients. It implements the account.Client
+// interface.
+type AccountClient struct {
+	endpoints
+	clients []account.Client
+}
+
+// NewAccountClient returns an account-based client that is backed by the given
+// clients. Until the first health check, all clients are assumed to be
+// healthy, and are used in the given order. An error is returned if there
+// are no clients, or if the quorum is greater than the number of clients.
+func NewAccountClient(clients []account.Client, opts ClientOptions) (*AccountClient, error) {
+	clients = append([]account.Client{}, clients...)
+	endpoints, err := newEndpoints(len(clients), opts, func(ctx context.Context, i int) (pack.U64, error) {
+		return clients[i].LatestBlock(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AccountClient{
+		endpoints: endpoints,
+		clients:   clients,
+	}, nil
+}
+
+// LatestBlock returns the smallest latest block reported by a quorum of
+// endpoints.
+func (client *AccountClient) LatestBlock(ctx context.Context) (pack.U64, error) {
+	heights := make([]pack.U64, len(client.clients))
+	quorum, err := client.quorum(ctx, func(i int) (string, error) {
+		height, err := client.clients[i].LatestBlock(ctx)
+		heights[i] = height
+		return "", err
+	})
+	if err != nil {
+		return pack.NewU64(0), fmt.Errorf("latest block: %v", err)
+	}
+	return minU64(heights, quorum), nil
+}
+
+// AccountBalance returns the balance of the given account, as long as it is
+// the same for a quorum of endpoints.
+func (client *AccountClient) AccountBalance(ctx context.Context, addr address.Address) (pack.U256, error) {
+	balances := make([]pack.U256, len(client.clients))
+	quorum, err := client.quorum(ctx, func(i int) (string, error) {
+		balance, err := client.clients[i].AccountBalance(ctx, addr)
+		balances[i] = balance
+		return balance.String(), err
+	})
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("account balance: %v", err)
+	}
+	return balances[quorum[0]], nil
+}
+
+// AccountNonce returns the nonce of the given account from the first endpoint
+// that responds successfully. A quorum is not required, because endpoints can
+// return different nonces depending on the transactions in their mempools.
+func (client *AccountClient) AccountNonce(ctx context.Context, addr address.Address) (pack.U256, error) {
+	var nonce pack.U256
+	err := client.failover(ctx, func(i int) error {
+		var err error
+		nonce, err = client.clients[i].AccountNonce(ctx, addr)
+		return err
+	})
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("account nonce: %v", err)
+	}
+	return nonce, nil
+}
+
+// Tx returns the transaction with the given hash, as long as it is the same
+// for a quorum of endpoints. The smallest number of confirmations reported by
+// the quorum is returned.
+func (client *AccountClient) Tx(ctx context.Context, txHash pack.Bytes) (account.Tx, pack.U64, error) {
+	txs := make([]account.Tx, len(client.clients))
+	confs := make([]pack.U64, len(client.clients))
+	quorum, err := client.quorum(ctx, func(i int) (string, error) {
+		tx, txConfs, err := client.clients[i].Tx(ctx, txHash)
+		if err != nil {
+			return "", err
+		}
+		serializedTx, err := tx.Serialize()
+		if err != nil {
+			return "", fmt.Errorf("serializing tx: %v", err)
+		}
+		txs[i], confs[i] = tx, txConfs
+		return hex.EncodeToString(tx.Hash()) + hex.EncodeToString(serializedTx), nil
+	})
+	if err != nil {
+		return nil, pack.NewU64(0), fmt.Errorf("tx %v: %v", txHash, err)
+	}
+	return txs[quorum[0]], minU64(confs, quorum), nil
+}
+
+// TxReceipt returns the receipt of the transaction with the given hash, as
+// long as it is the same for a quorum of endpoints. The smallest number of
+// confirmations reported by the quorum is returned.
+func (client *AccountClient) TxReceipt(ctx context.Context, txHash pack.Bytes) (confirmation.TxReceipt, error) {
+	receipt, err := client.txReceipt(ctx, func(i int) (confirmation.TxReceipt, error) {
+		return client.clients[i].TxReceipt(ctx, txHash)
+	})
+	if err != nil {
+		return confirmation.TxReceipt{}, fmt.Errorf("tx receipt %v: %v", txHash, err)
+	}
+	return receipt, nil
+}
+
+// SubmitTx to every endpoint. No error is returned as long as at least one
+// endpoint accepts the transaction.
+func (client *AccountClient) SubmitTx(ctx context.Context, tx account.Tx) error {
+	var err error
+	accepted := false
+	for _, i := range client.order() {
+		if submitErr := client.clients[i].SubmitTx(ctx, tx); submitErr != nil {
+			err = submitErr
+			continue
+		}
+		accepted = true
+	}
+	if !accepted {
+		return fmt.Errorf("submit tx: all endpoints failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+// Package multi implements account-based and utxo-based clients that are
+// backed by several underlying clients (usually connected to different RPC
+// endpoints, run by different providers). Requests fail over to the next
+// endpoint when an endpoint returns an error, and endpoints that fail health
+// checks (or fall too far behind the other endpoints) are only used when no
+// healthy endpoint is available.
+//
+// Reads that are used to decide whether or not something has happened on the
+// underlying chain (the latest block, transactions, outputs, and their
+// confirmations) can require agreement from a quorum of endpoints. When a
+// quorum is required, the content returned by the quorum must be identical,
+// and the smallest number of confirmations (or block height) reported by the
+// quorum is returned. This means that a single lagging or malicious endpoint
+// cannot convince the client that a transaction is confirmed.
+package multi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultQuorum used by the clients. By default, reads are returned from
+	// the first endpoint that responds successfully.
+	DefaultQuorum = 1
+	// DefaultMaxBlockLag used by the clients.
+	DefaultMaxBlockLag = 6
+	// DefaultHealthCheckInterval used by the clients.
+	DefaultHealthCheckInterval = 30 * time.Second
+)
+
+// ClientOptions are used to parameterise the behaviour of the clients.
+type ClientOptions struct {
+	// Quorum is the number of endpoints that must agree on the result of a
+	// read before it is returned. If fewer endpoints agree, the read returns
+	// an error.
+	Quorum int
+	// MaxBlockLag is the number of blocks that an endpoint can fall behind the
+	// median latest block of all endpoints before it is considered unhealthy.
+	MaxBlockLag uint64
+	// HealthCheckInterval is the time between health checks, when the client
+	// is running.
+	HealthCheckInterval time.Duration
+}
+
+// DefaultClientOptions returns ClientOptions with the default settings.
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Quorum:              DefaultQuorum,
+		MaxBlockLag:         DefaultMaxBlockLag,
+		HealthCheckInterval: DefaultHealthCheckInterval,
+	}
+}
+
+// WithQuorum sets the number of endpoints that must agree on the result of a
+// read. The quorum cannot be greater than the number of endpoints.
+func (opts ClientOptions) WithQuorum(quorum int) ClientOptions {
+	opts.Quorum = quorum
+	return opts
+}
+
+// WithMaxBlockLag sets the number of blocks that an endpoint can fall behind
+// before it is considered unhealthy.
+func (opts ClientOptions) WithMaxBlockLag(maxBlockLag uint64) ClientOptions {
+	opts.MaxBlockLag = maxBlockLag
+	return opts
+}
+
+// WithHealthCheckInterval sets the time between health checks.
+func (opts ClientOptions) WithHealthCheckInterval(interval time.Duration) ClientOptions {
+	opts.HealthCheckInterval = interval
+	return opts
+}
+
+// endpoints keeps track of the health of the underlying clients, and
+// implements failover and quorum reads on top of them. Endpoints are always
+// identified by their index in the list of underlying clients.
+type endpoints struct {
+	opts        ClientOptions
+	latestBlock func(ctx context.Context, i int) (pack.U64, error)
+
+	mu      *sync.RWMutex
+	healthy []bool
+}
+
+func newEndpoints(n int, opts ClientOptions, latestBlock func(ctx context.Context, i int) (pack.U64, error)) (endpoints, error) {
+	if n == 0 {
+		return endpoints{}, fmt.Errorf("no clients")
+	}
+	if opts.Quorum > n {
+		return endpoints{}, fmt.Errorf("bad quorum: expected at most %v, got %v", n, opts.Quorum)
+	}
+	healthy := make([]bool, n)
+	for i := range healthy {
+		healthy[i] = true
+	}
+	return endpoints{
+		opts:        opts,
+		latestBlock: latestBlock,
+
+		mu:      new(sync.RWMutex),
+		healthy: healthy,
+	}, nil
+}
+
+// order returns the indices of the endpoints in the order in which they should
+// be used. Healthy endpoints are used before unhealthy endpoints.
+func (endpoints endpoints) order() []int {
+	endpoints.mu.RLock()
+	defer endpoints.mu.RUnlock()
+
+	order := make([]int, 0, len(endpoints.healthy))
+	for i, healthy := range endpoints.healthy {
+		if healthy {
+			order = append(order, i)
+		}
+	}
+	for i, healthy := range endpoints.healthy {
+		if !healthy {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// Healthy returns whether or not each endpoint passed its last health check.
+func (endpoints endpoints) Healthy() []bool {
+	endpoints.mu.RLock()
+	defer endpoints.mu.RUnlock()
+
+	return append([]bool{}, endpoints.healthy...)
+}
+
+// CheckHealth queries the latest block from every endpoint. Endpoints that
+// return an error, or that are more than the maximum block lag behind the
+// median latest block, are marked as unhealthy. All other endpoints are
+// marked as healthy. The median is used so that a single endpoint reporting a
+// latest block that is too high cannot mark the other endpoints as unhealthy.
+// An error is returned if no endpoint is healthy.
+func (endpoints endpoints) CheckHealth(ctx context.Context) error {
+	n := len(endpoints.healthy)
+	heights := make([]uint64, n)
+	errs := make([]error, n)
+
+	wg := new(sync.WaitGroup)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			height, err := endpoints.latestBlock(ctx, i)
+			heights[i], errs[i] = uint64(height), err
+		}(i)
+	}
+	wg.Wait()
+
+	ok := make([]uint64, 0, n)
+	for i := range heights {
+		if errs[i] == nil {
+			ok = append(ok, heights[i])
+		}
+	}
+	sort.Slice(ok, func(i, j int) bool { return ok[i] < ok[j] })
+	median := uint64(0)
+	if len(ok) > 0 {
+		median = ok[len(ok)/2]
+	}
+
+	endpoints.mu.Lock()
+	defer endpoints.mu.Unlock()
+
+	numHealthy := 0
+	for i := range endpoints.healthy {
+		endpoints.healthy[i] = errs[i] == nil && heights[i]+endpoints.opts.MaxBlockLag >= median
+		if endpoints.healthy[i] {
+			numHealthy++
+		}
+	}
+	if numHealthy == 0 {
+		return fmt.Errorf("no healthy endpoints")
+	}
+	return nil
+}
+
+// Run health checks at the health check interval, until the context is done.
+// This function blocks, and is usually called in a background goroutine.
+func (endpoints endpoints) Run(ctx context.Context) {
+	ticker := time.NewTicker(endpoints.opts.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		// Errors are ignored, because unhealthy endpoints are still used when
+		// no healthy endpoints are available.
+		_ = endpoints.CheckHealth(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// failover calls the function for each endpoint, in order, until it succeeds.
+// If it does not succeed for any endpoint, the last error is returned.
+func (endpoints endpoints) failover(ctx context.Context, f func(i int) error) error {
+	var err error
+	for _, i := range endpoints.order() {
+		if err = f(i); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return fmt.Errorf("all endpoints failed: %v", err)
+}
+
+// quorum calls the function for each endpoint, in order, until a quorum of
+// endpoints have succeeded and returned the same key. The indices of the
+// endpoints in the quorum are returned. The key must uniquely identify the
+// content returned by the endpoint (but not its block height or
+// confirmations, which are expected to differ between endpoints).
+func (endpoints endpoints) quorum(ctx context.Context, f func(i int) (string, error)) ([]int, error) {
+	quorum := endpoints.opts.Quorum
+	if quorum < 1 {
+		quorum = 1
+	}
+
+	var err error
+	votes := map[string][]int{}
+	for _, i := range endpoints.order() {
+		key, keyErr := f(i)
+		if keyErr != nil {
+			err = keyErr
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		votes[key] = append(votes[key], i)
+		if len(votes[key]) >= quorum {
+			return votes[key], nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no quorum: expected %v endpoints to agree, got %v distinct results: %v", quorum, len(votes), err)
+	}
+	return nil, fmt.Errorf("no quorum: expected %v endpoints to agree, got %v distinct results", quorum, len(votes))
+}
+
+// txReceipt returns the receipt returned by the function, as long as it is the
+// same for a quorum of endpoints. The smallest number of confirmations reported
+// by the quorum is used.
+func (endpoints endpoints) txReceipt(ctx context.Context, f func(i int) (confirmation.TxReceipt, error)) (confirmation.TxReceipt, error) {
+	receipts := make([]confirmation.TxReceipt, len(endpoints.healthy))
+	confs := make([]pack.U64, len(endpoints.healthy))
+	quorum, err := endpoints.quorum(ctx, func(i int) (string, error) {
+		receipt, err := f(i)
+		if err != nil {
+			return "", err
+		}
+		receipts[i], confs[i] = receipt, receipt.Confirmations
+		return fmt.Sprintf("%v/%x/%v/%v/%v", receipt.Status, []byte(receipt.BlockHash), receipt.Height, receipt.GasUsed, receipt.RevertReason), nil
+	})
+	if err != nil {
+		return confirmation.TxReceipt{}, err
+	}
+	receipt := receipts[quorum[0]]
+	receipt.Confirmations = minU64(confs, quorum)
+	return receipt, nil
+}
+
+// minU64 returns the minimum of the values at the given indices.
+func minU64(values []pack.U64, indices []int) pack.U64 {
+	min := values[indices[0]]
+	for _, i := range indices[1:] {
+		if values[i] < min {
+			min = values[i]
+		}
+	}
+	return min
+}
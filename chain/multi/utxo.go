@@ -0,0 +1,139 @@
+package multi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/renproject/multichain/api/confirmation"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/pack"
+)
+
+// UTXOClient is a utxo-based client that is backed by several underlying
+// utxo-based clients. It implements the utxo.Client interface.
+type UTXOClient struct {
+	endpoints
+	clients []utxo.Client
+}
+
+// NewUTXOClient returns a utxo-based client that is backed by the given
+// clients. Until the first health check, all clients are assumed to be
+// healthy, and are used in the given order. An error is returned if there
+// are no clients, or if the quorum is greater than the number of clients.
+func NewUTXOClient(clients []utxo.Client, opts ClientOptions) (*UTXOClient, error) {
+	clients = append([]utxo.Client{}, clients...)
+	endpoints, err := newEndpoints(len(clients), opts, func(ctx context.Context, i int) (pack.U64, error) {
+		return clients[i].LatestBlock(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UTXOClient{
+		endpoints: endpoints,
+		clients:   clients,
+	}, nil
+}
+
+// LatestBlock returns the smallest latest block reported by a quorum of
+// endpoints.
+func (client *UTXOClient) LatestBlock(ctx context.Context) (pack.U64, error) {
+	heights := make([]pack.U64, len(client.clients))
+	quorum, err := client.quorum(ctx, func(i int) (string, error) {
+		height, err := client.clients[i].LatestBlock(ctx)
+		heights[i] = height
+		return "", err
+	})
+	if err != nil {
+		return pack.NewU64(0), fmt.Errorf("latest block: %v", err)
+	}
+	return minU64(heights, quorum), nil
+}
+
+// Output returns the output identified by the given outpoint, as long as it is
+// the same for a quorum of endpoints. The smallest number of confirmations
+// reported by the quorum is returned.
+func (client *UTXOClient) Output(ctx context.Context, outpoint utxo.Outpoint) (utxo.Output, pack.U64, error) {
+	return client.output(ctx, outpoint, func(i int) (utxo.Output, pack.U64, error) {
+		return client.clients[i].Output(ctx, outpoint)
+	})
+}
+
+// UnspentOutput returns the unspent output identified by the given outpoint,
+// as long as it is the same for a quorum of endpoints. The smallest number of
+// confirmations reported by the quorum is returned.
+func (client *UTXOClient) UnspentOutput(ctx context.Context, outpoint utxo.Outpoint) (utxo.Output, pack.U64, error) {
+	return client.output(ctx, outpoint, func(i int) (utxo.Output, pack.U64, error) {
+		return client.clients[i].UnspentOutput(ctx, outpoint)
+	})
+}
+
+// SubmitTx to every endpoint. No error is returned as long as at least one
+// endpoint accepts the transaction.
+func (client *UTXOClient) SubmitTx(ctx context.Context, tx utxo.Tx) error {
+	var err error
+	accepted := false
+	for _, i := range client.order() {
+		if submitErr := client.clients[i].SubmitTx(ctx, tx); submitErr != nil {
+			err = submitErr
+			continue
+		}
+		accepted = true
+	}
+	if !accepted {
+		return fmt.Errorf("submit tx: all endpoints failed: %v", err)
+	}
+	return nil
+}
+
+// TxSenders returns the senders of the transaction with the given hash, as
+// long as they are the same for a quorum of endpoints.
+func (client *UTXOClient) TxSenders(ctx context.Context, txHash pack.Bytes) ([]pack.String, error) {
+	senders := make([][]pack.String, len(client.clients))
+	quorum, err := client.quorum(ctx, func(i int) (string, error) {
+		txSenders, err := client.clients[i].TxSenders(ctx, txHash)
+		if err != nil {
+			return "", err
+		}
+		senders[i] = txSenders
+		keys := make([]string, len(txSenders))
+		for j := range txSenders {
+			keys[j] = string(txSenders[j])
+		}
+		return strings.Join(keys, ","), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("tx senders %v: %v", txHash, err)
+	}
+	return senders[quorum[0]], nil
+}
+
+// TxReceipt returns the receipt of the transaction with the given hash, as
+// long as it is the same for a quorum of endpoints. The smallest number of
+// confirmations reported by the quorum is returned.
+func (client *UTXOClient) TxReceipt(ctx context.Context, txHash pack.Bytes) (confirmation.TxReceipt, error) {
+	receipt, err := client.txReceipt(ctx, func(i int) (confirmation.TxReceipt, error) {
+		return client.clients[i].TxReceipt(ctx, txHash)
+	})
+	if err != nil {
+		return confirmation.TxReceipt{}, fmt.Errorf("tx receipt %v: %v", txHash, err)
+	}
+	return receipt, nil
+}
+
+func (client *UTXOClient) output(ctx context.Context, outpoint utxo.Outpoint, f func(i int) (utxo.Output, pack.U64, error)) (utxo.Output, pack.U64, error) {
+	outputs := make([]utxo.Output, len(client.clients))
+	confs := make([]pack.U64, len(client.clients))
+	quorum, err := client.quorum(ctx, func(i int) (string, error) {
+		output, outputConfs, err := f(i)
+		if err != nil {
+			return "", err
+		}
+		outputs[i], confs[i] = output, outputConfs
+		return fmt.Sprintf("%v:%v:%v:%v", output.Outpoint.Hash, output.Outpoint.Index, output.Value, output.PubKeyScript), nil
+	})
+	if err != nil {
+		return utxo.Output{}, pack.NewU64(0), fmt.Errorf("output %v:%v: %v", outpoint.Hash, outpoint.Index, err)
+	}
+	return outputs[quorum[0]], minU64(confs, quorum), nil
+}
@@ -0,0 +1,128 @@
+package multi_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/renproject/id"
+	"github.com/renproject/multichain/api/utxo"
+	"github.com/renproject/multichain/chain/mock"
+	"github.com/renproject/multichain/chain/multi"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// unavailableUTXOClient returns an error for every read.
+type unavailableUTXOClient struct {
+	utxo.Client
+}
+
+func (unavailableUTXOClient) LatestBlock(context.Context) (pack.U64, error) {
+	return pack.NewU64(0), fmt.Errorf("unavailable")
+}
+
+func (unavailableUTXOClient) UnspentOutput(context.Context, utxo.Outpoint) (utxo.Output, pack.U64, error) {
+	return utxo.Output{}, pack.NewU64(0), fmt.Errorf("unavailable")
+}
+
+var _ = Describe("UTXO", func() {
+	ctx := context.Background()
+
+	// fund the same address on every client. The mock clients are
+	// deterministic, so the funded outputs are the same.
+	fund := func(clients ...*mock.UTXOClient) utxo.Output {
+		addr := mock.UTXOAddressFromPubKey(id.NewPrivKey().PubKey())
+		var output utxo.Output
+		for _, client := range clients {
+			var err error
+			output, err = client.Fund(addr, pack.NewU256FromUint64(100000))
+			Expect(err).ToNot(HaveOccurred())
+		}
+		return output
+	}
+
+	Context("when an endpoint is unavailable", func() {
+		It("should fail over to the next endpoint", func() {
+			client := mock.NewUTXOClient(mock.DefaultClientOptions())
+			output := fund(client)
+
+			multiClient, err := multi.NewUTXOClient([]utxo.Client{unavailableUTXOClient{}, client}, multi.DefaultClientOptions())
+			Expect(err).ToNot(HaveOccurred())
+			unspentOutput, confs, err := multiClient.UnspentOutput(ctx, output.Outpoint)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unspentOutput).To(Equal(output))
+			Expect(confs).To(Equal(pack.NewU64(1)))
+		})
+
+		It("should mark the endpoint as unhealthy", func() {
+			multiClient, err := multi.NewUTXOClient([]utxo.Client{unavailableUTXOClient{}, mock.NewUTXOClient(mock.DefaultClientOptions())}, multi.DefaultClientOptions())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(multiClient.CheckHealth(ctx)).To(Succeed())
+			Expect(multiClient.Healthy()).To(Equal([]bool{false, true}))
+		})
+	})
+
+	Context("when an endpoint is lagging", func() {
+		It("should mark the endpoint as unhealthy", func() {
+			clients := []*mock.UTXOClient{
+				mock.NewUTXOClient(mock.DefaultClientOptions()),
+				mock.NewUTXOClient(mock.DefaultClientOptions()),
+				mock.NewUTXOClient(mock.DefaultClientOptions()),
+			}
+			clients[1].Mine(10)
+			clients[2].Mine(10)
+
+			multiClient, err := multi.NewUTXOClient([]utxo.Client{clients[0], clients[1], clients[2]}, multi.DefaultClientOptions().WithMaxBlockLag(5))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(multiClient.CheckHealth(ctx)).To(Succeed())
+			Expect(multiClient.Healthy()).To(Equal([]bool{false, true, true}))
+		})
+	})
+
+	Context("when a quorum is required", func() {
+		It("should return an error if the quorum is greater than the number of endpoints", func() {
+			clients := []utxo.Client{
+				mock.NewUTXOClient(mock.DefaultClientOptions()),
+				mock.NewUTXOClient(mock.DefaultClientOptions()),
+			}
+			_, err := multi.NewUTXOClient(clients, multi.DefaultClientOptions().WithQuorum(3))
+			Expect(err).To(HaveOccurred())
+			_, err = multi.NewAccountClient(nil, multi.DefaultClientOptions())
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return the smallest number of confirmations", func() {
+			clients := []*mock.UTXOClient{
+				mock.NewUTXOClient(mock.DefaultClientOptions()),
+				mock.NewUTXOClient(mock.DefaultClientOptions()),
+			}
+			output := fund(clients...)
+			clients[0].Mine(5)
+
+			multiClient, err := multi.NewUTXOClient([]utxo.Client{clients[0], clients[1]}, multi.DefaultClientOptions().WithQuorum(2))
+			Expect(err).ToNot(HaveOccurred())
+			_, confs, err := multiClient.UnspentOutput(ctx, output.Outpoint)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(Equal(pack.NewU64(1)))
+
+			height, err := multiClient.LatestBlock(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(height).To(Equal(pack.NewU64(1)))
+		})
+
+		It("should return an error if the endpoints disagree", func() {
+			clients := []*mock.UTXOClient{
+				mock.NewUTXOClient(mock.DefaultClientOptions()),
+				mock.NewUTXOClient(mock.DefaultClientOptions()),
+			}
+			output := fund(clients[0])
+
+			multiClient, err := multi.NewUTXOClient([]utxo.Client{clients[0], clients[1]}, multi.DefaultClientOptions().WithQuorum(2))
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = multiClient.UnspentOutput(ctx, output.Outpoint)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -1,11 +1,159 @@
 package optimism
 
 import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/renproject/multichain/api/account"
 	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/pack"
 )
 
-// GasEstimator re-exports evm.GasEstimator.
-type GasEstimator = evm.GasEstimator
+const (
+	// GasPriceOracleAddress is the address of the GasPriceOracle predeploy,
+	// which reports the fee paid to post transactions to layer 1.
+	GasPriceOracleAddress = "0x420000000000000000000000000000000000000F"
+
+	// gasPriceOracleABI is the part of the GasPriceOracle ABI that is used by
+	// the GasEstimator.
+	gasPriceOracleABI = `[{"inputs":[{"internalType":"bytes","name":"_data","type":"bytes"}],"name":"getL1Fee","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`
+)
+
+// A GasEstimator estimates fees on optimism. Gas prices are estimated in the
+// same way as evm.GasEstimator, but the fee paid by a transaction also
+// includes the fee paid to post the transaction to layer 1.
+type GasEstimator struct {
+	*evm.GasEstimator
+	client *Client
+}
+
+// NewGasEstimator returns a gas estimator that estimates fees using the
+// GasPriceOracle predeploy.
+func NewGasEstimator(client *Client) *GasEstimator {
+	return &GasEstimator{
+		GasEstimator: evm.NewGasEstimator(client),
+		client:       client,
+	}
+}
+
+// WithFeeOptions returns a copy of the gas estimator that estimates fees for
+// the type of transaction defined by the fee options.
+func (gasEstimator *GasEstimator) WithFeeOptions(feeOptions FeeOptions) *GasEstimator {
+	return &GasEstimator{
+		GasEstimator: gasEstimator.GasEstimator.WithFeeOptions(feeOptions),
+		client:       gasEstimator.client,
+	}
+}
+
+// L1Fee returns the fee paid to post the serialized transaction to layer 1,
+// as reported by the GasPriceOracle predeploy.
+func (gasEstimator *GasEstimator) L1Fee(ctx context.Context, serializedTx pack.Bytes) (pack.U256, error) {
+	oracleABI, err := abi.JSON(strings.NewReader(gasPriceOracleABI))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("parsing gas price oracle abi: %v", err)
+	}
+	calldata, err := oracleABI.Pack("getL1Fee", []byte(serializedTx))
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("packing getL1Fee: %v", err)
+	}
+	oracle := common.HexToAddress(GasPriceOracleAddress)
+	result, err := gasEstimator.client.EthClient.CallContract(ctx, ethereum.CallMsg{To: &oracle, Data: calldata}, nil)
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("calling getL1Fee: %v", err)
+	}
+	outputs, err := oracleABI.Unpack("getL1Fee", result)
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("unpacking getL1Fee: %v", err)
+	}
+	l1Fee, ok := outputs[0].(*big.Int)
+	if !ok {
+		return pack.U256{}, fmt.Errorf("expected type %T, got type %T", new(big.Int), outputs[0])
+	}
+	return pack.NewU256FromInt(l1Fee), nil
+}
+
+// EstimateTxFee returns the fee that will be paid by the transaction if it
+// uses all of its gas, including the fee paid to post the transaction to
+// layer 1.
+func (gasEstimator *GasEstimator) EstimateTxFee(ctx context.Context, tx account.Tx) (pack.U256, error) {
+	l2Fee, err := gasEstimator.GasEstimator.EstimateTxFee(ctx, tx)
+	if err != nil {
+		return pack.U256{}, err
+	}
+	serializedTx, err := serializeForL1Fee(tx)
+	if err != nil {
+		return pack.U256{}, fmt.Errorf("serializing tx: %v", err)
+	}
+	l1Fee, err := gasEstimator.L1Fee(ctx, serializedTx)
+	if err != nil {
+		return pack.U256{}, err
+	}
+	return pack.NewU256FromInt(new(big.Int).Add(l2Fee.Int(), l1Fee.Int())), nil
+}
+
+// serializeForL1Fee serializes the transaction without its signature. The
+// GasPriceOracle expects an unsigned transaction, and adds the cost of posting
+// a signature itself, so a signed transaction would pay for its signature
+// twice.
+func serializeForL1Fee(tx account.Tx) (pack.Bytes, error) {
+	ethTx, ok := tx.(*evm.Tx)
+	if !ok {
+		return tx.Serialize()
+	}
+	t := ethTx.EthTx
+	var fields []interface{}
+	switch t.Type() {
+	case types.LegacyTxType:
+		fields = []interface{}{t.Nonce(), t.GasPrice(), t.Gas(), t.To(), t.Value(), t.Data()}
+	case types.AccessListTxType:
+		fields = []interface{}{t.ChainId(), t.Nonce(), t.GasPrice(), t.Gas(), t.To(), t.Value(), t.Data(), t.AccessList()}
+	case types.DynamicFeeTxType:
+		fields = []interface{}{t.ChainId(), t.Nonce(), t.GasTipCap(), t.GasFeeCap(), t.Gas(), t.To(), t.Value(), t.Data(), t.AccessList()}
+	default:
+		return nil, fmt.Errorf("unsupported tx type %v", t.Type())
+	}
+	serializedTx, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+	if t.Type() == types.LegacyTxType {
+		return serializedTx, nil
+	}
+	return append([]byte{t.Type()}, serializedTx...), nil
+}
+
+const (
+	// FeeMarketLegacy re-exports evm.FeeMarketLegacy.
+	FeeMarketLegacy = evm.FeeMarketLegacy
+	// FeeMarketDynamic re-exports evm.FeeMarketDynamic.
+	FeeMarketDynamic = evm.FeeMarketDynamic
+	// FeeMarketAuto re-exports evm.FeeMarketAuto.
+	FeeMarketAuto = evm.FeeMarketAuto
+)
+
+type (
+	// FeeMarket re-exports evm.FeeMarket.
+	FeeMarket = evm.FeeMarket
+
+	// FeeOptions re-exports evm.FeeOptions.
+	FeeOptions = evm.FeeOptions
+)
+
+// DefaultFeeOptions re-exports evm.DefaultFeeOptions.
+var DefaultFeeOptions = evm.DefaultFeeOptions
+
+// DefaultGasLimitMultiplier re-exports evm.DefaultGasLimitMultiplier.
+const DefaultGasLimitMultiplier = evm.DefaultGasLimitMultiplier
+
+// GasLimitOptions re-exports evm.GasLimitOptions.
+type GasLimitOptions = evm.GasLimitOptions
 
-// NewGasEstimator re-exports evm.NewGasEstimator.
-var NewGasEstimator = evm.NewGasEstimator
+// DefaultGasLimitOptions re-exports evm.DefaultGasLimitOptions.
+var DefaultGasLimitOptions = evm.DefaultGasLimitOptions
@@ -0,0 +1,175 @@
+package optimism_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/chain/evm/evmtest"
+	"github.com/renproject/multichain/chain/optimism"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// l1FeeNode returns a fake node with a GasPriceOracle that reports the L1 fee,
+// and a function that returns the transactions that were passed to getL1Fee.
+func l1FeeNode(l1Fee uint64) (*evmtest.Node, func() [][]byte) {
+	bytesType, err := abi.NewType("bytes", "", nil)
+	Expect(err).ToNot(HaveOccurred())
+	args := abi.Arguments{{Type: bytesType}}
+	selector := crypto.Keccak256([]byte("getL1Fee(bytes)"))[:4]
+
+	node := evmtest.NewNode().Handle("eth_call", func(params []json.RawMessage) (interface{}, error) {
+		msg, err := evmtest.DecodeCallMsg(params)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(msg.To, optimism.GasPriceOracleAddress) {
+			return nil, fmt.Errorf("unexpected call to %v", msg.To)
+		}
+		if !bytes.Equal(msg.CallData()[:4], selector) {
+			return nil, fmt.Errorf("unexpected selector %x", msg.CallData()[:4])
+		}
+		if _, err := args.Unpack(msg.CallData()[4:]); err != nil {
+			return nil, err
+		}
+		return hexutil.Encode(common.LeftPadBytes(new(big.Int).SetUint64(l1Fee).Bytes(), 32)), nil
+	})
+	serializedTxs := func() [][]byte {
+		txs := [][]byte{}
+		for _, req := range node.Requests("eth_call") {
+			msg, err := evmtest.DecodeCallMsg(req.Params)
+			Expect(err).ToNot(HaveOccurred())
+			outputs, err := args.Unpack(msg.CallData()[4:])
+			Expect(err).ToNot(HaveOccurred())
+			txs = append(txs, outputs[0].([]byte))
+		}
+		return txs
+	}
+	return node, serializedTxs
+}
+
+var _ = Describe("Gas", func() {
+	ctx := context.Background()
+	chainID := big.NewInt(10)
+	to := address.Address("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+
+	// unsignedLegacyTx is the RLP encoding of the nonce, gas price, gas,
+	// recipient, value, and data of the legacy transactions that are built by
+	// the tests.
+	recipient := common.HexToAddress(string(to))
+	unsignedLegacyTx, err := rlp.EncodeToBytes([]interface{}{uint64(0), big.NewInt(2), uint64(21000), &recipient, big.NewInt(1), []byte{}})
+	if err != nil {
+		panic(err)
+	}
+
+	dial := func(node *evmtest.Node) (*optimism.Client, func()) {
+		client, closeNode, err := node.Dial(chainID)
+		Expect(err).ToNot(HaveOccurred())
+		return client, closeNode
+	}
+
+	Context("when getting the L1 fee", func() {
+		It("should return the fee reported by the gas price oracle", func() {
+			node, serializedTxs := l1FeeNode(1234)
+			client, done := dial(node)
+			defer done()
+
+			l1Fee, err := optimism.NewGasEstimator(client).L1Fee(ctx, pack.Bytes{1, 2, 3})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(l1Fee).To(Equal(pack.NewU256FromUint64(1234)))
+			Expect(serializedTxs()).To(Equal([][]byte{{1, 2, 3}}))
+		})
+
+		It("should return an error if the call fails", func() {
+			client, done := dial(evmtest.NewNode())
+			defer done()
+
+			_, err := optimism.NewGasEstimator(client).L1Fee(ctx, pack.Bytes{1, 2, 3})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when estimating the fee of a transaction", func() {
+		It("should add the L1 fee to the L2 fee", func() {
+			node, _ := l1FeeNode(1234)
+			client, done := dial(node)
+			defer done()
+
+			tx, err := optimism.NewTxBuilder(chainID).BuildTx(ctx, nil, to, pack.NewU256FromUint64(1), pack.NewU256FromUint64(0), pack.NewU256FromUint64(21000), pack.NewU256FromUint64(2), pack.NewU256FromUint64(2), nil)
+			Expect(err).ToNot(HaveOccurred())
+			fee, err := optimism.NewGasEstimator(client).EstimateTxFee(ctx, tx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fee).To(Equal(pack.NewU256FromUint64(21000*2 + 1234)))
+		})
+
+		It("should post unsigned transactions without a signature", func() {
+			node, serializedTxs := l1FeeNode(1234)
+			client, done := dial(node)
+			defer done()
+
+			tx, err := optimism.NewTxBuilder(chainID).BuildTx(ctx, nil, to, pack.NewU256FromUint64(1), pack.NewU256FromUint64(0), pack.NewU256FromUint64(21000), pack.NewU256FromUint64(2), pack.NewU256FromUint64(2), nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = optimism.NewGasEstimator(client).EstimateTxFee(ctx, tx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serializedTxs()).To(Equal([][]byte{unsignedLegacyTx}))
+		})
+
+		It("should strip the signature of signed transactions", func() {
+			node, serializedTxs := l1FeeNode(1234)
+			client, done := dial(node)
+			defer done()
+
+			tx, err := optimism.NewTxBuilder(chainID).BuildTx(ctx, nil, to, pack.NewU256FromUint64(1), pack.NewU256FromUint64(0), pack.NewU256FromUint64(21000), pack.NewU256FromUint64(2), pack.NewU256FromUint64(2), nil)
+			Expect(err).ToNot(HaveOccurred())
+			key, err := crypto.GenerateKey()
+			Expect(err).ToNot(HaveOccurred())
+			sighashes, err := tx.Sighashes()
+			Expect(err).ToNot(HaveOccurred())
+			sig, err := crypto.Sign(sighashes[0][:], key)
+			Expect(err).ToNot(HaveOccurred())
+			var signature pack.Bytes65
+			copy(signature[:], sig)
+			Expect(tx.Sign([]pack.Bytes65{signature}, pack.Bytes(crypto.CompressPubkey(&key.PublicKey)))).To(Succeed())
+
+			_, err = optimism.NewGasEstimator(client).EstimateTxFee(ctx, tx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serializedTxs()).To(Equal([][]byte{unsignedLegacyTx}))
+		})
+
+		It("should post typed transactions without a signature", func() {
+			node, serializedTxs := l1FeeNode(1234)
+			node.Result("eth_getBlockByNumber", evmtest.Header(100, big.NewInt(1)))
+			client, done := dial(node)
+			defer done()
+
+			txBuilder := optimism.NewTxBuilder(chainID).WithFeeOptions(nil, optimism.DefaultFeeOptions().WithFeeMarket(optimism.FeeMarketDynamic))
+			tx, err := txBuilder.BuildTx(ctx, nil, to, pack.NewU256FromUint64(1), pack.NewU256FromUint64(0), pack.NewU256FromUint64(21000), pack.NewU256FromUint64(2), pack.NewU256FromUint64(2), nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = optimism.NewGasEstimator(client).EstimateTxFee(ctx, tx)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(serializedTxs()).To(HaveLen(1))
+			posted := serializedTxs()[0]
+			Expect(posted[0]).To(Equal(byte(types.DynamicFeeTxType)))
+			fields, rest, err := rlp.SplitList(posted[1:])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rest).To(BeEmpty())
+			// The chain id, nonce, tip cap, fee cap, gas, recipient, value,
+			// data, and access list, without the signature values.
+			Expect(rlp.CountValues(fields)).To(Equal(9))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package optimism_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOptimism(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Optimism Suite")
+}
@@ -2,6 +2,13 @@ package polygon
 
 import (
 	"github.com/renproject/multichain/chain/evm"
+	"github.com/renproject/pack"
+)
+
+const (
+	// DefaultMinPriorityFee is the smallest priority fee, in wei, that is
+	// accepted by polygon validators (30 gwei).
+	DefaultMinPriorityFee = 30000000000
 )
 
 // GasEstimator re-exports evm.GasEstimator.
@@ -9,3 +16,35 @@ type GasEstimator = evm.GasEstimator
 
 // NewGasEstimator re-exports evm.NewGasEstimator.
 var NewGasEstimator = evm.NewGasEstimator
+
+const (
+	// FeeMarketLegacy re-exports evm.FeeMarketLegacy.
+	FeeMarketLegacy = evm.FeeMarketLegacy
+	// FeeMarketDynamic re-exports evm.FeeMarketDynamic.
+	FeeMarketDynamic = evm.FeeMarketDynamic
+	// FeeMarketAuto re-exports evm.FeeMarketAuto.
+	FeeMarketAuto = evm.FeeMarketAuto
+)
+
+type (
+	// FeeMarket re-exports evm.FeeMarket.
+	FeeMarket = evm.FeeMarket
+
+	// FeeOptions re-exports evm.FeeOptions.
+	FeeOptions = evm.FeeOptions
+)
+
+// DefaultFeeOptions returns evm.DefaultFeeOptions with the smallest priority
+// fee accepted by polygon validators.
+func DefaultFeeOptions() FeeOptions {
+	return evm.DefaultFeeOptions().WithMinPriorityFee(pack.NewU256FromUint64(DefaultMinPriorityFee))
+}
+
+// DefaultGasLimitMultiplier re-exports evm.DefaultGasLimitMultiplier.
+const DefaultGasLimitMultiplier = evm.DefaultGasLimitMultiplier
+
+// GasLimitOptions re-exports evm.GasLimitOptions.
+type GasLimitOptions = evm.GasLimitOptions
+
+// DefaultGasLimitOptions re-exports evm.DefaultGasLimitOptions.
+var DefaultGasLimitOptions = evm.DefaultGasLimitOptions
@@ -0,0 +1,210 @@
+package solana
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/pack"
+)
+
+// DefaultSignaturesLimit is the number of signatures requested in each page of
+// the getSignaturesForAddress query.
+const DefaultSignaturesLimit = 1000
+
+var (
+	// ProgramLogTopic is the topic of logs emitted by programs using the
+	// "msg!" macro. The data of these logs is the message.
+	ProgramLogTopic = pack.Bytes("log")
+
+	// ProgramDataTopic is the topic of logs emitted by programs using the
+	// "sol_log_data" syscall (for example, Anchor events). The data of these
+	// logs is the decoded data.
+	ProgramDataTopic = pack.Bytes("data")
+)
+
+// LatestBlock returns the latest finalized slot.
+func (client *Client) LatestBlock(ctx context.Context) (pack.U64, error) {
+	res, err := SendDataWithRetry("getSlot", json.RawMessage(`[]`), client.opts.RPCURL)
+	if err != nil {
+		return pack.NewU64(0), fmt.Errorf("calling rpc method \"getSlot\": %v", err)
+	}
+	if res.Result == nil {
+		return pack.NewU64(0), fmt.Errorf("decoding result: empty")
+	}
+	var slot uint64
+	if err := json.Unmarshal(*res.Result, &slot); err != nil {
+		return pack.NewU64(0), fmt.Errorf("decoding result: %v", err)
+	}
+	return pack.NewU64(slot), nil
+}
+
+// FilterLogs returns the program logs that match the filter. The block range
+// of the filter is a range of slots, and a to block of zero is the latest slot.
+// Solana cannot filter logs over all programs, so at least one program address
+// must be given. The logs of failed transactions are ignored. The address of a
+// log is the program that emitted it, which can be different from the program
+// that was invoked by the transaction. See ProgramLogTopic and ProgramDataTopic
+// for the topics and data of the logs.
+func (client *Client) FilterLogs(ctx context.Context, filter contract.LogFilter) ([]contract.Log, error) {
+	if len(filter.Addresses) == 0 {
+		return nil, fmt.Errorf("filtering logs: expected at least one program address")
+	}
+
+	// Find the transactions that involve the programs. The same transaction
+	// can involve more than one of the programs.
+	seen := map[string]bool{}
+	sigs := []SignatureInfo{}
+	for _, program := range filter.Addresses {
+		programSigs, err := client.signatures(ctx, program, filter.FromBlock, filter.ToBlock)
+		if err != nil {
+			return nil, err
+		}
+		for _, sig := range programSigs {
+			if seen[sig.Signature] {
+				continue
+			}
+			seen[sig.Signature] = true
+			sigs = append(sigs, sig)
+		}
+	}
+	// Signatures are returned with the latest first, but logs are returned
+	// with the earliest first.
+	sort.SliceStable(sigs, func(i, j int) bool {
+		return sigs[i].Slot < sigs[j].Slot
+	})
+
+	logs := []contract.Log{}
+	for _, sig := range sigs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		tx, err := client.transaction(sig.Signature)
+		if err != nil {
+			return nil, err
+		}
+		if tx.Meta.Err != nil {
+			continue
+		}
+		for _, log := range ParseProgramLogs(tx.Meta.LogMessages) {
+			if !filter.Match(log) {
+				continue
+			}
+			log.BlockNumber = pack.NewU64(tx.Slot)
+			log.TxHash = pack.NewBytes(base58.Decode(sig.Signature))
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// ParseProgramLogs parses the log messages of a transaction into logs. The
+// index of each log is its position in the log messages. Messages that are not
+// emitted by programs (for example, invocations and compute units) are ignored.
+func ParseProgramLogs(messages []string) []contract.Log {
+	logs := []contract.Log{}
+	programs := []string{}
+	for i, message := range messages {
+		fields := strings.Fields(message)
+		switch {
+		case len(fields) >= 3 && fields[0] == "Program" && fields[2] == "invoke":
+			programs = append(programs, fields[1])
+		case len(fields) >= 3 && fields[0] == "Program" && (fields[2] == "success" || fields[2] == "failed:"):
+			if len(programs) > 0 {
+				programs = programs[:len(programs)-1]
+			}
+		case len(programs) > 0 && strings.HasPrefix(message, "Program log: "):
+			logs = append(logs, contract.Log{
+				Address: address.Address(programs[len(programs)-1]),
+				Topics:  []pack.Bytes{ProgramLogTopic},
+				Data:    pack.Bytes(strings.TrimPrefix(message, "Program log: ")),
+				Index:   pack.NewU32(uint32(i)),
+			})
+		case len(programs) > 0 && strings.HasPrefix(message, "Program data: "):
+			data := []byte{}
+			for _, field := range fields[2:] {
+				decoded, err := base64.StdEncoding.DecodeString(field)
+				if err != nil {
+					continue
+				}
+				data = append(data, decoded...)
+			}
+			logs = append(logs, contract.Log{
+				Address: address.Address(programs[len(programs)-1]),
+				Topics:  []pack.Bytes{ProgramDataTopic},
+				Data:    pack.NewBytes(data),
+				Index:   pack.NewU32(uint32(i)),
+			})
+		}
+	}
+	return logs
+}
+
+// signatures returns the signatures of the successful transactions that
+// involve the program, between the slots (inclusive). A to slot of zero is the
+// latest slot.
+func (client *Client) signatures(ctx context.Context, program address.Address, fromSlot, toSlot pack.U64) ([]SignatureInfo, error) {
+	sigs := []SignatureInfo{}
+	before := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		opts := map[string]interface{}{"limit": DefaultSignaturesLimit}
+		if before != "" {
+			opts["before"] = before
+		}
+		params, err := json.Marshal([]interface{}{string(program), opts})
+		if err != nil {
+			return nil, fmt.Errorf("encoding params: %v", err)
+		}
+		res, err := SendDataWithRetry("getSignaturesForAddress", params, client.opts.RPCURL)
+		if err != nil {
+			return nil, fmt.Errorf("calling rpc method \"getSignaturesForAddress\": %v", err)
+		}
+		if res.Result == nil {
+			return nil, fmt.Errorf("decoding result: empty")
+		}
+		page := []SignatureInfo{}
+		if err := json.Unmarshal(*res.Result, &page); err != nil {
+			return nil, fmt.Errorf("decoding result: %v", err)
+		}
+
+		for _, sig := range page {
+			if sig.Slot < fromSlot.Uint64() {
+				return sigs, nil
+			}
+			if sig.Err != nil || (toSlot != 0 && sig.Slot > toSlot.Uint64()) {
+				continue
+			}
+			sigs = append(sigs, sig)
+		}
+		if len(page) < DefaultSignaturesLimit {
+			return sigs, nil
+		}
+		before = page[len(page)-1].Signature
+	}
+}
+
+// transaction returns the transaction with the signature.
+func (client *Client) transaction(signature string) (ResponseGetTransaction, error) {
+	params := json.RawMessage(fmt.Sprintf(`["%v", {"encoding":"json","maxSupportedTransactionVersion":0}]`, signature))
+	res, err := SendDataWithRetry("getTransaction", params, client.opts.RPCURL)
+	if err != nil {
+		return ResponseGetTransaction{}, fmt.Errorf("calling rpc method \"getTransaction\": %v", err)
+	}
+	if res.Result == nil {
+		return ResponseGetTransaction{}, fmt.Errorf("transaction %v: not found", signature)
+	}
+	tx := ResponseGetTransaction{}
+	if err := json.Unmarshal(*res.Result, &tx); err != nil {
+		return ResponseGetTransaction{}, fmt.Errorf("decoding result: %v", err)
+	}
+	return tx, nil
+}
@@ -0,0 +1,80 @@
+package solana_test
+
+import (
+	"github.com/renproject/multichain/api/address"
+	"github.com/renproject/multichain/api/contract"
+	"github.com/renproject/multichain/chain/solana"
+	"github.com/renproject/pack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logs", func() {
+	const (
+		program = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+		inner   = "ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL"
+	)
+
+	log := func(addr string, topic pack.Bytes, data pack.Bytes, index uint32) contract.Log {
+		return contract.Log{
+			Address: address.Address(addr),
+			Topics:  []pack.Bytes{topic},
+			Data:    data,
+			Index:   pack.NewU32(index),
+		}
+	}
+
+	DescribeTable("when parsing program logs",
+		func(messages []string, expected []contract.Log) {
+			Expect(solana.ParseProgramLogs(messages)).To(Equal(expected))
+		},
+		Entry("should parse messages logged by the invoked program", []string{
+			"Program " + program + " invoke [1]",
+			"Program log: Instruction: Transfer",
+			"Program " + program + " consumed 4645 of 200000 compute units",
+			"Program " + program + " success",
+		}, []contract.Log{
+			log(program, solana.ProgramLogTopic, pack.Bytes("Instruction: Transfer"), 1),
+		}),
+		Entry("should use the innermost program as the address", []string{
+			"Program " + program + " invoke [1]",
+			"Program " + inner + " invoke [2]",
+			"Program log: inner",
+			"Program " + inner + " success",
+			"Program log: outer",
+			"Program " + program + " success",
+		}, []contract.Log{
+			log(inner, solana.ProgramLogTopic, pack.Bytes("inner"), 2),
+			log(program, solana.ProgramLogTopic, pack.Bytes("outer"), 4),
+		}),
+		Entry("should return to the invoking program when an invocation fails", []string{
+			"Program " + program + " invoke [1]",
+			"Program " + inner + " invoke [2]",
+			"Program " + inner + " failed: custom program error: 0x1",
+			"Program log: recovered",
+		}, []contract.Log{
+			log(program, solana.ProgramLogTopic, pack.Bytes("recovered"), 3),
+		}),
+		Entry("should decode program data", []string{
+			"Program " + program + " invoke [1]",
+			"Program data: AQID BAU=",
+			"Program " + program + " success",
+		}, []contract.Log{
+			log(program, solana.ProgramDataTopic, pack.Bytes{1, 2, 3, 4, 5}, 1),
+		}),
+		Entry("should skip program data that is not base64", []string{
+			"Program " + program + " invoke [1]",
+			"Program data: AQID !!!",
+		}, []contract.Log{
+			log(program, solana.ProgramDataTopic, pack.Bytes{1, 2, 3}, 1),
+		}),
+		Entry("should ignore messages outside of programs", []string{
+			"Program log: orphan",
+			"Program " + program + " invoke [1]",
+			"Program " + program + " success",
+			"Program log: after",
+		}, []contract.Log{}),
+	)
+})
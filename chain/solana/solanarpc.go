@@ -21,3 +21,25 @@ type ResponseGetAccountInfo struct {
 	Context AccountContext `json:"context"`
 	Value   AccountValue   `json:"value"`
 }
+
+// SignatureInfo is the JSON-interface of a transaction signature returned by
+// the getSignaturesForAddress query.
+type SignatureInfo struct {
+	Signature string      `json:"signature"`
+	Slot      uint64      `json:"slot"`
+	Err       interface{} `json:"err"`
+}
+
+// TransactionMeta is the JSON-interface of the status metadata of a
+// transaction.
+type TransactionMeta struct {
+	Err         interface{} `json:"err"`
+	LogMessages []string    `json:"logMessages"`
+}
+
+// ResponseGetTransaction is the JSON-interface of the response for the
+// getTransaction query.
+type ResponseGetTransaction struct {
+	Slot uint64          `json:"slot"`
+	Meta TransactionMeta `json:"meta"`
+}